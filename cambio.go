@@ -0,0 +1,85 @@
+// SPDX-FileCopyrightText: 2023 Adriano Prado <dev@dude333.com>
+//
+// SPDX-License-Identifier: MIT
+
+package rapina
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// _diasSemCâmbio é o número máximo de dias antes da data da conversão em que
+// se busca a última cotação da moeda (fins de semana e feriados).
+const _diasSemCâmbio = 10
+
+var ErrSemCâmbio = errors.New("sem cotação da moeda")
+
+// Câmbio é a cotação de uma moeda em reais (ex.: PTAX de venda) numa data.
+type Câmbio struct {
+	Moeda string // US$
+	Data  Data
+	Taxa  Decimal // reais por unidade da moeda
+}
+
+// TabelaCâmbio contém as cotações das moedas usadas na conversão dos valores
+// das empresas que não divulgam seus demonstrativos em reais.
+type TabelaCâmbio struct {
+	cotações map[string][]Câmbio // moeda => cotações em ordem crescente de data
+}
+
+func NovaTabelaCâmbio(cotações []Câmbio) *TabelaCâmbio {
+	t := &TabelaCâmbio{cotações: make(map[string][]Câmbio)}
+	for _, c := range cotações {
+		t.cotações[c.Moeda] = append(t.cotações[c.Moeda], c)
+	}
+	for _, lista := range t.cotações {
+		sort.SliceStable(lista, func(i, j int) bool {
+			return time.Time(lista[i].Data).Before(time.Time(lista[j].Data))
+		})
+	}
+	return t
+}
+
+// Taxa retorna a cotação da moeda em reais na data informada ou, se não
+// houver, a última cotação nos _diasSemCâmbio anteriores. A cotação do real
+// é sempre 1.
+func (t *TabelaCâmbio) Taxa(moeda string, data Data) (Decimal, error) {
+	if moeda == Real {
+		return DecimalDeInt(1), nil
+	}
+	if t != nil {
+		dia := time.Time(data)
+		lista := t.cotações[moeda]
+		i := sort.Search(len(lista), func(i int) bool {
+			return time.Time(lista[i].Data).After(dia)
+		})
+		if i > 0 && !time.Time(lista[i-1].Data).Before(dia.AddDate(0, 0, -_diasSemCâmbio)) {
+			return lista[i-1].Taxa, nil
+		}
+	}
+	return Decimal{}, fmt.Errorf("%w: %s em %s (adicione na seção cambio do arquivo de configuração: - {moeda: %s, data: %s, taxa: <reais por %s>})",
+		ErrSemCâmbio, moeda, data, moeda, data, moeda)
+}
+
+// Converter converte o valor para a moeda informada usando as cotações das
+// duas moedas na data.
+func (t *TabelaCâmbio) Converter(d Dinheiro, moeda string, data Data) (Dinheiro, error) {
+	if d.Moeda == moeda {
+		return d, nil
+	}
+	origem, err := t.Taxa(d.Moeda, data)
+	if err != nil {
+		return d, err
+	}
+	destino, err := t.Taxa(moeda, data)
+	if err != nil {
+		return d, err
+	}
+	// converte primeiro para reais, sem arredondar a razão entre as taxas
+	r := d.Converter(Real, origem)
+	r.Moeda, r.Valor = moeda, r.Valor.Div(destino)
+	return r, nil
+}
@@ -0,0 +1,59 @@
+// SPDX-FileCopyrightText: 2023 Adriano Prado <dev@dude333.com>
+//
+// SPDX-License-Identifier: MIT
+
+package rapina
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTabelaCâmbio(t *testing.T) {
+	data := func(s string) Data {
+		d, err := NovaData(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+	tabela := NovaTabelaCâmbio([]Câmbio{
+		{Moeda: Dólar, Data: data("2023-12-29"), Taxa: dec("4.8413")},
+		{Moeda: Dólar, Data: data("2023-09-29"), Taxa: dec("5.0076")},
+		{Moeda: Euro, Data: data("2023-12-29"), Taxa: dec("5.3516")},
+	})
+
+	tests := []struct {
+		moeda string
+		data  string
+		want  Decimal
+		err   error
+	}{
+		{Dólar, "2023-12-29", dec("4.8413"), nil},
+		{Dólar, "2023-12-31", dec("4.8413"), nil}, // fim de semana: última cotação
+		{Dólar, "2023-09-30", dec("5.0076"), nil},
+		{Dólar, "2023-06-30", Decimal{}, ErrSemCâmbio}, // sem cotação próxima
+		{Dólar, "2023-12-28", Decimal{}, ErrSemCâmbio},
+		{Real, "2000-01-01", dec("1"), nil},
+		{"IENE", "2023-12-29", Decimal{}, ErrSemCâmbio},
+	}
+	for _, tt := range tests {
+		got, err := tabela.Taxa(tt.moeda, data(tt.data))
+		if got != tt.want || !errors.Is(err, tt.err) {
+			t.Errorf("Taxa(%s, %s) = %s, %v, want %s, %v", tt.moeda, tt.data, got, err, tt.want, tt.err)
+		}
+	}
+
+	usd := Dinheiro{Moeda: Dólar, Valor: dec("1000"), Escala: 1000}
+	got, err := tabela.Converter(usd, Real, data("2023-12-31"))
+	if err != nil || got != (Dinheiro{Moeda: Real, Valor: dec("4841.3"), Escala: 1000}) {
+		t.Errorf("Converter(US$ => R$) = %+v, %v", got, err)
+	}
+	got, err = tabela.Converter(usd, Euro, data("2023-12-29"))
+	if err != nil || got.Moeda != Euro || got.Valor != dec("904.6453397115") {
+		t.Errorf("Converter(US$ => €) = %+v, %v", got, err)
+	}
+	if _, err := (*TabelaCâmbio)(nil).Converter(usd, Real, data("2023-12-29")); !errors.Is(err, ErrSemCâmbio) {
+		t.Errorf("Converter() sem tabela error = %v, want %v", err, ErrSemCâmbio)
+	}
+}
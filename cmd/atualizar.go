@@ -5,15 +5,30 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"runtime"
 	"time"
 
 	"github.com/dude333/rapinav2/pkg/contabil"
+	"github.com/dude333/rapinav2/pkg/contabil/repositorio"
 	"github.com/dude333/rapinav2/pkg/progress"
 	"github.com/spf13/cobra"
 )
 
 type flagsAtualizar struct {
-	ano int
+	ano       int
+	arquivos  []string // arquivos da CVM já baixados (zip ou csv)
+	dir       string   // diretório com arquivos da CVM já baixados
+	jobs      int      // arquivos CSV processados em paralelo
+	downloads int      // arquivos baixados em paralelo
+	retomar   bool     // continuar a última atualização interrompida
+	relatorio bool     // exibir os registros descartados na última atualização
+	json      bool     // relatório da atualização em JSON
 }
 
 // atualizarCmd represents the atualizar command
@@ -27,6 +42,13 @@ var atualizarCmd = &cobra.Command{
 
 func init() {
 	atualizarCmd.Flags().IntVarP(&flags.atualizar.ano, "ano", "a", 0, "Ano do relatório")
+	atualizarCmd.Flags().StringSliceVar(&flags.atualizar.arquivos, "arquivo", nil, "Importar arquivo da CVM (zip ou csv) já existente no disco")
+	atualizarCmd.Flags().StringVar(&flags.atualizar.dir, "dir", "", "Importar os arquivos da CVM (zip ou csv) existentes no diretório")
+	atualizarCmd.Flags().IntVarP(&flags.atualizar.jobs, "jobs", "j", runtime.NumCPU(), "Número de arquivos processados em paralelo")
+	atualizarCmd.Flags().IntVar(&flags.atualizar.downloads, "downloads", 2, "Número de arquivos baixados em paralelo")
+	atualizarCmd.Flags().BoolVar(&flags.atualizar.retomar, "retomar", false, "Continuar a última atualização interrompida")
+	atualizarCmd.Flags().BoolVar(&flags.atualizar.relatorio, "relatorio-importacao", false, "Exibir os registros descartados na última atualização, sem atualizar")
+	atualizarCmd.Flags().BoolVar(&flags.atualizar.json, "json", false, "Exibir o relatório da atualização em JSON (com --relatorio-importacao)")
 
 	rootCmd.AddCommand(atualizarCmd)
 }
@@ -42,22 +64,117 @@ func atualizar(_ *cobra.Command, _ []string) {
 		anof = anoi
 	}
 
-	dfp, err := contabil.NovaDemonstraçãoFinanceira(db(), flags.tempDir)
+	dfp, err := contabil.NovaDemonstraçãoFinanceira(db(), flags.tempDir,
+		repositorio.CfgJobs(flags.atualizar.jobs),
+		repositorio.CfgDownloads(flags.atualizar.downloads),
+		repositorio.CfgURLBase(flags.urlCVM),
+	)
 	if err != nil {
 		panic(err)
 	}
 
-	importar := func(trimestral bool) {
+	// Ctrl+C interrompe a importação, que pode ser continuada com --retomar
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	switch {
+	case flags.atualizar.relatorio:
+		exibirRelatórioImportação(ctx, dfp)
+		return
+	case flags.atualizar.retomar:
+		err = dfp.Retomar(ctx)
+		if errors.Is(err, repositorio.ErrNenhumaImportação) {
+			progress.Status("Nenhuma atualização interrompida")
+			return
+		}
+	case len(flags.atualizar.arquivos) > 0 || flags.atualizar.dir != "":
+		atualizarArquivos(dfp)
+		return
+	default:
+		var anos []int
 		for ano := anof; ano >= anoi; ano-- {
-			err := dfp.Importar(ano, trimestral)
-			if err != nil {
-				progress.Error(err)
-				continue
-			}
+			anos = append(anos, ano)
+		}
+		err = dfp.ImportarAnos(ctx, anos)
+	}
+	if errors.Is(err, context.Canceled) {
+		progress.FatalMsg("Atualização interrompida. Para continuar: rapinav2 atualizar --retomar")
+	}
+	if errors.Is(err, contabil.ErrImportaçãoIncompleta) {
+		progress.FatalMsg("%v. Para concluir: rapinav2 atualizar --retomar", err)
+	}
+	falhou := err != nil
+	if falhou {
+		progress.Error(err)
+	}
+	resumirImportação(ctx, dfp)
+
+	if err := dfp.ImportarCadastro(); err != nil {
+		progress.Error(err)
+		falhou = true
+	}
+	if falhou {
+		os.Exit(1)
+	}
+}
+
+// exibirRelatórioImportação mostra os registros descartados na última
+// atualização, em texto ou em JSON (--json).
+func exibirRelatórioImportação(ctx context.Context, dfp *contabil.DemonstraçãoFinanceira) {
+	r, err := dfp.RelatórioImportação(ctx, 0)
+	if err != nil {
+		progress.Fatal(err)
+	}
+
+	if flags.atualizar.json {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(r); err != nil {
+			progress.Fatal(err)
+		}
+		return
+	}
+	fmt.Print(r)
+}
+
+// resumirImportação informa quantos registros foram descartados na última
+// atualização.
+func resumirImportação(ctx context.Context, dfp *contabil.DemonstraçãoFinanceira) {
+	r, err := dfp.RelatórioImportação(ctx, 0)
+	if err != nil {
+		progress.Error(err)
+		return
+	}
+	if r.Ignoradas+r.Rejeitadas == 0 {
+		return
+	}
+	progress.Status("%d linhas lidas, %d registros ignorados, %d rejeitados. Detalhes: rapinav2 atualizar --relatorio-importacao",
+		r.Lidas, r.Ignoradas, r.Rejeitadas)
+}
+
+// atualizarArquivos importa os arquivos da CVM já existentes no disco,
+// informados por --arquivo e/ou --dir.
+func atualizarArquivos(dfp *contabil.DemonstraçãoFinanceira) {
+	arquivos := flags.atualizar.arquivos
+	if flags.atualizar.dir != "" {
+		arqs, err := listarArquivos(flags.atualizar.dir, ".zip", ".csv")
+		if err != nil {
+			progress.Fatal(err)
 		}
+		arquivos = append(arquivos, arqs...)
 	}
 
-	importar(false)
-	importar(true)
+	if len(arquivos) == 0 {
+		progress.Warning("Nenhum arquivo encontrado")
+		return
+	}
 
+	err := dfp.ImportarArquivos(arquivos)
+	if errors.Is(err, contabil.ErrImportaçãoIncompleta) {
+		progress.FatalMsg("%v. Para concluir: rapinav2 atualizar --retomar", err)
+	}
+	if err != nil {
+		progress.Fatal(err)
+	}
+	resumirImportação(context.Background(), dfp)
 }
@@ -0,0 +1,100 @@
+// SPDX-FileCopyrightText: 2023 Adriano Prado <dev@dude333.com>
+//
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/dustin/go-humanize"
+	"github.com/spf13/cobra"
+
+	ext "github.com/dude333/rapinav2/pkg/infra"
+	"github.com/dude333/rapinav2/pkg/progress"
+)
+
+type flagsCache struct {
+	dir   string // diretório do cache
+	maxMB int64  // tamanho máximo do cache em MB (0 = ilimitado)
+}
+
+// cacheCmd represents the cache command
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manutenção do cache de downloads",
+	Long: `Os arquivos baixados da CVM e da B3 são mantidos no cache (configurável com
+cacheDir e cacheMaxMB no arquivo de configuração) e só são baixados novamente
+se forem alterados no site de origem.`,
+}
+
+var cacheListarCmd = &cobra.Command{
+	Use:   "listar",
+	Short: "Listar os arquivos do cache",
+	Args:  cobra.NoArgs,
+	Run:   listarCache,
+}
+
+var cacheLimparCmd = &cobra.Command{
+	Use:   "limpar",
+	Short: "Apagar todos os arquivos do cache",
+	Args:  cobra.NoArgs,
+	Run:   limparCache,
+}
+
+var cacheVerificarCmd = &cobra.Command{
+	Use:   "verificar",
+	Short: "Verificar a integridade dos arquivos do cache",
+	Long: `Verificar o hash dos arquivos do cache, apagando os arquivos corrompidos,
+que serão baixados novamente na próxima atualização.`,
+	Args: cobra.NoArgs,
+	Run:  verificarCache,
+}
+
+func init() {
+	cacheCmd.AddCommand(cacheListarCmd, cacheLimparCmd, cacheVerificarCmd)
+	rootCmd.AddCommand(cacheCmd)
+}
+
+func abrirCache() *ext.Cache {
+	cache, err := ext.NewCache(flags.cache.dir, flags.cache.maxMB*1024*1024)
+	if err != nil {
+		progress.Fatal(err)
+	}
+	return cache
+}
+
+func listarCache(_ *cobra.Command, _ []string) {
+	entradas, err := abrirCache().Entries()
+	if err != nil {
+		progress.Fatal(err)
+	}
+
+	var total int64
+	for _, e := range entradas {
+		fmt.Printf("%8s  %s  %s\n", humanize.Bytes(uint64(e.Size)), e.Downloaded.Format("2006-01-02 15:04"), e.URL)
+		total += e.Size
+	}
+	progress.Status("%d arquivos, %s em %s", len(entradas), humanize.Bytes(uint64(total)), flags.cache.dir)
+}
+
+func limparCache(_ *cobra.Command, _ []string) {
+	n, tamanho, err := abrirCache().Clear()
+	if err != nil {
+		progress.Fatal(err)
+	}
+	progress.Status("%d arquivos apagados (%s)", n, humanize.Bytes(uint64(tamanho)))
+}
+
+func verificarCache(_ *cobra.Command, _ []string) {
+	ruins, err := abrirCache().Verify()
+	for _, e := range ruins {
+		progress.Warning("Arquivo corrompido apagado: %s", e.URL)
+	}
+	if err != nil {
+		progress.Fatal(err)
+	}
+	if len(ruins) == 0 {
+		progress.Status("Nenhum arquivo corrompido")
+	}
+}
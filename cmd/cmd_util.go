@@ -9,6 +9,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/chzyer/readline"
 	"github.com/manifoldco/promptui"
@@ -49,17 +50,19 @@ func escolherEmpresa(empresas []rapina.Empresa) (rapina.Empresa, bool) {
 --------------------------------------
 | {{ "Name:" | bold }}	{{ .Nome }}
 | {{ "CNPJ:" | faint }}	{{ .CNPJ }}
+| {{ "Setor:" | faint }}	{{ .Setor }}
 ------------------------------------------`,
 	}
 
 	// A searcher function is implemented which enabled the search mode for the select. The function follows
-	// the required searcher signature and finds any pepper whose name contains the searched string.
+	// the required searcher signature and finds any company whose name or sector contains the searched string.
 	searcher := func(input string, index int) bool {
 		empresa := empresas[index]
 		nome := rapina.NormalizeString(empresa.Nome)
+		setor := rapina.NormalizeString(empresa.Setor)
 		ninput := rapina.NormalizeString(input)
 
-		return strings.Contains(nome, ninput)
+		return strings.Contains(nome, ninput) || strings.Contains(setor, ninput)
 	}
 
 	prompt := promptui.Select{
@@ -79,6 +82,25 @@ func escolherEmpresa(empresas []rapina.Empresa) (rapina.Empresa, bool) {
 	return empresas[i], true
 }
 
+var _arquivosMu sync.Mutex
+
+// reservarArquivo retorna o nome de um arquivo novo (ver prepareFilename),
+// criando-o vazio para que outra goroutine não use o mesmo nome.
+func reservarArquivo(path, name string) (string, error) {
+	_arquivosMu.Lock()
+	defer _arquivosMu.Unlock()
+
+	fpath, err := prepareFilename(path, name)
+	if err != nil {
+		return "", err
+	}
+	fh, err := os.Create(fpath)
+	if err != nil {
+		return "", err
+	}
+	return fpath, fh.Close()
+}
+
 // prepareFilename cleans up the filename and returns the path/filename
 func prepareFilename(path, name string) (fpath string, err error) {
 	clean := func(r rune) rune {
@@ -124,3 +146,26 @@ func prepareFilename(path, name string) (fpath string, err error) {
 
 	return
 }
+
+// listarArquivos retorna os arquivos do diretório dir, e de seus
+// subdiretórios, com uma das extensões informadas, em ordem alfabética.
+func listarArquivos(dir string, extensões ...string) ([]string, error) {
+	var arquivos []string
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		ext := filepath.Ext(path)
+		for _, e := range extensões {
+			if strings.EqualFold(ext, e) {
+				arquivos = append(arquivos, path)
+				break
+			}
+		}
+		return nil
+	})
+	return arquivos, err
+}
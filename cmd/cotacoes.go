@@ -0,0 +1,227 @@
+// SPDX-FileCopyrightText: 2023 Adriano Prado <dev@dude333.com>
+//
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	repositório "github.com/dude333/rapinav2/pkg/cotacao/repositorio"
+	serviço "github.com/dude333/rapinav2/pkg/cotacao/servico"
+	"github.com/dude333/rapinav2/pkg/progress"
+)
+
+type flagsCotacoes struct {
+	anos     string // ano ou intervalo de anos (ex.: 2015..2024)
+	mês      int
+	bdi      []string
+	mercados []string
+	eventos  string   // arquivo CSV ou YAML com eventos corporativos
+	detectar []string // códigos dos ativos para a detecção de eventos
+	salvar   bool     // salvar os eventos detectados sem revisão
+}
+
+// cotacoesCmd represents the cotacoes command
+var cotacoesCmd = &cobra.Command{
+	Use:     "cotacoes",
+	Aliases: []string{"quotes"},
+	Short:   "Cotações dos ativos negociados na B3",
+	Long:    `Cotações dos ativos negociados na B3`,
+}
+
+// cotacoesAtualizarCmd represents the cotacoes atualizar command
+var cotacoesAtualizarCmd = &cobra.Command{
+	Use:     "atualizar",
+	Aliases: []string{"update"},
+	Short:   "Atualizar as cotações do banco de dados",
+	Long: `Atualizar o banco de dados com as séries históricas de cotações da B3,
+usando os arquivos anuais (ou mensais, com --mes)`,
+	Run: atualizarCotações,
+}
+
+// cotacoesEventosCmd represents the cotacoes eventos command
+var cotacoesEventosCmd = &cobra.Command{
+	Use:     "eventos",
+	Aliases: []string{"events"},
+	Short:   "Cadastrar eventos corporativos",
+	Long: `Cadastrar os eventos corporativos (desdobramentos, grupamentos,
+bonificações, dividendos e JCP) usados no ajuste das cotações, a partir de um
+arquivo CSV ou YAML (--arquivo) ou detectando desdobramentos e grupamentos nas
+cotações já importadas (--detectar).
+
+Os eventos detectados são estimados pelos saltos de preço e apenas listados,
+no formato do arquivo CSV, para que sejam revisados e cadastrados com
+--arquivo. Use --salvar-detectados para cadastrá-los sem revisão.`,
+	Run: cadastrarEventos,
+}
+
+func init() {
+	ano := strconv.Itoa(time.Now().Year())
+	cotacoesAtualizarCmd.Flags().StringVarP(&flags.cotacoes.anos, "ano", "a", ano, "Ano ou intervalo de anos (ex.: 2015..2024)")
+	cotacoesAtualizarCmd.Flags().IntVarP(&flags.cotacoes.mês, "mes", "m", 0, "Mês (usa os arquivos mensais dos anos informados)")
+	cotacoesAtualizarCmd.Flags().StringSliceVar(&flags.cotacoes.bdi, "bdi", nil, "Códigos BDI a importar (default 02,12,96; \"todos\" = sem filtro)")
+	cotacoesAtualizarCmd.Flags().StringSliceVar(&flags.cotacoes.mercados, "mercado", nil, "Tipos de mercado a importar (default 010,020; \"todos\" = sem filtro). Sem --bdi, usa os BDIs dos mercados (ex.: 070 => 78)")
+
+	cotacoesEventosCmd.Flags().StringVar(&flags.cotacoes.eventos, "arquivo", "", "Arquivo CSV ou YAML com os eventos (codigo;data_ex;tipo;fator;valor)")
+	cotacoesEventosCmd.Flags().StringSliceVar(&flags.cotacoes.detectar, "detectar", nil, "Códigos dos ativos para detectar desdobramentos e grupamentos")
+	cotacoesEventosCmd.Flags().BoolVar(&flags.cotacoes.salvar, "salvar-detectados", false, "Cadastrar os eventos detectados sem revisão")
+
+	cotacoesCmd.AddCommand(cotacoesAtualizarCmd)
+	cotacoesCmd.AddCommand(cotacoesEventosCmd)
+	rootCmd.AddCommand(cotacoesCmd)
+}
+
+func atualizarCotações(_ *cobra.Command, _ []string) {
+	anoi, anof, err := intervaloAnos(flags.cotacoes.anos)
+	if err != nil {
+		progress.Fatal(err)
+	}
+
+	bd, err := repositório.NovoSqlite(db())
+	if err != nil {
+		progress.Fatal(err)
+	}
+	configs := []repositório.ConfigFn{repositório.CfgURLBase(flags.urlB3)}
+	if len(flags.cotacoes.bdi) > 0 {
+		configs = append(configs, repositório.CfgBDI(filtro(flags.cotacoes.bdi)...))
+	}
+	if len(flags.cotacoes.mercados) > 0 {
+		mercados := filtro(flags.cotacoes.mercados)
+		configs = append(configs, repositório.CfgMercados(mercados...))
+		if len(flags.cotacoes.bdi) == 0 {
+			configs = append(configs, repositório.CfgBDI(bdiMercados(mercados)...))
+		}
+	}
+	api := []serviço.Importação{repositório.NovoB3(flags.tempDir, configs...)}
+	s := serviço.NovoServiço(api, bd)
+
+	falhas := 0
+	for ano := anof; ano >= anoi; ano-- {
+		var n int
+		if flags.cotacoes.mês > 0 {
+			progress.Status("Cotações de %02d/%d", flags.cotacoes.mês, ano)
+			n, err = s.ImportarMês(ano, flags.cotacoes.mês)
+		} else {
+			progress.Status("Cotações de %d", ano)
+			n, err = s.ImportarAno(ano)
+		}
+		if err != nil {
+			progress.Error(err)
+			falhas++
+			continue
+		}
+		progress.Status("%d cotações salvas", n)
+	}
+
+	if falhas > 0 {
+		progress.FatalMsg("%d ano(s) não importado(s)", falhas)
+	}
+}
+
+func cadastrarEventos(cmd *cobra.Command, _ []string) {
+	if flags.cotacoes.eventos == "" && len(flags.cotacoes.detectar) == 0 {
+		_ = cmd.Help()
+		return
+	}
+
+	bd, err := repositório.NovoSqlite(db())
+	if err != nil {
+		progress.Fatal(err)
+	}
+	s := serviço.NovoServiço(nil, bd)
+
+	if flags.cotacoes.eventos != "" {
+		eventos, err := repositório.LerEventos(flags.cotacoes.eventos)
+		if err != nil {
+			progress.Fatal(err)
+		}
+		if err := s.SalvarEventos(eventos); err != nil {
+			progress.Fatal(err)
+		}
+		progress.Status("%d evento(s) cadastrado(s)", len(eventos))
+	}
+
+	if len(flags.cotacoes.detectar) > 0 {
+		fmt.Println("codigo;data_ex;tipo;fator;valor")
+	}
+	for _, código := range flags.cotacoes.detectar {
+		código = strings.ToUpper(código)
+		eventos, err := s.DetectarEventos(código)
+		if err != nil {
+			progress.Error(err)
+			continue
+		}
+		for _, e := range eventos {
+			fmt.Printf("%s;%s;%s;%g;%g\n", e.Código, e.DataEx, e.Tipo, e.Fator, e.Valor)
+		}
+		if !flags.cotacoes.salvar || len(eventos) == 0 {
+			progress.Status("%s: %d evento(s) detectado(s), não cadastrado(s)", código, len(eventos))
+			continue
+		}
+		if err := s.SalvarEventos(eventos); err != nil {
+			progress.Error(err)
+			continue
+		}
+		progress.Status("%s: %d evento(s) detectado(s) e cadastrado(s)", código, len(eventos))
+	}
+}
+
+// intervaloAnos converte "AAAA" ou "AAAA..AAAA" no ano inicial e final.
+func intervaloAnos(s string) (int, int, error) {
+	partes := strings.SplitN(s, "..", 2)
+	anoi, err := strconv.Atoi(strings.TrimSpace(partes[0]))
+	if err != nil {
+		return 0, 0, fmt.Errorf("ano inválido: %s", s)
+	}
+	anof := anoi
+	if len(partes) == 2 {
+		anof, err = strconv.Atoi(strings.TrimSpace(partes[1]))
+		if err != nil {
+			return 0, 0, fmt.Errorf("ano inválido: %s", s)
+		}
+	}
+	if anoi > anof {
+		return 0, 0, fmt.Errorf("intervalo de anos inválido: %s", s)
+	}
+	return anoi, anof, nil
+}
+
+// _bdiMercado contém os códigos BDI dos registros de cada tipo de mercado,
+// usados quando --mercado é informado sem --bdi.
+var _bdiMercado = map[string][]string{
+	"010": {"02", "12"}, // lote padrão e FIIs
+	"020": {"96"},       // fracionário
+	"070": {"78"},       // opções de compra
+	"080": {"82"},       // opções de venda
+}
+
+// bdiMercados retorna os códigos BDI dos tipos de mercado informados, ou
+// nenhum (sem filtro) se algum mercado não estiver em _bdiMercado.
+func bdiMercados(mercados []string) []string {
+	var bdi []string
+	for _, m := range mercados {
+		códigos, ok := _bdiMercado[m]
+		if !ok {
+			return nil
+		}
+		bdi = append(bdi, códigos...)
+	}
+	return bdi
+}
+
+// filtro retorna os códigos informados na linha de comando, ou nenhum código
+// (sem filtro) se "todos" for informado.
+func filtro(códigos []string) []string {
+	for _, c := range códigos {
+		if strings.EqualFold(c, "todos") {
+			return nil
+		}
+	}
+	return códigos
+}
@@ -0,0 +1,52 @@
+// SPDX-FileCopyrightText: 2023 Adriano Prado <dev@dude333.com>
+//
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_intervaloAnos(t *testing.T) {
+	tests := []struct {
+		s          string
+		anoi, anof int
+		wantErr    bool
+	}{
+		{s: "2021", anoi: 2021, anof: 2021},
+		{s: "2015..2024", anoi: 2015, anof: 2024},
+		{s: "2024..2015", wantErr: true},
+		{s: "2015-2024", wantErr: true},
+		{s: "2015..", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			anoi, anof, err := intervaloAnos(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("intervaloAnos() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if anoi != tt.anoi || anof != tt.anof {
+				t.Errorf("intervaloAnos() = %d, %d, want %d, %d", anoi, anof, tt.anoi, tt.anof)
+			}
+		})
+	}
+}
+
+func Test_bdiMercados(t *testing.T) {
+	tests := []struct {
+		mercados []string
+		want     []string
+	}{
+		{[]string{"070", "080"}, []string{"78", "82"}},
+		{[]string{"010"}, []string{"02", "12"}},
+		{[]string{"070", "030"}, nil}, // mercado sem BDI conhecido: sem filtro
+		{nil, nil},
+	}
+	for _, tt := range tests {
+		if got := bdiMercados(tt.mercados); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("bdiMercados(%v) = %v, want %v", tt.mercados, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+// SPDX-FileCopyrightText: 2023 Adriano Prado <dev@dude333.com>
+//
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/spf13/cobra"
+
+	"github.com/dude333/rapinav2/pkg/contabil/repositorio"
+	cotRepositório "github.com/dude333/rapinav2/pkg/cotacao/repositorio"
+	ext "github.com/dude333/rapinav2/pkg/infra"
+	"github.com/dude333/rapinav2/pkg/progress"
+)
+
+type flagsDB struct {
+	simular bool
+	para    int
+	módulo  string
+}
+
+// dbCmd represents the db command
+var dbCmd = &cobra.Command{
+	Use:   "db",
+	Short: "Manutenção do banco de dados",
+	Long:  `Comandos de manutenção do banco de dados local`,
+}
+
+// migrateCmd represents the db migrate command
+var migrateCmd = &cobra.Command{
+	Use:     "migrate",
+	Aliases: []string{"migrar"},
+	Short:   "Migrar o esquema do banco de dados",
+	Long: `Atualizar (ou reverter, com --to) o esquema do banco de dados mantendo
+os dados já importados`,
+	Run: migrar,
+}
+
+func init() {
+	migrateCmd.Flags().BoolVarP(&flags.db.simular, "dry-run", "n", false, "Apenas mostrar as alterações, sem executá-las")
+	migrateCmd.Flags().IntVar(&flags.db.para, "to", -1, "Versão de destino (default = última versão); exige --module")
+	migrateCmd.Flags().StringVar(&flags.db.módulo, "module", "", "Migrar apenas o módulo informado: contabil ou cotacao (default = todos)")
+
+	dbCmd.AddCommand(migrateCmd)
+	rootCmd.AddCommand(dbCmd)
+}
+
+func migrar(_ *cobra.Command, _ []string) {
+	if flags.db.para >= 0 && flags.db.módulo == "" {
+		progress.FatalMsg("--to exige --module, pois cada módulo tem sua própria numeração de versões")
+	}
+	if err := migrarBD(db(), flags.db.módulo, flags.db.para, flags.db.simular); err != nil {
+		progress.Fatal(err)
+	}
+}
+
+// móduloBD agrupa as funções de migração de cada módulo com esquema próprio.
+type móduloBD struct {
+	nome   string
+	versão func(*sqlx.DB) (int, error)
+	migrar func(*sqlx.DB, int, bool) ([]ext.MigrationStep, error)
+}
+
+var módulosBD = []móduloBD{
+	{"contabil", repositorio.VersãoAplicada, repositorio.Migrar},
+	{"cotacao", cotRepositório.VersãoAplicada, cotRepositório.Migrar},
+}
+
+// migrarBD move o esquema de cada módulo (ou apenas de "módulo", se
+// informado) para a versão "para" (negativo = última versão). Com
+// simular == true apenas lista os passos, sem alterar o banco de dados.
+func migrarBD(bd *sqlx.DB, módulo string, para int, simular bool) error {
+	encontrado := false
+	for _, m := range módulosBD {
+		if módulo != "" && m.nome != módulo {
+			continue
+		}
+		encontrado = true
+
+		atual, err := m.versão(bd)
+		if err != nil {
+			return err
+		}
+		progress.Status("Versão atual do esquema (%s): %d", m.nome, atual)
+
+		passos, err := m.migrar(bd, para, simular)
+		for _, p := range passos {
+			direção := "up"
+			if !p.Up {
+				direção = "down"
+			}
+			progress.Status("%s v%d (%s): %s", direção, p.Version, p.Module, p.Descr)
+			if simular {
+				for _, query := range p.SQL {
+					progress.Status("    %s", strings.Join(strings.Fields(query), " "))
+				}
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", m.nome, err)
+		}
+
+		if len(passos) == 0 {
+			progress.Status("Nenhuma migração a executar (%s)", m.nome)
+		}
+	}
+	if !encontrado {
+		return fmt.Errorf("módulo inexistente: %s", módulo)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+// SPDX-FileCopyrightText: 2023 Adriano Prado <dev@dude333.com>
+//
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func Test_migrarBD_simular(t *testing.T) {
+	bd := sqlx.MustConnect("sqlite3", ":memory:")
+	bd.SetMaxOpenConns(1)
+	defer bd.Close()
+
+	objetos := func() []string {
+		var nomes []string
+		if err := bd.Select(&nomes, `SELECT name FROM sqlite_master ORDER BY name`); err != nil {
+			t.Fatal(err)
+		}
+		return nomes
+	}
+
+	antes := objetos()
+	if err := migrarBD(bd, "", -1, true); err != nil {
+		t.Fatalf("migrarBD() error = %v", err)
+	}
+	if depois := objetos(); len(depois) != len(antes) {
+		t.Errorf("sqlite_master alterado na simulação: antes %v, depois %v", antes, depois)
+	}
+
+	if err := migrarBD(bd, "", -1, false); err != nil {
+		t.Fatalf("migrarBD() error = %v", err)
+	}
+	depois := objetos()
+	for _, tabela := range []string{"contas", "cotacoes"} {
+		if !contém(depois, tabela) {
+			t.Errorf("tabela %s não criada pela migração: %v", tabela, depois)
+		}
+	}
+
+	if err := migrarBD(bd, "inexistente", -1, true); err == nil {
+		t.Error("migrarBD() com módulo inexistente deveria retornar erro")
+	}
+}
+
+func contém(lista []string, s string) bool {
+	for _, x := range lista {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,124 @@
+// SPDX-FileCopyrightText: 2023 Adriano Prado <dev@dude333.com>
+//
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	rapina "github.com/dude333/rapinav2"
+	"github.com/dude333/rapinav2/pkg/contabil"
+	"github.com/dude333/rapinav2/pkg/progress"
+)
+
+type flagsEmpresas struct {
+	setor      string
+	canceladas bool // incluir as empresas com registro cancelado
+}
+
+// empresasCmd represents the empresas command
+var empresasCmd = &cobra.Command{
+	Use:   "empresas",
+	Short: "Listar as empresas por setor",
+	Long: `Listar as empresas com demonstrações financeiras no banco de dados,
+agrupadas pelo setor de atividade do cadastro de companhias abertas da CVM
+(importado por "rapinav2 atualizar")`,
+	Args: cobra.NoArgs,
+	Run:  listarEmpresas,
+}
+
+func init() {
+	empresasCmd.Flags().StringVar(&flags.empresas.setor, "setor", "", "Listar apenas as empresas do setor (ou início do nome do setor)")
+	empresasCmd.Flags().BoolVar(&flags.empresas.canceladas, "canceladas", false, "Incluir as empresas com registro cancelado na CVM")
+
+	rootCmd.AddCommand(empresasCmd)
+}
+
+func listarEmpresas(_ *cobra.Command, _ []string) {
+	dfp, err := contabil.NovaDemonstraçãoFinanceira(db(), flags.tempDir)
+	if err != nil {
+		progress.Fatal(err)
+	}
+
+	empresas, err := dfp.Empresas()
+	if err != nil {
+		progress.Fatal(err)
+	}
+	if !flags.empresas.canceladas {
+		empresas = ativas(empresas)
+	}
+	if flags.empresas.setor != "" {
+		empresas = doSetor(empresas, flags.empresas.setor)
+	}
+
+	for _, grupo := range porSetor(empresas) {
+		fmt.Printf("\n%s (%d)\n", grupo.setor, len(grupo.empresas))
+		for _, e := range grupo.empresas {
+			fmt.Printf("  %-18s  %-6s  %s\n", e.CNPJ, e.CódigoCVM, e.Nome)
+		}
+	}
+}
+
+// ativas retorna as empresas cujo registro na CVM não foi cancelado.
+func ativas(empresas []rapina.Empresa) []rapina.Empresa {
+	var ret []rapina.Empresa
+	for _, e := range empresas {
+		if !e.Cancelada() {
+			ret = append(ret, e)
+		}
+	}
+	return ret
+}
+
+// doSetor retorna as empresas cujo setor começa com o texto informado,
+// ignorando maiúsculas, acentos e espaços.
+func doSetor(empresas []rapina.Empresa, setor string) []rapina.Empresa {
+	setor = rapina.NormalizeString(setor)
+	var ret []rapina.Empresa
+	for _, e := range empresas {
+		if s := rapina.NormalizeString(e.Setor); s != "" && strings.HasPrefix(s, setor) {
+			ret = append(ret, e)
+		}
+	}
+	return ret
+}
+
+type grupoSetor struct {
+	setor    string
+	empresas []rapina.Empresa
+}
+
+// porSetor agrupa as empresas por setor, em ordem alfabética, com as
+// empresas sem setor no fim.
+func porSetor(empresas []rapina.Empresa) []grupoSetor {
+	const semSetor = "Sem setor"
+
+	índice := make(map[string]int)
+	var grupos []grupoSetor
+	for _, e := range empresas {
+		setor := e.Setor
+		if setor == "" {
+			setor = semSetor
+		}
+		i, ok := índice[setor]
+		if !ok {
+			i = len(grupos)
+			índice[setor] = i
+			grupos = append(grupos, grupoSetor{setor: setor})
+		}
+		grupos[i].empresas = append(grupos[i].empresas, e)
+	}
+
+	sort.SliceStable(grupos, func(i, j int) bool {
+		if (grupos[i].setor == semSetor) != (grupos[j].setor == semSetor) {
+			return grupos[j].setor == semSetor
+		}
+		return rapina.NormalizeString(grupos[i].setor) < rapina.NormalizeString(grupos[j].setor)
+	})
+	return grupos
+}
@@ -0,0 +1,42 @@
+// SPDX-FileCopyrightText: 2023 Adriano Prado <dev@dude333.com>
+//
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"testing"
+
+	rapina "github.com/dude333/rapinav2"
+)
+
+func Test_porSetor(t *testing.T) {
+	empresas := []rapina.Empresa{
+		{Nome: "A", Setor: "Energia Elétrica"},
+		{Nome: "B"},
+		{Nome: "C", Setor: "Bancos", DataCancelamento: "2020-01-01"},
+		{Nome: "D", Setor: "Energia Elétrica"},
+		{Nome: "E", Setor: "Bancos"},
+	}
+
+	grupos := porSetor(empresas)
+	want := []struct {
+		setor string
+		n     int
+	}{{"Bancos", 2}, {"Energia Elétrica", 2}, {"Sem setor", 1}}
+	if len(grupos) != len(want) {
+		t.Fatalf("porSetor() = %+v", grupos)
+	}
+	for i, w := range want {
+		if grupos[i].setor != w.setor || len(grupos[i].empresas) != w.n {
+			t.Errorf("porSetor()[%d] = %s (%d), want %s (%d)", i, grupos[i].setor, len(grupos[i].empresas), w.setor, w.n)
+		}
+	}
+
+	if got := doSetor(ativas(empresas), "energia eletrica"); len(got) != 2 {
+		t.Errorf("doSetor(energia) = %+v", got)
+	}
+	if got := doSetor(ativas(empresas), "banco"); len(got) != 1 || got[0].Nome != "E" {
+		t.Errorf("doSetor(banco) = %+v", got)
+	}
+}
@@ -0,0 +1,99 @@
+// SPDX-FileCopyrightText: 2023 Adriano Prado <dev@dude333.com>
+//
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/spf13/cobra"
+
+	"github.com/dude333/rapinav2/pkg/contabil"
+	"github.com/dude333/rapinav2/pkg/contabil/dominio"
+	"github.com/dude333/rapinav2/pkg/progress"
+)
+
+type flagsReapresentacoes struct {
+	cnpj string
+}
+
+// reapresentacoesCmd represents the reapresentacoes command
+var reapresentacoesCmd = &cobra.Command{
+	Use:   "reapresentacoes",
+	Short: "Listar as contas alteradas nas reapresentações",
+	Long: `Listar as contas das DFPs e ITRs da empresa cujo valor foi alterado
+em uma nova versão do documento (reapresentação), comparando cada versão
+com a anterior. Os relatórios usam sempre a versão mais recente.`,
+	Args: cobra.NoArgs,
+	Run:  listarReapresentações,
+}
+
+func init() {
+	reapresentacoesCmd.Flags().StringVar(&flags.reapres.cnpj, "cnpj", "", "CNPJ da empresa")
+	_ = reapresentacoesCmd.MarkFlagRequired("cnpj")
+
+	rootCmd.AddCommand(reapresentacoesCmd)
+}
+
+func listarReapresentações(_ *cobra.Command, _ []string) {
+	dfp, err := contabil.NovaDemonstraçãoFinanceira(db(), flags.tempDir)
+	if err != nil {
+		progress.Fatal(err)
+	}
+
+	reapresentações, err := dfp.Reapresentações(flags.reapres.cnpj)
+	if err != nil {
+		progress.Fatal(err)
+	}
+	if len(reapresentações) == 0 {
+		progress.Status("Nenhuma conta alterada em reapresentações de %s", flags.reapres.cnpj)
+		return
+	}
+
+	for _, doc := range porVersão(reapresentações) {
+		r := doc[0]
+		fmt.Printf("\n%s: versão %d => %d", r.DataRefer, r.VersãoAnterior, r.Versão)
+		if r.DataRecebimento != "" {
+			fmt.Printf(" (recebida em %s)", r.DataRecebimento)
+		}
+		fmt.Println()
+		for _, r := range doc {
+			descr := r.Descr
+			if r.Coluna != "" {
+				descr += " (" + r.Coluna + ")"
+			}
+			período := r.DataFimExerc
+			if r.DataIniExerc != "" {
+				período = r.DataIniExerc + " a " + r.DataFimExerc
+			}
+			fmt.Printf("  %-4s %-12s %-40.40s %-24s %16.2f %16.2f %16.2f %s\n",
+				ifElse(r.Consolidado, "con", "ind"), r.Código, descr, período,
+				r.ValorAnterior.Float64(), r.Valor.Float64(), r.Diferença().Float64(),
+				percentual(r.ValorAnterior.Float64(), r.Valor.Float64()))
+		}
+	}
+}
+
+// porVersão agrupa as reapresentações, que devem estar ordenadas por data
+// de referência e versão, por documento (data de referência + versão).
+func porVersão(reapresentações []dominio.Reapresentação) [][]dominio.Reapresentação {
+	var grupos [][]dominio.Reapresentação
+	for i, r := range reapresentações {
+		if i == 0 || r.DataRefer != reapresentações[i-1].DataRefer || r.Versão != reapresentações[i-1].Versão {
+			grupos = append(grupos, nil)
+		}
+		grupos[len(grupos)-1] = append(grupos[len(grupos)-1], r)
+	}
+	return grupos
+}
+
+// percentual retorna a variação percentual formatada entre os valores, ou
+// vazio se o valor anterior for zero.
+func percentual(anterior, valor float64) string {
+	if anterior == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%+.1f%%", (valor-anterior)/math.Abs(anterior)*100)
+}
@@ -0,0 +1,40 @@
+// SPDX-FileCopyrightText: 2023 Adriano Prado <dev@dude333.com>
+//
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"testing"
+
+	"github.com/dude333/rapinav2/pkg/contabil/dominio"
+)
+
+func Test_porVersão(t *testing.T) {
+	reapresentações := []dominio.Reapresentação{
+		{DataRefer: "2022-12-31", Versão: 2, Código: "3.01"},
+		{DataRefer: "2022-12-31", Versão: 2, Código: "3.11"},
+		{DataRefer: "2022-12-31", Versão: 3, Código: "3.11"},
+		{DataRefer: "2023-03-31", Versão: 2, Código: "3.11"},
+	}
+	got := porVersão(reapresentações)
+	if len(got) != 3 || len(got[0]) != 2 || got[1][0].Versão != 3 || got[2][0].DataRefer != "2023-03-31" {
+		t.Errorf("porVersão() = %+v", got)
+	}
+}
+
+func Test_percentual(t *testing.T) {
+	tests := []struct {
+		anterior, valor float64
+		want            string
+	}{
+		{100, 110, "+10.0%"},
+		{-100, -150, "-50.0%"},
+		{0, 10, ""},
+	}
+	for _, tt := range tests {
+		if got := percentual(tt.anterior, tt.valor); got != tt.want {
+			t.Errorf("percentual(%v, %v) = %q, want %q", tt.anterior, tt.valor, got, tt.want)
+		}
+	}
+}
@@ -8,12 +8,15 @@ import (
 	"fmt"
 	"math"
 	"os"
+	"runtime"
 	"strings"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 
 	rapina "github.com/dude333/rapinav2"
 	"github.com/dude333/rapinav2/pkg/contabil"
+	"github.com/dude333/rapinav2/pkg/contabil/repositorio"
 	"github.com/dude333/rapinav2/pkg/excel"
 	"github.com/dude333/rapinav2/pkg/progress"
 )
@@ -27,6 +30,14 @@ const (
 type flagsRelatorio struct {
 	outputDir string
 	crescente bool
+	cnpjs     []string // CNPJs das empresas (modo não interativo)
+	nomes     []string // nomes das empresas (modo não interativo)
+	todas     bool     // relatório de todas as empresas
+	setor     string   // relatório das empresas ativas do setor
+	arquivo   string   // arquivo com a lista de CNPJs/nomes das empresas
+	paralelo  int      // número de relatórios gerados simultaneamente
+	unidade   string   // escala dos valores (unidade, mil ou milhao)
+	moeda     string   // moeda dos valores (ex.: R$, US$)
 }
 
 // relatorioCmd represents the relatorio command
@@ -34,27 +45,82 @@ var relatorioCmd = &cobra.Command{
 	Use:     "relatorio",
 	Aliases: []string{"relat", "report"},
 	Short:   "imprimir relatório",
-	Long:    `relatorio das informações financeiras de uma empresa`,
-	Run:     menuRelatório,
+	Long: `relatorio das informações financeiras de uma empresa
+
+Sem as flags --cnpj, --nome, --todas, --setor ou --arquivo, a empresa é
+escolhida num menu interativo, que não lista as empresas com registro
+cancelado na CVM. Com essas flags, os relatórios são gerados sem
+interação e, para cada empresa, é impresso na saída padrão uma linha JSON
+com o CNPJ, o nome, o arquivo gerado e o erro, se houver.
+
+Os valores são apresentados na escala e na moeda mais usadas pela empresa,
+ou na escala e na moeda definidas por --unidade e --moeda, o que permite
+comparar empresas diferentes. Os valores de cada trimestre em outras
+moedas são convertidos, depois de descontado o valor acumulado até o
+trimestre anterior, pela cotação do último dia do trimestre, definida no
+arquivo de configuração (seção "cambio"):
+
+  cambio:
+    - {moeda: US$, data: 2023-12-29, taxa: 4.8413}
+
+O valor de mercado e os múltiplos usam as cotações das ações ON e PN. As
+cotações das units (UNT) não são usadas, pois a sua composição não é
+importada: o valor de mercado das empresas negociadas apenas em units
+fica em branco.`,
+	Run: menuRelatório,
 }
 
 func init() {
 	relatorioCmd.Flags().StringVarP(&flags.relatorio.outputDir, "dir", "d", ".", "Diretório do relatório")
 	relatorioCmd.Flags().BoolVarP(&flags.relatorio.crescente, "crescente", "c", false, "Mostrar trimestres em ordem crescente")
+	relatorioCmd.Flags().StringSliceVar(&flags.relatorio.cnpjs, "cnpj", nil, "CNPJ da empresa (pode ser repetido)")
+	relatorioCmd.Flags().StringSliceVar(&flags.relatorio.nomes, "nome", nil, "Nome, início do nome ou ticker da empresa (pode ser repetido)")
+	relatorioCmd.Flags().BoolVar(&flags.relatorio.todas, "todas", false, "Gerar o relatório de todas as empresas")
+	relatorioCmd.Flags().StringVar(&flags.relatorio.setor, "setor", "", "Gerar o relatório das empresas ativas do setor (ou início do nome do setor)")
+	relatorioCmd.Flags().StringVar(&flags.relatorio.arquivo, "arquivo", "", "Arquivo com um CNPJ ou nome de empresa por linha")
+	relatorioCmd.Flags().IntVarP(&flags.relatorio.paralelo, "paralelo", "p", runtime.NumCPU(), "Número de relatórios gerados simultaneamente")
+	relatorioCmd.Flags().StringVar(&flags.relatorio.unidade, "unidade", "", "Escala dos valores: unidade, mil ou milhao (padrão: a mais usada pela empresa)")
+	relatorioCmd.Flags().StringVar(&flags.relatorio.moeda, "moeda", "", "Moeda dos valores, ex.: R$ ou US$, convertidos trimestre a trimestre (padrão: a mais usada pela empresa)")
 
 	rootCmd.AddCommand(relatorioCmd)
 }
 
 func menuRelatório(_ *cobra.Command, _ []string) {
-	dfp, err := contabil.NovaDemonstraçãoFinanceira(db(), flags.tempDir)
+	configs, err := configUnidade(flags.relatorio.unidade, flags.relatorio.moeda)
 	if err != nil {
 		progress.Fatal(err)
 	}
+	câmbio, err := carregarCâmbio()
+	if err != nil {
+		progress.Fatal(err)
+	}
+	configs = append(configs, repositorio.CfgCâmbio(câmbio))
+
+	dfp, err := contabil.NovaDemonstraçãoFinanceira(db(), flags.tempDir, configs...)
+	if err != nil {
+		progress.Fatal(err)
+	}
+
+	modelos, err := carregarModelos()
+	if err != nil {
+		progress.Fatal(err)
+	}
+
+	m, err := novoMercado(dfp, câmbio)
+	if err != nil {
+		progress.Fatal(err)
+	}
+
+	f := flags.relatorio
+	if len(f.cnpjs) > 0 || len(f.nomes) > 0 || f.todas || f.setor != "" || f.arquivo != "" {
+		os.Exit(relatórioLote(dfp, modelos, m))
+	}
 
 	empresas, err := dfp.Empresas()
 	if err != nil {
 		progress.Fatal(err)
 	}
+	empresas = ativas(empresas)
 
 	for {
 		empresa, ok := escolherEmpresa(empresas)
@@ -63,93 +129,179 @@ func menuRelatório(_ *cobra.Command, _ []string) {
 			os.Exit(0)
 		}
 
-		criarRelatório(empresa, dfp)
+		criarRelatório(empresa, dfp, modelos, m)
 	}
 }
 
-func criarRelatório(empresa rapina.Empresa, dfp *contabil.DemonstraçãoFinanceira) {
-	filename, err := prepareFilename(flags.relatorio.outputDir, empresa.Nome)
+// carregarModelos carrega os modelos de contas do arquivo de configuração
+// (seção "modelos"), se existir.
+func carregarModelos() (*repositorio.Contas, error) {
+	arquivo := viper.ConfigFileUsed()
+	if arquivo == "" {
+		return &repositorio.Contas{}, nil
+	}
+	data, err := os.ReadFile(arquivo)
+	if os.IsNotExist(err) {
+		return &repositorio.Contas{}, nil
+	}
 	if err != nil {
-		progress.Fatal(err)
+		return nil, err
 	}
-
-	x := excel.New()
-	defer func() {
-		if err := x.Close(); err != nil {
-			progress.Error(err)
-		}
-	}()
-
-	// DADOS CONSOLIDADOS
-	progress.Running("Relatório de dados consolidados")
-	itr, err := dfp.RelatórioTrimestal(empresa.CNPJ, true)
+	modelos, err := repositorio.Unmarshal(string(data))
 	if err != nil {
-		progress.Fatal(err)
+		return nil, fmt.Errorf("%s: %w", arquivo, err)
 	}
-	if len(itr) > 0 {
-		progress.Debug("Dados consolidados: %d registros", len(itr))
-		itrUnificado := rapina.UnificarContasSimilares(itr)
+	return modelos, nil
+}
 
-		if err = x.NewSheet("consolidado"); err != nil {
-			progress.Fatal(err)
+// configUnidade retorna as configurações da escala (--unidade) e da moeda
+// (--moeda) em que os valores são apresentados.
+func configUnidade(unidade, moeda string) ([]repositorio.ConfigFn, error) {
+	var configs []repositorio.ConfigFn
+	if unidade != "" {
+		escala, err := rapina.NovaEscala(unidade)
+		if err != nil {
+			return nil, fmt.Errorf("--unidade: %w", err)
 		}
-		excelReport(x, itrUnificado, !flags.relatorio.crescente)
+		configs = append(configs, repositorio.CfgUnidade(escala))
+	}
+	if moeda != "" {
+		configs = append(configs, repositorio.CfgMoeda(rapina.NovaMoeda(moeda)))
+	}
+	return configs, nil
+}
 
-		if err = x.NewSheet("resumo - consolidado"); err != nil {
-			progress.Fatal(err)
-		}
-		excelSummaryReport(x, itrUnificado, false, !flags.relatorio.crescente)
+// carregarCâmbio carrega as cotações das moedas do arquivo de configuração
+// (seção "cambio"), se existir.
+func carregarCâmbio() (*rapina.TabelaCâmbio, error) {
+	arquivo := viper.ConfigFileUsed()
+	if arquivo == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(arquivo)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	cotações, err := repositorio.UnmarshalCâmbio(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", arquivo, err)
+	}
+	return rapina.NovaTabelaCâmbio(cotações), nil
+}
 
-		if err = x.NewSheet("resumo - consolidado vert"); err != nil {
-			progress.Fatal(err)
-		}
-		excelSummaryReport(x, itrUnificado, true, !flags.relatorio.crescente)
+func criarRelatório(empresa rapina.Empresa, dfp *contabil.DemonstraçãoFinanceira, modelos *repositorio.Contas, m *mercado) {
+	progress.Running("Relatório " + empresa.Nome)
+	filename, err := gerarRelatório(empresa, dfp, modelos, m)
+	if err != nil {
+		progress.RunFail()
+		progress.Error(err)
+		return
 	}
 	progress.RunOK()
 
-	// DADOS INDIVIDUAIS
+	status := fmt.Sprintf("Relatório salvo como: %s", filename)
+	line := strings.Repeat("-", min(len(status), 80))
+	progress.Status(line)
+	progress.Status(status)
+	progress.Status(line + "\n\n")
+}
+
+// gerarRelatório cria a planilha com os dados consolidados da empresa (ou
+// com os dados individuais, caso não existam dados consolidados) e retorna
+// o nome do arquivo gerado. Não imprime o progresso, para que possa ser
+// chamada por várias goroutines.
+func gerarRelatório(empresa rapina.Empresa, dfp *contabil.DemonstraçãoFinanceira, modelos *repositorio.Contas, m *mercado) (string, error) {
+	contas := tabelaContas(modelos.ModeloEmpresa(empresa.CNPJ))
+
+	tipo := "consolidado"
+	itr, err := dfp.RelatórioTrimestal(empresa.CNPJ, true)
+	if err != nil {
+		return "", err
+	}
 	if len(itr) == 0 {
-		progress.Running("Relatório de dados individual")
+		tipo = "individual"
 		itr, err = dfp.RelatórioTrimestal(empresa.CNPJ, false)
 		if err != nil {
-			progress.Fatal(err)
+			return "", err
 		}
-		if len(itr) > 0 {
-			progress.Debug("Dados individuais: %d registros", len(itr))
-			itrUnificado := rapina.UnificarContasSimilares(itr)
+	}
+	if len(itr) == 0 {
+		return "", fmt.Errorf("%s: %w", empresa, repositorio.ErrSemDados)
+	}
+	progress.Debug("Dados %s: %d registros", tipo, len(itr))
+	itrUnificado := rapina.UnificarContasSimilares(itr)
 
-			if err = x.NewSheet("individual"); err != nil {
-				progress.Fatal(err)
-			}
-			excelReport(x, itrUnificado, !flags.relatorio.crescente)
+	escala, err := dfp.Escala(empresa.CNPJ)
+	if err != nil {
+		return "", err
+	}
+	moeda, err := dfp.Moeda(empresa.CNPJ)
+	if err != nil {
+		return "", err
+	}
+	unidade := rapina.Dinheiro{Moeda: moeda, Escala: escala}.Unidade()
 
-			if err = x.NewSheet("resumo - individual"); err != nil {
-				progress.Fatal(err)
-			}
-			excelSummaryReport(x, itrUnificado, false, !flags.relatorio.crescente)
+	dm, err := m.dados(empresa, rapina.RangeAnos(itrUnificado))
+	if err != nil {
+		return "", err
+	}
 
-			if err = x.NewSheet("resumo - individual vert"); err != nil {
-				progress.Fatal(err)
-			}
-			excelSummaryReport(x, itrUnificado, true, !flags.relatorio.crescente)
+	x := excel.New()
+	defer func() {
+		if err := x.Close(); err != nil {
+			progress.Error(err)
 		}
-		progress.RunOK()
+	}()
+
+	if err = x.NewSheet(tipo); err != nil {
+		return "", err
+	}
+	excelReport(x, itrUnificado, unidade, !flags.relatorio.crescente)
+
+	if err = x.NewSheet("resumo - " + tipo); err != nil {
+		return "", err
+	}
+	excelSummaryReport(x, itrUnificado, contas, dm, false, !flags.relatorio.crescente)
+
+	if err = x.NewSheet("resumo - " + tipo + " vert"); err != nil {
+		return "", err
+	}
+	excelSummaryReport(x, itrUnificado, contas, dm, true, !flags.relatorio.crescente)
+
+	// DMPL e DRA em planilhas separadas, se existirem
+	for _, grupo := range repositorio.GruposSeparados {
+		itrGrupo, err := dfp.RelatórioTrimestralGrupo(empresa.CNPJ, tipo == "consolidado", grupo)
+		if err != nil {
+			return "", err
+		}
+		if len(itrGrupo) == 0 {
+			continue
+		}
+		if err = x.NewSheet(grupo + " - " + tipo); err != nil {
+			return "", err
+		}
+		excelReport(x, itrGrupo, unidade, !flags.relatorio.crescente)
 	}
 
 	// Salva planilha
+	filename, err := reservarArquivo(flags.relatorio.outputDir, empresa.Nome)
+	if err != nil {
+		return "", err
+	}
 	if err := x.SaveAs(filename); err != nil {
-		progress.Fatal(err)
-		os.Exit(1)
+		_ = os.Remove(filename)
+		return "", err
 	}
 
-	status := fmt.Sprintf("Relatório salvo como: %s", filename)
-	line := strings.Repeat("-", min(len(status), 80))
-	progress.Status(line)
-	progress.Status(status)
-	progress.Status(line + "\n\n")
+	return filename, nil
 }
 
-func excelReport(x *excel.Excel, itr []rapina.InformeTrimestral, decrescente bool) {
+// excelReport imprime os valores trimestrais de todas as contas, com a
+// unidade dos valores (ex.: "R$ mil") no cabeçalho.
+func excelReport(x *excel.Excel, itr []rapina.InformeTrimestral, unidade string, decrescente bool) {
 	if err := x.SetZoom(90.0); err != nil {
 		progress.Fatal(err)
 	}
@@ -173,7 +325,7 @@ func excelReport(x *excel.Excel, itr []rapina.InformeTrimestral, decrescente boo
 
 	cabeçalho := func(row, col int) {
 		x.PrintCell(row, 1, titleFont, "Código")
-		x.PrintCell(row, 2, titleFont, "Descrição")
+		x.PrintCell(row, 2, titleFont, "Descrição ("+unidade+")")
 		for _, ano := range anos {
 			x.PrintCell(row, col+seq[0], titleFont, fmt.Sprintf("1T%d", ano))
 			x.PrintCell(row, col+seq[1], titleFont, fmt.Sprintf("2T%d", ano))
@@ -391,8 +543,61 @@ var _tabelaContas = map[accountType][]conta{
 	Dividendos:   {{"7.*", "Dividendos"}},
 }
 
-func acctCode(cod, descr string) accountType {
+// tabelaContas retorna a tabela de contas padrão (_tabelaContas) com as
+// contas substituídas pelas definidas no modelo, quando houver.
+func tabelaContas(m repositorio.Modelo) map[accountType][]conta {
+	modelo := map[accountType][][]string{
+		AtivoTotal:        m.AtivoTotal,
+		AtivoCirc:         m.AtivoCirc,
+		AtivoNCirc:        m.AtivoNCirc,
+		Caixa:             m.Caixa,
+		AplicFinanceiras:  m.AplicFinanceiras,
+		Estoque:           m.Estoque,
+		ContasARecebCirc:  m.ContasARecebCirc,
+		ContasARecebNCirc: m.ContasARecebNCirc,
+		PassivoTotal:      m.PassivoTotal,
+		PassivoCirc:       m.PassivoCirc,
+		PassivoNCirc:      m.PassivoNCirc,
+		Equity:            m.Equity,
+		DividaCirc:        m.DividaCirc,
+		DividaNCirc:       m.DividaNCirc,
+		DividendosJCP:     m.DividendosJCP,
+		DividendosMin:     m.DividendosMin,
+		Vendas:            m.Vendas,
+		CustoVendas:       m.CustoVendas,
+		DespesasOp:        m.DespesasOp,
+		EBIT:              m.EBIT,
+		ResulFinanc:       m.ResulFinanc,
+		ResulOpDescont:    m.ResulOpDescont,
+		LucLiq:            m.LucLiq,
+		FCO:               m.FCO,
+		FCI:               m.FCI,
+		FCF:               m.FCF,
+		Deprec:            m.Deprec,
+		JurosCapProp:      m.JurosCapProp,
+		Dividendos:        m.Dividendos,
+	}
+
+	tabela := make(map[accountType][]conta, len(_tabelaContas))
 	for key, v := range _tabelaContas {
+		tabela[key] = v
+	}
+	for key, v := range modelo {
+		if len(v) == 0 {
+			continue
+		}
+		contas := make([]conta, 0, len(v))
+		for _, c := range v {
+			contas = append(contas, conta{cod: c[0], descr: c[1]})
+		}
+		tabela[key] = contas
+	}
+
+	return tabela
+}
+
+func acctCode(tabela map[accountType][]conta, cod, descr string) accountType {
+	for key, v := range tabela {
 		for _, acc := range v {
 			l := len(acc.cod)
 			if cod == acc.cod || (l > 1 && acc.cod[l-1] == '*' && strings.HasPrefix(cod, acc.cod[:l-1])) {
@@ -415,7 +620,10 @@ func ifElse[T any](cond bool, a, b T) T {
 	return b
 }
 
-func excelSummaryReport(x *excel.Excel, itr []rapina.InformeTrimestral, vert, decrescente bool) {
+// excelSummaryReport imprime o resumo dos principais indicadores. Com os
+// dados de mercado, imprime também os valores por ação (LPA e VPA) e os
+// múltiplos (P/L, EV/EBITDA...).
+func excelSummaryReport(x *excel.Excel, itr []rapina.InformeTrimestral, contas map[accountType][]conta, dm dadosMercado, vert, decrescente bool) {
 	if err := x.SetZoom(90.0); err != nil {
 		progress.Fatal(err)
 	}
@@ -453,15 +661,21 @@ func excelSummaryReport(x *excel.Excel, itr []rapina.InformeTrimestral, vert, de
 		}
 	}
 
+	// os trimestres ainda não divulgados ficam fora das somas dos últimos 12 meses
+	último, _ := rapina.ÚltimoTrimestre(itr)
+	ttm := func(valores []rapina.ValoresTrimestrais) []rapina.ValoresTrimestrais {
+		return rapina.TTM(valores, último)
+	}
+
 	c := map[accountType][]rapina.ValoresTrimestrais{}
 	for _, informe := range itr {
-		c[acctCode(informe.Codigo, informe.Descr)] = informe.Valores
+		c[acctCode(contas, informe.Codigo, informe.Descr)] = informe.Valores
 	}
 
 	const row2 = 2
 	const colB = 2
-	sumRows := make([]float64, len(anos)*4)
-	sumCols := make([]float64, len(anos)*4)
+	sumRows := make([]rapina.Decimal, len(anos)*4)
+	sumCols := make([]rapina.Decimal, len(anos)*4)
 	imprimirTrimestres := func(row, col int, estilo int, valores []rapina.ValoresTrimestrais) {
 		for _, ano := range anos {
 			for _, valor := range valores {
@@ -474,20 +688,20 @@ func excelSummaryReport(x *excel.Excel, itr []rapina.InformeTrimestral, vert, de
 					x.PrintCell(row, col+seq[2], estilo, valor.T3)
 					x.PrintCell(row, col+seq[3], estilo, valor.T4)
 
-					sumCols[col+seq[0]-colB] += valor.T1
-					sumCols[col+seq[1]-colB] += valor.T2
-					sumCols[col+seq[2]-colB] += valor.T3
-					sumCols[col+seq[3]-colB] += valor.T4
+					sumCols[col+seq[0]-colB] = sumCols[col+seq[0]-colB].Add(valor.T1)
+					sumCols[col+seq[1]-colB] = sumCols[col+seq[1]-colB].Add(valor.T2)
+					sumCols[col+seq[2]-colB] = sumCols[col+seq[2]-colB].Add(valor.T3)
+					sumCols[col+seq[3]-colB] = sumCols[col+seq[3]-colB].Add(valor.T4)
 				} else {
 					x.PrintCell(row+seq[0], col, estilo, valor.T1)
 					x.PrintCell(row+seq[1], col, estilo, valor.T2)
 					x.PrintCell(row+seq[2], col, estilo, valor.T3)
 					x.PrintCell(row+seq[3], col, estilo, valor.T4)
 
-					sumRows[row+seq[0]-row2] += valor.T1
-					sumRows[row+seq[1]-row2] += valor.T2
-					sumRows[row+seq[2]-row2] += valor.T3
-					sumRows[row+seq[3]-row2] += valor.T4
+					sumRows[row+seq[0]-row2] = sumRows[row+seq[0]-row2].Add(valor.T1)
+					sumRows[row+seq[1]-row2] = sumRows[row+seq[1]-row2].Add(valor.T2)
+					sumRows[row+seq[2]-row2] = sumRows[row+seq[2]-row2].Add(valor.T3)
+					sumRows[row+seq[3]-row2] = sumRows[row+seq[3]-row2].Add(valor.T4)
 				}
 			}
 			if !vert {
@@ -547,6 +761,23 @@ func excelSummaryReport(x *excel.Excel, itr []rapina.InformeTrimestral, vert, de
 	proventos := rapina.AddVTs(c[Dividendos], c[JurosCapProp])
 	p("Proventos", number, proventos)
 	p("Payout", frac, rapina.DivVTs(proventos, c[LucLiq]))
+	if len(dm.ações) > 0 {
+		row += ifElse(vert, 0, 1)
+		p("LPA", frac, rapina.DivVTs(ttm(c[LucLiq]), dm.ações))
+		p("VPA", frac, rapina.DivVTs(c[Equity], dm.ações))
+	}
+	if valorMercado := dm.valorMercado; len(valorMercado) > 0 {
+		row += ifElse(vert, 0, 1)
+		ev := comValor(rapina.AddVTs(valorMercado, dividaLiquida), valorMercado)
+		p("Valor de Mercado", number, valorMercado)
+		p("EV", number, ev)
+		p("P/L", frac, rapina.DivVTs(valorMercado, ttm(c[LucLiq])))
+		p("P/VP", frac, rapina.DivVTs(valorMercado, c[Equity]))
+		p("EV/EBIT", frac, rapina.DivVTs(ev, ttm(c[EBIT])))
+		p("EV/EBITDA", frac, rapina.DivVTs(ev, ttm(ebitda)))
+		p("PSR", frac, rapina.DivVTs(valorMercado, ttm(c[Vendas])))
+		p("Dividend Yield", percent, rapina.DivVTs(ttm(proventos), valorMercado))
+	}
 	// -------------------------------------------------
 
 	// Auto-resize columns
@@ -564,13 +795,13 @@ func excelSummaryReport(x *excel.Excel, itr []rapina.InformeTrimestral, vert, de
 	if !vert {
 		// Trim empty columns
 		for i := len(sumCols) - 1; i >= 0; i-- {
-			if sumCols[i] != 0.0 {
+			if !sumCols[i].IsZero() {
 				break
 			}
 			_ = x.RemoveCol(colB + i)
 		}
 		for i := 0; i < len(sumCols); i++ {
-			if sumCols[i] != 0.0 {
+			if !sumCols[i].IsZero() {
 				break
 			}
 			_ = x.RemoveCol(colB)
@@ -578,94 +809,16 @@ func excelSummaryReport(x *excel.Excel, itr []rapina.InformeTrimestral, vert, de
 	} else {
 		// Trim empty rows
 		for i := len(sumRows) - 1; i >= 0; i-- {
-			if sumRows[i] != 0.0 {
+			if !sumRows[i].IsZero() {
 				break
 			}
 			_ = x.RemoveRow(row2 + i)
 		}
 		for i := 0; i < len(sumRows); i++ {
-			if sumRows[i] != 0.0 {
+			if !sumRows[i].IsZero() {
 				break
 			}
 			_ = x.RemoveRow(row2)
 		}
 	}
 }
-
-// ultTrim retorna o último trimestre com valor não nulo
-func ultTrim(ano int, valores []rapina.ValoresTrimestrais) int {
-	for _, valor := range valores {
-		if valor.Ano != ano {
-			continue
-		}
-		if valor.T4 != 0.0 {
-			return 4
-		} else if valor.T3 != 0.0 {
-			return 3
-		} else if valor.T2 != 0.0 {
-			return 2
-		}
-	}
-	return 1
-}
-
-// ttm armazena a soma dos últimos 4 trimestres em cada um dos trimestres; usado em métricas
-// que comparam como valores do balanço patrimonial.
-// Exemplo: ROE = Patrim.Líq. / Lucro Líq. dos últimos 12 meses
-func ttm(acct []rapina.ValoresTrimestrais) []rapina.ValoresTrimestrais {
-	min, max := rapina.MinMax([]rapina.InformeTrimestral{{Codigo: "", Descr: "", Valores: acct}})
-	t := ultTrim(max, acct)
-
-	// if min == max && t <= 3 {
-	// 	return []rapina.ValoresTrimestrais{{Ano: acct[0].Ano}}
-	// }
-
-	valores := make([]float64, len(acct)*4)
-
-	for ano := min; ano <= max; ano++ {
-		for _, valor := range acct {
-			if valor.Ano != ano {
-				continue
-			}
-			idx := 4 * (ano - min)
-			valores[idx+0] = valor.T1
-			valores[idx+1] = valor.T2
-			valores[idx+2] = valor.T3
-			valores[idx+3] = valor.T4
-		}
-	}
-
-	somaValores := func(from, to int) float64 {
-		invalidParm := from < 0 || to >= len(valores) || from > to
-		invalidPeriod := to >= (len(valores) - (4 - t))
-		if invalidParm || invalidPeriod {
-			return 0.0
-		}
-
-		total := 0.0
-		for i := from; i <= to; i++ {
-			total += valores[i]
-		}
-		return total
-	}
-
-	valoresAcum := make([]rapina.ValoresTrimestrais, len(acct))
-
-	for ano := min; ano <= max; ano++ {
-		for _, valor := range acct {
-			if valor.Ano != ano {
-				continue
-			}
-			idx := 4 * (ano - min)
-			valoresAcum[ano-min] = rapina.ValoresTrimestrais{
-				Ano: ano,
-				T1:  somaValores(idx-3, idx+0),
-				T2:  somaValores(idx-2, idx+1),
-				T3:  somaValores(idx-1, idx+2),
-				T4:  somaValores(idx-0, idx+3),
-			}
-		}
-	}
-
-	return valoresAcum
-}
@@ -0,0 +1,243 @@
+// SPDX-FileCopyrightText: 2023 Adriano Prado <dev@dude333.com>
+//
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	rapina "github.com/dude333/rapinav2"
+	"github.com/dude333/rapinav2/pkg/contabil"
+	"github.com/dude333/rapinav2/pkg/contabil/repositorio"
+	"github.com/dude333/rapinav2/pkg/progress"
+)
+
+// saídaRelatório é impressa, em JSON, na saída padrão para cada relatório
+// gerado no modo não interativo.
+type saídaRelatório struct {
+	CNPJ    string `json:"cnpj"`
+	Nome    string `json:"nome"`
+	Arquivo string `json:"arquivo,omitempty"`
+	Erro    string `json:"erro,omitempty"`
+}
+
+// relatórioLote gera os relatórios das empresas selecionadas pelas flags,
+// usando até flags.relatorio.paralelo goroutines, e retorna o código de
+// saída do programa (0 = todos os relatórios foram gerados).
+func relatórioLote(dfp *contabil.DemonstraçãoFinanceira, modelos *repositorio.Contas, m *mercado) int {
+	empresas, err := selecionarEmpresas(dfp)
+	if err != nil {
+		progress.Error(err)
+		return 1
+	}
+	progress.Status("Gerando %d relatório(s)", len(empresas))
+
+	paralelo := flags.relatorio.paralelo
+	if paralelo < 1 {
+		paralelo = 1
+	}
+
+	empresasCh := make(chan rapina.Empresa)
+	saídas := make(chan saídaRelatório)
+
+	var wg sync.WaitGroup
+	for i := 0; i < paralelo; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for empresa := range empresasCh {
+				s := saídaRelatório{CNPJ: empresa.CNPJ, Nome: empresa.Nome}
+				arquivo, err := gerarRelatório(empresa, dfp, modelos, m)
+				if err != nil {
+					s.Erro = err.Error()
+				}
+				s.Arquivo = arquivo
+				saídas <- s
+			}
+		}()
+	}
+
+	go func() {
+		for _, empresa := range empresas {
+			empresasCh <- empresa
+		}
+		close(empresasCh)
+		wg.Wait()
+		close(saídas)
+	}()
+
+	falhas := 0
+	enc := json.NewEncoder(os.Stdout)
+	for s := range saídas {
+		if s.Erro != "" {
+			falhas++
+			progress.ErrorMsg("%s - %s: %s", s.CNPJ, s.Nome, s.Erro)
+		}
+		if err := enc.Encode(s); err != nil {
+			progress.Error(err)
+		}
+	}
+
+	if falhas > 0 {
+		progress.Warning("%d de %d relatório(s) com erro", falhas, len(empresas))
+		return 1
+	}
+	return 0
+}
+
+// selecionarEmpresas retorna as empresas selecionadas pelas flags --todas,
+// --setor, --cnpj, --nome e --arquivo, sem repetição.
+func selecionarEmpresas(dfp *contabil.DemonstraçãoFinanceira) ([]rapina.Empresa, error) {
+	todas, err := dfp.Empresas()
+	if err != nil {
+		return nil, err
+	}
+
+	cnpjs := flags.relatorio.cnpjs
+	nomes := flags.relatorio.nomes
+	if flags.relatorio.arquivo != "" {
+		fh, err := os.Open(flags.relatorio.arquivo)
+		if err != nil {
+			return nil, err
+		}
+		c, n, err := lerListaEmpresas(fh)
+		fh.Close()
+		if err != nil {
+			return nil, err
+		}
+		cnpjs = append(cnpjs, c...)
+		nomes = append(nomes, n...)
+	}
+
+	var empresas []rapina.Empresa
+	incluída := make(map[string]bool)
+	incluir := func(e rapina.Empresa) {
+		if !incluída[e.CNPJ] {
+			incluída[e.CNPJ] = true
+			empresas = append(empresas, e)
+		}
+	}
+
+	if flags.relatorio.todas {
+		for _, e := range todas {
+			incluir(e)
+		}
+	}
+
+	if flags.relatorio.setor != "" {
+		setor := doSetor(ativas(todas), flags.relatorio.setor)
+		if len(setor) == 0 {
+			return nil, fmt.Errorf("nenhuma empresa ativa no setor: %s", flags.relatorio.setor)
+		}
+		for _, e := range setor {
+			incluir(e)
+		}
+	}
+
+	for _, cnpj := range cnpjs {
+		dígitos := dígitosCNPJ(cnpj)
+		if dígitos == "" {
+			return nil, fmt.Errorf("CNPJ inválido: %s", cnpj)
+		}
+		achou := false
+		for _, e := range todas {
+			if dígitosCNPJ(e.CNPJ) == dígitos {
+				incluir(e)
+				achou = true
+				break
+			}
+		}
+		if !achou {
+			return nil, fmt.Errorf("CNPJ não encontrado: %s", cnpj)
+		}
+	}
+
+	for _, nome := range nomes {
+		e, err := buscarEmpresa(dfp, nome)
+		if err != nil {
+			return nil, err
+		}
+		incluir(e)
+	}
+
+	return empresas, nil
+}
+
+// buscarEmpresa retorna a empresa cujo nome seja igual ao informado ou,
+// não havendo, a única empresa cujo nome comece com o texto informado.
+func buscarEmpresa(dfp *contabil.DemonstraçãoFinanceira, nome string) (rapina.Empresa, error) {
+	encontradas, err := dfp.BuscaEmpresas(nome)
+	if err != nil {
+		return rapina.Empresa{}, err
+	}
+	for _, e := range encontradas {
+		if rapina.NormalizeString(e.Nome) == rapina.NormalizeString(nome) {
+			return e, nil
+		}
+	}
+
+	únicas := make(map[string]rapina.Empresa)
+	for _, e := range encontradas {
+		únicas[e.CNPJ] = e
+	}
+	switch len(únicas) {
+	case 0:
+		return rapina.Empresa{}, fmt.Errorf("empresa não encontrada: %s", nome)
+	case 1:
+		return encontradas[0], nil
+	}
+
+	var candidatas []string
+	for _, e := range encontradas {
+		candidatas = append(candidatas, e.Nome)
+	}
+	return rapina.Empresa{}, fmt.Errorf("nome ambíguo %q: %s", nome, strings.Join(candidatas, "; "))
+}
+
+var _reCNPJ = regexp.MustCompile(`^\d{2}\.\d{3}\.\d{3}/\d{4}-\d{2}$`)
+
+// dígitosCNPJ retorna os 14 dígitos do CNPJ, formatado (60.840.055/0001-31)
+// ou não (60840055000131), ou "" se s não for um CNPJ.
+func dígitosCNPJ(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r == '.' || r == '/' || r == '-':
+		default:
+			return ""
+		}
+	}
+	if b.Len() != 14 {
+		return ""
+	}
+	return b.String()
+}
+
+// lerListaEmpresas lê uma lista com um CNPJ (formatado ou apenas os dígitos)
+// ou nome de empresa por linha, ignorando linhas vazias e comentários
+// (iniciados por #).
+func lerListaEmpresas(r io.Reader) (cnpjs, nomes []string, err error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		linha := strings.TrimSpace(scanner.Text())
+		if linha == "" || strings.HasPrefix(linha, "#") {
+			continue
+		}
+		if dígitosCNPJ(linha) != "" {
+			cnpjs = append(cnpjs, linha)
+		} else {
+			nomes = append(nomes, linha)
+		}
+	}
+	return cnpjs, nomes, scanner.Err()
+}
@@ -0,0 +1,53 @@
+// SPDX-FileCopyrightText: 2023 Adriano Prado <dev@dude333.com>
+//
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_lerListaEmpresas(t *testing.T) {
+	lista := `
+# empresas do relatório semanal
+60.840.055/0001-31
+33041260065290
+  Lojas Renner
+3R PETROLEUM
+RAIA DROGASIL S.A.
+`
+	cnpjs, nomes, err := lerListaEmpresas(strings.NewReader(lista))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantCNPJs := []string{"60.840.055/0001-31", "33041260065290"}
+	wantNomes := []string{"Lojas Renner", "3R PETROLEUM", "RAIA DROGASIL S.A."}
+	if !reflect.DeepEqual(cnpjs, wantCNPJs) {
+		t.Errorf("cnpjs = %v, want %v", cnpjs, wantCNPJs)
+	}
+	if !reflect.DeepEqual(nomes, wantNomes) {
+		t.Errorf("nomes = %v, want %v", nomes, wantNomes)
+	}
+}
+
+func Test_dígitosCNPJ(t *testing.T) {
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{"60.840.055/0001-31", "60840055000131"},
+		{"60840055000131", "60840055000131"},
+		{"60.840.055/0001", ""},
+		{"3R PETROLEUM", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := dígitosCNPJ(tt.s); got != tt.want {
+			t.Errorf("dígitosCNPJ(%q) = %q, want %q", tt.s, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,284 @@
+// SPDX-FileCopyrightText: 2023 Adriano Prado <dev@dude333.com>
+//
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+
+	rapina "github.com/dude333/rapinav2"
+	"github.com/dude333/rapinav2/pkg/contabil"
+	"github.com/dude333/rapinav2/pkg/contabil/repositorio"
+	cotação "github.com/dude333/rapinav2/pkg/cotacao"
+	cotRepositório "github.com/dude333/rapinav2/pkg/cotacao/repositorio"
+	serviço "github.com/dude333/rapinav2/pkg/cotacao/servico"
+)
+
+// _diasSemCotação é o número máximo de dias antes do fim do trimestre em
+// que se busca o último fechamento (feriados, ativos com pouca liquidez).
+const _diasSemCotação = 14
+
+// mercado reúne as cotações e a quantidade de ações das empresas, usadas no
+// cálculo dos valores por ação, do valor de mercado e dos múltiplos do
+// relatório.
+type mercado struct {
+	dfp      *contabil.DemonstraçãoFinanceira
+	cotações *serviço.Serviço
+	ações    map[string][]rapina.QuantidadeAções // CNPJ => quantidades do arquivo de configuração
+	câmbio   *rapina.TabelaCâmbio                // cotações usadas se os valores não estiverem em reais
+}
+
+// dadosMercado contém, em cada trimestre, o número de ações em circulação e
+// o valor de mercado, ambos divididos pela escala dos valores contábeis, com
+// o valor de mercado na moeda dos valores contábeis.
+type dadosMercado struct {
+	ações        []rapina.ValoresTrimestrais
+	valorMercado []rapina.ValoresTrimestrais
+}
+
+func novoMercado(dfp *contabil.DemonstraçãoFinanceira, câmbio *rapina.TabelaCâmbio) (*mercado, error) {
+	bd, err := cotRepositório.NovoSqlite(db())
+	if err != nil {
+		return nil, err
+	}
+
+	lista, err := carregarAções()
+	if err != nil {
+		return nil, err
+	}
+	ações := make(map[string][]rapina.QuantidadeAções)
+	for _, a := range lista {
+		ações[a.CNPJ] = append(ações[a.CNPJ], a)
+	}
+
+	return &mercado{dfp: dfp, cotações: serviço.NovoServiço(nil, bd), ações: ações, câmbio: câmbio}, nil
+}
+
+// carregarAções carrega a quantidade de ações do arquivo de configuração
+// (seção "acoes"), se existir. Essas quantidades substituem as importadas da
+// CVM na mesma data.
+func carregarAções() ([]rapina.QuantidadeAções, error) {
+	arquivo := viper.ConfigFileUsed()
+	if arquivo == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(arquivo)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	ações, err := repositorio.UnmarshalAções(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", arquivo, err)
+	}
+	return ações, nil
+}
+
+// dados retorna o número de ações e o valor de mercado da empresa no fim de
+// cada trimestre dos anos informados. Os trimestres sem quantidade de ações
+// ou sem cotação ficam zerados, assim como o valor de mercado nos trimestres
+// sem o câmbio da moeda dos valores contábeis. O valor de mercado fica vazio
+// se a empresa não tiver tickers cadastrados, e zerado se apenas as suas
+// units (UNT) tiverem cotação.
+func (m *mercado) dados(empresa rapina.Empresa, anos []int) (dadosMercado, error) {
+	var dm dadosMercado
+	if m == nil || len(anos) == 0 {
+		return dm, nil
+	}
+
+	ações, err := m.quantidades(empresa.CNPJ)
+	if err != nil || len(ações) == 0 {
+		return dm, err
+	}
+	escala, err := m.dfp.Escala(empresa.CNPJ)
+	if err != nil {
+		return dm, err
+	}
+	moeda, err := m.dfp.Moeda(empresa.CNPJ)
+	if err != nil {
+		return dm, err
+	}
+
+	primeiro, último := anos[0], anos[len(anos)-1]
+	if primeiro > último {
+		primeiro, último = último, primeiro
+	}
+
+	for ano := primeiro; ano <= último; ano++ {
+		v := rapina.ValoresTrimestrais{Ano: ano}
+		for trimestre, t := range []*rapina.Decimal{&v.T1, &v.T2, &v.T3, &v.T4} {
+			if q, ok := quantidadeEm(ações, fimTrimestre(ano, trimestre+1)); ok {
+				on, pn := q.EmCirculação()
+				*t = rapina.DecimalDeInt(on + pn).Div(rapina.DecimalDeInt(int64(escala)))
+			}
+		}
+		dm.ações = append(dm.ações, v)
+	}
+
+	vms, err := m.dfp.ValoresMobiliários(empresa.CNPJ)
+	if err != nil || len(vms) == 0 {
+		return dm, err
+	}
+
+	de := rapina.Data(fimTrimestre(primeiro, 1).AddDate(0, 0, -_diasSemCotação))
+	até := rapina.Data(fimTrimestre(último, 4))
+
+	var sériesON, sériesPN [][]cotação.Ativo
+	for _, vm := range vms {
+		var séries *[][]cotação.Ativo
+		switch {
+		case vm.Classe == "ON":
+			séries = &sériesON
+		case strings.HasPrefix(vm.Classe, "PN"):
+			séries = &sériesPN
+		default:
+			// as units (UNT) não são usadas, pois a sua composição em ações
+			// ON e PN não é importada
+			continue
+		}
+		série, err := m.cotações.Cotações(vm.Ticker, de, até, cotação.SemAjuste)
+		if err != nil {
+			return dm, err
+		}
+		*séries = append(*séries, série)
+	}
+
+	for ano := primeiro; ano <= último; ano++ {
+		v := rapina.ValoresTrimestrais{Ano: ano}
+		for trimestre, t := range []*rapina.Decimal{&v.T1, &v.T2, &v.T3, &v.T4} {
+			dia := fimTrimestre(ano, trimestre+1)
+			q, ok := quantidadeEm(ações, dia)
+			if !ok {
+				continue
+			}
+			valor := rapina.Dinheiro{Moeda: rapina.Real, Valor: valorDeMercado(q, fechamento(sériesON, dia), fechamento(sériesPN, dia))}
+			valor, err := m.câmbio.Converter(valor, moeda, rapina.Data(dia))
+			if err != nil {
+				continue
+			}
+			*t = valor.NaEscala(escala).Valor
+		}
+		dm.valorMercado = append(dm.valorMercado, v)
+	}
+
+	return dm, nil
+}
+
+// quantidades retorna a quantidade de ações da empresa importada da
+// composição do capital, em ordem crescente de data, com as quantidades do
+// arquivo de configuração substituindo as da mesma data.
+func (m *mercado) quantidades(cnpj string) ([]rapina.QuantidadeAções, error) {
+	importadas, err := m.dfp.Ações(cnpj)
+	if err != nil {
+		return nil, err
+	}
+	return combinarAções(importadas, m.ações[cnpj]), nil
+}
+
+// combinarAções junta as duas listas de quantidades, com as substituições
+// prevalecendo nas datas repetidas, e as ordena por data.
+func combinarAções(ações, substituições []rapina.QuantidadeAções) []rapina.QuantidadeAções {
+	porData := make(map[string]rapina.QuantidadeAções, len(ações)+len(substituições))
+	for _, a := range ações {
+		porData[a.Data.String()] = a
+	}
+	for _, a := range substituições {
+		porData[a.Data.String()] = a
+	}
+
+	lista := make([]rapina.QuantidadeAções, 0, len(porData))
+	for _, a := range porData {
+		lista = append(lista, a)
+	}
+	sort.Slice(lista, func(i, j int) bool {
+		return time.Time(lista[i].Data).Before(time.Time(lista[j].Data))
+	})
+	return lista
+}
+
+// fimTrimestre retorna o último dia do trimestre (1 a 4) do ano.
+func fimTrimestre(ano, trimestre int) time.Time {
+	return time.Date(ano, time.Month(3*trimestre+1), 0, 0, 0, 0, 0, time.UTC)
+}
+
+// quantidadeEm retorna a quantidade de ações mais recente até o dia
+// informado. As quantidades devem estar em ordem crescente de data.
+func quantidadeEm(ações []rapina.QuantidadeAções, dia time.Time) (rapina.QuantidadeAções, bool) {
+	for i := len(ações) - 1; i >= 0; i-- {
+		if !time.Time(ações[i].Data).After(dia) {
+			return ações[i], true
+		}
+	}
+	return rapina.QuantidadeAções{}, false
+}
+
+// fechamento retorna o preço unitário do último fechamento até o dia
+// informado (e no máximo _diasSemCotação dias antes) da primeira série com
+// cotação, ou 0 se nenhuma tiver. As séries devem estar em ordem crescente
+// de data.
+func fechamento(séries [][]cotação.Ativo, dia time.Time) rapina.Decimal {
+	início := dia.AddDate(0, 0, -_diasSemCotação)
+	for _, série := range séries {
+		for i := len(série) - 1; i >= 0; i-- {
+			d := time.Time(série[i].Data)
+			if d.After(dia) {
+				continue
+			}
+			if d.Before(início) {
+				break
+			}
+			preço := série[i].Encerramento.Valor
+			if série[i].FatorCotação > 1 {
+				preço = preço.Div(rapina.DecimalDeInt(int64(série[i].FatorCotação)))
+			}
+			if preço.Sign() > 0 {
+				return preço
+			}
+		}
+	}
+	return rapina.Decimal{}
+}
+
+// valorDeMercado multiplica as ações em circulação de cada classe pelo seu
+// preço. Se apenas uma das classes tiver cotação, o seu preço é usado para
+// as duas.
+func valorDeMercado(q rapina.QuantidadeAções, preçoON, preçoPN rapina.Decimal) rapina.Decimal {
+	on, pn := q.EmCirculação()
+	if preçoON.IsZero() {
+		preçoON = preçoPN
+	}
+	if preçoPN.IsZero() {
+		preçoPN = preçoON
+	}
+	return rapina.DecimalDeInt(on).Mul(preçoON).Add(rapina.DecimalDeInt(pn).Mul(preçoPN))
+}
+
+// comValor retorna os valores apenas dos trimestres em que a referência não
+// é nula (ex.: EV só onde há valor de mercado).
+func comValor(valores, referência []rapina.ValoresTrimestrais) []rapina.ValoresTrimestrais {
+	ref := make(map[int]rapina.ValoresTrimestrais, len(referência))
+	for _, r := range referência {
+		ref[r.Ano] = r
+	}
+	ret := make([]rapina.ValoresTrimestrais, len(valores))
+	for i, v := range valores {
+		r := ref[v.Ano]
+		ret[i] = rapina.ValoresTrimestrais{
+			Ano: v.Ano,
+			T1:  ifElse(!r.T1.IsZero(), v.T1, rapina.Decimal{}),
+			T2:  ifElse(!r.T2.IsZero(), v.T2, rapina.Decimal{}),
+			T3:  ifElse(!r.T3.IsZero(), v.T3, rapina.Decimal{}),
+			T4:  ifElse(!r.T4.IsZero(), v.T4, rapina.Decimal{}),
+		}
+	}
+	return ret
+}
@@ -0,0 +1,122 @@
+// SPDX-FileCopyrightText: 2023 Adriano Prado <dev@dude333.com>
+//
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	rapina "github.com/dude333/rapinav2"
+	cotação "github.com/dude333/rapinav2/pkg/cotacao"
+)
+
+func dia(s string) time.Time {
+	t, _ := time.Parse("2006-01-02", s)
+	return t
+}
+
+func Test_fimTrimestre(t *testing.T) {
+	for tri, want := range []string{"2023-03-31", "2023-06-30", "2023-09-30", "2023-12-31"} {
+		if got := fimTrimestre(2023, tri+1); !got.Equal(dia(want)) {
+			t.Errorf("fimTrimestre(2023, %d) = %v, want %s", tri+1, got, want)
+		}
+	}
+}
+
+func Test_quantidadeEm(t *testing.T) {
+	ações := []rapina.QuantidadeAções{
+		{Data: rapina.Data(dia("2022-12-31")), Ordinárias: 100},
+		{Data: rapina.Data(dia("2023-06-30")), Ordinárias: 200},
+	}
+	tests := []struct {
+		dia    string
+		want   int64
+		wantOk bool
+	}{
+		{"2022-09-30", 0, false},
+		{"2023-03-31", 100, true},
+		{"2023-06-30", 200, true},
+		{"2023-12-31", 200, true},
+	}
+	for _, tt := range tests {
+		got, ok := quantidadeEm(ações, dia(tt.dia))
+		if ok != tt.wantOk || got.Ordinárias != tt.want {
+			t.Errorf("quantidadeEm(%s) = %d, %v, want %d, %v", tt.dia, got.Ordinárias, ok, tt.want, tt.wantOk)
+		}
+	}
+}
+
+func Test_fechamento(t *testing.T) {
+	cot := func(d string, preço float64, fator int) cotação.Ativo {
+		return cotação.Ativo{
+			Data:         rapina.Data(dia(d)),
+			Encerramento: rapina.Dinheiro{Valor: rapina.DecimalDeFloat(preço)},
+			FatorCotação: fator,
+		}
+	}
+	séries := [][]cotação.Ativo{
+		{cot("2023-03-01", 5, 1)}, // fora do intervalo
+		{cot("2023-03-29", 1000, 100), cot("2023-04-03", 12, 1)},
+	}
+	if got := fechamento(séries, dia("2023-03-31")); got != rapina.DecimalDeInt(10) {
+		t.Errorf("fechamento() = %v, want 10", got)
+	}
+	if got := fechamento(séries, dia("2022-12-31")); !got.IsZero() {
+		t.Errorf("fechamento() = %v, want 0", got)
+	}
+}
+
+func Test_valorDeMercado(t *testing.T) {
+	q := rapina.QuantidadeAções{Ordinárias: 100, Preferenciais: 200, TesourariaPN: 50}
+	tests := []struct {
+		name             string
+		preçoON, preçoPN float64
+		want             float64
+	}{
+		{"duas classes", 10, 20, 100*10 + 150*20},
+		{"sem ON", 0, 20, 250 * 20},
+		{"sem PN", 10, 0, 250 * 10},
+		{"sem cotação", 0, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := valorDeMercado(q, rapina.DecimalDeFloat(tt.preçoON), rapina.DecimalDeFloat(tt.preçoPN))
+			if got != rapina.DecimalDeFloat(tt.want) {
+				t.Errorf("valorDeMercado() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_comValor(t *testing.T) {
+	n := rapina.DecimalDeInt
+	valores := []rapina.ValoresTrimestrais{{Ano: 2022, T1: n(1), T2: n(2), T3: n(3), T4: n(4)}, {Ano: 2023, T1: n(5)}}
+	ref := []rapina.ValoresTrimestrais{{Ano: 2022, T2: n(9), T4: n(9)}}
+	want := []rapina.ValoresTrimestrais{{Ano: 2022, T2: n(2), T4: n(4)}, {Ano: 2023}}
+	if got := comValor(valores, ref); !reflect.DeepEqual(got, want) {
+		t.Errorf("comValor() = %+v, want %+v", got, want)
+	}
+}
+
+func Test_combinarAções(t *testing.T) {
+	d := func(s string) rapina.Data { return rapina.Data(dia(s)) }
+	importadas := []rapina.QuantidadeAções{
+		{Data: d("2023-03-31"), Ordinárias: 100},
+		{Data: d("2023-06-30"), Ordinárias: 100},
+	}
+	substituições := []rapina.QuantidadeAções{
+		{Data: d("2023-06-30"), Ordinárias: 200},
+		{Data: d("2022-12-31"), Ordinárias: 50},
+	}
+	want := []rapina.QuantidadeAções{
+		{Data: d("2022-12-31"), Ordinárias: 50},
+		{Data: d("2023-03-31"), Ordinárias: 100},
+		{Data: d("2023-06-30"), Ordinárias: 200},
+	}
+	if got := combinarAções(importadas, substituições); !reflect.DeepEqual(got, want) {
+		t.Errorf("combinarAções() = %+v, want %+v", got, want)
+	}
+}
@@ -4,7 +4,14 @@
 
 package main
 
-import "testing"
+import (
+	"errors"
+	"testing"
+
+	rapina "github.com/dude333/rapinav2"
+
+	"github.com/dude333/rapinav2/pkg/contabil/repositorio"
+)
 
 func Test_acctCode(t *testing.T) {
 	type args struct {
@@ -27,9 +34,38 @@ func Test_acctCode(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := acctCode(tt.args.cod, tt.args.descr); got != tt.want {
+			if got := acctCode(_tabelaContas, tt.args.cod, tt.args.descr); got != tt.want {
 				t.Errorf("acctCode() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
+
+func Test_tabelaContas(t *testing.T) {
+	m := repositorio.Modelo{
+		Estoque: [][]string{{"1.01.05", "Estoques"}},
+	}
+	tabela := tabelaContas(m)
+
+	if got := acctCode(tabela, "1.01.05", "Estoques"); got != Estoque {
+		t.Errorf("acctCode() = %v, want %v", got, Estoque)
+	}
+	if got := acctCode(tabela, "1.01.04", "Estoques"); got != UNDEF {
+		t.Errorf("acctCode() = %v, want %v", got, UNDEF)
+	}
+	if got := acctCode(tabela, "1", "Ativo Total"); got != AtivoTotal {
+		t.Errorf("acctCode() = %v, want %v (tabela padrão)", got, AtivoTotal)
+	}
+}
+
+func Test_configUnidade(t *testing.T) {
+	if configs, err := configUnidade("", ""); err != nil || len(configs) != 0 {
+		t.Errorf("configUnidade() = %d configurações, %v, want nenhuma", len(configs), err)
+	}
+	if configs, err := configUnidade("milhao", "dólar"); err != nil || len(configs) != 2 {
+		t.Errorf("configUnidade() = %d configurações, %v, want 2", len(configs), err)
+	}
+	if _, err := configUnidade("bilhao", ""); !errors.Is(err, rapina.ErrEscalaInválida) {
+		t.Errorf("configUnidade() error = %v, want %v", err, rapina.ErrEscalaInválida)
+	}
+}
@@ -7,6 +7,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
 
@@ -14,6 +15,7 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
+	ext "github.com/dude333/rapinav2/pkg/infra"
 	"github.com/dude333/rapinav2/pkg/progress"
 )
 
@@ -21,8 +23,19 @@ var flags = struct {
 	cfgFile   string
 	dataSrc   string // banco de dados sqlite (ex.: "file:/var/local/rapina.db")
 	tempDir   string // arquivos temporários
+	urlCVM    string // site de dados da CVM ou espelho local
+	urlB3     string // site da B3 (séries históricas) ou espelho local
+	proxy     string // proxy HTTP para os downloads
+	inseguro  bool   // não verificar os certificados TLS nos downloads
+	semCache  bool   // não usar o cache de downloads
+	cache     flagsCache
 	relatorio flagsRelatorio
 	atualizar flagsAtualizar
+	db        flagsDB
+	cotacoes  flagsCotacoes
+	tickers   flagsTickers
+	empresas  flagsEmpresas
+	reapres   flagsReapresentacoes
 	debug     bool
 	trace     bool
 }{}
@@ -31,6 +44,8 @@ const (
 	configFileName = "rapina.yaml"
 	dataSrcDefault = ".dados" + string(os.PathSeparator) + "rapina.db?cache=shared&mode=rwc&_journal_mode=WAL&_busy_timeout=5000"
 	tempDirDefault = ".dados" + string(os.PathSeparator)
+
+	cacheMaxMBDefault = 10 * 1024
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -63,6 +78,11 @@ func init() {
 	rootCmd.PersistentFlags().StringVar(&flags.cfgFile, "config", "", `arquivo de configuração (default = ./`+configFileName+`)`)
 	rootCmd.PersistentFlags().BoolVarP(&flags.debug, "debug", "g", false, "Mostrar logs de depuração")
 	rootCmd.PersistentFlags().BoolVarP(&flags.trace, "trace", "t", false, "Mostrar logs de rastreamento")
+	rootCmd.PersistentFlags().StringVar(&flags.urlCVM, "url-cvm", "", "Endereço do site de dados da CVM ou de um espelho (default = https://dados.cvm.gov.br)")
+	rootCmd.PersistentFlags().StringVar(&flags.urlB3, "url-b3", "", "Endereço do site da B3 (séries históricas) ou de um espelho (default = http://bvmf.bmfbovespa.com.br)")
+	rootCmd.PersistentFlags().StringVar(&flags.proxy, "proxy", "", "Proxy HTTP para os downloads (default = variáveis HTTP_PROXY/HTTPS_PROXY)")
+	rootCmd.PersistentFlags().BoolVar(&flags.inseguro, "inseguro", false, "Não verificar os certificados TLS nos downloads")
+	rootCmd.PersistentFlags().BoolVar(&flags.semCache, "sem-cache", false, "Não usar o cache de downloads")
 
 	str := `Uso:{{if .Runnable}}
   {{.UseLine}}{{end}}{{if .HasAvailableSubCommands}}
@@ -139,7 +159,48 @@ func initConfig() {
 		progress.Fatal(err)
 	}
 
-	fmt.Printf("\n\n")
+	if flags.urlCVM == "" && viper.IsSet("urlCVM") {
+		flags.urlCVM = viper.GetString("urlCVM")
+	}
+	if flags.urlB3 == "" && viper.IsSet("urlB3") {
+		flags.urlB3 = viper.GetString("urlB3")
+	}
+	if flags.proxy == "" && viper.IsSet("proxy") {
+		flags.proxy = viper.GetString("proxy")
+	}
+
+	flags.cache.dir = filepath.Join(flags.tempDir, "cache")
+	if viper.IsSet("cacheDir") {
+		flags.cache.dir = viper.GetString("cacheDir")
+	}
+	flags.cache.maxMB = cacheMaxMBDefault
+	if viper.IsSet("cacheMaxMB") {
+		flags.cache.maxMB = viper.GetInt64("cacheMaxMB")
+	}
+	progress.Debug("cacheDir = %s (máx. %d MB)", flags.cache.dir, flags.cache.maxMB)
+	configurarDownloads()
+
+	fmt.Fprint(os.Stderr, "\n\n")
+}
+
+// configurarDownloads configura o proxy e a verificação dos certificados
+// usados em todos os downloads.
+func configurarDownloads() {
+	opções := []ext.DownloaderOption{ext.WithInsecureSkipVerify(flags.inseguro)}
+	if flags.inseguro {
+		progress.Warning("Os certificados TLS não serão verificados")
+	}
+	if flags.proxy != "" {
+		proxy, err := url.Parse(flags.proxy)
+		if err != nil {
+			progress.FatalMsg("Proxy inválido: %s", flags.proxy)
+		}
+		opções = append(opções, ext.WithProxy(proxy))
+	}
+	if !flags.semCache {
+		opções = append(opções, ext.WithCache(abrirCache()))
+	}
+	ext.SetDefaultDownloader(ext.NewDownloader(opções...))
 }
 
 var _db *sqlx.DB
@@ -149,7 +210,7 @@ func db() *sqlx.DB {
 		return _db // abre o banco de dados apenas umas vez
 	}
 	var err error
-	_db, err := sqlx.Open("sqlite3", flags.dataSrc)
+	_db, err = sqlx.Open("sqlite3", flags.dataSrc)
 	if err != nil {
 		progress.FatalMsg("Erro ao abrir/criar o banco de dados, verificar se o diretório existe: %s", flags.dataSrc)
 	}
@@ -0,0 +1,165 @@
+// SPDX-FileCopyrightText: 2023 Adriano Prado <dev@dude333.com>
+//
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	rapina "github.com/dude333/rapinav2"
+	"github.com/dude333/rapinav2/pkg/contabil"
+	"github.com/dude333/rapinav2/pkg/contabil/repositorio"
+	cotRepositório "github.com/dude333/rapinav2/pkg/cotacao/repositorio"
+	"github.com/dude333/rapinav2/pkg/progress"
+)
+
+type flagsTickers struct {
+	ano      int
+	arquivos []string // arquivos do FCA já baixados (zip ou csv)
+}
+
+// tickersCmd represents the tickers command
+var tickersCmd = &cobra.Command{
+	Use:   "tickers [TICKER|CNPJ|NOME]",
+	Short: "Listar os tickers das empresas",
+	Long: `Listar os códigos de negociação (tickers) e a empresa emissora de cada um,
+filtrando por ticker, CNPJ ou início do nome da empresa`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  listarTickers,
+}
+
+// tickersAtualizarCmd represents the tickers atualizar command
+var tickersAtualizarCmd = &cobra.Command{
+	Use:     "atualizar",
+	Aliases: []string{"update"},
+	Short:   "Atualizar os tickers do banco de dados",
+	Long: `Atualizar os tickers de cada empresa com base no arquivo de valores
+mobiliários do FCA (Formulário Cadastral) da CVM e na seção "tickers" do
+arquivo de configuração`,
+	Run: atualizarTickers,
+}
+
+func init() {
+	tickersAtualizarCmd.Flags().IntVarP(&flags.tickers.ano, "ano", "a", 0, "Ano do FCA (default = ano atual ou anterior)")
+	tickersAtualizarCmd.Flags().StringSliceVar(&flags.tickers.arquivos, "arquivo", nil, "Importar arquivo do FCA (zip ou csv) já existente no disco")
+
+	tickersCmd.AddCommand(tickersAtualizarCmd)
+	rootCmd.AddCommand(tickersCmd)
+}
+
+func atualizarTickers(_ *cobra.Command, _ []string) {
+	dfp, err := contabil.NovaDemonstraçãoFinanceira(db(), flags.tempDir, repositorio.CfgURLBase(flags.urlCVM))
+	if err != nil {
+		progress.Fatal(err)
+	}
+
+	substituições, err := carregarTickers()
+	if err != nil {
+		progress.Fatal(err)
+	}
+
+	var n int
+	switch {
+	case len(flags.tickers.arquivos) > 0, flags.tickers.ano > 0:
+		n, err = dfp.ImportarValoresMobiliários(flags.tickers.ano, flags.tickers.arquivos, substituições)
+	default:
+		// O FCA do ano atual pode ainda não ter sido publicado
+		ano := time.Now().Year()
+		n, err = dfp.ImportarValoresMobiliários(ano, nil, substituições)
+		if err != nil {
+			progress.Warning("FCA %d: %v", ano, err)
+			n, err = dfp.ImportarValoresMobiliários(ano-1, nil, substituições)
+		}
+	}
+	if err != nil {
+		progress.Fatal(err)
+	}
+	progress.Status("%d ticker(s) salvo(s)", n)
+
+	// Complementa os tickers com o ISIN das cotações já importadas
+	bd, err := cotRepositório.NovoSqlite(db())
+	if err != nil {
+		progress.Fatal(err)
+	}
+	isins, err := bd.ISINs(context.Background())
+	if err != nil {
+		progress.Fatal(err)
+	}
+	vms, err := dfp.ValoresMobiliários("")
+	if err != nil {
+		progress.Fatal(err)
+	}
+	var comISIN []rapina.ValorMobiliário
+	for _, vm := range vms {
+		if isin, ok := isins[vm.Ticker]; ok && vm.ISIN == "" {
+			vm.ISIN = isin
+			comISIN = append(comISIN, vm)
+		}
+	}
+	if err := dfp.SalvarValoresMobiliários(comISIN); err != nil {
+		progress.Fatal(err)
+	}
+}
+
+func listarTickers(_ *cobra.Command, args []string) {
+	dfp, err := contabil.NovaDemonstraçãoFinanceira(db(), flags.tempDir)
+	if err != nil {
+		progress.Fatal(err)
+	}
+
+	var vms []rapina.ValorMobiliário
+	switch {
+	case len(args) == 0:
+		vms, err = dfp.ValoresMobiliários("")
+	case _reCNPJ.MatchString(args[0]):
+		vms, err = dfp.ValoresMobiliários(args[0])
+	default:
+		var vm rapina.ValorMobiliário
+		vm, err = dfp.ValorMobiliário(args[0])
+		if err == nil {
+			vms = append(vms, vm)
+			break
+		}
+		var empresas []rapina.Empresa
+		empresas, err = dfp.BuscaEmpresas(args[0])
+		for _, e := range empresas {
+			v, _ := dfp.ValoresMobiliários(e.CNPJ)
+			vms = append(vms, v...)
+		}
+	}
+	if err != nil {
+		progress.Fatal(err)
+	}
+
+	for _, vm := range vms {
+		fmt.Printf("%-6s  %-4s  %-12s  %-18s  %s\n", vm.Ticker, vm.Classe, vm.ISIN, vm.CNPJ, vm.CódigoCVM)
+	}
+}
+
+// carregarTickers carrega os tickers do arquivo de configuração (seção
+// "tickers"), se existir.
+func carregarTickers() ([]rapina.ValorMobiliário, error) {
+	arquivo := viper.ConfigFileUsed()
+	if arquivo == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(arquivo)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	vms, err := repositorio.UnmarshalTickers(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", arquivo, err)
+	}
+	return vms, nil
+}
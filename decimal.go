@@ -0,0 +1,333 @@
+// SPDX-FileCopyrightText: 2023 Adriano Prado <dev@dude333.com>
+//
+// SPDX-License-Identifier: MIT
+
+package rapina
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+// Decimal é um número de ponto fixo com EscalaDecimal casas decimais, como o
+// VL_CONTA dos arquivos da CVM (precisão 29, escala 10). As operações são
+// exatas, exceto Mul e Div, que arredondam o resultado na 10ª casa decimal
+// (metade para longe do zero). Os resultados que não cabem em 128 bits são
+// saturados no maior (ou menor) valor possível.
+//
+// O valor é guardado como um inteiro de 128 bits (valor × 10¹⁰), portanto
+// Decimal pode ser comparado com == e o valor zero (Decimal{}) é 0.
+type Decimal struct {
+	hi int64
+	lo uint64
+}
+
+const (
+	// EscalaDecimal é o número de casas decimais de Decimal.
+	EscalaDecimal = 10
+	// PrecisãoDecimal é o número máximo de dígitos aceitos por NovoDecimal.
+	PrecisãoDecimal = 29
+)
+
+var (
+	ErrDecimalInválido     = errors.New("decimal inválido")
+	ErrDecimalForaDoLimite = errors.New("decimal fora do limite")
+)
+
+var (
+	fatorEscala   = big.NewInt(1e10)
+	limiteDecimal = new(big.Int).Exp(big.NewInt(10), big.NewInt(PrecisãoDecimal), nil)
+)
+
+// NovoDecimal converte um número no formato "-123.45" (ou "1.2e+15") em
+// Decimal, arredondando na 10ª casa decimal. Números com mais de 19 dígitos
+// na parte inteira retornam ErrDecimalForaDoLimite.
+func NovoDecimal(s string) (Decimal, error) {
+	inválido := func() (Decimal, error) {
+		return Decimal{}, fmt.Errorf("%w: %q", ErrDecimalInválido, s)
+	}
+
+	num := strings.TrimSpace(s)
+	neg := false
+	if num != "" && (num[0] == '-' || num[0] == '+') {
+		neg = num[0] == '-'
+		num = num[1:]
+	}
+
+	exp := 0
+	if i := strings.IndexAny(num, "eE"); i >= 0 {
+		e, err := strconv.Atoi(num[i+1:])
+		if err != nil || e > 1000 || e < -1000 {
+			return inválido()
+		}
+		exp = e
+		num = num[:i]
+	}
+
+	inteiro, fração, _ := strings.Cut(num, ".")
+	dígitos := inteiro + fração
+	if dígitos == "" {
+		return inválido()
+	}
+	for _, c := range dígitos {
+		if c < '0' || c > '9' {
+			return inválido()
+		}
+	}
+
+	// dígitos × 10^casas = valor × 10^EscalaDecimal
+	casas := EscalaDecimal - len(fração) + exp
+	if casas >= 0 && len(dígitos)+casas <= 18 {
+		// caso mais comum, sem alocação: cabe em int64
+		v, _ := strconv.ParseInt(dígitos, 10, 64)
+		for ; casas > 0; casas-- {
+			v *= 10
+		}
+		if neg {
+			v = -v
+		}
+		return Decimal{hi: v >> 63, lo: uint64(v)}, nil
+	}
+	coef, _ := new(big.Int).SetString(dígitos, 10)
+	if casas >= 0 {
+		coef.Mul(coef, pot10(casas))
+	} else {
+		coef = dividir(coef, pot10(-casas))
+	}
+	if coef.CmpAbs(limiteDecimal) >= 0 {
+		return Decimal{}, fmt.Errorf("%w: %q", ErrDecimalForaDoLimite, s)
+	}
+	if neg {
+		coef.Neg(coef)
+	}
+	return decimalDeBig(coef), nil
+}
+
+// DecimalDeInt retorna o Decimal com o valor inteiro i.
+func DecimalDeInt(i int64) Decimal {
+	return decimalDeBig(new(big.Int).Mul(big.NewInt(i), fatorEscala))
+}
+
+// DecimalDeFloat converte um float64 em Decimal usando a menor representação
+// decimal de f (ex.: 0.1 => 0.1), arredondada na 10ª casa. NaN e valores
+// fora do limite retornam zero.
+func DecimalDeFloat(f float64) Decimal {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return Decimal{}
+	}
+	d, err := NovoDecimal(strconv.FormatFloat(f, 'g', -1, 64))
+	if err != nil {
+		return Decimal{}
+	}
+	return d
+}
+
+// Float64 retorna o float64 mais próximo do valor, para uso em cálculos
+// aproximados e na gravação de planilhas.
+func (d Decimal) Float64() float64 {
+	f, _ := strconv.ParseFloat(d.String(), 64)
+	return f
+}
+
+// Add retorna d + other, saturado no maior (ou menor) valor possível se o
+// resultado não couber em 128 bits.
+func (d Decimal) Add(other Decimal) Decimal {
+	lo, c := bits.Add64(d.lo, other.lo, 0)
+	r := Decimal{hi: d.hi + other.hi + int64(c), lo: lo}
+	// estouro: parcelas com o mesmo sinal e resultado com o sinal oposto
+	if (d.hi < 0) == (other.hi < 0) && (r.hi < 0) != (d.hi < 0) {
+		return saturado(d.hi < 0)
+	}
+	return r
+}
+
+// Sub retorna d - other, saturado no maior (ou menor) valor possível se o
+// resultado não couber em 128 bits.
+func (d Decimal) Sub(other Decimal) Decimal {
+	lo, b := bits.Sub64(d.lo, other.lo, 0)
+	r := Decimal{hi: d.hi - other.hi - int64(b), lo: lo}
+	// estouro: operandos com sinais opostos e resultado com o sinal de other
+	if (d.hi < 0) != (other.hi < 0) && (r.hi < 0) != (d.hi < 0) {
+		return saturado(d.hi < 0)
+	}
+	return r
+}
+
+func (d Decimal) Neg() Decimal {
+	return Decimal{}.Sub(d)
+}
+
+func (d Decimal) Abs() Decimal {
+	if d.hi < 0 {
+		return d.Neg()
+	}
+	return d
+}
+
+// Mul retorna d × other, arredondado na 10ª casa decimal.
+func (d Decimal) Mul(other Decimal) Decimal {
+	p := new(big.Int).Mul(d.big(), other.big())
+	return decimalDeBig(dividir(p, fatorEscala))
+}
+
+// Div retorna d / other, arredondado na 10ª casa decimal, ou zero se
+// other == 0.
+func (d Decimal) Div(other Decimal) Decimal {
+	if other.IsZero() {
+		return Decimal{}
+	}
+	n := new(big.Int).Mul(d.big(), fatorEscala)
+	return decimalDeBig(dividir(n, other.big()))
+}
+
+func (d Decimal) IsZero() bool {
+	return d.hi == 0 && d.lo == 0
+}
+
+// Sign retorna -1, 0 ou +1 conforme o sinal de d.
+func (d Decimal) Sign() int {
+	switch {
+	case d.hi < 0:
+		return -1
+	case d.IsZero():
+		return 0
+	}
+	return 1
+}
+
+// Cmp retorna -1 se d < other, 0 se d == other e +1 se d > other.
+func (d Decimal) Cmp(other Decimal) int {
+	switch {
+	case d.hi < other.hi:
+		return -1
+	case d.hi > other.hi:
+		return 1
+	case d.lo < other.lo:
+		return -1
+	case d.lo > other.lo:
+		return 1
+	}
+	return 0
+}
+
+// String retorna o valor sem zeros à direita (ex.: "-1234.5").
+func (d Decimal) String() string {
+	s := new(big.Int).Abs(d.big()).String()
+	if len(s) <= EscalaDecimal {
+		s = strings.Repeat("0", EscalaDecimal-len(s)+1) + s
+	}
+	inteiro, fração := s[:len(s)-EscalaDecimal], strings.TrimRight(s[len(s)-EscalaDecimal:], "0")
+	if fração != "" {
+		inteiro += "." + fração
+	}
+	if d.hi < 0 {
+		inteiro = "-" + inteiro
+	}
+	return inteiro
+}
+
+// Value grava o valor como texto, sem a perda de precisão do REAL.
+func (d Decimal) Value() (driver.Value, error) {
+	return d.String(), nil
+}
+
+// Scan lê o valor gravado como texto ou como número.
+func (d *Decimal) Scan(src any) error {
+	var err error
+	switch v := src.(type) {
+	case nil:
+		*d = Decimal{}
+	case string:
+		*d, err = NovoDecimal(v)
+	case []byte:
+		*d, err = NovoDecimal(string(v))
+	case int64:
+		*d = DecimalDeInt(v)
+	case float64:
+		*d = DecimalDeFloat(v)
+	default:
+		err = fmt.Errorf("%w: %T", ErrDecimalInválido, src)
+	}
+	return err
+}
+
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalJSON aceita o valor como número ou como texto.
+func (d *Decimal) UnmarshalJSON(b []byte) error {
+	s := strings.Trim(string(b), `"`)
+	if s == "null" {
+		return nil
+	}
+	v, err := NovoDecimal(s)
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
+
+// big retorna o valor × 10¹⁰.
+func (d Decimal) big() *big.Int {
+	neg := d.hi < 0
+	if neg {
+		d = d.Neg()
+	}
+	b := new(big.Int).SetUint64(uint64(d.hi))
+	b.Lsh(b, 64).Or(b, new(big.Int).SetUint64(d.lo))
+	if neg {
+		b.Neg(b)
+	}
+	return b
+}
+
+// decimalDeBig converte o valor × 10¹⁰ em Decimal. Valores que não cabem em
+// 128 bits são saturados no maior (ou menor) valor possível.
+func decimalDeBig(b *big.Int) Decimal {
+	if b.BitLen() > 127 {
+		return saturado(b.Sign() < 0)
+	}
+	abs := new(big.Int).Abs(b)
+	lo := new(big.Int).And(abs, new(big.Int).SetUint64(math.MaxUint64)).Uint64()
+	d := Decimal{hi: int64(new(big.Int).Rsh(abs, 64).Uint64()), lo: lo}
+	if b.Sign() < 0 {
+		d = d.Neg()
+	}
+	return d
+}
+
+// saturado retorna o maior valor possível ou, se neg, o seu oposto.
+func saturado(neg bool) Decimal {
+	máx := Decimal{hi: math.MaxInt64, lo: math.MaxUint64}
+	if neg {
+		return máx.Neg()
+	}
+	return máx
+}
+
+// dividir retorna n / m arredondado para o inteiro mais próximo (metade para
+// longe do zero).
+func dividir(n, m *big.Int) *big.Int {
+	q, r := new(big.Int).QuoRem(n, m, new(big.Int))
+	r.Abs(r).Lsh(r, 1)
+	if r.CmpAbs(m) >= 0 {
+		if n.Sign()*m.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	return q
+}
+
+func pot10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
@@ -0,0 +1,154 @@
+// SPDX-FileCopyrightText: 2023 Adriano Prado <dev@dude333.com>
+//
+// SPDX-License-Identifier: MIT
+
+package rapina
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+)
+
+func dec(s string) Decimal {
+	d, err := NovoDecimal(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func TestNovoDecimal(t *testing.T) {
+	tests := []struct {
+		s    string
+		want string
+		err  error
+	}{
+		{"0", "0", nil},
+		{"123.45", "123.45", nil},
+		{"-123.4500", "-123.45", nil},
+		{"+7", "7", nil},
+		{".5", "0.5", nil},
+		{"-0.0000000001", "-0.0000000001", nil},
+		{"0.00000000005", "0.0000000001", nil},   // arredonda na 10ª casa
+		{"-0.00000000005", "-0.0000000001", nil}, // metade para longe do zero
+		{"0.00000000004", "0", nil},
+		{"1.0e+15", "1000000000000000", nil},
+		{"1.5E-3", "0.0015", nil},
+		{"9999999999999999999.9999999999", "9999999999999999999.9999999999", nil},
+		{"-9999999999999999999.9999999999", "-9999999999999999999.9999999999", nil},
+		{"10000000000000000000", "", ErrDecimalForaDoLimite},
+		{"", "", ErrDecimalInválido},
+		{"-", "", ErrDecimalInválido},
+		{"1,5", "", ErrDecimalInválido},
+		{"abc", "", ErrDecimalInválido},
+		{"1e", "", ErrDecimalInválido},
+	}
+	for _, tt := range tests {
+		got, err := NovoDecimal(tt.s)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("NovoDecimal(%q) error = %v, want %v", tt.s, err, tt.err)
+			continue
+		}
+		if err == nil && got.String() != tt.want {
+			t.Errorf("NovoDecimal(%q) = %s, want %s", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestDecimal_operações(t *testing.T) {
+	// 0.1 + 0.2 em float64 = 0.30000000000000004
+	if got := dec("0.1").Add(dec("0.2")); got != dec("0.3") {
+		t.Errorf("0.1 + 0.2 = %s", got)
+	}
+	// valores acumulados do 2º e do 1º trimestre
+	if got := dec("1234567.89").Sub(dec("1234567.8")); got != dec("0.09") {
+		t.Errorf("q2 - q1 = %s", got)
+	}
+	if got := dec("-3").Sub(dec("2.5")); got.String() != "-5.5" || got.Sign() != -1 {
+		t.Errorf("-3 - 2.5 = %s", got)
+	}
+	if got := dec("-1.5").Mul(dec("2")); got != dec("-3") {
+		t.Errorf("-1.5 × 2 = %s", got)
+	}
+	if got := dec("123456789012345.67").Mul(dec("1000")); got.String() != "123456789012345670" {
+		t.Errorf("mul grande = %s", got)
+	}
+	if got := dec("2").Div(dec("3")); got != dec("0.6666666667") {
+		t.Errorf("2 / 3 = %s", got)
+	}
+	if got := dec("-2").Div(dec("3")); got != dec("-0.6666666667") {
+		t.Errorf("-2 / 3 = %s", got)
+	}
+	if got := dec("5").Div(Decimal{}); !got.IsZero() {
+		t.Errorf("5 / 0 = %s, want 0", got)
+	}
+	if got := dec("-7.25").Abs(); got != dec("7.25") {
+		t.Errorf("|-7.25| = %s", got)
+	}
+	if dec("-1").Cmp(dec("0.5")) != -1 || dec("2").Cmp(dec("1.9999999999")) != 1 || dec("1").Cmp(DecimalDeInt(1)) != 0 {
+		t.Error("Cmp() incorreto")
+	}
+}
+
+func TestDecimal_saturação(t *testing.T) {
+	máx := Decimal{hi: math.MaxInt64, lo: math.MaxUint64}
+	mín := máx.Neg()
+	um := DecimalDeInt(1)
+
+	tests := []struct {
+		name string
+		got  Decimal
+		want Decimal
+	}{
+		{"máx + 1", máx.Add(um), máx},
+		{"mín + -1", mín.Add(um.Neg()), mín},
+		{"mín + máx", mín.Add(máx), Decimal{}},
+		{"máx - -1", máx.Sub(um.Neg()), máx},
+		{"mín - 1", mín.Sub(um), mín},
+		{"máx - máx", máx.Sub(máx), Decimal{}},
+		{"-mín", mín.Neg(), máx},
+		{"máx - 1", máx.Sub(um), Decimal{hi: math.MaxInt64, lo: math.MaxUint64 - 1e10}},
+		{"máx × 2", máx.Mul(DecimalDeInt(2)), máx},
+		{"mín × 2", mín.Mul(DecimalDeInt(2)), mín},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %s, want %s", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestDecimal_conversões(t *testing.T) {
+	if got := DecimalDeFloat(0.1); got != dec("0.1") {
+		t.Errorf("DecimalDeFloat(0.1) = %s", got)
+	}
+	if got := DecimalDeFloat(-1234.5).Float64(); got != -1234.5 {
+		t.Errorf("Float64() = %v", got)
+	}
+	if got := DecimalDeInt(-42); got.String() != "-42" {
+		t.Errorf("DecimalDeInt(-42) = %s", got)
+	}
+
+	var d Decimal
+	for _, src := range []any{"12.5", []byte("12.5"), 12.5} {
+		if err := d.Scan(src); err != nil || d != dec("12.5") {
+			t.Errorf("Scan(%#v) = %s, %v", src, d, err)
+		}
+	}
+	if err := d.Scan(int64(3)); err != nil || d != dec("3") {
+		t.Errorf("Scan(int64) = %s, %v", d, err)
+	}
+	if v, _ := dec("-0.5").Value(); v != "-0.5" {
+		t.Errorf("Value() = %v", v)
+	}
+
+	var v struct{ A, B Decimal }
+	if err := json.Unmarshal([]byte(`{"A": 1.25, "B": "-3"}`), &v); err != nil || v.A != dec("1.25") || v.B != dec("-3") {
+		t.Errorf("json.Unmarshal() = %+v, %v", v, err)
+	}
+	if b, _ := json.Marshal(v); string(b) != `{"A":1.25,"B":-3}` {
+		t.Errorf("json.Marshal() = %s", b)
+	}
+}
@@ -0,0 +1,318 @@
+// SPDX-FileCopyrightText: 2023 Adriano Prado <dev@dude333.com>
+//
+// SPDX-License-Identifier: MIT
+
+package rapina
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Trimestre identifica um trimestre (T = 1 a 4) de um ano.
+type Trimestre struct {
+	Ano int
+	T   int
+}
+
+// Somar retorna o trimestre n trimestres depois de t (ou antes, se n < 0).
+func (t Trimestre) Somar(n int) Trimestre {
+	i := t.índice() + n
+	return Trimestre{Ano: floorDiv(i, 4), T: i - 4*floorDiv(i, 4) + 1}
+}
+
+// String retorna o trimestre no formato usado nos relatórios (ex.: 1T2023).
+func (t Trimestre) String() string {
+	return fmt.Sprintf("%dT%d", t.T, t.Ano)
+}
+
+// Fim retorna o último dia do trimestre.
+func (t Trimestre) Fim() Data {
+	return Data(time.Date(t.Ano, time.Month(3*t.T+1), 0, 0, 0, 0, 0, time.UTC))
+}
+
+func (t Trimestre) índice() int {
+	return 4*t.Ano + t.T - 1
+}
+
+// Opcional é o valor de um trimestre de uma Série. Ok é falso se o
+// trimestre não tiver dados (ex.: ainda não divulgado) ou se o cálculo não
+// for possível (ex.: TTM sem os 4 trimestres, crescimento sobre zero).
+type Opcional struct {
+	Valor Decimal
+	Ok    bool
+}
+
+func presente(v Decimal) Opcional {
+	return Opcional{Valor: v, Ok: true}
+}
+
+// Série contém os valores de trimestres consecutivos a partir de Início.
+// Diferente de ValoresTrimestrais, em que o trimestre sem dados é zero, a
+// Série distingue o valor zero da ausência de valor, que é propagada nos
+// cálculos.
+type Série struct {
+	Início  Trimestre
+	Valores []Opcional
+}
+
+// NovaSérie cria a série com os valores trimestrais de uma conta. Os
+// trimestres dos anos que não estão em valores são ausentes, assim como os
+// trimestres posteriores a último, o último trimestre divulgado (ver
+// ÚltimoTrimestre), para que os trimestres ainda não divulgados não sejam
+// confundidos com valores zerados. Se um ano aparecer mais de uma vez, vale a
+// última ocorrência.
+func NovaSérie(valores []ValoresTrimestrais, último Trimestre) Série {
+	anoInicial, anoFinal := MinMax([]InformeTrimestral{{Valores: valores}})
+	if len(valores) == 0 {
+		return Série{}
+	}
+
+	s := Série{
+		Início:  Trimestre{Ano: anoInicial, T: 1},
+		Valores: make([]Opcional, 4*(anoFinal-anoInicial+1)),
+	}
+	for _, v := range valores {
+		i := 4 * (v.Ano - anoInicial)
+		for j, valor := range []Decimal{v.T1, v.T2, v.T3, v.T4} {
+			if s.Início.Somar(i+j).índice() <= último.índice() {
+				s.Valores[i+j] = presente(valor)
+			}
+		}
+	}
+
+	return s
+}
+
+// ÚltimoTrimestre retorna o último trimestre com algum valor diferente de
+// zero nos informes, ou seja, o último trimestre divulgado pela empresa.
+func ÚltimoTrimestre(itr []InformeTrimestral) (Trimestre, bool) {
+	anoInicial, _ := MinMax(itr)
+	colunas := TrimestresComDados(itr)
+	for i := len(colunas) - 1; i >= 0; i-- {
+		if colunas[i] {
+			return Trimestre{Ano: anoInicial, T: 1}.Somar(i), true
+		}
+	}
+	return Trimestre{}, false
+}
+
+// Fim retorna o último trimestre da série.
+func (s Série) Fim() Trimestre {
+	return s.Início.Somar(len(s.Valores) - 1)
+}
+
+// ÚltimoTrimestre retorna o último trimestre da série com valor.
+func (s Série) ÚltimoTrimestre() (Trimestre, bool) {
+	for i := len(s.Valores) - 1; i >= 0; i-- {
+		if s.Valores[i].Ok {
+			return s.Início.Somar(i), true
+		}
+	}
+	return Trimestre{}, false
+}
+
+// Valor retorna o valor do trimestre, ausente se estiver fora da série.
+func (s Série) Valor(t Trimestre) Opcional {
+	i := t.índice() - s.Início.índice()
+	if i < 0 || i >= len(s.Valores) {
+		return Opcional{}
+	}
+	return s.Valores[i]
+}
+
+// ValoresTrimestrais converte a série no formato usado nos relatórios, com
+// zero nos trimestres ausentes. Os anos sem nenhum valor são omitidos.
+func (s Série) ValoresTrimestrais() []ValoresTrimestrais {
+	var valores []ValoresTrimestrais
+	if len(s.Valores) == 0 {
+		return valores
+	}
+	for ano := s.Início.Ano; ano <= s.Fim().Ano; ano++ {
+		v := ValoresTrimestrais{Ano: ano}
+		algum := false
+		for t, valor := range []*Decimal{&v.T1, &v.T2, &v.T3, &v.T4} {
+			if o := s.Valor(Trimestre{Ano: ano, T: t + 1}); o.Ok {
+				*valor = o.Valor
+				algum = true
+			}
+		}
+		if algum {
+			valores = append(valores, v)
+		}
+	}
+	return valores
+}
+
+// Deslocar retorna a série com cada valor n trimestres depois (defasagem),
+// ou antes se n < 0. Ex.: s.Deslocar(4) contém, em 1T2023, o valor de 1T2022.
+func (s Série) Deslocar(n int) Série {
+	return Série{
+		Início:  s.Início.Somar(n),
+		Valores: append([]Opcional(nil), s.Valores...),
+	}
+}
+
+// TTM retorna, em cada trimestre, a soma dos últimos 12 meses (o trimestre e
+// os 3 anteriores), usada para comparar valores de fluxo (ex.: lucro) com
+// valores do balanço (ex.: patrimônio líquido).
+func (s Série) TTM() Série {
+	return s.mapear(func(t Trimestre) Opcional {
+		return s.somar(t.Somar(-3), t)
+	})
+}
+
+// AcumuladoAno retorna, em cada trimestre, a soma dos valores desde o início
+// do ano.
+func (s Série) AcumuladoAno() Série {
+	return s.mapear(func(t Trimestre) Opcional {
+		return s.somar(Trimestre{Ano: t.Ano, T: 1}, t)
+	})
+}
+
+// Anualizar retorna o valor de cada trimestre multiplicado por 4.
+func (s Série) Anualizar() Série {
+	return s.mapear(func(t Trimestre) Opcional {
+		v := s.Valor(t)
+		v.Valor = v.Valor.Mul(DecimalDeInt(4))
+		return v
+	})
+}
+
+// CrescimentoAnual retorna a variação de cada trimestre em relação ao mesmo
+// trimestre do ano anterior (YoY).
+func (s Série) CrescimentoAnual() Série {
+	return s.Crescimento(4)
+}
+
+// CrescimentoTrimestral retorna a variação de cada trimestre em relação ao
+// trimestre anterior (QoQ).
+func (s Série) CrescimentoTrimestral() Série {
+	return s.Crescimento(1)
+}
+
+// Crescimento retorna a variação de cada trimestre em relação ao valor n
+// trimestres antes: (v - anterior) / |anterior|. O crescimento é ausente se
+// o valor anterior for zero.
+func (s Série) Crescimento(n int) Série {
+	return s.mapear(func(t Trimestre) Opcional {
+		v, anterior := s.Valor(t), s.Valor(t.Somar(-n))
+		if !v.Ok || !anterior.Ok || anterior.Valor.IsZero() {
+			return Opcional{}
+		}
+		return presente(v.Valor.Sub(anterior.Valor).Div(anterior.Valor.Abs()))
+	})
+}
+
+// CAGR retorna a taxa de crescimento anual composta de cada trimestre em
+// relação ao mesmo trimestre de n anos antes. A taxa é ausente se um dos
+// valores não for positivo.
+func (s Série) CAGR(anos int) Série {
+	return s.mapear(func(t Trimestre) Opcional {
+		v, anterior := s.Valor(t), s.Valor(t.Somar(-4*anos))
+		if anos < 1 || !v.Ok || !anterior.Ok || v.Valor.Sign() <= 0 || anterior.Valor.Sign() <= 0 {
+			return Opcional{}
+		}
+		razão := v.Valor.Div(anterior.Valor).Float64()
+		return presente(DecimalDeFloat(math.Pow(razão, 1/float64(anos)) - 1))
+	})
+}
+
+// SaldoMédio retorna, em cada trimestre, a média entre o saldo no fim do
+// trimestre e o saldo 12 meses antes, usada nos indicadores sobre valores
+// médios do balanço (ex.: ROE = lucro TTM / patrimônio líquido médio).
+func (s Série) SaldoMédio() Série {
+	return s.mapear(func(t Trimestre) Opcional {
+		v, anterior := s.Valor(t), s.Valor(t.Somar(-4))
+		if !v.Ok || !anterior.Ok {
+			return Opcional{}
+		}
+		return presente(v.Valor.Add(anterior.Valor).Div(DecimalDeInt(2)))
+	})
+}
+
+// MédiaMóvel retorna, em cada trimestre, a média do trimestre e dos n-1
+// trimestres anteriores.
+func (s Série) MédiaMóvel(n int) Série {
+	return s.mapear(func(t Trimestre) Opcional {
+		soma := s.somar(t.Somar(1-n), t)
+		if n < 1 || !soma.Ok {
+			return Opcional{}
+		}
+		return presente(soma.Valor.Div(DecimalDeInt(int64(n))))
+	})
+}
+
+// Somar soma os valores das duas séries em cada trimestre de s.
+func (s Série) Somar(outra Série) Série {
+	return s.combinar(outra, func(a, b Decimal) Opcional { return presente(a.Add(b)) })
+}
+
+// Subtrair subtrai os valores das duas séries em cada trimestre de s.
+func (s Série) Subtrair(outra Série) Série {
+	return s.combinar(outra, func(a, b Decimal) Opcional { return presente(a.Sub(b)) })
+}
+
+// Dividir divide os valores das duas séries em cada trimestre de s. A
+// divisão por zero resulta em valor ausente.
+func (s Série) Dividir(outra Série) Série {
+	return s.combinar(outra, func(a, b Decimal) Opcional {
+		if b.IsZero() {
+			return Opcional{}
+		}
+		return presente(a.Div(b))
+	})
+}
+
+// TTM retorna a soma dos últimos 12 meses de cada trimestre dos valores de
+// uma conta, com zero nos trimestres sem os 4 valores (ver Série.TTM) e nos
+// posteriores a último.
+func TTM(valores []ValoresTrimestrais, último Trimestre) []ValoresTrimestrais {
+	return NovaSérie(valores, último).TTM().ValoresTrimestrais()
+}
+
+// mapear retorna a série com o mesmo período de s e os valores calculados
+// por f.
+func (s Série) mapear(f func(t Trimestre) Opcional) Série {
+	r := Série{Início: s.Início, Valores: make([]Opcional, len(s.Valores))}
+	for i := range r.Valores {
+		r.Valores[i] = f(s.Início.Somar(i))
+	}
+	return r
+}
+
+// combinar aplica f aos valores das duas séries em cada trimestre de s; o
+// resultado é ausente se um dos valores for ausente.
+func (s Série) combinar(outra Série, f func(a, b Decimal) Opcional) Série {
+	return s.mapear(func(t Trimestre) Opcional {
+		a, b := s.Valor(t), outra.Valor(t)
+		if !a.Ok || !b.Ok {
+			return Opcional{}
+		}
+		return f(a.Valor, b.Valor)
+	})
+}
+
+// somar retorna a soma dos valores de de até até, ausente se algum dos
+// trimestres não tiver valor.
+func (s Série) somar(de, até Trimestre) Opcional {
+	var soma Decimal
+	for t := de; t.índice() <= até.índice(); t = t.Somar(1) {
+		v := s.Valor(t)
+		if !v.Ok {
+			return Opcional{}
+		}
+		soma = soma.Add(v.Valor)
+	}
+	return presente(soma)
+}
+
+// floorDiv retorna a divisão inteira arredondada para baixo.
+func floorDiv(a, b int) int {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
@@ -0,0 +1,184 @@
+// SPDX-FileCopyrightText: 2023 Adriano Prado <dev@dude333.com>
+//
+// SPDX-License-Identifier: MIT
+
+package rapina
+
+import (
+	"reflect"
+	"testing"
+)
+
+// valores retorna os valores de s a partir de de, com "-" nos ausentes.
+func valores(s Série, de Trimestre, n int) []string {
+	r := make([]string, n)
+	for i := range r {
+		v := s.Valor(de.Somar(i))
+		r[i] = "-"
+		if v.Ok {
+			r[i] = v.Valor.String()
+		}
+	}
+	return r
+}
+
+func TestTrimestre_Somar(t *testing.T) {
+	tests := []struct {
+		t    Trimestre
+		n    int
+		want Trimestre
+	}{
+		{Trimestre{2023, 1}, 0, Trimestre{2023, 1}},
+		{Trimestre{2023, 4}, 1, Trimestre{2024, 1}},
+		{Trimestre{2023, 1}, -1, Trimestre{2022, 4}},
+		{Trimestre{2023, 2}, -9, Trimestre{2021, 1}},
+		{Trimestre{2023, 3}, 6, Trimestre{2025, 1}},
+	}
+	for _, tt := range tests {
+		if got := tt.t.Somar(tt.n); got != tt.want {
+			t.Errorf("%v.Somar(%d) = %v, want %v", tt.t, tt.n, got, tt.want)
+		}
+	}
+	if s := (Trimestre{2023, 2}).String(); s != "2T2023" {
+		t.Errorf("String() = %s", s)
+	}
+}
+
+func TestNovaSérie(t *testing.T) {
+	s := NovaSérie([]ValoresTrimestrais{
+		vt(2020, 1, 0, 3, 4),
+		vt(2022, 9, 9, 9, 9),
+		vt(2022, 5, 6, 0, 0), // última ocorrência do ano
+	}, Trimestre{2022, 2})
+	if s.Início != (Trimestre{2020, 1}) || s.Fim() != (Trimestre{2022, 4}) {
+		t.Fatalf("NovaSérie() = %v a %v", s.Início, s.Fim())
+	}
+	want := []string{"1", "0", "3", "4", "-", "-", "-", "-", "5", "6", "-", "-"}
+	if got := valores(s, s.Início, 12); !reflect.DeepEqual(got, want) {
+		t.Errorf("NovaSérie() = %v, want %v", got, want)
+	}
+	if ult, ok := s.ÚltimoTrimestre(); !ok || ult != (Trimestre{2022, 2}) {
+		t.Errorf("ÚltimoTrimestre() = %v, %v", ult, ok)
+	}
+	if v := s.Valor(Trimestre{2019, 4}); v.Ok {
+		t.Errorf("Valor() fora da série = %+v", v)
+	}
+
+	got := s.ValoresTrimestrais()
+	wantVTs := []ValoresTrimestrais{vt(2020, 1, 0, 3, 4), vt(2022, 5, 6, 0, 0)}
+	if !reflect.DeepEqual(got, wantVTs) {
+		t.Errorf("ValoresTrimestrais() = %v, want %v", got, wantVTs)
+	}
+
+	// zeros divulgados não são ausentes
+	s = NovaSérie([]ValoresTrimestrais{vt(2022, 5, 6, 0, 0)}, Trimestre{2022, 3})
+	want = []string{"5", "6", "0", "-"}
+	if got := valores(s, s.Início, 4); !reflect.DeepEqual(got, want) {
+		t.Errorf("NovaSérie() = %v, want %v", got, want)
+	}
+
+	if s := NovaSérie(nil, Trimestre{2022, 4}); len(s.Valores) != 0 || s.ValoresTrimestrais() != nil {
+		t.Errorf("NovaSérie(nil) = %+v", s)
+	}
+}
+
+func TestÚltimoTrimestre(t *testing.T) {
+	itr := []InformeTrimestral{
+		{Codigo: "3.01", Valores: []ValoresTrimestrais{vt(2021, 1, 2, 3, 4), vt(2022, 5, 6, 0, 0)}},
+		{Codigo: "6.01", Valores: []ValoresTrimestrais{vt(2022, 0, 0, 7, 0)}},
+	}
+	if got, ok := ÚltimoTrimestre(itr); !ok || got != (Trimestre{2022, 3}) {
+		t.Errorf("ÚltimoTrimestre() = %v, %v, want 3T2022", got, ok)
+	}
+	if _, ok := ÚltimoTrimestre(nil); ok {
+		t.Error("ÚltimoTrimestre(nil) deveria retornar false")
+	}
+}
+
+func TestSérie_somas(t *testing.T) {
+	s := NovaSérie([]ValoresTrimestrais{
+		vt(2021, 1, 2, 3, 4),
+		vt(2022, 5, 6, 7, 0), // 4T2022 ainda não divulgado
+	}, Trimestre{2022, 3})
+	de := Trimestre{2021, 1}
+
+	tests := []struct {
+		nome string
+		s    Série
+		want []string
+	}{
+		{"TTM", s.TTM(), []string{"-", "-", "-", "10", "14", "18", "22", "-"}},
+		{"AcumuladoAno", s.AcumuladoAno(), []string{"1", "3", "6", "10", "5", "11", "18", "-"}},
+		{"Anualizar", s.Anualizar(), []string{"4", "8", "12", "16", "20", "24", "28", "-"}},
+		{"MédiaMóvel", s.MédiaMóvel(2), []string{"-", "1.5", "2.5", "3.5", "4.5", "5.5", "6.5", "-"}},
+		{"Deslocar", s.Deslocar(4), []string{"-", "-", "-", "-", "1", "2", "3", "4"}},
+		{"Deslocar(-1)", s.Deslocar(-1), []string{"2", "3", "4", "5", "6", "7", "-", "-"}},
+	}
+	for _, tt := range tests {
+		if got := valores(tt.s, de, 8); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s = %v, want %v", tt.nome, got, tt.want)
+		}
+	}
+
+	// ano sem dados interrompe o TTM
+	lacuna := NovaSérie([]ValoresTrimestrais{vt(2020, 1, 1, 1, 1), vt(2022, 2, 2, 2, 2)}, Trimestre{2022, 4})
+	want := []string{"-", "-", "-", "4", "-", "-", "-", "-", "-", "-", "-", "8"}
+	if got := valores(lacuna.TTM(), Trimestre{2020, 1}, 12); !reflect.DeepEqual(got, want) {
+		t.Errorf("TTM() com lacuna = %v, want %v", got, want)
+	}
+
+	// formato do relatório
+	wantVTs := []ValoresTrimestrais{vt(2021, 0, 0, 0, 10), vt(2022, 14, 18, 22, 0)}
+	if got := TTM(s.ValoresTrimestrais(), Trimestre{2022, 3}); !reflect.DeepEqual(got, wantVTs) {
+		t.Errorf("TTM() = %v, want %v", got, wantVTs)
+	}
+}
+
+func TestSérie_crescimento(t *testing.T) {
+	s := NovaSérie([]ValoresTrimestrais{
+		vt(2020, 100, 0, -50, 80),
+		vt(2021, 110, 50, -25, 100),
+		vt(2022, 121, 60, 10, 125),
+	}, Trimestre{2022, 4})
+	de := Trimestre{2021, 1}
+
+	tests := []struct {
+		nome string
+		s    Série
+		want []string
+	}{
+		{"CrescimentoAnual", s.CrescimentoAnual(), []string{"0.1", "-", "0.5", "0.25", "0.1", "0.2", "1.4", "0.25"}},
+		{"CrescimentoTrimestral", s.CrescimentoTrimestral(), []string{"0.375", "-0.5454545455", "-1.5", "5", "0.21", "-0.5041322314", "-0.8333333333", "11.5"}},
+		{"CAGR", s.CAGR(2), []string{"-", "-", "-", "-", "0.1", "-", "-", "0.25"}},
+		{"SaldoMédio", s.SaldoMédio(), []string{"105", "25", "-37.5", "90", "115.5", "55", "-7.5", "112.5"}},
+	}
+	for _, tt := range tests {
+		if got := valores(tt.s, de, 8); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s = %v, want %v", tt.nome, got, tt.want)
+		}
+	}
+	if got := valores(s.CAGR(0), de, 1); got[0] != "-" {
+		t.Errorf("CAGR(0) = %v", got)
+	}
+}
+
+func TestSérie_operações(t *testing.T) {
+	lucro := NovaSérie([]ValoresTrimestrais{vt(2021, 10, 10, 10, 10), vt(2022, 20, 20, 20, 0)}, Trimestre{2022, 3})
+	pl := NovaSérie([]ValoresTrimestrais{vt(2021, 100, 100, 100, 100), vt(2022, 100, 0, 140, 160)}, Trimestre{2022, 4})
+	de := Trimestre{2022, 1}
+
+	// ROE sobre o patrimônio líquido médio
+	roe := lucro.TTM().Dividir(pl.SaldoMédio())
+	if got, want := valores(roe, de, 4), []string{"0.5", "1.2", "0.5833333333", "-"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ROE = %v, want %v", got, want)
+	}
+	if got, want := valores(lucro.Dividir(pl), de, 4), []string{"0.2", "-", "0.1428571429", "-"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Dividir() = %v, want %v", got, want)
+	}
+	if got, want := valores(lucro.Somar(pl), de, 4), []string{"120", "20", "160", "-"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Somar() = %v, want %v", got, want)
+	}
+	if got, want := valores(pl.Subtrair(lucro), de, 4), []string{"80", "-20", "120", "-"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Subtrair() = %v, want %v", got, want)
+	}
+}
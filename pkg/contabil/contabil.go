@@ -7,6 +7,8 @@ package contabil
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/jmoiron/sqlx"
@@ -17,10 +19,15 @@ import (
 	"github.com/dude333/rapinav2/pkg/progress"
 )
 
-var ErrRepositórioInválido = errors.New("repositório inválido")
+var (
+	ErrRepositórioInválido  = errors.New("repositório inválido")
+	ErrImportaçãoIncompleta = errors.New("importação incompleta")
+)
 
 type Importação interface {
 	Importar(ctx context.Context, ano int, trimestral bool) <-chan dominio.Resultado
+	ImportarAnos(ctx context.Context, anos []int) <-chan dominio.Resultado
+	ImportarArquivos(ctx context.Context, arquivos []string) <-chan dominio.Resultado
 }
 
 type Leitura interface {
@@ -46,18 +53,22 @@ type DemonstraçãoFinanceira struct {
 	bd  *repositorio.Sqlite
 }
 
-func NovaDemonstraçãoFinanceira(db *sqlx.DB, tempDir string) (*DemonstraçãoFinanceira, error) {
+// NovaDemonstraçãoFinanceira cria o serviço. As configurações opcionais são
+// repassadas aos repositórios da CVM (ex.: repositorio.CfgJobs) e do banco de
+// dados (ex.: repositorio.CfgUnidade).
+func NovaDemonstraçãoFinanceira(db *sqlx.DB, tempDir string, configs ...repositorio.ConfigFn) (*DemonstraçãoFinanceira, error) {
 	dfp := DemonstraçãoFinanceira{}
 
-	repoSqlite, err := repositorio.NovoSqlite(db)
+	repoSqlite, err := repositorio.NovoSqlite(db, configs...)
 	if err != nil {
 		return &dfp, err
 	}
 
-	repoCVM, err := repositorio.NovoCVM(
+	configs = append([]repositorio.ConfigFn{
 		repositorio.CfgDirDados(tempDir),
 		repositorio.CfgArquivosJáProcessados(repoSqlite.Hashes()),
-	)
+	}, configs...)
+	repoCVM, err := repositorio.NovoCVM(configs...)
 	if err != nil {
 		return &dfp, err
 	}
@@ -72,30 +83,233 @@ func (df *DemonstraçãoFinanceira) Importar(ano int, trimestral bool) error {
 	ctx, cancel := context.WithTimeout(ctx, 20*time.Minute)
 	defer cancel()
 
+	return df.salvar(ctx, func(ctx context.Context) <-chan dominio.Resultado {
+		return df.api.Importar(ctx, ano, trimestral)
+	}, df.gravar)
+}
+
+// ImportarAnos importa as DFPs e ITRs dos anos especificados, baixando e
+// processando os arquivos em paralelo (ver repositorio.CfgJobs e
+// repositorio.CfgDownloads), e os salva no banco de dados. A importação é
+// registrada no diário e pode ser continuada com Retomar se for
+// interrompida (ex.: contexto cancelado).
+func (df *DemonstraçãoFinanceira) ImportarAnos(ctx context.Context, anos []int) error {
+	parâmetros := make([]string, len(anos))
+	for i, ano := range anos {
+		parâmetros[i] = strconv.Itoa(ano)
+	}
+	id, err := df.bd.NovaImportação(ctx, repositorio.ImportaçãoAnos, parâmetros)
+	if err != nil {
+		return err
+	}
+	return df.importarAnos(ctx, id, anos)
+}
+
+func (df *DemonstraçãoFinanceira) importarAnos(ctx context.Context, id int64, anos []int) error {
+	return df.importar(ctx, id, func(ctx context.Context) <-chan dominio.Resultado {
+		return df.api.ImportarAnos(ctx, anos)
+	})
+}
+
+// ImportarCadastro importa o cadastro de companhias abertas da CVM (setor,
+// situação, código CVM...) e o salva no banco de dados.
+func (df *DemonstraçãoFinanceira) ImportarCadastro() error {
+	ctx := context.Background()
+	return df.salvar(ctx, df.api.ImportarCadastro, df.gravar)
+}
+
+// ImportarArquivos importa os relatórios contábeis de arquivos da CVM (zip
+// ou CSV) já existentes no disco e os salva no banco de dados. A importação é
+// registrada no diário, como em ImportarAnos.
+func (df *DemonstraçãoFinanceira) ImportarArquivos(arquivos []string) error {
+	ctx := context.Background()
+	id, err := df.bd.NovaImportação(ctx, repositorio.ImportaçãoArquivos, arquivos)
+	if err != nil {
+		return err
+	}
+	return df.importarArquivos(ctx, id, arquivos)
+}
+
+func (df *DemonstraçãoFinanceira) importarArquivos(ctx context.Context, id int64, arquivos []string) error {
+	return df.importar(ctx, id, func(ctx context.Context) <-chan dominio.Resultado {
+		return df.api.ImportarArquivos(ctx, arquivos)
+	})
+}
+
+// Retomar continua a última importação interrompida (ImportarAnos ou
+// ImportarArquivos), sem reprocessar os arquivos já gravados no diário.
+// Retorna repositorio.ErrNenhumaImportação se não houver importação a
+// retomar.
+func (df *DemonstraçãoFinanceira) Retomar(ctx context.Context) error {
+	imp, err := df.bd.ImportaçãoInterrompida(ctx)
+	if err != nil {
+		return err
+	}
+	hashes, err := df.bd.ArquivosImportados(ctx, imp.ID)
+	if err != nil {
+		return err
+	}
+	progress.Status("Retomando a importação %d (%d arquivos já gravados)", imp.ID, len(hashes))
+	df.api.IgnorarArquivos(hashes)
+
+	switch imp.Tipo {
+	case repositorio.ImportaçãoAnos:
+		anos := make([]int, 0, len(imp.Parâmetros))
+		for _, p := range imp.Parâmetros {
+			ano, err := strconv.Atoi(p)
+			if err != nil {
+				return fmt.Errorf("importação %d: ano inválido: %s", imp.ID, p)
+			}
+			anos = append(anos, ano)
+		}
+		return df.importarAnos(ctx, imp.ID, anos)
+	case repositorio.ImportaçãoArquivos:
+		return df.importarArquivos(ctx, imp.ID, imp.Parâmetros)
+	}
+	return fmt.Errorf("importação %d: tipo inválido: %s", imp.ID, imp.Tipo)
+}
+
+// importar grava os registros recebidos e as estatísticas de leitura de cada
+// arquivo no diário da importação e, se todos os arquivos forem processados,
+// substitui os dados de cada empresa/ano. Se algum arquivo falhar, os dados
+// não são substituídos e a importação fica pendente, para ser concluída com
+// Retomar.
+func (df *DemonstraçãoFinanceira) importar(ctx context.Context, id int64, importar func(context.Context) <-chan dominio.Resultado) error {
+	err := df.salvar(ctx, importar, func(ctx context.Context, lote []*dominio.DemonstraçãoFinanceira, hash string, stats *dominio.Estatísticas) error {
+		if stats != nil {
+			if err := df.bd.SalvarEstatísticas(ctx, id, stats); err != nil {
+				return err
+			}
+		}
+		return df.bd.PrepararLote(ctx, id, lote, hash)
+	})
+	if err != nil {
+		return fmt.Errorf("importação %d: %w", id, err)
+	}
+	return df.bd.AplicarImportação(ctx, id)
+}
+
+// gravar salva diretamente o lote de demonstrações financeiras e o hash do
+// arquivo processado, se houver. As estatísticas só são gravadas nas
+// importações registradas no diário.
+func (df *DemonstraçãoFinanceira) gravar(ctx context.Context, lote []*dominio.DemonstraçãoFinanceira, hash string, _ *dominio.Estatísticas) error {
+	if err := df.bd.SalvarLote(ctx, lote); err != nil {
+		return err
+	}
+	if hash != "" {
+		if err := df.bd.SalvarHash(ctx, hash); err != nil {
+			progress.ErrorMsg("erro salvando hash: %v", err)
+		}
+	}
+	return nil
+}
+
+// maxContasLote é o número de contas acumuladas antes de gravar o lote de
+// demonstrações financeiras no banco de dados.
+const maxContasLote = 200000
+
+// salvar grava no banco de dados os registros recebidos do repositório de
+// importação. A gravação é feita apenas por esta goroutine, em lotes: as
+// demonstrações financeiras são gravadas (gravar) quando o lote fica cheio e
+// junto com o hash de cada arquivo processado, de modo que um arquivo só é
+// marcado como processado depois de todos os seus dados terem sido gravados.
+// As estatísticas de leitura do arquivo são repassadas junto com o hash. Em
+// caso de erro na gravação, a importação é cancelada; os erros na leitura dos
+// arquivos são exibidos e, no fim, retornados como ErrImportaçãoIncompleta.
+func (df *DemonstraçãoFinanceira) salvar(ctx context.Context, importar func(context.Context) <-chan dominio.Resultado,
+	gravar func(ctx context.Context, lote []*dominio.DemonstraçãoFinanceira, hash string, stats *dominio.Estatísticas) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := importar(ctx)
+	defer func() {
+		// Cancela a importação e libera as suas goroutines
+		cancel()
+		for range results {
+		}
+	}()
+
+	var lote []*dominio.DemonstraçãoFinanceira
+	numContas, falhas := 0, 0
+	gravarLote := func(hash string, stats *dominio.Estatísticas) error {
+		if len(lote) == 0 && hash == "" {
+			return nil
+		}
+		err := gravar(ctx, lote, hash, stats)
+		lote, numContas = nil, 0
+		return err
+	}
+
 	// result retorna o registro após a leitura de cada linha
 	// do arquivo importado
-	for result := range df.api.Importar(ctx, ano, trimestral) {
+	for result := range results {
 		if result.Error != nil {
 			progress.Error(result.Error)
+			falhas++
 			continue
 		}
 		if result.Empresa != nil {
-			err := df.bd.Salvar(ctx, result.Empresa)
+			lote = append(lote, result.Empresa)
+			numContas += len(result.Empresa.Contas)
+			if numContas >= maxContasLote {
+				if err := gravarLote("", nil); err != nil {
+					return err
+				}
+			}
+		}
+		if len(result.Cadastro) > 0 {
+			err := df.bd.SalvarCadastro(ctx, result.Cadastro)
 			if err != nil {
 				return err
 			}
 		}
-		if len(result.Hash) > 0 {
-			err := df.bd.SalvarHash(ctx, result.Hash)
+		if len(result.Documentos) > 0 {
+			err := df.bd.SalvarDocumentos(ctx, result.Documentos)
 			if err != nil {
-				progress.ErrorMsg("erro salvando hash: %v", err)
+				return err
+			}
+		}
+		if len(result.Ações) > 0 {
+			err := df.bd.SalvarAções(ctx, result.Ações)
+			if err != nil {
+				return err
+			}
+		}
+		if e := result.Estatísticas; e != nil {
+			if e.TotalRejeitadas() > 0 {
+				progress.Warning("%v", e)
+			} else {
+				progress.Debug("%v", e)
+			}
+		}
+		if len(result.Hash) > 0 {
+			if err := gravarLote(result.Hash, result.Estatísticas); err != nil {
+				return err
 			}
 		}
 	}
 
+	if err := gravarLote("", nil); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if falhas > 0 {
+		return fmt.Errorf("%w: %d erro(s) na leitura dos arquivos", ErrImportaçãoIncompleta, falhas)
+	}
 	return nil
 }
 
+// RelatórioImportação retorna o relatório dos registros descartados na
+// importação informada ou, se id <= 0, na última importação.
+func (df *DemonstraçãoFinanceira) RelatórioImportação(ctx context.Context, id int64) (*dominio.RelatórioImportação, error) {
+	if df.bd == nil {
+		return nil, ErrRepositórioInválido
+	}
+	return df.bd.RelatórioImportação(ctx, id)
+}
+
 func (df *DemonstraçãoFinanceira) Relatório(cnpj string, ano int) (*dominio.DemonstraçãoFinanceira, error) {
 	if df.bd == nil {
 		return &dominio.DemonstraçãoFinanceira{}, ErrRepositórioInválido
@@ -112,6 +326,15 @@ func (df *DemonstraçãoFinanceira) RelatórioTrimestal(cnpj string, consolidado
 	return df.bd.Trimestral(context.Background(), cnpj, consolidado)
 }
 
+// RelatórioTrimestralGrupo retorna os valores trimestrais das contas de um
+// dos grupos apresentados separadamente (repositorio.GruposSeparados).
+func (df *DemonstraçãoFinanceira) RelatórioTrimestralGrupo(cnpj string, consolidado bool, grupo string) ([]rapina.InformeTrimestral, error) {
+	if df.bd == nil {
+		return nil, ErrRepositórioInválido
+	}
+	return df.bd.TrimestralGrupo(context.Background(), cnpj, consolidado, grupo)
+}
+
 func (df *DemonstraçãoFinanceira) Empresas() ([]rapina.Empresa, error) {
 	if df.bd == nil {
 		return []rapina.Empresa{}, ErrRepositórioInválido
@@ -126,3 +349,92 @@ func (df *DemonstraçãoFinanceira) BuscaEmpresas(nome string) ([]rapina.Empresa
 	progress.Debug("Empresas(%s)", nome)
 	return df.bd.BuscaEmpresas(context.Background(), nome)
 }
+
+// ImportarValoresMobiliários importa do FCA do ano informado (ou dos arquivos
+// do FCA já existentes no disco, se informados) os tickers de cada empresa e
+// os salva no banco de dados, aplicando em seguida as substituições
+// informadas (ex.: definidas no arquivo de configuração). Retorna o número de
+// tickers salvos.
+func (df *DemonstraçãoFinanceira) ImportarValoresMobiliários(ano int, arquivos []string, substituições []rapina.ValorMobiliário) (int, error) {
+	if df.api == nil || df.bd == nil {
+		return 0, ErrRepositórioInválido
+	}
+	ctx := context.Background()
+
+	var vms []rapina.ValorMobiliário
+	var err error
+	if len(arquivos) > 0 {
+		vms, err = df.api.ImportarArquivosFCA(ctx, arquivos)
+	} else if ano > 0 {
+		vms, err = df.api.ImportarValoresMobiliários(ctx, ano)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	vms = append(vms, substituições...)
+	return len(vms), df.bd.SalvarValoresMobiliários(ctx, vms)
+}
+
+// SalvarValoresMobiliários complementa os dados dos tickers já existentes
+// (ex.: ISIN obtido das cotações).
+func (df *DemonstraçãoFinanceira) SalvarValoresMobiliários(vms []rapina.ValorMobiliário) error {
+	if df.bd == nil {
+		return ErrRepositórioInválido
+	}
+	return df.bd.SalvarValoresMobiliários(context.Background(), vms)
+}
+
+// ValorMobiliário retorna os dados do ticker (ex.: PETR4), incluindo o CNPJ
+// da empresa emissora.
+func (df *DemonstraçãoFinanceira) ValorMobiliário(ticker string) (rapina.ValorMobiliário, error) {
+	if df.bd == nil {
+		return rapina.ValorMobiliário{}, ErrRepositórioInválido
+	}
+	return df.bd.ValorMobiliário(context.Background(), ticker)
+}
+
+// ValoresMobiliários retorna os tickers da empresa com o CNPJ informado, ou
+// de todas as empresas se o CNPJ for vazio.
+func (df *DemonstraçãoFinanceira) ValoresMobiliários(cnpj string) ([]rapina.ValorMobiliário, error) {
+	if df.bd == nil {
+		return nil, ErrRepositórioInválido
+	}
+	return df.bd.ValoresMobiliários(context.Background(), cnpj)
+}
+
+// Escala retorna a escala monetária (1, 1000...) dos valores da empresa
+// retornados nos relatórios trimestrais.
+func (df *DemonstraçãoFinanceira) Escala(cnpj string) (int, error) {
+	if df.bd == nil {
+		return 0, ErrRepositórioInválido
+	}
+	return df.bd.Escala(context.Background(), cnpj)
+}
+
+// Moeda retorna a moeda dos valores da empresa retornados nos relatórios
+// trimestrais.
+func (df *DemonstraçãoFinanceira) Moeda(cnpj string) (string, error) {
+	if df.bd == nil {
+		return "", ErrRepositórioInválido
+	}
+	return df.bd.Moeda(context.Background(), cnpj)
+}
+
+// Ações retorna a quantidade de ações da empresa em cada data de referência
+// da composição do capital, em ordem crescente de data.
+func (df *DemonstraçãoFinanceira) Ações(cnpj string) ([]rapina.QuantidadeAções, error) {
+	if df.bd == nil {
+		return nil, ErrRepositórioInválido
+	}
+	return df.bd.Ações(context.Background(), cnpj)
+}
+
+// Reapresentações retorna as contas da empresa cujo valor foi alterado em
+// novas versões (reapresentações) das DFPs e ITRs.
+func (df *DemonstraçãoFinanceira) Reapresentações(cnpj string) ([]dominio.Reapresentação, error) {
+	if df.bd == nil {
+		return nil, ErrRepositórioInválido
+	}
+	return df.bd.Reapresentações(context.Background(), cnpj)
+}
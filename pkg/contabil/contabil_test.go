@@ -0,0 +1,55 @@
+// SPDX-FileCopyrightText: 2023 Adriano Prado <dev@dude333.com>
+//
+// SPDX-License-Identifier: MIT
+
+package contabil
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/dude333/rapinav2/pkg/contabil/dominio"
+	"github.com/dude333/rapinav2/pkg/contabil/repositorio"
+)
+
+func TestDemonstraçãoFinanceira_importar_falha(t *testing.T) {
+	db := sqlx.MustConnect("sqlite3", ":memory:")
+	db.SetMaxOpenConns(1)
+
+	df, err := NovaDemonstraçãoFinanceira(db, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	id, err := df.bd.NovaImportação(ctx, repositorio.ImportaçãoArquivos, []string{"a.zip", "b.zip"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// o primeiro arquivo é gravado e o segundo falha
+	err = df.importar(ctx, id, func(context.Context) <-chan dominio.Resultado {
+		results := make(chan dominio.Resultado, 2)
+		results <- dominio.Resultado{Hash: "a"}
+		results <- dominio.Resultado{Error: errors.New("b.zip: arquivo corrompido")}
+		close(results)
+		return results
+	})
+	if !errors.Is(err, ErrImportaçãoIncompleta) {
+		t.Fatalf("importar() error = %v, want %v", err, ErrImportaçãoIncompleta)
+	}
+
+	// a importação não foi aplicada e pode ser retomada sem o arquivo gravado
+	imp, err := df.bd.ImportaçãoInterrompida(ctx)
+	if err != nil || imp.ID != id {
+		t.Fatalf("ImportaçãoInterrompida() = %+v, %v, want importação %d", imp, err, id)
+	}
+	hashes, err := df.bd.ArquivosImportados(ctx, id)
+	if err != nil || !reflect.DeepEqual(hashes, []string{"a"}) {
+		t.Errorf("ArquivosImportados() = %v, %v, want [a]", hashes, err)
+	}
+}
@@ -5,6 +5,7 @@
 package dominio
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 
@@ -20,11 +21,38 @@ type DemonstraçãoFinanceira struct {
 	Contas       []Conta
 }
 
+// Motivos pelos quais uma demonstração financeira ou uma conta é
+// descartada na importação.
+var (
+	ErrCNPJInválido       = errors.New("CNPJ inválido")
+	ErrSemNome            = errors.New("nome da empresa vazio")
+	ErrAnoInválido        = errors.New("ano inválido")
+	ErrSemContas          = errors.New("sem contas")
+	ErrSemCódigo          = errors.New("conta sem código")
+	ErrSemDescrição       = errors.New("conta sem descrição")
+	ErrDataFimInválida    = errors.New("data de fim do exercício inválida")
+	ErrExercícioAnterior  = errors.New("exercício anterior")
+	ErrOrdemExercInválida = errors.New("ordem do exercício inválida")
+)
+
 func (df *DemonstraçãoFinanceira) Válida() bool {
-	return len(df.CNPJ) == len("17.836.901/0001-10") &&
-		len(df.Nome) > 0 &&
-		df.Ano >= 2000 && df.Ano < 2221 && // 2 séculos de rapina :)
-		len(df.Contas) > 0
+	return df.Validar() == nil
+}
+
+// Validar retorna o motivo pelo qual a demonstração financeira é inválida,
+// ou nil se ela for válida.
+func (df *DemonstraçãoFinanceira) Validar() error {
+	switch {
+	case len(df.CNPJ) != len("17.836.901/0001-10"):
+		return ErrCNPJInválido
+	case len(df.Nome) == 0:
+		return ErrSemNome
+	case df.Ano < 2000 || df.Ano >= 2221: // 2 séculos de rapina :)
+		return ErrAnoInválido
+	case len(df.Contas) == 0:
+		return ErrSemContas
+	}
+	return nil
 }
 
 // Conta com os dados das Demonstrações Financeiras Padronizadas (DFP) ou
@@ -39,17 +67,38 @@ type Conta struct {
 	Total        rapina.Dinheiro // $
 	Meses        int             // Meses acumulados desde o início do período
 	Consolidado  bool            // Individual ou Consolidado
+	DataRefer    string          // AAAA-MM-DD, data de referência do documento
+	Versão       int             // Versão do documento (reapresentações)
+	Coluna       string          // Coluna da DMPL (ex.: Capital Social Integralizado)
 }
 
 // Válida retorna verdadeiro se os dados da conta são válidos. Ignora os registros
 // do penúltimo ano, com exceção de 2009, uma vez que a CVM só disponibliza (pelo
 // menos em 2021) dados até 2010.
 func (c *Conta) Válida() bool {
-	return len(c.Código) > 0 &&
-		len(c.Descr) > 0 &&
-		len(c.DataFimExerc) == len("AAAA-MM-DD") &&
-		(c.OrdemExerc == "ÚLTIMO" ||
-			(c.OrdemExerc == "PENÚLTIMO" && strings.HasPrefix(c.DataFimExerc, "2009")))
+	return c.Validar() == nil
+}
+
+// Validar retorna o motivo pelo qual a conta é inválida (ver Válida), ou nil
+// se ela for válida. Os registros do penúltimo ano retornam
+// ErrExercícioAnterior.
+func (c *Conta) Validar() error {
+	switch {
+	case len(c.Código) == 0:
+		return ErrSemCódigo
+	case len(c.Descr) == 0:
+		return ErrSemDescrição
+	case len(c.DataFimExerc) != len("AAAA-MM-DD"):
+		return ErrDataFimInválida
+	case c.OrdemExerc == "ÚLTIMO":
+		return nil
+	case c.OrdemExerc == "PENÚLTIMO":
+		if strings.HasPrefix(c.DataFimExerc, "2009") {
+			return nil
+		}
+		return ErrExercícioAnterior
+	}
+	return ErrOrdemExercInválida
 }
 
 func (df *DemonstraçãoFinanceira) String() string {
@@ -68,6 +117,40 @@ func (df *DemonstraçãoFinanceira) String() string {
 		df.CNPJ, df.Nome, df.Ano, df.DataIniExerc, strings.Join(contasStr, "\n"))
 }
 
+// Documento é uma versão de uma DFP ou ITR entregue à CVM. Novas versões
+// do mesmo documento (mesma empresa, tipo e data de referência) são
+// reapresentações.
+type Documento struct {
+	CNPJ            string
+	Tipo            string // DFP ou ITR
+	DataRefer       string // AAAA-MM-DD
+	Versão          int
+	DataRecebimento string // AAAA-MM-DD
+}
+
+// Reapresentação contém o valor de uma conta que foi alterado entre duas
+// versões do mesmo documento.
+type Reapresentação struct {
+	DataRefer       string // AAAA-MM-DD
+	Código          string
+	Descr           string
+	Coluna          string // coluna da DMPL
+	Consolidado     bool
+	DataIniExerc    string
+	DataFimExerc    string
+	VersãoAnterior  int
+	Versão          int
+	DataRecebimento string // recebimento da nova versão pela CVM, se conhecido
+	ValorAnterior   rapina.Decimal
+	Valor           rapina.Decimal
+	Escala          int
+}
+
+// Diferença retorna a variação do valor entre as versões.
+func (r Reapresentação) Diferença() rapina.Decimal {
+	return r.Valor.Sub(r.ValorAnterior)
+}
+
 type ConfigConta struct {
 	AtivoTotal        []string
 	AtivoCirc         []string
@@ -103,9 +186,14 @@ type ConfigConta struct {
 // -- REPOSITÓRIO & SERVIÇO --
 
 type Resultado struct {
-	Error   error
-	Empresa *DemonstraçãoFinanceira
-	Hash    string
+	Error      error
+	Empresa    *DemonstraçãoFinanceira
+	Ações      []rapina.QuantidadeAções // composição do capital
+	Cadastro   []rapina.Empresa         // cadastro de companhias abertas
+	Documentos []Documento              // versões das DFPs/ITRs entregues
+	Hash       string
+
+	Estatísticas *Estatísticas // leitura do arquivo, enviada junto com o Hash
 }
 
 type Serviço interface {
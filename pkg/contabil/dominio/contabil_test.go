@@ -25,7 +25,7 @@ var cc = []Conta{
 		DataFimExerc: "2020-12-31",
 		OrdemExerc:   "ÚLTIMO",
 		Total: rapina.Dinheiro{
-			Valor:  123.45,
+			Valor:  rapina.DecimalDeFloat(123.45),
 			Escala: 1000,
 			Moeda:  "R$",
 		},
@@ -38,7 +38,7 @@ var cc = []Conta{
 		DataFimExerc: "2020-12-31",
 		OrdemExerc:   "ÚLTIMO",
 		Total: rapina.Dinheiro{
-			Valor:  123.45,
+			Valor:  rapina.DecimalDeFloat(123.45),
 			Escala: 1000,
 			Moeda:  "R$",
 		},
@@ -50,7 +50,7 @@ var cc = []Conta{
 		DataFimExerc: "2020-12-31",
 		OrdemExerc:   "ÚLTIMO",
 		Total: rapina.Dinheiro{
-			Valor:  123.45,
+			Valor:  rapina.DecimalDeFloat(123.45),
 			Escala: 1000,
 			Moeda:  "R$",
 		},
@@ -0,0 +1,282 @@
+// SPDX-FileCopyrightText: 2023 Adriano Prado <dev@dude333.com>
+//
+// SPDX-License-Identifier: MIT
+
+package dominio
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// maxAmostras é o número de registros descartados guardados como exemplo
+// para cada motivo.
+const maxAmostras = 5
+
+// Descarte é um registro (ou grupo de registros) descartado na importação.
+type Descarte struct {
+	Arquivo   string `json:"arquivo"`
+	Linha     int    `json:"linha,omitempty"`
+	CNPJ      string `json:"cnpj,omitempty"`
+	Motivo    string `json:"motivo"`
+	Detalhe   string `json:"detalhe,omitempty"`   // ex.: código da conta, valor inválido
+	Registros int    `json:"registros,omitempty"` // registros descartados juntos; 0 = 1
+	Rejeitado bool   `json:"rejeitado"`           // com erro ou descartado intencionalmente
+}
+
+func (d Descarte) registros() int {
+	if d.Registros > 0 {
+		return d.Registros
+	}
+	return 1
+}
+
+func (d Descarte) String() string {
+	s := d.Arquivo
+	if d.Linha > 0 {
+		s += fmt.Sprintf(":%d", d.Linha)
+	}
+	for _, x := range []string{d.CNPJ, d.Detalhe} {
+		if x != "" {
+			s += " " + x
+		}
+	}
+	if d.Registros > 1 {
+		s += fmt.Sprintf(" (%d registros)", d.Registros)
+	}
+	return s
+}
+
+// Estatísticas contém a contagem das linhas de um arquivo importado e dos
+// registros descartados, por motivo e por empresa.
+type Estatísticas struct {
+	Arquivo    string         `json:"arquivo"`
+	Lidas      int            `json:"lidas"`                // linhas de dados (sem o cabeçalho)
+	Anteriores int            `json:"anteriores,omitempty"` // registros do exercício anterior, importados com o ano anterior
+	Ignoradas  map[string]int `json:"ignoradas,omitempty"`  // descartados intencionalmente, por motivo
+	Rejeitadas map[string]int `json:"rejeitadas,omitempty"` // registros com erro, por motivo
+	Empresas   map[string]int `json:"empresas,omitempty"`   // descartados por CNPJ
+	Amostras   []Descarte     `json:"amostras,omitempty"`   // até maxAmostras por motivo
+}
+
+// Ignorar contabiliza um registro descartado intencionalmente (ex.: período
+// não trimestral).
+func (e *Estatísticas) Ignorar(d Descarte) {
+	d.Rejeitado = false
+	e.Ignoradas = e.descartar(e.Ignoradas, d)
+}
+
+// Rejeitar contabiliza um registro descartado por conter erros.
+func (e *Estatísticas) Rejeitar(d Descarte) {
+	d.Rejeitado = true
+	e.Rejeitadas = e.descartar(e.Rejeitadas, d)
+}
+
+func (e *Estatísticas) descartar(motivos map[string]int, d Descarte) map[string]int {
+	if motivos == nil {
+		motivos = make(map[string]int)
+	}
+	if motivos[d.Motivo] < maxAmostras {
+		e.Amostras = append(e.Amostras, d)
+	}
+	motivos[d.Motivo] += d.registros()
+	if d.CNPJ != "" {
+		if e.Empresas == nil {
+			e.Empresas = make(map[string]int)
+		}
+		e.Empresas[d.CNPJ] += d.registros()
+	}
+	return motivos
+}
+
+// TotalIgnoradas retorna o número de registros descartados intencionalmente.
+func (e *Estatísticas) TotalIgnoradas() int {
+	return soma(e.Ignoradas)
+}
+
+// TotalRejeitadas retorna o número de registros rejeitados.
+func (e *Estatísticas) TotalRejeitadas() int {
+	return soma(e.Rejeitadas)
+}
+
+func soma(m map[string]int) int {
+	total := 0
+	for _, n := range m {
+		total += n
+	}
+	return total
+}
+
+func (e *Estatísticas) String() string {
+	motivos := make([]string, 0, len(e.Rejeitadas))
+	for m, n := range e.Rejeitadas {
+		motivos = append(motivos, fmt.Sprintf("%s: %d", m, n))
+	}
+	sort.Strings(motivos)
+	s := fmt.Sprintf("%s: %d linhas lidas, %d ignoradas, %d rejeitadas",
+		e.Arquivo, e.Lidas, e.TotalIgnoradas(), e.TotalRejeitadas())
+	if len(motivos) > 0 {
+		s += " (" + strings.Join(motivos, ", ") + ")"
+	}
+	return s
+}
+
+// RelatórioImportação resume os registros descartados numa importação, por
+// motivo, por empresa e por arquivo.
+type RelatórioImportação struct {
+	ID       int64  `json:"id"`
+	Tipo     string `json:"tipo"`
+	Situação string `json:"situacao"`
+	Início   string `json:"inicio"`
+	Fim      string `json:"fim,omitempty"`
+
+	Lidas      int `json:"lidas"`
+	Anteriores int `json:"anteriores"`
+	Ignoradas  int `json:"ignoradas"`
+	Rejeitadas int `json:"rejeitadas"`
+
+	Motivos  []ResumoMotivo  `json:"motivos"`
+	Empresas []ResumoEmpresa `json:"empresas"`
+	Arquivos []Estatísticas  `json:"arquivos"`
+}
+
+// ResumoMotivo contém o total de registros descartados por um motivo e
+// alguns exemplos.
+type ResumoMotivo struct {
+	Motivo    string     `json:"motivo"`
+	Rejeitado bool       `json:"rejeitado"`
+	Registros int        `json:"registros"`
+	Amostras  []Descarte `json:"amostras"`
+}
+
+// ResumoEmpresa contém o total de registros descartados de uma empresa.
+type ResumoEmpresa struct {
+	CNPJ      string `json:"cnpj"`
+	Registros int    `json:"registros"`
+}
+
+// NovoRelatórioImportação soma as estatísticas dos arquivos importados. Os
+// motivos e as empresas são ordenados do maior para o menor número de
+// registros descartados.
+func NovoRelatórioImportação(arquivos []Estatísticas) *RelatórioImportação {
+	r := &RelatórioImportação{
+		Motivos:  []ResumoMotivo{},
+		Empresas: []ResumoEmpresa{},
+		Arquivos: arquivos,
+	}
+	if r.Arquivos == nil {
+		r.Arquivos = []Estatísticas{}
+	}
+
+	type chave struct {
+		motivo    string
+		rejeitado bool
+	}
+	motivos := make(map[chave]*ResumoMotivo)
+	somar := func(m map[string]int, rejeitado bool) {
+		for motivo, n := range m {
+			k := chave{motivo, rejeitado}
+			if _, ok := motivos[k]; !ok {
+				motivos[k] = &ResumoMotivo{Motivo: motivo, Rejeitado: rejeitado, Amostras: []Descarte{}}
+			}
+			motivos[k].Registros += n
+		}
+	}
+	empresas := make(map[string]int)
+
+	for i := range arquivos {
+		e := &arquivos[i]
+		r.Lidas += e.Lidas
+		r.Anteriores += e.Anteriores
+		r.Ignoradas += e.TotalIgnoradas()
+		r.Rejeitadas += e.TotalRejeitadas()
+		somar(e.Ignoradas, false)
+		somar(e.Rejeitadas, true)
+		for cnpj, n := range e.Empresas {
+			empresas[cnpj] += n
+		}
+	}
+	for i := range arquivos {
+		for _, d := range arquivos[i].Amostras {
+			m, ok := motivos[chave{d.Motivo, d.Rejeitado}]
+			if ok && len(m.Amostras) < maxAmostras {
+				m.Amostras = append(m.Amostras, d)
+			}
+		}
+	}
+
+	for _, m := range motivos {
+		r.Motivos = append(r.Motivos, *m)
+	}
+	sort.Slice(r.Motivos, func(i, j int) bool {
+		a, b := r.Motivos[i], r.Motivos[j]
+		if a.Registros != b.Registros {
+			return a.Registros > b.Registros
+		}
+		return a.Motivo < b.Motivo
+	})
+
+	for cnpj, n := range empresas {
+		r.Empresas = append(r.Empresas, ResumoEmpresa{CNPJ: cnpj, Registros: n})
+	}
+	sort.Slice(r.Empresas, func(i, j int) bool {
+		a, b := r.Empresas[i], r.Empresas[j]
+		if a.Registros != b.Registros {
+			return a.Registros > b.Registros
+		}
+		return a.CNPJ < b.CNPJ
+	})
+
+	return r
+}
+
+// maxEmpresasResumo é o número de empresas listadas no resumo (String).
+const maxEmpresasResumo = 10
+
+// String retorna o resumo do relatório para ser exibido no terminal.
+func (r *RelatórioImportação) String() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Importação %d (%s, %s): %s", r.ID, r.Tipo, r.Situação, r.Início)
+	if r.Fim != "" {
+		fmt.Fprintf(&b, " a %s", r.Fim)
+	}
+	fmt.Fprintf(&b, "\n%d linhas lidas (%d do exercício anterior), %d registros ignorados, %d rejeitados\n",
+		r.Lidas, r.Anteriores, r.Ignoradas, r.Rejeitadas)
+
+	if len(r.Motivos) > 0 {
+		b.WriteString("\nMotivos:\n")
+		for _, m := range r.Motivos {
+			tipo := "ignorado"
+			if m.Rejeitado {
+				tipo = "rejeitado"
+			}
+			fmt.Fprintf(&b, "  %-9s  %-40s %10d\n", tipo, m.Motivo, m.Registros)
+			for _, d := range m.Amostras {
+				fmt.Fprintf(&b, "      %v\n", d)
+			}
+		}
+	}
+
+	if len(r.Empresas) > 0 {
+		b.WriteString("\nEmpresas:\n")
+		for i, e := range r.Empresas {
+			if i == maxEmpresasResumo {
+				fmt.Fprintf(&b, "  ... mais %d empresas\n", len(r.Empresas)-i)
+				break
+			}
+			fmt.Fprintf(&b, "  %-20s %10d\n", e.CNPJ, e.Registros)
+		}
+	}
+
+	if len(r.Arquivos) > 0 {
+		b.WriteString("\nArquivos:\n")
+		for _, a := range r.Arquivos {
+			fmt.Fprintf(&b, "  %-45s %10d lidas %10d ignoradas %10d rejeitadas\n",
+				a.Arquivo, a.Lidas, a.TotalIgnoradas(), a.TotalRejeitadas())
+		}
+	}
+
+	return b.String()
+}
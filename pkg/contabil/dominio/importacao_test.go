@@ -0,0 +1,53 @@
+// SPDX-FileCopyrightText: 2023 Adriano Prado <dev@dude333.com>
+//
+// SPDX-License-Identifier: MIT
+
+package dominio
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNovoRelatórioImportação(t *testing.T) {
+	a := Estatísticas{Arquivo: "a.csv", Lidas: 100}
+	for i := 0; i < maxAmostras+2; i++ {
+		a.Rejeitar(Descarte{Arquivo: "a.csv", Linha: i + 2, CNPJ: "11.111.111/0001-11", Motivo: "valor inválido"})
+	}
+	a.Ignorar(Descarte{Arquivo: "a.csv", Linha: 50, CNPJ: "22.222.222/0001-22", Motivo: "meses não trimestrais"})
+	a.Anteriores = 30
+
+	b := Estatísticas{Arquivo: "b.csv", Lidas: 50}
+	b.Rejeitar(Descarte{Arquivo: "b.csv", CNPJ: "22.222.222/0001-22", Motivo: "DFP inválida", Registros: 10})
+	b.Rejeitar(Descarte{Arquivo: "b.csv", Linha: 2, CNPJ: "11.111.111/0001-11", Motivo: "valor inválido"})
+
+	r := NovoRelatórioImportação([]Estatísticas{a, b})
+
+	if r.Lidas != 150 || r.Anteriores != 30 || r.Ignoradas != 1 || r.Rejeitadas != 18 {
+		t.Errorf("totais = %d, %d, %d, %d, want 150, 30, 1 e 18", r.Lidas, r.Anteriores, r.Ignoradas, r.Rejeitadas)
+	}
+
+	var motivos []string
+	for _, m := range r.Motivos {
+		motivos = append(motivos, m.Motivo)
+	}
+	if want := []string{"DFP inválida", "valor inválido", "meses não trimestrais"}; !reflect.DeepEqual(motivos, want) {
+		t.Errorf("motivos = %v, want %v", motivos, want)
+	}
+	if m := r.Motivos[1]; m.Registros != maxAmostras+3 || len(m.Amostras) != maxAmostras || !m.Rejeitado {
+		t.Errorf("motivo = %+v, want %d registros e %d amostras", m, maxAmostras+3, maxAmostras)
+	}
+
+	want := []ResumoEmpresa{{"22.222.222/0001-22", 11}, {"11.111.111/0001-11", 8}}
+	if !reflect.DeepEqual(r.Empresas, want) {
+		t.Errorf("empresas = %v, want %v", r.Empresas, want)
+	}
+
+	s := r.String()
+	for _, x := range []string{"150 linhas lidas (30 do exercício anterior)", "b.csv 22.222.222/0001-22 (10 registros)", "a.csv:50"} {
+		if !strings.Contains(s, x) {
+			t.Errorf("String() não contém %q:\n%s", x, s)
+		}
+	}
+}
@@ -4,10 +4,26 @@
 
 package repositorio
 
+import (
+	"strings"
+
+	rapina "github.com/dude333/rapinav2"
+)
+
+// urlCVM é o endereço do site de dados abertos da CVM.
+const urlCVM = "https://dados.cvm.gov.br"
+
 // cfg contém as configurações usadas nos construtores deste repositório.
 type cfg struct {
 	dirDados              string   // Diretório de dados temporários
 	arquivosJáProcessados []string // Hashes dos arquivos já processados
+	jobs                  int      // Arquivos CSV processados em paralelo
+	downloads             int      // Arquivos baixados em paralelo
+	urlBase               string   // Endereço do site de dados da CVM (ou de um espelho)
+
+	unidade int                  // Escala dos valores lidos (0: a mais usada pela empresa)
+	moeda   string               // Moeda dos valores lidos ("": a mais usada pela empresa)
+	câmbio  *rapina.TabelaCâmbio // Cotações usadas na conversão entre moedas
 }
 
 type ConfigFn func(*cfg)
@@ -27,3 +43,65 @@ func CfgArquivosJáProcessados(hashes []string) ConfigFn {
 		}
 	}
 }
+
+// CfgJobs define o número de arquivos CSV processados em paralelo (padrão 1).
+func CfgJobs(n int) ConfigFn {
+	return func(c *cfg) {
+		if n > 0 {
+			c.jobs = n
+		}
+	}
+}
+
+// CfgDownloads define o número de arquivos baixados em paralelo (padrão 1).
+func CfgDownloads(n int) ConfigFn {
+	return func(c *cfg) {
+		if n > 0 {
+			c.downloads = n
+		}
+	}
+}
+
+// CfgURLBase define o endereço do site de dados da CVM (padrão urlCVM), que
+// pode ser substituído por um espelho local ou um servidor de testes. Os
+// arquivos são buscados no mesmo caminho do site da CVM (ex.:
+// <url>/dados/CIA_ABERTA/DOC/DFP/DADOS/dfp_cia_aberta_2022.zip).
+func CfgURLBase(url string) ConfigFn {
+	return func(c *cfg) {
+		if len(url) > 0 {
+			c.urlBase = strings.TrimSuffix(url, "/")
+		}
+	}
+}
+
+// CfgUnidade define a escala (1, 1000 ou 1000000) em que os valores das
+// contas são retornados na leitura, independente da escala em que foram
+// divulgados, para que os valores de empresas diferentes sejam comparáveis.
+func CfgUnidade(escala int) ConfigFn {
+	return func(c *cfg) {
+		if escala > 0 {
+			c.unidade = escala
+		}
+	}
+}
+
+// CfgMoeda define a moeda em que os valores das contas são retornados na
+// leitura. Os valores em outras moedas são convertidos com as cotações de
+// CfgCâmbio.
+func CfgMoeda(moeda string) ConfigFn {
+	return func(c *cfg) {
+		if len(moeda) > 0 {
+			c.moeda = moeda
+		}
+	}
+}
+
+// CfgCâmbio define as cotações usadas na conversão dos valores divulgados
+// em moedas diferentes da moeda dos valores lidos.
+func CfgCâmbio(câmbio *rapina.TabelaCâmbio) ConfigFn {
+	return func(c *cfg) {
+		if câmbio != nil {
+			c.câmbio = câmbio
+		}
+	}
+}
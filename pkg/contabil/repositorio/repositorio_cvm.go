@@ -5,16 +5,15 @@
 package repositorio
 
 import (
-	"bufio"
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
-
-	"golang.org/x/text/encoding/charmap"
-	"golang.org/x/text/transform"
+	"sync"
 
 	rapina "github.com/dude333/rapinav2"
 	"github.com/dude333/rapinav2/pkg/contabil/dominio"
@@ -29,15 +28,17 @@ type cvmDFP struct {
 	Ano         string
 	Consolidado bool
 	Versão      string
+	DataRefer   string // AAAA-MM-DD, data de referência do documento
 
 	Código       string
 	Descr        string
 	GrupoDFP     string
+	Coluna       string // COLUNA_DF, apenas na DMPL
 	DataIniExerc string // AAAA-MM-DD
 	DataFimExerc string // AAAA-MM-DD
 	Meses        int    // Número de meses acumulados desde o início do exercício
 	OrdemExerc   string // ÚLTIMO ou PENÚLTIMO
-	Valor        float64
+	Valor        rapina.Decimal
 	Escala       int
 	Moeda        string
 }
@@ -49,6 +50,10 @@ func (c *cvmDFP) converteConta() dominio.Conta {
 
 	grp := c.GrupoDFP
 	switch {
+	case contém("Mutações do Patrimônio Líquido"):
+		grp = "DMPL"
+	case contém("Resultado Abrangente"):
+		grp = "DRA"
 	case contém("Balanço Patrimonial Passivo"):
 		grp = "BPP"
 	case contém("Balanço Patrimonial Ativo"):
@@ -61,15 +66,20 @@ func (c *cvmDFP) converteConta() dominio.Conta {
 		grp = "DVA"
 	}
 
+	versão, _ := strconv.Atoi(c.Versão)
+
 	conta := dominio.Conta{
 		Código:       c.Código,
 		Descr:        c.Descr,
 		Consolidado:  c.Consolidado,
 		Grupo:        grp,
+		Coluna:       c.Coluna,
 		DataIniExerc: c.DataIniExerc,
 		DataFimExerc: c.DataFimExerc,
 		Meses:        c.Meses,
 		OrdemExerc:   c.OrdemExerc,
+		DataRefer:    c.DataRefer,
+		Versão:       versão,
 		Total: rapina.Dinheiro{
 			Valor:  c.Valor,
 			Escala: c.Escala,
@@ -85,6 +95,11 @@ func (c *cvmDFP) converteConta() dominio.Conta {
 type CVM struct {
 	infra
 	cfg
+
+	// versões de cada documento encontradas nos arquivos de composição do
+	// capital processados
+	versões *versõesDocumentos
+	saída   sync.Mutex // progresso do processamento dos arquivos
 }
 
 func NovoCVM(configs ...ConfigFn) (*CVM, error) {
@@ -102,7 +117,18 @@ func NovoCVM(configs ...ConfigFn) (*CVM, error) {
 		}
 	}
 
+	if cvm.urlBase == "" {
+		cvm.urlBase = urlCVM
+	}
+	if cvm.jobs < 1 {
+		cvm.jobs = 1
+	}
+	if cvm.downloads < 1 {
+		cvm.downloads = 1
+	}
+
 	cvm.infra = &localInfra{dirDados: cvm.dirDados}
+	cvm.versões = novasVersõesDocumentos()
 
 	return &cvm, nil
 }
@@ -115,9 +141,9 @@ func (c *CVM) Importar(ctx context.Context, ano int, trimestral bool) <-chan dom
 	go func() {
 		defer close(results)
 
-		url := urlArquivo(ano, trimestral)
+		url := c.urlArquivo(ano, trimestral)
 
-		arquivos, err := c.DownloadAndUnzip(url, filtros())
+		arquivos, err := c.DownloadZip(ctx, url, filtros())
 		if err != nil {
 			results <- dominio.Resultado{Error: err}
 			return
@@ -126,24 +152,228 @@ func (c *CVM) Importar(ctx context.Context, ano int, trimestral bool) <-chan dom
 			_ = c.Cleanup(arquivos)
 		}()
 
-		for _, arquivo := range arquivos {
-			progress.Running(arquivo.path)
+		c.processarArquivos(ctx, arquivos, results)
+	}()
+
+	return results
+}
+
+// ImportarAnos baixa e processa os arquivos de DFPs e ITRs dos anos
+// informados. Até cfg.downloads arquivos são baixados e até cfg.jobs arquivos
+// CSV são processados ao mesmo tempo, lidos diretamente dos zips; cada zip é
+// liberado assim que todos os seus arquivos forem processados. O canal
+// retornado é fechado quando todos os arquivos forem processados ou o
+// contexto for cancelado.
+func (c *CVM) ImportarAnos(ctx context.Context, anos []int) <-chan dominio.Resultado {
+	results := make(chan dominio.Resultado)
+
+	urls := make(chan string)
+	go func() {
+		defer close(urls)
+		for _, ano := range anos {
+			for _, trimestral := range []bool{false, true} {
+				select {
+				case urls <- c.urlArquivo(ano, trimestral):
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	// Download
+	fila := make(chan tarefaArquivo)
+	var downloads, limpeza sync.WaitGroup
+	for i := 0; i < c.downloads; i++ {
+		downloads.Add(1)
+		go func() {
+			defer downloads.Done()
+			for url := range urls {
+				if ctx.Err() != nil {
+					continue
+				}
+				arquivos, err := c.DownloadZip(ctx, url, filtros())
+				if err != nil {
+					results <- dominio.Resultado{Error: err}
+					continue
+				}
+				pendentes := &sync.WaitGroup{}
+				pendentes.Add(len(arquivos))
+				limpeza.Add(1)
+				go func() {
+					defer limpeza.Done()
+					pendentes.Wait()
+					_ = c.Cleanup(arquivos)
+				}()
+				enfileirar(ctx, fila, arquivos, pendentes)
+			}
+		}()
+	}
+	go func() {
+		downloads.Wait()
+		close(fila)
+	}()
+
+	// Processamento
+	var jobs sync.WaitGroup
+	for i := 0; i < c.jobs; i++ {
+		jobs.Add(1)
+		go func() {
+			defer jobs.Done()
+			for t := range fila {
+				c.processarArquivo(ctx, t.arquivo, results)
+				t.pendentes.Done()
+			}
+		}()
+	}
+
+	go func() {
+		jobs.Wait()
+		limpeza.Wait()
+		close(results)
+	}()
+
+	return results
+}
+
+// tarefaArquivo é um arquivo de um zip a ser processado. pendentes
+// conta os arquivos do mesmo zip ainda não processados.
+type tarefaArquivo struct {
+	arquivo   Arquivo
+	pendentes *sync.WaitGroup
+}
+
+// enfileirar envia os arquivos para a fila de processamento. Se o contexto
+// for cancelado, os arquivos restantes são dados como processados.
+func enfileirar(ctx context.Context, fila chan<- tarefaArquivo, arquivos []Arquivo, pendentes *sync.WaitGroup) {
+	for i, arquivo := range arquivos {
+		select {
+		case fila <- tarefaArquivo{arquivo, pendentes}:
+		case <-ctx.Done():
+			pendentes.Add(i - len(arquivos))
+			return
+		}
+	}
+}
+
+// ImportarArquivos importa as DFPs/ITRs a partir de arquivos já existentes no
+// disco, sem acessar o site da CVM. Cada caminho pode ser um arquivo zip (ex.:
+// dfp_cia_aberta_2022.zip) ou um arquivo CSV já extraído (ex.:
+// dfp_cia_aberta_DRE_con_2022.csv). Os arquivos originais não são apagados.
+func (c *CVM) ImportarArquivos(ctx context.Context, caminhos []string) <-chan dominio.Resultado {
+	results := make(chan dominio.Resultado)
+
+	go func() {
+		defer close(results)
+
+		for _, caminho := range caminhos {
+			if strings.EqualFold(filepath.Ext(caminho), ".zip") {
+				arquivos, err := c.OpenZip(caminho, filtros())
+				if err != nil {
+					results <- dominio.Resultado{Error: fmt.Errorf("%s: %w", caminho, err)}
+					_ = c.Cleanup(arquivos)
+					continue
+				}
+				c.processarArquivos(ctx, arquivos, results)
+				_ = c.Cleanup(arquivos)
+				continue
+			}
 
-			// Ignora arquivos já processados
-			if c.existe(arquivo.hash) {
-				progress.RunWarningMsg("já foi processado anteriormente")
+			if !arquivoDFP(caminho) {
+				progress.Debug("Ignorando arquivo %s", caminho)
 				continue
 			}
-			// Processa o arquivo e envia o resultado para o canal 'results'
-			_ = processarArquivoDFP(ctx, arquivo, results)
-			progress.RunOK()
+			c.processarArquivos(ctx, []Arquivo{arquivoDisco(caminho)}, results)
 		}
 	}()
 
 	return results
 }
 
-func (c CVM) existe(hash string) bool {
+// processarArquivos processa os arquivos CSV, ignorando os que já foram
+// processados anteriormente, e envia os dados para o canal 'results'. Até
+// cfg.jobs arquivos são processados em paralelo.
+func (c *CVM) processarArquivos(ctx context.Context, arquivos []Arquivo, results chan<- dominio.Resultado) {
+	fila := make(chan Arquivo)
+	var wg sync.WaitGroup
+	for i := 0; i < c.jobs; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for arquivo := range fila {
+				c.processarArquivo(ctx, arquivo, results)
+			}
+		}()
+	}
+
+	for _, arquivo := range arquivos {
+		if ctx.Err() != nil {
+			break
+		}
+		fila <- arquivo
+	}
+	close(fila)
+	wg.Wait()
+}
+
+// processarArquivo processa um arquivo CSV e envia os dados para o canal
+// 'results'. Pode ser chamado por várias goroutines.
+func (c *CVM) processarArquivo(ctx context.Context, arquivo Arquivo, results chan<- dominio.Resultado) {
+	if ctx.Err() != nil {
+		return
+	}
+
+	// O hash só é conhecido após a leitura do arquivo; se houver arquivos já
+	// processados, o arquivo é lido uma vez antes para calcular o hash
+	if arquivo.hash == "" && len(c.arquivosJáProcessados) > 0 {
+		hash, err := arquivo.ler(func(io.Reader) error { return nil })
+		if err != nil {
+			c.relatar(arquivo.nome, progress.RunFail)
+			results <- dominio.Resultado{Error: err}
+			return
+		}
+		arquivo.hash = hash
+	}
+
+	// Ignora arquivos já processados
+	if c.existe(arquivo.hash) {
+		c.relatar(arquivo.nome, func() { progress.RunWarningMsg("já foi processado anteriormente") })
+		return
+	}
+	// Processa o arquivo e envia o resultado para o canal 'results'
+	processar := c.processarArquivoDFP
+	switch {
+	case arquivoComposiçãoCapital(arquivo.nome):
+		processar = c.processarComposiçãoCapital
+	case arquivoCadastro(arquivo.nome):
+		processar = processarCadastro
+	case arquivoDocumentos(arquivo.nome):
+		processar = processarDocumentos
+	}
+	if err := processar(ctx, arquivo, results); err != nil {
+		c.relatar(arquivo.nome, progress.RunFail)
+		results <- dominio.Resultado{Error: err}
+		return
+	}
+	c.relatar(arquivo.nome, progress.RunOK)
+}
+
+// relatar exibe o resultado do processamento do arquivo sem misturar a
+// saída das goroutines.
+func (c *CVM) relatar(arquivo string, fim func()) {
+	c.saída.Lock()
+	defer c.saída.Unlock()
+	progress.Running(arquivo)
+	fim()
+}
+
+// IgnorarArquivos acrescenta os hashes aos dos arquivos já processados, que
+// não serão processados novamente. Deve ser chamado antes da importação.
+func (c *CVM) IgnorarArquivos(hashes []string) {
+	c.arquivosJáProcessados = append(c.arquivosJáProcessados, hashes...)
+}
+
+func (c *CVM) existe(hash string) bool {
 	if len(hash) == 0 {
 		return false
 	}
@@ -163,6 +393,8 @@ func filtros() []string {
 		"BPP",
 		"DFC_MD",
 		"DFC_MI",
+		"DMPL",
+		"DRA",
 		"DRE",
 		"DVA",
 	}
@@ -175,86 +407,140 @@ func filtros() []string {
 			"itr_cia_aberta_"+t+"_ind",
 		)
 	}
+	filtros = append(filtros,
+		"dfp_cia_aberta_"+cvmComposiçãoCapital,
+		"itr_cia_aberta_"+cvmComposiçãoCapital,
+		cvmCadastro,
+		"dfp_cia_aberta_20", // índice dos documentos (ex.: dfp_cia_aberta_2023.csv)
+		"itr_cia_aberta_20",
+	)
 
 	return filtros
 }
 
-func urlArquivo(ano int, trimestral bool) string {
+// arquivoDFP retorna verdadeiro se o nome do arquivo corresponde a um dos
+// arquivos de demonstrações financeiras usados (ver filtros).
+func arquivoDFP(caminho string) bool {
+	nome := strings.ToLower(filepath.Base(caminho))
+	for _, f := range filtros() {
+		if strings.Contains(nome, strings.ToLower(f)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *CVM) urlArquivo(ano int, trimestral bool) string {
 	tipo := "DFP"
 	if trimestral {
 		tipo = "ITR"
 	}
 	zip := fmt.Sprintf(`%s_cia_aberta_%d.zip`, tipo, ano)
-	return `http://dados.cvm.gov.br/dados/CIA_ABERTA/DOC/` + tipo + `/DADOS/` + zip
+	return c.urlBase + `/dados/CIA_ABERTA/DOC/` + tipo + `/DADOS/` + zip
 }
 
-func processarArquivoDFP(_ context.Context, arquivo Arquivo, results chan<- dominio.Resultado) error {
-	fh, err := os.Open(arquivo.path)
-	if err != nil {
-		return err
-	}
-	defer fh.Close()
-
-	csv := &csv{sep: ";"}
-
-	stream := transform.NewReader(fh, charmap.ISO8859_1.NewDecoder())
-	scanner := bufio.NewScanner(stream)
+// processarArquivoDFP lê as demonstrações financeiras do arquivo e as envia
+// para o canal 'results', agrupadas por empresa, ano e versão do documento.
+// Todas as versões são enviadas (histórico de reapresentações), da mais
+// antiga para a mais recente.
+func (c *CVM) processarArquivoDFP(_ context.Context, arquivo Arquivo, results chan<- dominio.Resultado) error {
+	documentos := make(map[chaveVersão][]*cvmDFP)
+	var stats *dominio.Estatísticas
 
-	empresas := make(map[string][]*cvmDFP)
+	hash, err := arquivo.ler(func(r io.Reader) error {
+		l, err := novoLeitorCSV(arquivo.nome, r, colunasDFP...)
+		if err != nil {
+			return err
+		}
+		stats = l.stats
+		csv := novoCSV(l)
 
-	for scanner.Scan() {
-		linha := scanner.Text()
+		for {
+			itens, err := l.ler()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
 
-		dfp, err := csv.carregaDFP(linha)
+			dfp, err := csv.carregaDFP(itens)
+			if err != nil {
+				l.rejeitar(csv.cnpj(itens), err)
+				continue
+			}
+			if err := validarRegistro(dfp); err != nil {
+				// os registros do exercício anterior repetem os do documento
+				// do ano anterior e não são descartes
+				if errors.Is(err, dominio.ErrExercícioAnterior) {
+					l.stats.Anteriores++
+					continue
+				}
+				if errors.Is(err, ErrMesesNãoTrimestrais) {
+					l.ignorar(dfp.CNPJ, err)
+				} else {
+					l.rejeitar(dfp.CNPJ, err)
+				}
+				continue
+			}
 
-		if err != nil || ignorarRegistro(dfp) {
-			continue
+			k := novaChaveVersão(dfp.CNPJ, dfp.DataRefer, dfp.Versão)
+			documentos[k] = append(documentos[k], dfp)
 		}
-
-		k := dfp.CNPJ + dfp.Ano + ";" + dfp.Versão
-		empresas[k] = append(empresas[k], dfp)
+	})
+	if err != nil {
+		return err
 	}
 
-	enviarDFP(empresas, arquivo.hash, results)
+	enviarDFP(documentos, hash, stats, results)
 
 	return nil
 }
 
-func ignorarRegistro(dfp *cvmDFP) bool {
+// validarRegistro retorna o motivo pelo qual o registro deve ser
+// descartado, ou nil se ele deve ser importado.
+func validarRegistro(dfp *cvmDFP) error {
 	if dfp.Meses%3 != 0 {
-		progress.Trace("Ignorando registro não multiplo de 3: %v", dfp)
-		return true
+		return fmt.Errorf("%w: %d meses", ErrMesesNãoTrimestrais, dfp.Meses)
 	}
-	return false
+	conta := dfp.converteConta()
+	if err := conta.Validar(); err != nil {
+		return fmt.Errorf("%w: %s %s", err, dfp.Código, dfp.DataFimExerc)
+	}
+	return nil
 }
 
-// enviarDFP envia os dados de todas as empresas de todos os anos do arquivo
-// lido, com base no o mapa empresas[ano]*cvmDFP. Os dados são enviados pelo
-// canal criado pelo método Importar.
-func enviarDFP(empresas map[string][]*cvmDFP, hash string, results chan<- dominio.Resultado) {
-	num := 0
-	for k := range empresas {
-		// Ignora se existir uma versão mais nova
-		if _, ok := empresas[próxChave(k)]; ok {
-			continue
-		}
+// enviarDFP envia os dados de todas as versões dos documentos do arquivo
+// lido, separados por ano. Os dados são enviados pelo canal criado pelo
+// método Importar. Os registros já devem ter sido validados
+// (validarRegistro); as demonstrações inválidas são contabilizadas nas
+// estatísticas do arquivo.
+func enviarDFP(documentos map[chaveVersão][]*cvmDFP, hash string, stats *dominio.Estatísticas, results chan<- dominio.Resultado) {
+	chaves := make([]chaveVersão, 0, len(documentos))
+	for k := range documentos {
+		chaves = append(chaves, k)
+	}
+	ordenarVersões(chaves)
 
-		registros := empresas[k]
+	num := 0
+	for _, k := range chaves {
+		registros := documentos[k]
 		if len(registros) == 0 {
 			continue
 		}
 
 		contas := make(map[string][]dominio.Conta)
+		var anos []string
 
 		for _, reg := range registros {
-			c := reg.converteConta()
-			if c.Válida() {
-				contas[reg.Ano] = append(contas[reg.Ano], c)
-				num++
+			if _, ok := contas[reg.Ano]; !ok {
+				anos = append(anos, reg.Ano)
 			}
+			contas[reg.Ano] = append(contas[reg.Ano], reg.converteConta())
+			num++
 		}
 
-		for ano := range contas {
+		for _, ano := range anos {
 			a, err := strconv.Atoi(ano)
 			if err != nil {
 				continue
@@ -269,50 +555,60 @@ func enviarDFP(empresas map[string][]*cvmDFP, hash string, results chan<- domini
 				Contas: contas[ano],
 			}
 
-			if empresa.Válida() {
-				results <- dominio.Resultado{Empresa: &empresa}
-			} else {
-				results <- dominio.Resultado{Error: ErrDFPInválida}
+			if err := empresa.Validar(); err != nil {
+				stats.Rejeitar(dominio.Descarte{
+					Arquivo:   stats.Arquivo,
+					CNPJ:      empresa.CNPJ,
+					Motivo:    fmt.Sprintf("%v: %v", ErrDFPInválida, err),
+					Detalhe:   fmt.Sprintf("%s %d", empresa.Nome, empresa.Ano),
+					Registros: len(empresa.Contas),
+				})
+				results <- dominio.Resultado{Error: fmt.Errorf("%s: %s %d: %w: %v",
+					stats.Arquivo, empresa.CNPJ, empresa.Ano, ErrDFPInválida, err)}
+				continue
 			}
+			results <- dominio.Resultado{Empresa: &empresa}
 		}
 	} // next k
 
-	results <- dominio.Resultado{Hash: hash}
+	results <- dominio.Resultado{Hash: hash, Estatísticas: stats}
 	progress.Debug("Linhas processadas: %d", num)
 }
 
-// próxChave retora a chave com a próxima chave:
-// "CNPJANO;1" => "CNPJANO;2"
-func próxChave(k string) string {
-	ks := strings.Split(k, ";")
-	if len(ks) != 2 {
-		return k
-	}
-	ver, err := strconv.Atoi(ks[1])
-	if err != nil {
-		return k
-	}
-	return ks[0] + strconv.Itoa(ver+1)
-}
-
 var (
-	ErrCabeçalho    = errors.New("cabeçalho")
-	ErrFaltaItem    = errors.New("itens faltando")
-	ErrDataInválida = errors.New("data inválida")
+	ErrFaltaItem           = errors.New("itens faltando")
+	ErrDataInválida        = errors.New("data inválida")
+	ErrValorInválido       = errors.New("valor inválido")
+	ErrMesesNãoTrimestrais = errors.New("período não múltiplo de 3 meses")
 )
 
-const (
-	numItens int = 11 // número de itens (soma dos parâmetros pos___ da struct csv)
-)
+// colunasDFP são as colunas obrigatórias dos arquivos de DFP/ITR.
+// DT_INI_EXERC não aparece no balanço patrimonial, DT_REFER não aparece nos
+// arquivos antigos e COLUNA_DF aparece apenas na DMPL.
+var colunasDFP = []string{
+	"CNPJ_CIA",
+	"DENOM_CIA",
+	"DT_FIM_EXERC",
+	"VERSAO",
+	"CD_CONTA",
+	"DS_CONTA",
+	"GRUPO_DFP",
+	"ORDEM_EXERC",
+	"VL_CONTA",
+	"ESCALA_MOEDA",
+	"MOEDA",
+}
 
+// csv contém a posição das colunas de um arquivo de DFP/ITR.
 type csv struct {
-	sep           string // separador de campos
-	cabeçalhoLido bool
+	numColunas int
 
 	posCnpj        int
 	posDenomCia    int
 	posDtIniExerc  int
 	posDtFimExerc  int
+	posDtRefer     int
+	posColunaDF    int
 	posVersao      int
 	posCdConta     int
 	posDsConta     int
@@ -323,38 +619,34 @@ type csv struct {
 	posMoeda       int
 }
 
-func (c *csv) lerCabeçalho(linha string) {
-	c.posDtIniExerc = -1 // Este campo não aparece nos dados do balanço patrimonial
-	c.cabeçalhoLido = true
-	títulos := strings.Split(linha, c.sep)
-	for i, t := range títulos {
-		switch t {
-		case "CNPJ_CIA":
-			c.posCnpj = i
-		case "DENOM_CIA":
-			c.posDenomCia = i
-		case "DT_INI_EXERC":
-			c.posDtIniExerc = i
-		case "DT_FIM_EXERC":
-			c.posDtFimExerc = i
-		case "VERSAO":
-			c.posVersao = i
-		case "CD_CONTA":
-			c.posCdConta = i
-		case "DS_CONTA":
-			c.posDsConta = i
-		case "GRUPO_DFP":
-			c.posGrupoDFP = i
-		case "ORDEM_EXERC":
-			c.posOrdemExerc = i
-		case "VL_CONTA":
-			c.posVlConta = i
-		case "ESCALA_MOEDA":
-			c.posEscalaMoeda = i
-		case "MOEDA":
-			c.posMoeda = i
-		}
+// novoCSV retorna a posição das colunas lidas no cabeçalho. As colunas
+// opcionais ausentes ficam com posição -1.
+func novoCSV(l *leitorCSV) *csv {
+	return &csv{
+		numColunas:     len(l.colunas),
+		posCnpj:        l.coluna("CNPJ_CIA"),
+		posDenomCia:    l.coluna("DENOM_CIA"),
+		posDtIniExerc:  l.coluna("DT_INI_EXERC"),
+		posDtFimExerc:  l.coluna("DT_FIM_EXERC"),
+		posDtRefer:     l.coluna("DT_REFER"),
+		posColunaDF:    l.coluna("COLUNA_DF"),
+		posVersao:      l.coluna("VERSAO"),
+		posCdConta:     l.coluna("CD_CONTA"),
+		posDsConta:     l.coluna("DS_CONTA"),
+		posGrupoDFP:    l.coluna("GRUPO_DFP"),
+		posOrdemExerc:  l.coluna("ORDEM_EXERC"),
+		posVlConta:     l.coluna("VL_CONTA"),
+		posEscalaMoeda: l.coluna("ESCALA_MOEDA"),
+		posMoeda:       l.coluna("MOEDA"),
+	}
+}
+
+// cnpj retorna o CNPJ da linha, se houver.
+func (c *csv) cnpj(itens []string) string {
+	if c.posCnpj < len(itens) {
+		return itens[c.posCnpj]
 	}
+	return ""
 }
 
 // carregaDFP transforma uma linha do arquivo DFP em uma estrutura DFP.
@@ -456,15 +748,9 @@ func (c *csv) lerCabeçalho(linha string) {
 //	Tipo Dados: decimal
 //	Precisão  : 29
 //	Scale     : 10
-func (c *csv) carregaDFP(linha string) (*cvmDFP, error) {
-	if !c.cabeçalhoLido {
-		c.lerCabeçalho(linha)
-		return nil, ErrCabeçalho
-	}
-
-	itens := strings.Split(linha, c.sep)
-	if len(itens) < numItens {
-		return nil, ErrFaltaItem
+func (c *csv) carregaDFP(itens []string) (*cvmDFP, error) {
+	if len(itens) < c.numColunas {
+		return nil, fmt.Errorf("%w: %d de %d", ErrFaltaItem, len(itens), c.numColunas)
 	}
 
 	dtIni := "" // dado não aparece no BP
@@ -476,9 +762,18 @@ func (c *csv) carregaDFP(linha string) (*cvmDFP, error) {
 		return nil, err
 	}
 
-	vl, err := strconv.ParseFloat(itens[c.posVlConta], 64)
+	vl, err := rapina.NovoDecimal(itens[c.posVlConta])
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w: %q", ErrValorInválido, itens[c.posVlConta])
+	}
+
+	dtRefer := itens[c.posDtFimExerc]
+	if c.posDtRefer >= 0 {
+		dtRefer = itens[c.posDtRefer]
+	}
+	coluna := ""
+	if c.posColunaDF >= 0 {
+		coluna = itens[c.posColunaDF]
 	}
 
 	return &cvmDFP{
@@ -487,9 +782,11 @@ func (c *csv) carregaDFP(linha string) (*cvmDFP, error) {
 		Ano:          itens[c.posDtFimExerc][:4],
 		Consolidado:  strings.Contains(itens[c.posGrupoDFP], "onsolidado"),
 		Versão:       itens[c.posVersao],
+		DataRefer:    dtRefer,
 		Código:       itens[c.posCdConta],
 		Descr:        itens[c.posDsConta],
 		GrupoDFP:     itens[c.posGrupoDFP],
+		Coluna:       coluna,
 		DataIniExerc: dtIni,
 		DataFimExerc: itens[c.posDtFimExerc],
 		Meses:        m,
@@ -538,22 +835,15 @@ func meses(ini, fim string) (int, error) {
 	return meses, nil
 }
 
+// escala retorna o valor de ESCALA_MOEDA, ou 1 se não for reconhecido.
 func escala(s string) int {
-	switch strings.ToUpper(s) {
-	case "UNIDADE":
+	e, err := rapina.NovaEscala(s)
+	if err != nil {
 		return 1
-	case "MIL":
-		return 1000
-	case "MILHÃO", "MILHAO":
-		return 1e6
 	}
-	return 1
+	return e
 }
 
 func moeda(s string) string {
-	switch strings.ToUpper(s) {
-	case "REAL":
-		return "R$"
-	}
-	return s
+	return rapina.NovaMoeda(s)
 }
@@ -0,0 +1,118 @@
+// SPDX-FileCopyrightText: 2023 Adriano Prado <dev@dude333.com>
+//
+// SPDX-License-Identifier: MIT
+
+package repositorio
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	rapina "github.com/dude333/rapinav2"
+	"github.com/dude333/rapinav2/pkg/contabil/dominio"
+)
+
+// Parte do nome do arquivo com o cadastro das companhias abertas.
+const cvmCadastro = "cad_cia_aberta"
+
+const caminhoCadastro = `/dados/CIA_ABERTA/CAD/DADOS/cad_cia_aberta.csv`
+
+func arquivoCadastro(caminho string) bool {
+	return strings.HasPrefix(strings.ToLower(filepath.Base(caminho)), cvmCadastro)
+}
+
+// ImportarCadastro baixa o cadastro das companhias abertas do site da CVM
+// (setor, situação, código CVM...).
+func (c *CVM) ImportarCadastro(ctx context.Context) <-chan dominio.Resultado {
+	results := make(chan dominio.Resultado)
+
+	go func() {
+		defer close(results)
+
+		arquivo, err := c.Download(ctx, c.urlBase+caminhoCadastro)
+		if err != nil {
+			results <- dominio.Resultado{Error: err}
+			return
+		}
+		defer func() {
+			_ = c.Cleanup([]Arquivo{arquivo})
+		}()
+
+		c.processarArquivos(ctx, []Arquivo{arquivo}, results)
+	}()
+
+	return results
+}
+
+// processarCadastro lê o cadastro das companhias abertas e envia o
+// resultado para o canal 'results'.
+func processarCadastro(_ context.Context, arquivo Arquivo, results chan<- dominio.Resultado) error {
+	registros, hash, err := arquivo.lerCSV("CNPJ_CIA", "DENOM_SOCIAL")
+	if err != nil {
+		return err
+	}
+
+	empresas := cadastro(registros)
+	if len(empresas) == 0 {
+		return ErrSemDados
+	}
+	results <- dominio.Resultado{Cadastro: empresas}
+	results <- dominio.Resultado{Hash: hash}
+
+	return nil
+}
+
+// cadastro converte os registros do cadastro da CVM em empresas. Se houver
+// mais de um registro por CNPJ (ex.: registro antigo cancelado e um novo
+// ativo), prevalece o registro não cancelado ou, entre dois registros na
+// mesma situação, o de início de situação mais recente.
+func cadastro(registros []map[string]string) []rapina.Empresa {
+	type registro struct {
+		empresa     rapina.Empresa
+		iniSituação string
+	}
+	únicos := make(map[string]registro)
+
+	for _, r := range registros {
+		cnpj := r["CNPJ_CIA"]
+		if cnpj == "" {
+			continue
+		}
+		novo := registro{
+			empresa: rapina.Empresa{
+				CNPJ:             cnpj,
+				Nome:             r["DENOM_SOCIAL"],
+				CódigoCVM:        strings.TrimLeft(r["CD_CVM"], "0"),
+				NomeComercial:    r["DENOM_COMERC"],
+				Setor:            r["SETOR_ATIV"],
+				Situação:         r["SIT"],
+				DataRegistro:     r["DT_REG"],
+				DataCancelamento: r["DT_CANCEL"],
+				Auditor:          r["AUDITOR"],
+			},
+			iniSituação: r["DT_INI_SIT"],
+		}
+
+		atual, ok := únicos[cnpj]
+		switch {
+		case !ok:
+		case atual.empresa.Cancelada() != novo.empresa.Cancelada():
+			if novo.empresa.Cancelada() {
+				continue
+			}
+		case novo.iniSituação < atual.iniSituação:
+			continue
+		}
+		únicos[cnpj] = novo
+	}
+
+	empresas := make([]rapina.Empresa, 0, len(únicos))
+	for _, r := range únicos {
+		empresas = append(empresas, r.empresa)
+	}
+	sort.Slice(empresas, func(i, j int) bool { return empresas[i].CNPJ < empresas[j].CNPJ })
+
+	return empresas
+}
@@ -0,0 +1,90 @@
+// SPDX-FileCopyrightText: 2023 Adriano Prado <dev@dude333.com>
+//
+// SPDX-License-Identifier: MIT
+
+package repositorio
+
+import (
+	"context"
+	"math"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	rapina "github.com/dude333/rapinav2"
+	"github.com/dude333/rapinav2/pkg/contabil/dominio"
+)
+
+// Parte do nome dos arquivos com a composição do capital social, enviados
+// nos mesmos zips das DFPs e ITRs (ex.: itr_cia_aberta_composicao_capital_2023.csv).
+const cvmComposiçãoCapital = "composicao_capital"
+
+func arquivoComposiçãoCapital(caminho string) bool {
+	return strings.Contains(strings.ToLower(filepath.Base(caminho)), cvmComposiçãoCapital)
+}
+
+// processarComposiçãoCapital lê a quantidade de ações de cada empresa e
+// envia o resultado para o canal 'results'.
+func (c *CVM) processarComposiçãoCapital(_ context.Context, arquivo Arquivo, results chan<- dominio.Resultado) error {
+	registros, hash, err := arquivo.lerCSV("CNPJ_CIA", "DT_REFER", "VERSAO")
+	if err != nil {
+		return err
+	}
+
+	ações := composiçãoCapital(registros, c.versões)
+	if len(ações) > 0 {
+		results <- dominio.Resultado{Ações: ações}
+	}
+	results <- dominio.Resultado{Hash: hash}
+
+	return nil
+}
+
+// composiçãoCapital converte os registros do arquivo de composição do
+// capital na quantidade de ações de cada empresa e data de referência,
+// usando apenas a versão mais recente de cada documento, inclusive entre
+// arquivos já processados (versões registradas).
+func composiçãoCapital(registros []map[string]string, versões *versõesDocumentos) []rapina.QuantidadeAções {
+	ações := make(map[chaveDocumento]rapina.QuantidadeAções)
+
+	for _, r := range registros {
+		k := novaChaveVersão(r["CNPJ_CIA"], r["DT_REFER"], r["VERSAO"])
+		d, err := rapina.NovaData(k.dataRefer)
+		if err != nil || k.cnpj == "" {
+			continue
+		}
+		if !versões.registrar(k) {
+			continue
+		}
+		ações[k.chaveDocumento] = rapina.QuantidadeAções{
+			CNPJ:          k.cnpj,
+			Data:          d,
+			Ordinárias:    quantidade(r["QT_ACAO_ORDIN_CAP_INTEGR"]),
+			Preferenciais: quantidade(r["QT_ACAO_PREF_CAP_INTEGR"]),
+			TesourariaON:  quantidade(r["QT_ACAO_ORDIN_TESOURO"]),
+			TesourariaPN:  quantidade(r["QT_ACAO_PREF_TESOURO"]),
+		}
+	}
+
+	lista := make([]rapina.QuantidadeAções, 0, len(ações))
+	for _, a := range ações {
+		lista = append(lista, a)
+	}
+	sort.Slice(lista, func(i, j int) bool {
+		if lista[i].CNPJ != lista[j].CNPJ {
+			return lista[i].CNPJ < lista[j].CNPJ
+		}
+		return lista[i].Data.String() < lista[j].Data.String()
+	})
+
+	return lista
+}
+
+func quantidade(s string) int64 {
+	v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return 0
+	}
+	return int64(math.Round(v))
+}
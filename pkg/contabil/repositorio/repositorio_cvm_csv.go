@@ -0,0 +1,193 @@
+// SPDX-FileCopyrightText: 2023 Adriano Prado <dev@dude333.com>
+//
+// SPDX-License-Identifier: MIT
+
+package repositorio
+
+import (
+	encCSV "encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/transform"
+
+	"github.com/dude333/rapinav2/pkg/contabil/dominio"
+	"github.com/dude333/rapinav2/pkg/progress"
+)
+
+var (
+	ErrCabeçalho       = errors.New("cabeçalho")
+	ErrLinhaMalformada = errors.New("linha malformada")
+)
+
+// ErroLinha é um erro na leitura de uma linha de um arquivo CSV.
+type ErroLinha struct {
+	Arquivo string
+	Linha   int
+	Err     error
+}
+
+func (e *ErroLinha) Error() string {
+	return fmt.Sprintf("%s:%d: %v", e.Arquivo, e.Linha, e.Err)
+}
+
+func (e *ErroLinha) Unwrap() error { return e.Err }
+
+// leitorCSV lê um arquivo CSV da CVM (ISO-8859-1, separado por ";", com
+// campos opcionalmente entre aspas) linha a linha, sem limite de tamanho
+// de linha, e contabiliza as linhas lidas, ignoradas e rejeitadas.
+type leitorCSV struct {
+	arquivo string
+	r       *encCSV.Reader
+	colunas map[string]int // coluna => posição
+	linha   int            // linha do último registro lido
+	stats   *dominio.Estatísticas
+}
+
+// novoLeitorCSV lê o cabeçalho do arquivo, retornando erro caso falte
+// alguma das colunas obrigatórias.
+func novoLeitorCSV(arquivo string, r io.Reader, obrigatórias ...string) (*leitorCSV, error) {
+	l := &leitorCSV{
+		arquivo: arquivo,
+		r:       encCSV.NewReader(transform.NewReader(r, charmap.ISO8859_1.NewDecoder())),
+		colunas: make(map[string]int),
+		stats:   &dominio.Estatísticas{Arquivo: arquivo},
+	}
+	l.r.Comma = ';'
+	l.r.LazyQuotes = true
+	l.r.FieldsPerRecord = -1
+	l.r.ReuseRecord = true
+
+	cabeçalho, err := l.r.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("%s: %w: arquivo vazio", arquivo, ErrCabeçalho)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: %v", arquivo, ErrCabeçalho, err)
+	}
+	for i, col := range cabeçalho {
+		if i == 0 {
+			col = strings.TrimPrefix(col, "ï»¿") // BOM UTF-8 lido como ISO-8859-1
+		}
+		l.colunas[strings.TrimSpace(col)] = i
+	}
+
+	var faltando []string
+	for _, col := range obrigatórias {
+		if _, ok := l.colunas[col]; !ok {
+			faltando = append(faltando, col)
+		}
+	}
+	if len(faltando) > 0 {
+		return nil, fmt.Errorf("%s: %w: faltando %s", arquivo, ErrCabeçalho, strings.Join(faltando, ", "))
+	}
+
+	return l, nil
+}
+
+// coluna retorna a posição da coluna no arquivo ou -1 se ela não existir.
+func (l *leitorCSV) coluna(nome string) int {
+	if i, ok := l.colunas[nome]; ok {
+		return i
+	}
+	return -1
+}
+
+// ler retorna a próxima linha do arquivo ou io.EOF no fim do arquivo. O
+// slice retornado é reutilizado na leitura seguinte. Linhas malformadas
+// são rejeitadas e a leitura continua na linha seguinte.
+func (l *leitorCSV) ler() ([]string, error) {
+	for {
+		itens, err := l.r.Read()
+		if err == io.EOF {
+			return nil, err
+		}
+		var pe *encCSV.ParseError
+		if errors.As(err, &pe) {
+			l.linha = pe.StartLine
+			l.stats.Lidas++
+			l.rejeitar("", fmt.Errorf("%w: %v", ErrLinhaMalformada, pe.Err))
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", l.arquivo, err)
+		}
+		l.linha, _ = l.r.FieldPos(0)
+		l.stats.Lidas++
+		return itens, nil
+	}
+}
+
+// rejeitar contabiliza a última linha lida como rejeitada pelo motivo do
+// erro.
+func (l *leitorCSV) rejeitar(cnpj string, err error) {
+	progress.Debug("%v", &ErroLinha{Arquivo: l.arquivo, Linha: l.linha, Err: err})
+	l.stats.Rejeitar(l.descarte(cnpj, err))
+}
+
+// ignorar contabiliza a última linha lida como descartada intencionalmente.
+func (l *leitorCSV) ignorar(cnpj string, err error) {
+	progress.Trace("%v", &ErroLinha{Arquivo: l.arquivo, Linha: l.linha, Err: err})
+	l.stats.Ignorar(l.descarte(cnpj, err))
+}
+
+func (l *leitorCSV) descarte(cnpj string, err error) dominio.Descarte {
+	d := dominio.Descarte{
+		Arquivo: l.arquivo,
+		Linha:   l.linha,
+		CNPJ:    cnpj,
+		Motivo:  motivo(err),
+	}
+	if detalhe := err.Error(); detalhe != d.Motivo {
+		d.Detalhe = detalhe
+	}
+	return d
+}
+
+// registro retorna a linha como um mapa coluna => valor.
+func (l *leitorCSV) registro(itens []string) map[string]string {
+	registro := make(map[string]string, len(l.colunas))
+	for col, i := range l.colunas {
+		if i < len(itens) {
+			registro[col] = strings.TrimSpace(itens[i])
+		}
+	}
+	return registro
+}
+
+// motivo retorna a mensagem do erro mais interno, usada para agrupar as
+// linhas rejeitadas.
+func motivo(err error) string {
+	for {
+		e := errors.Unwrap(err)
+		if e == nil {
+			return err.Error()
+		}
+		err = e
+	}
+}
+
+// lerCSVCabeçalho lê um arquivo CSV da CVM com cabeçalho, retornando cada
+// linha como um mapa coluna => valor.
+func lerCSVCabeçalho(arquivo string, r io.Reader, obrigatórias ...string) ([]map[string]string, error) {
+	l, err := novoLeitorCSV(arquivo, r, obrigatórias...)
+	if err != nil {
+		return nil, err
+	}
+
+	var registros []map[string]string
+	for {
+		itens, err := l.ler()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		registros = append(registros, l.registro(itens))
+	}
+	return registros, nil
+}
@@ -0,0 +1,174 @@
+// SPDX-FileCopyrightText: 2023 Adriano Prado <dev@dude333.com>
+//
+// SPDX-License-Identifier: MIT
+
+package repositorio
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding/charmap"
+
+	"github.com/dude333/rapinav2/pkg/contabil/dominio"
+)
+
+func Test_novoLeitorCSV_cabeçalho(t *testing.T) {
+	_, err := novoLeitorCSV("dfp_cia_aberta_DRE_con_2022.csv", strings.NewReader("CNPJ_CIA;DENOM_CIA;DT_FIM_EXERC\n"), colunasDFP...)
+	if !errors.Is(err, ErrCabeçalho) {
+		t.Fatalf("novoLeitorCSV() error = %v, want %v", err, ErrCabeçalho)
+	}
+	for _, s := range []string{"dfp_cia_aberta_DRE_con_2022.csv", "VL_CONTA", "CD_CONTA"} {
+		if !strings.Contains(err.Error(), s) {
+			t.Errorf("novoLeitorCSV() error = %q, want %q", err, s)
+		}
+	}
+
+	if _, err := novoLeitorCSV("vazio.csv", strings.NewReader(""), "CNPJ_CIA"); !errors.Is(err, ErrCabeçalho) {
+		t.Errorf("novoLeitorCSV() error = %v, want %v", err, ErrCabeçalho)
+	}
+}
+
+func Test_leitorCSV_ler(t *testing.T) {
+	longo := strings.Repeat("x", 100*1024) // maior que o buffer do bufio.Scanner
+	csv := "A;B;C\n" +
+		`1;"com ; ponto e vírgula";"com ""aspas"""` + "\n" +
+		"2;" + longo + ";fim\n" +
+		"3;\"multi\nlinha\";c\n" +
+		"4;ação;c\n"
+	conteúdo, err := charmap.ISO8859_1.NewEncoder().String(csv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := novoLeitorCSV("teste.csv", strings.NewReader(conteúdo), "A", "C")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := [][]string{
+		{"1", "com ; ponto e vírgula", `com "aspas"`},
+		{"2", longo, "fim"},
+		{"3", "multi\nlinha", "c"},
+		{"4", "ação", "c"},
+	}
+	linhas := []int{2, 3, 4, 6}
+	for i := range want {
+		itens, err := l.ler()
+		if err != nil {
+			t.Fatalf("ler() error = %v", err)
+		}
+		if !reflect.DeepEqual(itens, want[i]) {
+			t.Errorf("ler() = %q, want %q", itens, want[i])
+		}
+		if l.linha != linhas[i] {
+			t.Errorf("linha = %d, want %d", l.linha, linhas[i])
+		}
+	}
+	if _, err := l.ler(); err != io.EOF {
+		t.Errorf("ler() error = %v, want EOF", err)
+	}
+	if l.stats.Lidas != 4 {
+		t.Errorf("Lidas = %d, want 4", l.stats.Lidas)
+	}
+}
+
+func Test_processarArquivoDFP_estatísticas(t *testing.T) {
+	csv := "CNPJ_CIA;DT_REFER;VERSAO;DENOM_CIA;GRUPO_DFP;MOEDA;ESCALA_MOEDA;ORDEM_EXERC;DT_INI_EXERC;DT_FIM_EXERC;CD_CONTA;DS_CONTA;VL_CONTA\n" +
+		"60.840.055/0001-31;2022-12-31;1;FLEURY S.A.;DF Consolidado - Demonstração do Resultado;REAL;MIL;ÚLTIMO;2022-01-01;2022-12-31;3.01;\"Receita; bruta\";100.0\n" +
+		"60.840.055/0001-31;2022-12-31;1;FLEURY S.A.;DF Consolidado - Demonstração do Resultado;REAL;MIL;ÚLTIMO;2022-01-01;2022-12-31;3.02;Custo;abc\n" +
+		"60.840.055/0001-31;2022-12-31;1;FLEURY S.A.;DF Consolidado - Demonstração do Resultado;REAL;MIL;ÚLTIMO;2022-01-01;2022-12;3.03;Lucro;1.0\n" +
+		"60.840.055/0001-31;2022-12-31;1;FLEURY S.A.;DF Consolidado - Demonstração do Resultado;REAL;MIL;ÚLTIMO;2022-01-01;2022-02-28;3.04;Lucro;1.0\n" +
+		"60.840.055/0001-31;2022-12-31;1;FLEURY S.A.;DF Consolidado - Demonstração do Resultado;REAL;MIL;PENÚLTIMO;2021-01-01;2021-12-31;3.01;Receita;90.0\n" +
+		"60.840.055/0001-31;2022-12-31;1\n"
+	conteúdo, err := charmap.ISO8859_1.NewEncoder().String(csv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	arquivo := Arquivo{
+		nome:  "dfp_cia_aberta_DRE_con_2022.csv",
+		abrir: func() (io.ReadCloser, error) { return io.NopCloser(strings.NewReader(conteúdo)), nil },
+	}
+
+	c, err := NovoCVM(CfgDirDados(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := make(chan dominio.Resultado, 10)
+	if err := c.processarArquivoDFP(context.Background(), arquivo, results); err != nil {
+		t.Fatal(err)
+	}
+	close(results)
+
+	var stats *dominio.Estatísticas
+	var contas []dominio.Conta
+	for r := range results {
+		if r.Empresa != nil {
+			contas = append(contas, r.Empresa.Contas...)
+		}
+		if r.Estatísticas != nil {
+			stats = r.Estatísticas
+		}
+	}
+
+	if len(contas) != 1 || contas[0].Descr != "Receita; bruta" {
+		t.Errorf("contas = %+v, want apenas 'Receita; bruta'", contas)
+	}
+	if stats == nil || stats.Lidas != 6 || stats.Anteriores != 1 {
+		t.Fatalf("estatísticas = %+v, want 6 linhas lidas, 1 do exercício anterior", stats)
+	}
+	ignoradas := map[string]int{ErrMesesNãoTrimestrais.Error(): 1}
+	rejeitadas := map[string]int{
+		ErrValorInválido.Error(): 1,
+		ErrDataInválida.Error():  1,
+		ErrFaltaItem.Error():     1,
+	}
+	if !reflect.DeepEqual(stats.Ignoradas, ignoradas) || !reflect.DeepEqual(stats.Rejeitadas, rejeitadas) {
+		t.Errorf("ignoradas = %v, rejeitadas = %v, want %v e %v", stats.Ignoradas, stats.Rejeitadas, ignoradas, rejeitadas)
+	}
+	if n := stats.Empresas["60.840.055/0001-31"]; n != 4 {
+		t.Errorf("descartes da empresa = %d, want 4", n)
+	}
+	want := dominio.Descarte{
+		Arquivo:   arquivo.nome,
+		Linha:     3,
+		CNPJ:      "60.840.055/0001-31",
+		Motivo:    ErrValorInválido.Error(),
+		Detalhe:   `valor inválido: "abc"`,
+		Rejeitado: true,
+	}
+	if len(stats.Amostras) != 4 || !reflect.DeepEqual(stats.Amostras[0], want) {
+		t.Errorf("amostras = %+v, want %+v primeiro", stats.Amostras, want)
+	}
+}
+
+func Test_enviarDFP_dfpInválida(t *testing.T) {
+	dfp := &cvmDFP{CNPJ: "123", Nome: "X", Ano: "2022", Código: "3.01", Descr: "Receita", DataFimExerc: "2022-12-31", OrdemExerc: "ÚLTIMO"}
+	documentos := map[chaveVersão][]*cvmDFP{novaChaveVersão("123", "2022-12-31", "1"): {dfp, dfp}}
+	stats := &dominio.Estatísticas{Arquivo: "dfp.csv"}
+	results := make(chan dominio.Resultado, 10)
+
+	enviarDFP(documentos, "h", stats, results)
+	close(results)
+
+	var erros []error
+	for r := range results {
+		if r.Empresa != nil {
+			t.Errorf("empresa inválida enviada: %+v", r.Empresa.Empresa)
+		}
+		if r.Error != nil {
+			erros = append(erros, r.Error)
+		}
+	}
+	if len(erros) != 1 || !errors.Is(erros[0], ErrDFPInválida) || !strings.Contains(erros[0].Error(), "dfp.csv: 123 2022") {
+		t.Errorf("erros = %v, want %v com contexto", erros, ErrDFPInválida)
+	}
+	motivo := ErrDFPInválida.Error() + ": " + dominio.ErrCNPJInválido.Error()
+	if stats.Rejeitadas[motivo] != 2 {
+		t.Errorf("rejeitadas = %v, want %s: 2", stats.Rejeitadas, motivo)
+	}
+}
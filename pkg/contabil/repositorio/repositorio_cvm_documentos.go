@@ -0,0 +1,79 @@
+// SPDX-FileCopyrightText: 2023 Adriano Prado <dev@dude333.com>
+//
+// SPDX-License-Identifier: MIT
+
+package repositorio
+
+import (
+	"context"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dude333/rapinav2/pkg/contabil/dominio"
+)
+
+// Nome dos arquivos com o índice dos documentos entregues à CVM, enviados
+// nos mesmos zips das DFPs e ITRs (ex.: itr_cia_aberta_2023.csv). Contêm a
+// versão e a data de recebimento de cada documento.
+var reArquivoDocumentos = regexp.MustCompile(`^(dfp|itr)_cia_aberta_\d{4}\.csv$`)
+
+func arquivoDocumentos(caminho string) bool {
+	return reArquivoDocumentos.MatchString(strings.ToLower(filepath.Base(caminho)))
+}
+
+// processarDocumentos lê o índice dos documentos entregues e envia as
+// versões de cada documento para o canal 'results'.
+func processarDocumentos(_ context.Context, arquivo Arquivo, results chan<- dominio.Resultado) error {
+	registros, hash, err := arquivo.lerCSV("CNPJ_CIA", "DT_REFER", "VERSAO")
+	if err != nil {
+		return err
+	}
+
+	tipo := strings.ToUpper(filepath.Base(arquivo.nome)[:3])
+	docs := documentos(registros, tipo)
+	if len(docs) > 0 {
+		results <- dominio.Resultado{Documentos: docs}
+	}
+	results <- dominio.Resultado{Hash: hash}
+
+	return nil
+}
+
+// documentos converte os registros do índice em documentos. O tipo (DFP ou
+// ITR) é o da categoria do documento ou, na sua falta, o informado.
+func documentos(registros []map[string]string, tipo string) []dominio.Documento {
+	docs := make([]dominio.Documento, 0, len(registros))
+	for _, r := range registros {
+		versão, err := strconv.Atoi(r["VERSAO"])
+		if err != nil || r["CNPJ_CIA"] == "" || r["DT_REFER"] == "" {
+			continue
+		}
+		t := tipo
+		if r["CATEG_DOC"] != "" {
+			t = strings.ToUpper(r["CATEG_DOC"])
+		}
+		docs = append(docs, dominio.Documento{
+			CNPJ:            r["CNPJ_CIA"],
+			Tipo:            t,
+			DataRefer:       r["DT_REFER"],
+			Versão:          versão,
+			DataRecebimento: r["DT_RECEB"],
+		})
+	}
+
+	sort.Slice(docs, func(i, j int) bool {
+		a, b := docs[i], docs[j]
+		if a.CNPJ != b.CNPJ {
+			return a.CNPJ < b.CNPJ
+		}
+		if a.DataRefer != b.DataRefer {
+			return a.DataRefer < b.DataRefer
+		}
+		return a.Versão < b.Versão
+	})
+
+	return docs
+}
@@ -0,0 +1,196 @@
+// SPDX-FileCopyrightText: 2023 Adriano Prado <dev@dude333.com>
+//
+// SPDX-License-Identifier: MIT
+
+package repositorio
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	rapina "github.com/dude333/rapinav2"
+)
+
+// Arquivos do FCA (Formulário Cadastral) usados na importação dos valores
+// mobiliários.
+const (
+	fcaValorMobiliário = "fca_cia_aberta_valor_mobiliario"
+	fcaGeral           = "fca_cia_aberta_geral"
+)
+
+func (c *CVM) urlArquivoFCA(ano int) string {
+	return c.urlBase + fmt.Sprintf(`/dados/CIA_ABERTA/DOC/FCA/DADOS/fca_cia_aberta_%d.zip`, ano)
+}
+
+// ImportarValoresMobiliários baixa o FCA do ano informado do site da CVM e
+// retorna os valores mobiliários negociados na B3 (ações, units e BDRs) com
+// o código de negociação de cada empresa.
+func (c *CVM) ImportarValoresMobiliários(ctx context.Context, ano int) ([]rapina.ValorMobiliário, error) {
+	if ano < 2010 {
+		return nil, ErrAnoInválidoFn(ano)
+	}
+
+	arquivos, err := c.DownloadZip(ctx, c.urlArquivoFCA(ano), []string{fcaValorMobiliário, fcaGeral})
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = c.Cleanup(arquivos)
+	}()
+
+	return lerFCA(arquivos)
+}
+
+// ImportarArquivosFCA lê os valores mobiliários de arquivos do FCA já
+// existentes no disco (zip ou CSVs extraídos).
+func (c *CVM) ImportarArquivosFCA(_ context.Context, caminhos []string) ([]rapina.ValorMobiliário, error) {
+	var csvs []Arquivo
+	defer func() {
+		_ = c.Cleanup(csvs)
+	}()
+	for _, caminho := range caminhos {
+		if !strings.EqualFold(filepath.Ext(caminho), ".zip") {
+			csvs = append(csvs, arquivoDisco(caminho))
+			continue
+		}
+		arquivos, err := c.OpenZip(caminho, []string{fcaValorMobiliário, fcaGeral})
+		if err != nil {
+			_ = c.Cleanup(arquivos)
+			return nil, fmt.Errorf("%s: %w", caminho, err)
+		}
+		csvs = append(csvs, arquivos...)
+	}
+	return lerFCA(csvs)
+}
+
+// lerFCA combina os arquivos de valores mobiliários (ticker e classe) e de
+// dados gerais (código CVM) do FCA, usando apenas a versão mais recente do
+// formulário de cada empresa.
+func lerFCA(arquivos []Arquivo) ([]rapina.ValorMobiliário, error) {
+	var valores, gerais []map[string]string
+	for _, arquivo := range arquivos {
+		nome := strings.ToLower(filepath.Base(arquivo.nome))
+		var destino *[]map[string]string
+		switch {
+		case strings.Contains(nome, fcaValorMobiliário):
+			destino = &valores
+		case strings.Contains(nome, fcaGeral):
+			destino = &gerais
+		default:
+			continue
+		}
+		registros, _, err := arquivo.lerCSV("CNPJ_Companhia", "Versao")
+		if err != nil {
+			return nil, err
+		}
+		*destino = append(*destino, registros...)
+	}
+	if len(valores) == 0 {
+		return nil, ErrSemDados
+	}
+
+	versões := últimasVersões(valores)
+	códigosCVM := make(map[string]string)
+	for cnpj, r := range últimasVersões(gerais) {
+		códigosCVM[cnpj] = strings.TrimLeft(r["Codigo_CVM"], "0")
+	}
+
+	únicos := make(map[string]rapina.ValorMobiliário)
+	for _, r := range valores {
+		cnpj := r["CNPJ_Companhia"]
+		if r["Versao"] != versões[cnpj]["Versao"] {
+			continue
+		}
+		ticker := strings.ToUpper(strings.TrimSpace(r["Codigo_Negociacao"]))
+		if !tickerVálido(ticker) {
+			continue
+		}
+		únicos[ticker] = rapina.ValorMobiliário{
+			Ticker:    ticker,
+			Classe:    classe(r["Valor_Mobiliario"], r["Sigla_Classe_Acao_Preferencial"], ticker),
+			CNPJ:      cnpj,
+			CódigoCVM: códigosCVM[cnpj],
+		}
+	}
+
+	vms := make([]rapina.ValorMobiliário, 0, len(únicos))
+	for _, vm := range únicos {
+		vms = append(vms, vm)
+	}
+	sort.Slice(vms, func(i, j int) bool { return vms[i].Ticker < vms[j].Ticker })
+
+	return vms, nil
+}
+
+// últimasVersões retorna o registro com a maior versão do formulário de
+// cada CNPJ.
+func últimasVersões(registros []map[string]string) map[string]map[string]string {
+	últimas := make(map[string]map[string]string)
+	for _, r := range registros {
+		cnpj := r["CNPJ_Companhia"]
+		atual, ok := últimas[cnpj]
+		if !ok {
+			últimas[cnpj] = r
+			continue
+		}
+		v1, _ := strconv.Atoi(atual["Versao"])
+		v2, _ := strconv.Atoi(r["Versao"])
+		if v2 > v1 {
+			últimas[cnpj] = r
+		}
+	}
+	return últimas
+}
+
+// tickerVálido verifica se o código está no formato da B3: 4 letras seguidas
+// de 1 ou 2 dígitos (ex.: PETR4, TAEE11).
+func tickerVálido(t string) bool {
+	if len(t) < 5 || len(t) > 6 {
+		return false
+	}
+	for i, r := range t {
+		letra := r >= 'A' && r <= 'Z'
+		dígito := r >= '0' && r <= '9'
+		if (i < 4 && !letra && !dígito) || (i >= 4 && !dígito) {
+			return false
+		}
+	}
+	return true
+}
+
+// classe converte o tipo de valor mobiliário do FCA na classe usada na B3,
+// usando o sufixo do ticker quando o tipo não for reconhecido.
+func classe(valorMobiliário, siglaPreferencial, ticker string) string {
+	vm := rapina.NormalizeString(valorMobiliário)
+	switch {
+	case strings.Contains(vm, "ordinaria"):
+		return "ON"
+	case strings.Contains(vm, "preferencia"):
+		if s := strings.ToUpper(strings.TrimSpace(siglaPreferencial)); strings.HasPrefix(s, "PN") {
+			return s
+		}
+		return "PN"
+	case strings.Contains(vm, "unit"):
+		return "UNT"
+	case strings.Contains(vm, "bdr") || strings.Contains(vm, "depositario"):
+		return "BDR"
+	}
+
+	switch ticker[4:] {
+	case "3":
+		return "ON"
+	case "4":
+		return "PN"
+	case "5":
+		return "PNA"
+	case "6":
+		return "PNB"
+	case "11":
+		return "UNT"
+	}
+	return ""
+}
@@ -0,0 +1,90 @@
+// SPDX-FileCopyrightText: 2023 Adriano Prado <dev@dude333.com>
+//
+// SPDX-License-Identifier: MIT
+
+package repositorio
+
+import (
+	"archive/zip"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// infraZipsAbertos conta os zips abertos e fechados pelo CVM.
+type infraZipsAbertos struct {
+	localInfra
+	abertos  int
+	fechados int
+}
+
+func (f *infraZipsAbertos) OpenZip(arquivo string, filtros []string) ([]Arquivo, error) {
+	arquivos, err := f.localInfra.OpenZip(arquivo, filtros)
+	if err != nil {
+		return arquivos, err
+	}
+	f.abertos++
+	for i := range arquivos {
+		fechar := arquivos[i].fechar
+		arquivos[i].fechar = func() error {
+			f.fechados++
+			return fechar()
+		}
+	}
+	return arquivos, nil
+}
+
+func TestCVM_ImportarArquivosFCA(t *testing.T) {
+	dir := t.TempDir()
+	zipFCA := filepath.Join(dir, "fca_cia_aberta_2023.zip")
+	fh, err := os.Create(zipFCA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	z := zip.NewWriter(fh)
+	for nome, conteúdo := range map[string]string{
+		"fca_cia_aberta_valor_mobiliario_2023.csv": "CNPJ_Companhia;Versao;Valor_Mobiliario;Codigo_Negociacao;Sigla_Classe_Acao_Preferencial\n" +
+			"60.840.055/0001-31;1;Ações Ordinárias;FLRY3;\n",
+		"fca_cia_aberta_geral_2023.csv": "CNPJ_Companhia;Versao;Codigo_CVM\n60.840.055/0001-31;1;021881\n",
+	} {
+		w, err := z.Create(nome)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(conteúdo)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := z.Close(); err != nil {
+		t.Fatal(err)
+	}
+	fh.Close()
+
+	t.Run("zips liberados após a leitura", func(t *testing.T) {
+		f := &infraZipsAbertos{}
+		c := &CVM{infra: f}
+		vms, err := c.ImportarArquivosFCA(context.Background(), []string{zipFCA})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(vms) != 1 || vms[0].Ticker != "FLRY3" || vms[0].CódigoCVM != "21881" {
+			t.Errorf("ImportarArquivosFCA() = %+v", vms)
+		}
+		if f.abertos != 1 || f.fechados != 2 {
+			t.Errorf("zips abertos = %d, arquivos fechados = %d, want 1 e 2", f.abertos, f.fechados)
+		}
+	})
+
+	t.Run("zips liberados após erro", func(t *testing.T) {
+		f := &infraZipsAbertos{}
+		c := &CVM{infra: f}
+		_, err := c.ImportarArquivosFCA(context.Background(), []string{zipFCA, filepath.Join(dir, "inexistente.zip")})
+		if err == nil {
+			t.Fatal("ImportarArquivosFCA() deveria retornar erro")
+		}
+		if f.abertos != 1 || f.fechados != 2 {
+			t.Errorf("zips abertos = %d, arquivos fechados = %d, want 1 e 2", f.abertos, f.fechados)
+		}
+	})
+}
@@ -5,13 +5,21 @@
 package repositorio
 
 import (
+	"archive/zip"
 	"context"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
+	"sync"
 	"testing"
 
+	rapina "github.com/dude333/rapinav2"
 	"github.com/dude333/rapinav2/pkg/contabil/dominio"
 	"github.com/jmoiron/sqlx"
+	"golang.org/x/text/encoding/charmap"
 )
 
 func Test_cvm_Importar(t *testing.T) {
@@ -78,6 +86,202 @@ func Test_cvm_Importar(t *testing.T) {
 	}
 }
 
+func Test_cvm_ImportarArquivos(t *testing.T) {
+	const csvDRE = "CNPJ_CIA;DT_REFER;VERSAO;DENOM_CIA;CD_CVM;GRUPO_DFP;MOEDA;ESCALA_MOEDA;ORDEM_EXERC;DT_INI_EXERC;DT_FIM_EXERC;CD_CONTA;DS_CONTA;VL_CONTA;ST_CONTA_FIXA\n" +
+		"60.840.055/0001-31;2022-12-31;1;FLEURY S.A.;021881;DF Consolidado - Demonstração do Resultado;REAL;MIL;ÚLTIMO;2022-01-01;2022-12-31;3.01;Receita de Venda de Bens e/ou Serviços;4000000.0000000000;S\n" +
+		"60.840.055/0001-31;2022-12-31;1;FLEURY S.A.;021881;DF Consolidado - Demonstração do Resultado;REAL;MIL;ÚLTIMO;2022-01-01;2022-12-31;3.11;Lucro/Prejuízo Consolidado do Período;70924.0000000000;S\n"
+
+	const csvDocumentos = "CNPJ_CIA;DT_REFER;VERSAO;DENOM_CIA;CD_CVM;CATEG_DOC;ID_DOC;DT_RECEB;LINK_DOC\n" +
+		"60.840.055/0001-31;2022-12-31;1;FLEURY S.A.;021881;DFP;123456;2023-03-10;http://www.rad.cvm.gov.br\n"
+
+	dir := t.TempDir()
+	conteúdo, err := charmap.ISO8859_1.NewEncoder().String(csvDRE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	índice, err := charmap.ISO8859_1.NewEncoder().String(csvDocumentos)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Arquivo zip, como baixado do site da CVM
+	caminhoZip := filepath.Join(dir, "dfp_cia_aberta_2022.zip")
+	fh, err := os.Create(caminhoZip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(fh)
+	for nome, s := range map[string]string{"dfp_cia_aberta_DRE_con_2022.csv": conteúdo, "dfp_cia_aberta_2022.csv": índice} {
+		w, err := zw.Create(nome)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = w.Write([]byte(s))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	fh.Close()
+
+	// Arquivo csv já extraído
+	caminhoCSV := filepath.Join(dir, "itr_cia_aberta_DRE_con_2022.csv")
+	if err := os.WriteFile(caminhoCSV, []byte(conteúdo), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := NovoCVM(CfgDirDados(filepath.Join(dir, "tmp")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var empresas, documentos int
+	var hashes []string
+	for result := range c.ImportarArquivos(context.Background(), []string{caminhoZip, caminhoCSV}) {
+		if result.Error != nil {
+			t.Fatalf("ImportarArquivos() error = %v", result.Error)
+		}
+		if result.Empresa != nil {
+			empresas++
+			if len(result.Empresa.Contas) != 2 {
+				t.Errorf("contas = %d, want 2", len(result.Empresa.Contas))
+			}
+		}
+		if len(result.Documentos) > 0 {
+			documentos++
+			if d := result.Documentos[0]; d.Versão != 1 || d.DataRecebimento != "2023-03-10" {
+				t.Errorf("documento = %+v", d)
+			}
+		}
+		if result.Hash != "" {
+			hashes = append(hashes, result.Hash)
+		}
+	}
+
+	if empresas != 2 || documentos != 1 || len(hashes) != 3 {
+		t.Errorf("empresas = %d, documentos = %d, hashes = %d, want 2, 1 e 3", empresas, documentos, len(hashes))
+	}
+	if _, err := os.Stat(caminhoCSV); err != nil {
+		t.Errorf("arquivo original não deveria ser apagado: %v", err)
+	}
+	// Os CSVs são lidos diretamente do zip, sem serem extraídos
+	if extraídos, _ := os.ReadDir(filepath.Join(dir, "tmp")); len(extraídos) > 0 {
+		t.Errorf("arquivos extraídos = %v, want nenhum", extraídos)
+	}
+
+	t.Run("arquivos já processados", func(t *testing.T) {
+		c, err := NovoCVM(CfgDirDados(filepath.Join(dir, "tmp")), CfgArquivosJáProcessados(hashes))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for result := range c.ImportarArquivos(context.Background(), []string{caminhoZip, caminhoCSV}) {
+			if result.Error != nil || result.Empresa != nil || result.Hash != "" {
+				t.Errorf("arquivo já processado não deveria ser importado: %+v", result)
+			}
+		}
+	})
+}
+
+// infraTeste simula o download dos arquivos da CVM: cada zip contém um CSV
+// de DRE com uma empresa no ano do arquivo.
+type infraTeste struct {
+	localInfra
+
+	mu       sync.Mutex
+	baixados []string
+	abertos  int
+	fechados int
+}
+
+func (f *infraTeste) DownloadZip(_ context.Context, url string, _ []string) ([]Arquivo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.baixados = append(f.baixados, url)
+
+	nome := strings.ToLower(strings.TrimSuffix(filepath.Base(url), ".zip"))
+	ano := nome[len(nome)-4:]
+	csv := "CNPJ_CIA;DT_REFER;VERSAO;DENOM_CIA;GRUPO_DFP;MOEDA;ESCALA_MOEDA;ORDEM_EXERC;DT_INI_EXERC;DT_FIM_EXERC;CD_CONTA;DS_CONTA;VL_CONTA\n" +
+		fmt.Sprintf("60.840.055/0001-31;%[1]s-12-31;1;FLEURY S.A.;DF Consolidado - Demonstração do Resultado;REAL;MIL;ÚLTIMO;%[1]s-01-01;%[1]s-12-31;3.01;Receita;100.0\n", ano)
+	conteúdo, err := charmap.ISO8859_1.NewEncoder().String(csv)
+	if err != nil {
+		return nil, err
+	}
+
+	var arquivos []Arquivo
+	for _, grupo := range []string{"DRE_con", "DRE_ind"} {
+		arquivos = append(arquivos, Arquivo{
+			nome:   strings.Replace(nome, "_aberta_", "_aberta_"+grupo+"_", 1) + ".csv",
+			abrir:  func() (io.ReadCloser, error) { return io.NopCloser(strings.NewReader(conteúdo)), nil },
+			fechar: func() error { return nil },
+		})
+	}
+	f.abertos += len(arquivos)
+	return arquivos, nil
+}
+
+func (f *infraTeste) Cleanup(arquivos []Arquivo) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.fechados += len(arquivos)
+	return f.localInfra.Cleanup(arquivos)
+}
+
+func Test_cvm_ImportarAnos(t *testing.T) {
+	novoCVM := func(t *testing.T) (*CVM, *infraTeste) {
+		c, err := NovoCVM(CfgDirDados(t.TempDir()), CfgJobs(4), CfgDownloads(2))
+		if err != nil {
+			t.Fatal(err)
+		}
+		f := &infraTeste{}
+		c.infra = f
+		return c, f
+	}
+
+	t.Run("todos os anos", func(t *testing.T) {
+		c, f := novoCVM(t)
+
+		anos := map[int]int{}
+		hashes := 0
+		for result := range c.ImportarAnos(context.Background(), []int{2020, 2021, 2022}) {
+			if result.Error != nil {
+				t.Fatalf("ImportarAnos() error = %v", result.Error)
+			}
+			if result.Empresa != nil {
+				anos[result.Empresa.Ano]++
+			}
+			if result.Hash != "" {
+				hashes++
+			}
+		}
+
+		// DFP e ITR de cada ano, com os arquivos con e ind
+		want := map[int]int{2020: 4, 2021: 4, 2022: 4}
+		if !reflect.DeepEqual(anos, want) || hashes != 12 {
+			t.Errorf("empresas por ano = %v, hashes = %d, want %v e 12", anos, hashes, want)
+		}
+		if len(f.baixados) != 6 {
+			t.Errorf("baixados = %v, want 6 arquivos", f.baixados)
+		}
+		if f.fechados != f.abertos {
+			t.Errorf("fechados = %d, want %d", f.fechados, f.abertos)
+		}
+	})
+
+	t.Run("contexto cancelado", func(t *testing.T) {
+		c, f := novoCVM(t)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		for result := range c.ImportarAnos(ctx, []int{2020, 2021, 2022}) {
+			if result.Empresa != nil {
+				t.Errorf("empresa importada após o cancelamento: %+v", result.Empresa.Empresa)
+			}
+		}
+		if f.fechados != f.abertos {
+			t.Errorf("fechados = %d, want %d", f.fechados, f.abertos)
+		}
+	})
+}
+
 func Test_meses(t *testing.T) {
 	type args struct {
 		ini string
@@ -181,19 +385,34 @@ func Test_csv_carregaDFP(t *testing.T) {
 				cabeçalho: "CNPJ_CIA;DT_REFER;VERSAO;DENOM_CIA;CD_CVM;GRUPO_DFP;MOEDA;ESCALA_MOEDA;ORDEM_EXERC;DT_INI_EXERC;DT_FIM_EXERC;CD_CONTA;DS_CONTA;VL_CONTA;ST_CONTA_FIXA",
 				linha:     "60.840.055/0001-31;2022-06-30;1;FLEURY S.A.;021881;DF Consolidado - Demonstração do Resultado;REAL;MIL;ÚLTIMO;2022-04-01;2022-06-30;3.11;Lucro/Prejuízo Consolidado do Período;70924.0000000000;S",
 			},
-			want:    &cvmDFP{CNPJ: "60.840.055/0001-31", Nome: "FLEURY S.A.", Ano: "2022", Consolidado: true, Versão: "1", Código: "3.11", Descr: "Lucro/Prejuízo Consolidado do Período", GrupoDFP: "DF Consolidado - Demonstração do Resultado", DataIniExerc: "2022-04-01", DataFimExerc: "2022-06-30", Meses: 3, OrdemExerc: "ÚLTIMO", Valor: 70924, Escala: 1000, Moeda: "R$"},
+			want:    &cvmDFP{CNPJ: "60.840.055/0001-31", Nome: "FLEURY S.A.", Ano: "2022", Consolidado: true, Versão: "1", DataRefer: "2022-06-30", Código: "3.11", Descr: "Lucro/Prejuízo Consolidado do Período", GrupoDFP: "DF Consolidado - Demonstração do Resultado", DataIniExerc: "2022-04-01", DataFimExerc: "2022-06-30", Meses: 3, OrdemExerc: "ÚLTIMO", Valor: rapina.DecimalDeInt(70924), Escala: 1000, Moeda: "R$"},
+			wantErr: false,
+		},
+		{
+			name: "carrega linha da DMPL",
+			args: args{
+				cabeçalho: "CNPJ_CIA;DT_REFER;VERSAO;DENOM_CIA;CD_CVM;GRUPO_DFP;MOEDA;ESCALA_MOEDA;ORDEM_EXERC;DT_INI_EXERC;DT_FIM_EXERC;COLUNA_DF;CD_CONTA;DS_CONTA;VL_CONTA;ST_CONTA_FIXA",
+				linha:     "60.840.055/0001-31;2022-12-31;2;FLEURY S.A.;021881;DF Consolidado - Demonstração das Mutações do Patrimônio Líquido;REAL;MIL;ÚLTIMO;2022-01-01;2022-12-31;Lucros ou Prejuízos Acumulados;5.04.06;Dividendos;-120000.0000000000;S",
+			},
+			want:    &cvmDFP{CNPJ: "60.840.055/0001-31", Nome: "FLEURY S.A.", Ano: "2022", Consolidado: true, Versão: "2", DataRefer: "2022-12-31", Código: "5.04.06", Descr: "Dividendos", GrupoDFP: "DF Consolidado - Demonstração das Mutações do Patrimônio Líquido", Coluna: "Lucros ou Prejuízos Acumulados", DataIniExerc: "2022-01-01", DataFimExerc: "2022-12-31", Meses: 12, OrdemExerc: "ÚLTIMO", Valor: rapina.DecimalDeInt(-120000), Escala: 1000, Moeda: "R$"},
 			wantErr: false,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			c := &csv{
-				sep:           ";",
-				cabeçalhoLido: false,
+			conteúdo, err := charmap.ISO8859_1.NewEncoder().String(tt.args.cabeçalho + "\n" + tt.args.linha)
+			if err != nil {
+				t.Fatal(err)
 			}
-			_, _ = c.carregaDFP(tt.args.cabeçalho)
-			got, err := c.carregaDFP(tt.args.linha)
-			fmt.Printf("%v", got)
+			l, err := novoLeitorCSV("teste.csv", strings.NewReader(conteúdo), colunasDFP...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			itens, err := l.ler()
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := novoCSV(l).carregaDFP(itens)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("csv.carregaDFP() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -205,6 +424,27 @@ func Test_csv_carregaDFP(t *testing.T) {
 	}
 }
 
+func Test_cvmDFP_converteConta_grupo(t *testing.T) {
+	tests := []struct {
+		grupoDFP string
+		want     string
+	}{
+		{"DF Consolidado - Balanço Patrimonial Ativo", "BPA"},
+		{"DF Individual - Balanço Patrimonial Passivo", "BPP"},
+		{"DF Consolidado - Demonstração do Fluxo de Caixa (Método Indireto)", "DFC"},
+		{"DF Consolidado - Demonstração do Resultado", "DRE"},
+		{"DF Consolidado - Demonstração de Resultado Abrangente", "DRA"},
+		{"DF Consolidado - Demonstração das Mutações do Patrimônio Líquido", "DMPL"},
+		{"DF Consolidado - Demonstração de Valor Adicionado", "DVA"},
+	}
+	for _, tt := range tests {
+		c := cvmDFP{GrupoDFP: tt.grupoDFP, Coluna: "X"}
+		if got := c.converteConta(); got.Grupo != tt.want || got.Coluna != "X" {
+			t.Errorf("converteConta(%s) = %s, want %s", tt.grupoDFP, got.Grupo, tt.want)
+		}
+	}
+}
+
 // ==== BENCHMARKS ====
 
 func benchmarkconverteConta(c *cvmDFP, b *testing.B) {
@@ -225,7 +465,7 @@ var cc = []cvmDFP{
 		GrupoDFP:     "Balanço Patrimonial Passivo",
 		DataFimExerc: "2020-12-30",
 		OrdemExerc:   "ÚLTIMO",
-		Valor:        12.34,
+		Valor:        rapina.DecimalDeFloat(12.34),
 		Escala:       1,
 		Moeda:        "R$",
 	},
@@ -240,7 +480,7 @@ var cc = []cvmDFP{
 		GrupoDFP:     "Demonstração de Valor Adicionado",
 		DataFimExerc: "2020-12-30",
 		OrdemExerc:   "ÚLTIMO",
-		Valor:        12.34,
+		Valor:        rapina.DecimalDeFloat(12.34),
 		Escala:       1,
 		Moeda:        "R$",
 	},
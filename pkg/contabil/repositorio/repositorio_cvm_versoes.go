@@ -0,0 +1,76 @@
+// SPDX-FileCopyrightText: 2023 Adriano Prado <dev@dude333.com>
+//
+// SPDX-License-Identifier: MIT
+
+package repositorio
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// chaveDocumento identifica um documento (DFP ou ITR) entregue à CVM. As
+// reapresentações do documento têm o mesmo CNPJ e data de referência, com
+// um número de versão maior.
+type chaveDocumento struct {
+	cnpj      string
+	dataRefer string // AAAA-MM-DD
+}
+
+// chaveVersão identifica uma versão de um documento.
+type chaveVersão struct {
+	chaveDocumento
+	versão int
+}
+
+// versõesDocumentos registra as versões de cada documento encontradas nos
+// arquivos de composição do capital e resolve qual é a mais recente: a de
+// maior número, mesmo que as versões não sejam contíguas (ex.: 1 e 3) ou
+// estejam em arquivos diferentes (ex.: zips de anos diferentes). As contas
+// das demonstrações não passam por aqui: todas as versões são gravadas e a
+// visão contas_recentes do banco de dados aplica a mesma regra. Pode ser
+// usado por várias goroutines.
+type versõesDocumentos struct {
+	mu sync.Mutex
+	m  map[chaveDocumento]int
+}
+
+func novasVersõesDocumentos() *versõesDocumentos {
+	return &versõesDocumentos{m: make(map[chaveDocumento]int)}
+}
+
+// novaChaveVersão cria a chave a partir dos campos CNPJ_CIA, DT_REFER e
+// VERSAO dos arquivos da CVM. Versões inválidas são consideradas 0.
+func novaChaveVersão(cnpj, dataRefer, versão string) chaveVersão {
+	v, _ := strconv.Atoi(versão)
+	return chaveVersão{chaveDocumento{cnpj, dataRefer}, v}
+}
+
+// registrar registra a versão do documento e retorna verdadeiro se ela é a
+// mais recente registrada até o momento.
+func (v *versõesDocumentos) registrar(k chaveVersão) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	atual, ok := v.m[k.chaveDocumento]
+	if ok && atual > k.versão {
+		return false
+	}
+	v.m[k.chaveDocumento] = k.versão
+	return true
+}
+
+// ordenarVersões ordena as chaves por CNPJ, data de referência e versão,
+// de forma que a versão mais recente de cada documento seja a última.
+func ordenarVersões(chaves []chaveVersão) {
+	sort.Slice(chaves, func(i, j int) bool {
+		a, b := chaves[i], chaves[j]
+		if a.cnpj != b.cnpj {
+			return a.cnpj < b.cnpj
+		}
+		if a.dataRefer != b.dataRefer {
+			return a.dataRefer < b.dataRefer
+		}
+		return a.versão < b.versão
+	})
+}
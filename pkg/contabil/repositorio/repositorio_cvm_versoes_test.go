@@ -0,0 +1,133 @@
+// SPDX-FileCopyrightText: 2023 Adriano Prado <dev@dude333.com>
+//
+// SPDX-License-Identifier: MIT
+
+package repositorio
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"golang.org/x/text/encoding/charmap"
+
+	rapina "github.com/dude333/rapinav2"
+	"github.com/dude333/rapinav2/pkg/contabil/dominio"
+)
+
+func Test_versõesDocumentos_registrar(t *testing.T) {
+	const cnpj = "60.840.055/0001-31"
+	doc := chaveDocumento{cnpj, "2022-12-31"}
+
+	tests := []struct {
+		name      string
+		registros []chaveVersão // em ordem de leitura, de um ou mais arquivos
+		want      []bool        // versão mais recente até o momento?
+	}{
+		{"versão única", []chaveVersão{{doc, 1}}, []bool{true}},
+		{"versões contíguas", []chaveVersão{{doc, 1}, {doc, 2}}, []bool{true, true}},
+		{"versões não contíguas", []chaveVersão{{doc, 1}, {doc, 3}, {doc, 2}}, []bool{true, true, false}},
+		{"versão mais nova lida antes", []chaveVersão{{doc, 3}, {doc, 1}}, []bool{true, false}},
+		{"mesma versão em outro arquivo", []chaveVersão{{doc, 3}, {doc, 3}}, []bool{true, true}},
+		{"outro documento não interfere", []chaveVersão{{doc, 2}, {chaveDocumento{cnpj, "2023-03-31"}, 1}}, []bool{true, true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := novasVersõesDocumentos()
+			for i, k := range tt.registros {
+				if got := v.registrar(k); got != tt.want[i] {
+					t.Errorf("registrar(%+v) = %v, want %v", k, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func Test_ordenarVersões(t *testing.T) {
+	chaves := []chaveVersão{
+		novaChaveVersão("B", "2022-12-31", "1"),
+		novaChaveVersão("A", "2023-03-31", "1"),
+		novaChaveVersão("A", "2022-12-31", "3"),
+		novaChaveVersão("A", "2022-12-31", "1"),
+	}
+	ordenarVersões(chaves)
+	want := []chaveVersão{
+		novaChaveVersão("A", "2022-12-31", "1"),
+		novaChaveVersão("A", "2022-12-31", "3"),
+		novaChaveVersão("A", "2023-03-31", "1"),
+		novaChaveVersão("B", "2022-12-31", "1"),
+	}
+	if !reflect.DeepEqual(chaves, want) {
+		t.Errorf("ordenarVersões() = %+v, want %+v", chaves, want)
+	}
+}
+
+func Test_processarArquivoDFP_versões(t *testing.T) {
+	const cabeçalho = "CNPJ_CIA;DT_REFER;VERSAO;DENOM_CIA;CD_CVM;GRUPO_DFP;MOEDA;ESCALA_MOEDA;ORDEM_EXERC;DT_INI_EXERC;DT_FIM_EXERC;CD_CONTA;DS_CONTA;VL_CONTA;ST_CONTA_FIXA\n"
+	linha := func(versão, valor string) string {
+		return "60.840.055/0001-31;2022-12-31;" + versão + ";FLEURY S.A.;021881;DF Consolidado - Demonstração do Resultado;REAL;MIL;ÚLTIMO;2022-01-01;2022-12-31;3.11;Lucro;" + valor + ";S\n"
+	}
+
+	dir := t.TempDir()
+	arquivos := []Arquivo{
+		arquivoDisco(filepath.Join(dir, "dfp_cia_aberta_DRE_con_2022.csv")),
+		arquivoDisco(filepath.Join(dir, "dfp_cia_aberta_DRE_con_2023.csv")),
+	}
+	// Versão 3 no primeiro arquivo e 1 no segundo
+	for i, conteúdo := range []string{cabeçalho + linha("3", "30"), cabeçalho + linha("1", "10")} {
+		latin1, err := charmap.ISO8859_1.NewEncoder().String(conteúdo)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(arquivos[i].nome, []byte(latin1), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c := &CVM{versões: novasVersõesDocumentos()}
+	results := make(chan dominio.Resultado, 10)
+	for _, a := range arquivos {
+		if err := c.processarArquivoDFP(context.Background(), a, results); err != nil {
+			t.Fatal(err)
+		}
+	}
+	close(results)
+
+	db := sqlx.MustConnect("sqlite3", ":memory:")
+	db.SetMaxOpenConns(1)
+	s, err := NovoSqlite(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var versões []int
+	for r := range results {
+		if r.Empresa == nil {
+			continue
+		}
+		versões = append(versões, r.Empresa.Contas[0].Versão)
+		if err := s.Salvar(context.Background(), r.Empresa); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Todas as versões são enviadas para manter o histórico
+	if !reflect.DeepEqual(versões, []int{3, 1}) {
+		t.Errorf("versões enviadas = %v, want [3 1]", versões)
+	}
+
+	// Os relatórios leem a versão mais recente, mesmo que ela tenha sido
+	// gravada antes
+	var recentes []struct {
+		Versão int            `db:"versao"`
+		Valor  rapina.Decimal `db:"valor"`
+	}
+	if err := db.Select(&recentes, `SELECT versao, valor FROM contas_recentes`); err != nil {
+		t.Fatal(err)
+	}
+	if len(recentes) != 1 || recentes[0].Versão != 3 || recentes[0].Valor != rapina.DecimalDeInt(30) {
+		t.Errorf("contas_recentes = %+v, want versão 3 com valor 30", recentes)
+	}
+}
@@ -14,5 +14,7 @@ var (
 	ErrDFPInválida     = errors.New("DFP inválida")
 	ErrSemDados        = errors.New("sem dados")
 
+	ErrTickerNãoEncontrado = errors.New("ticker não encontrado")
+
 	ErrAnoInválidoFn = func(ano int) error { return fmt.Errorf("ano inválido: %d", ano) }
 )
@@ -5,7 +5,10 @@
 package repositorio
 
 import (
+	"context"
+	"io"
 	"net/url"
+	"os"
 	"path"
 
 	ext "github.com/dude333/rapinav2/pkg/infra"
@@ -14,47 +17,135 @@ import (
 // infra define uma interface para que este respositório não fique amarrado
 // na implementação de uma única biblioteca externa.
 type infra interface {
-	DownloadAndUnzip(url string, filtros []string) ([]Arquivo, error)
+	DownloadZip(ctx context.Context, url string, filtros []string) ([]Arquivo, error)
+	Download(ctx context.Context, url string) (Arquivo, error)
+	OpenZip(arquivo string, filtros []string) ([]Arquivo, error)
 	Cleanup(files []Arquivo) []string
 }
 
+// Arquivo é um arquivo CSV a ser processado, lido diretamente do zip (sem
+// ser extraído) ou do disco. O hash do conteúdo é calculado durante a
+// leitura, caso ainda não seja conhecido.
 type Arquivo struct {
-	path string
-	hash string
+	nome   string
+	abrir  func() (io.ReadCloser, error)
+	hash   string
+	fechar func() error // libera o zip ou apaga o arquivo baixado
+}
+
+// arquivoDisco retorna um arquivo já existente no disco.
+func arquivoDisco(caminho string) Arquivo {
+	return Arquivo{
+		nome:  caminho,
+		abrir: func() (io.ReadCloser, error) { return os.Open(caminho) },
+	}
+}
+
+// ler abre o arquivo e o passa para a função ler, retornando o hash do
+// conteúdo, que é lido até o fim mesmo que ler não o faça.
+func (a Arquivo) ler(ler func(r io.Reader) error) (string, error) {
+	rc, err := a.abrir()
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+
+	hr := ext.NewHashReader(rc)
+	if err := ler(hr); err != nil {
+		return "", err
+	}
+	return hr.Sum()
+}
+
+// lerCSV lê um arquivo CSV da CVM com cabeçalho (ver lerCSVCabeçalho),
+// retornando também o hash do conteúdo.
+func (a Arquivo) lerCSV(obrigatórias ...string) ([]map[string]string, string, error) {
+	var registros []map[string]string
+	hash, err := a.ler(func(r io.Reader) (err error) {
+		registros, err = lerCSVCabeçalho(a.nome, r, obrigatórias...)
+		return err
+	})
+	return registros, hash, err
 }
 
 type localInfra struct {
 	dirDados string // diretório de dados
 }
 
-func (l localInfra) DownloadAndUnzip(urlString string, filtros []string) ([]Arquivo, error) {
+// DownloadZip baixa o arquivo zip para o diretório de dados (ou usa a cópia
+// do cache) e retorna os arquivos que correspondem aos filtros, que são lidos
+// diretamente do zip.
+func (l localInfra) DownloadZip(ctx context.Context, urlString string, filtros []string) ([]Arquivo, error) {
 	u, err := url.Parse(urlString)
 	if err != nil {
 		return []Arquivo{}, err
 	}
 	arquivo := path.Base(u.Path)
 	zip := path.Join(l.dirDados, arquivo)
-	arqs, err := ext.DownloadAndUnzip(urlString, zip, filtros)
+	z, err := ext.DownloadZip(ctx, urlString, zip, filtros)
+	if err != nil {
+		return []Arquivo{}, err
+	}
+
+	return arquivosZip(z), nil
+}
+
+// Download baixa um arquivo não compactado para o diretório de dados. O
+// arquivo é apagado pelo Cleanup.
+func (l localInfra) Download(ctx context.Context, urlString string) (Arquivo, error) {
+	u, err := url.Parse(urlString)
+	if err != nil {
+		return Arquivo{}, err
+	}
+	caminho := path.Join(l.dirDados, path.Base(u.Path))
+	if err := ext.Download(ctx, urlString, caminho); err != nil {
+		_ = os.Remove(caminho)
+		return Arquivo{}, err
+	}
+
+	arquivo := arquivoDisco(caminho)
+	arquivo.fechar = func() error { return os.Remove(caminho) }
+	return arquivo, nil
+}
+
+// OpenZip abre um arquivo zip já existente, sem apagá-lo, e retorna os
+// arquivos que correspondem aos filtros.
+func (l localInfra) OpenZip(arquivo string, filtros []string) ([]Arquivo, error) {
+	z, err := ext.OpenZip(arquivo, filtros)
 	if err != nil {
 		return []Arquivo{}, err
 	}
 
-	arquivos := make([]Arquivo, len(arqs))
-	for i := range arqs {
-		h, _ := ext.FileHash(arqs[i])
+	return arquivosZip(z), nil
+}
+
+// arquivosZip retorna os arquivos do zip, que é fechado pelo Cleanup.
+func arquivosZip(z *ext.ZipArchive) []Arquivo {
+	arquivos := make([]Arquivo, len(z.Files))
+	for i, f := range z.Files {
 		arquivos[i] = Arquivo{
-			path: arqs[i],
-			hash: h,
+			nome:   f.Name,
+			abrir:  f.Open,
+			fechar: z.Close,
 		}
 	}
-
-	return arquivos, nil
+	if len(arquivos) == 0 {
+		_ = z.Close()
+	}
+	return arquivos
 }
 
+// Cleanup libera os arquivos (fecha os zips e apaga os arquivos baixados),
+// retornando os que não puderam ser liberados.
 func (l localInfra) Cleanup(arqs []Arquivo) []string {
-	files := make([]string, len(arqs))
-	for i := range arqs {
-		files[i] = arqs[i].path
+	var falhas []string
+	for _, a := range arqs {
+		if a.fechar == nil {
+			continue
+		}
+		if err := a.fechar(); err != nil {
+			falhas = append(falhas, a.nome)
+		}
 	}
-	return ext.Cleanup(files)
+	return falhas
 }
@@ -7,6 +7,7 @@ package repositorio
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"sort"
 	"strconv"
 	"strings"
@@ -29,11 +30,11 @@ import (
 type Sqlite struct {
 	db *sqlx.DB
 
-	// limpo serve para sinalizar se os dados de um determinado CNPJ+ANO
-	// foi limpo ao rodar a primeira vez (para evitar duplicação de dados
-	// ao rodar a coleta mais de uma vez). Portanto, o armazenamento de
-	// *todas* as empresas em um determinado ano (CNPJ+ANO) deve ser feito
-	// uma única vez.
+	// limpo serve para sinalizar se os dados de uma versão de um documento
+	// (CNPJ+ANO+DATA_REFER+VERSAO) foram limpos ao rodar a primeira vez
+	// (para evitar duplicação de dados ao rodar a coleta mais de uma vez).
+	// Portanto, o armazenamento de *todas* as contas de uma versão deve ser
+	// feito uma única vez. As outras versões do documento são mantidas.
 	limpo map[string]bool
 
 	cacheEmpresas []rapina.Empresa
@@ -81,7 +82,7 @@ func (s *Sqlite) Ler(ctx context.Context, cnpj string, ano int) (*dominio.Demons
 
 	contas := make([]dominio.Conta, 0, 100)
 	rows, err := s.db.QueryxContext(ctx,
-		`SELECT * FROM contas WHERE id_empresa=? ORDER BY codigo`, &sd.ID)
+		`SELECT * FROM contas_recentes WHERE id_empresa=? ORDER BY codigo`, &sd.ID)
 	if err != nil {
 		progress.Error(err)
 		return nil, err
@@ -102,6 +103,9 @@ func (s *Sqlite) Ler(ctx context.Context, cnpj string, ano int) (*dominio.Demons
 			DataFimExerc: sc.DataFimExerc,
 			Meses:        sc.Meses,
 			OrdemExerc:   "",
+			DataRefer:    sc.DataRefer,
+			Versão:       sc.Versão,
+			Coluna:       sc.Coluna,
 			Total: rapina.Dinheiro{
 				Valor:  sc.Valor,
 				Escala: sc.Escala,
@@ -117,6 +121,13 @@ func (s *Sqlite) Ler(ctx context.Context, cnpj string, ano int) (*dominio.Demons
 }
 
 func (s *Sqlite) Trimestral(ctx context.Context, cnpj string, consolidado bool) ([]rapina.InformeTrimestral, error) {
+	return s.TrimestralGrupo(ctx, cnpj, consolidado, "")
+}
+
+// TrimestralGrupo retorna os valores trimestrais das contas do grupo
+// informado (ex.: DMPL), ou de todos os grupos exceto os GruposSeparados se
+// grupo == "". As contas da DMPL são separadas por coluna.
+func (s *Sqlite) TrimestralGrupo(ctx context.Context, cnpj string, consolidado bool, grupo string) ([]rapina.InformeTrimestral, error) {
 	var ids []int
 	err := s.db.SelectContext(ctx, &ids, `SELECT id FROM empresas WHERE cnpj=? ORDER BY ano`, &cnpj)
 	if err == sql.ErrNoRows {
@@ -129,18 +140,65 @@ func (s *Sqlite) Trimestral(ctx context.Context, cnpj string, consolidado bool)
 	progress.Trace("[]sqliteEmpresa => %+v", ids)
 
 	var resultados []resultadoTrimestral
-	err = s.db.SelectContext(ctx, &resultados, sqlTrimestral(ids, consolidado))
+	err = s.db.SelectContext(ctx, &resultados, sqlTrimestral(ids, consolidado, grupo))
 	if err != nil {
 		return nil, err
 	}
 
-	return converterResultadosTrimestrais(resultados)
+	escala, err := s.Escala(ctx, cnpj)
+	if err != nil {
+		return nil, err
+	}
+	moeda, err := s.Moeda(ctx, cnpj)
+	if err != nil {
+		return nil, err
+	}
+
+	return converterResultadosTrimestrais(resultados, conversão{escala: escala, moeda: moeda, câmbio: s.câmbio})
+}
+
+// Escala retorna a escala monetária (1, 1000...) dos valores retornados por
+// Trimestral: a definida por CfgUnidade ou, se não definida, a mais usada
+// nos valores armazenados da empresa (1 se não houver valores).
+func (s *Sqlite) Escala(ctx context.Context, cnpj string) (int, error) {
+	if s.unidade > 0 {
+		return s.unidade, nil
+	}
+	var escala int
+	err := s.db.GetContext(ctx, &escala, `SELECT c.escala FROM contas_recentes c
+		JOIN empresas e ON e.id = c.id_empresa
+		WHERE e.cnpj = ?
+		GROUP BY c.escala
+		ORDER BY COUNT(*) DESC
+		LIMIT 1`, cnpj)
+	if err == sql.ErrNoRows || (err == nil && escala < 1) {
+		return 1, nil
+	}
+	return escala, err
+}
+
+// Moeda retorna a moeda dos valores retornados por Trimestral: a definida
+// por CfgMoeda ou, se não definida, a mais usada nos valores armazenados da
+// empresa (rapina.Real se não houver valores).
+func (s *Sqlite) Moeda(ctx context.Context, cnpj string) (string, error) {
+	if s.moeda != "" {
+		return s.moeda, nil
+	}
+	var moeda string
+	err := s.db.GetContext(ctx, &moeda, `SELECT c.moeda FROM contas_recentes c
+		JOIN empresas e ON e.id = c.id_empresa
+		WHERE e.cnpj = ? AND c.moeda <> ''
+		GROUP BY c.moeda
+		ORDER BY COUNT(*) DESC
+		LIMIT 1`, cnpj)
+	if err == sql.ErrNoRows {
+		return rapina.Real, nil
+	}
+	return moeda, err
 }
 
 func (s *Sqlite) Empresas(ctx context.Context) ([]rapina.Empresa, error) {
-	var empresas []rapina.Empresa
-	err := s.db.SelectContext(ctx, &empresas,
-		`SELECT DISTINCT(cnpj), nome FROM empresas ORDER BY nome`)
+	empresas, err := s.listarEmpresas(ctx)
 	if err != nil {
 		progress.Error(err)
 		return nil, err
@@ -150,12 +208,12 @@ func (s *Sqlite) Empresas(ctx context.Context) ([]rapina.Empresa, error) {
 
 func (s *Sqlite) BuscaEmpresas(ctx context.Context, nome string) ([]rapina.Empresa, error) {
 	if len(s.cacheEmpresas) == 0 {
-		err := s.db.SelectContext(ctx, &s.cacheEmpresas,
-			`SELECT DISTINCT(cnpj), nome FROM empresas ORDER BY nome`)
+		empresas, err := s.listarEmpresas(ctx)
 		if err != nil {
 			progress.Error(err)
 			return nil, err
 		}
+		s.cacheEmpresas = empresas
 	}
 
 	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
@@ -172,7 +230,7 @@ func (s *Sqlite) BuscaEmpresas(ctx context.Context, nome string) ([]rapina.Empre
 			return nil, err
 		}
 		x = strings.ToLower(x)
-		if strings.HasPrefix(x, nome) {
+		if strings.HasPrefix(x, nome) || temTicker(empr, nome) {
 			ret = append(ret, empr)
 		}
 	}
@@ -205,116 +263,139 @@ type sqliteEmpresa struct {
 }
 
 type sqliteConta struct {
-	ID           int     `db:"id_empresa"`
-	Código       string  `db:"codigo"`
-	Descr        string  `db:"descr"`
-	Grupo        string  `db:"grupo"`
-	Consolidado  int     `db:"consolidado"`
-	DataIniExerc string  `db:"data_ini_exerc"`
-	DataFimExerc string  `db:"data_fim_exerc"`
-	Meses        int     `db:"meses"` // diferença entre data_ini_exerc e data_fim_exerc
-	Valor        float64 `db:"valor"`
-	Escala       int     `db:"escala"`
-	Moeda        string  `db:"moeda"`
+	ID           int            `db:"id_empresa"`
+	Código       string         `db:"codigo"`
+	Descr        string         `db:"descr"`
+	Grupo        string         `db:"grupo"`
+	Consolidado  int            `db:"consolidado"`
+	DataIniExerc string         `db:"data_ini_exerc"`
+	DataFimExerc string         `db:"data_fim_exerc"`
+	Meses        int            `db:"meses"` // diferença entre data_ini_exerc e data_fim_exerc
+	Valor        rapina.Decimal `db:"valor"`
+	Escala       int            `db:"escala"`
+	Moeda        string         `db:"moeda"`
+	DataRefer    string         `db:"data_refer"`
+	Versão       int            `db:"versao"`
+	Coluna       string         `db:"coluna"`
 }
 
+// Salvar salva a demonstração financeira numa transação.
 func (s *Sqlite) Salvar(ctx context.Context, dfp *dominio.DemonstraçãoFinanceira) error {
-	progress.Trace("%-60s %4d\n", dfp.Nome, len(dfp.Contas))
+	return s.SalvarLote(ctx, []*dominio.DemonstraçãoFinanceira{dfp})
+}
 
-	d := sqliteEmpresa{
-		CNPJ: dfp.CNPJ,
-		Nome: dfp.Nome,
-		Ano:  dfp.Ano,
+// SalvarLote salva as demonstrações financeiras numa única transação, o que
+// é bem mais rápido do que uma transação por empresa. Em caso de erro,
+// nenhuma delas é salva.
+func (s *Sqlite) SalvarLote(ctx context.Context, dfps []*dominio.DemonstraçãoFinanceira) error {
+	if len(dfps) == 0 {
+		return nil
 	}
 
-	idRegistro := func() (int, error) {
-		var id int
-		err := s.db.GetContext(ctx, &id, `SELECT id FROM empresas WHERE cnpj=? AND ano=?`, d.CNPJ, d.Ano)
-		return id, err
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
 	}
 
-	k := d.CNPJ + strconv.Itoa(d.Ano)
-	if _, ok := s.limpo[k]; !ok {
-		// Verificar o id do registro e apagá-lo caso exista
-		id, err := idRegistro()
-
-		if err != nil && err != sql.ErrNoRows {
-			return err
-		}
-		if err != sql.ErrNoRows {
-			progress.Debug("Apagando empresa %s, %d (%d): ", d.Nome, d.Ano, id)
-			if err := removerEmpresa(ctx, s.db, id); err != nil {
-				return err
-			}
+	var limpos []string // versões limpas nesta transação
+	for _, dfp := range dfps {
+		k, err := s.salvar(ctx, tx, dfp)
+		limpos = append(limpos, k...)
+		if err == nil {
+			continue
 		}
-		s.limpo[k] = true
-		// Criar novo registro
-		query := `INSERT INTO empresas (cnpj, nome, ano) VALUES (:cnpj, :nome, :ano)`
-		_, err = s.db.NamedExecContext(ctx, query, &d)
-		if err != nil {
-			progress.Debug("Falha ao inserir %v", d)
-			return err
+		_ = tx.Rollback()
+		// Os dados apagados foram restaurados pelo rollback
+		for _, k := range limpos {
+			delete(s.limpo, k)
 		}
+		return err
 	}
 
-	id, err := idRegistro()
+	progress.Spinner()
+
+	return tx.Commit()
+}
+
+// salvar insere a demonstração financeira na transação, apagando antes os
+// dados já gravados das mesmas versões do documento (DataRefer e Versão das
+// contas). Retorna as chaves das versões apagadas.
+func (s *Sqlite) salvar(ctx context.Context, tx *sqlx.Tx, dfp *dominio.DemonstraçãoFinanceira) ([]string, error) {
+	progress.Trace("%-60s %4d\n", dfp.Nome, len(dfp.Contas))
+
+	id, err := registrarEmpresa(ctx, tx, dfp.CNPJ, dfp.Ano, dfp.Nome)
 	if err != nil {
-		return err
+		progress.Debug("Falha ao inserir %s, %d", dfp.Nome, dfp.Ano)
+		return nil, err
 	}
 
-	return inserirContas(ctx, s.db, id, dfp.Contas, dfp.Nome)
+	var limpos []string
+	for i := range dfp.Contas {
+		c := novaSqliteConta(id, dfp.Contas[i])
+		k := dfp.CNPJ + strconv.Itoa(dfp.Ano) + c.DataRefer + strconv.Itoa(c.Versão)
+		if s.limpo[k] {
+			continue
+		}
+		progress.Debug("Apagando empresa %s, %d (%d), versão %s %d", dfp.Nome, dfp.Ano, id, c.DataRefer, c.Versão)
+		_, err := tx.ExecContext(ctx, `DELETE FROM contas WHERE id_empresa = ? AND data_refer = ? AND versao IN (?, 0)`,
+			id, c.DataRefer, c.Versão)
+		if err != nil {
+			return limpos, err
+		}
+		s.limpo[k] = true
+		limpos = append(limpos, k)
+	}
+
+	return limpos, inserirContas(ctx, tx, id, dfp.Contas, dfp.Nome)
 }
 
-// inserirContas insere os registro das contas, sendo que deve ter sido garantido
-// previamente que não exista nenhum registro com o id_empresa das contas a serem
-// inseridas.
-func inserirContas(ctx context.Context, db *sqlx.DB, id int, contas []dominio.Conta, nome string) error {
-	if len(contas) == 0 {
-		return nil
+// registrarEmpresa retorna o id da empresa no ano, criando o registro se ele
+// não existir ou atualizando o nome se existir.
+func registrarEmpresa(ctx context.Context, tx *sqlx.Tx, cnpj string, ano int, nome string) (int, error) {
+	var id int
+	err := tx.GetContext(ctx, &id, `SELECT id FROM empresas WHERE cnpj=? AND ano=?`, cnpj, ano)
+	if err == nil {
+		_, err = tx.ExecContext(ctx, `UPDATE empresas SET nome = ? WHERE id = ?`, nome, id)
+		return id, err
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return 0, err
 	}
 
-	tx, err := db.Beginx()
+	res, err := tx.ExecContext(ctx, `INSERT INTO empresas (cnpj, nome, ano) VALUES (?, ?, ?)`, cnpj, nome, ano)
 	if err != nil {
-		return err
+		return 0, err
+	}
+	novoID, err := res.LastInsertId()
+	return int(novoID), err
+}
+
+// inserirContas insere os registro das contas na transação, sendo que deve ter
+// sido garantido previamente que não exista nenhum registro com o id_empresa
+// das contas a serem inseridas.
+func inserirContas(ctx context.Context, tx *sqlx.Tx, id int, contas []dominio.Conta, nome string) error {
+	if len(contas) == 0 {
+		return nil
 	}
 
 	stmt, err := tx.PrepareNamedContext(ctx, `INSERT or IGNORE INTO contas
-		(id_empresa, codigo, descr, grupo, consolidado, data_ini_exerc, data_fim_exerc, meses, valor, escala, moeda)
+		(id_empresa, codigo, descr, grupo, consolidado, data_ini_exerc, data_fim_exerc, meses, valor, escala, moeda, data_refer, versao, coluna)
 		VALUES
-		(:id_empresa, :codigo, :descr, :grupo, :consolidado, :data_ini_exerc, :data_fim_exerc, :meses, :valor, :escala, :moeda)`)
+		(:id_empresa, :codigo, :descr, :grupo, :consolidado, :data_ini_exerc, :data_fim_exerc, :meses, :valor, :escala, :moeda, :data_refer, :versao, :coluna)`)
 	if err != nil {
 		return err
 	}
-
-	boolToInt := func(is bool) int {
-		if is {
-			return 1
-		}
-		return 0
-	}
+	defer stmt.Close()
 
 	for i := range contas {
-		c := sqliteConta{
-			ID:           id,
-			Código:       contas[i].Código,
-			Descr:        contas[i].Descr,
-			Grupo:        contas[i].Grupo,
-			Consolidado:  boolToInt(contas[i].Consolidado),
-			DataIniExerc: contas[i].DataIniExerc,
-			DataFimExerc: contas[i].DataFimExerc,
-			Meses:        contas[i].Meses,
-			Valor:        contas[i].Total.Valor,
-			Escala:       contas[i].Total.Escala,
-			Moeda:        contas[i].Total.Moeda,
-		}
+		c := novaSqliteConta(id, contas[i])
 
 		_, err = stmt.ExecContext(ctx, c)
 		// Erros no banco de dados estão sendo ignorados ("INSERT or IGNORE INTO").
 		// Verificar PRIMARY KEY da tabela 'contas'.
 		if err != nil {
-			sqliteErr := err.(sqlite3.Error)
-			if sqliteErr.Code != sqlite3.ErrConstraint {
-				_ = tx.Rollback()
+			var sqliteErr sqlite3.Error
+			if !errors.As(err, &sqliteErr) || sqliteErr.Code != sqlite3.ErrConstraint {
 				return err
 			}
 			progress.ErrorMsg("%s: %d, %s, %#v", err, id, nome, contas[i])
@@ -322,138 +403,36 @@ func inserirContas(ctx context.Context, db *sqlx.DB, id int, contas []dominio.Co
 		}
 	}
 
-	progress.Spinner()
-
-	return tx.Commit()
-}
-
-func removerEmpresa(ctx context.Context, db *sqlx.DB, id int) error {
-	query := `DELETE FROM contas WHERE id_empresa=?`
-	_, err := db.ExecContext(ctx, query, &id)
-	if err != nil && err != sql.ErrNoRows {
-		return err
-	}
-
-	query = `DELETE FROM empresas WHERE id=?`
-	_, err = db.ExecContext(ctx, query, &id)
-
-	return err
-}
-
-// tabelas
-//
-//	+------------+      +------------+
-//	| empresas   |      | contas     |
-//	+------------+      +------------+
-//	| id*        |-----<| id_empresa*|
-//	| cnpj       |      | codigo*    |
-//	| nome       |      | descr      |
-//	| ano        |      | ...        |
-//	+------------+      +------------+
-//
-// Passos oo inserir um registro empresa:
-//
-//  1. Verificar e remover se o registro já existe:
-//     a. SELECT id FROM empresas WHERE cnpj = ? AND ano = ?;
-//     b. DELETE FROM contas WHERE id_empresa = ?;
-//     c. DELETE FROM empresas WHERE id = ?;
-//  2. Inserir os novos registro:
-//     a. INSERT INTO empresas (cnpj, nome, ano) VALUES (?,?,?);
-//     b. SELECT id FROM empresas WHERE cnpj = ? AND ano = ?;
-//     b. for range contas => INSERT INTO contas (id_empresa, ...) VALUES (?, ...)
-var tabelas = []struct {
-	nome   string
-	versão int
-	up     string
-	down   string
-}{
-	{
-		nome:   "empresas",
-		versão: _ver_,
-		up: `CREATE TABLE IF NOT EXISTS empresas (
-			id             INTEGER PRIMARY KEY AUTOINCREMENT,
-			cnpj           VARCHAR NOT NULL,
-			nome           VARCHAR NOT NULL,
-			ano            INT NOT NULL,
-			UNIQUE (cnpj, ano)
-		)`,
-		down: `DROP TABLE IF EXISTS empresas`,
-	},
-	{
-		nome:   "contas",
-		versão: _ver_,
-		up: `CREATE TABLE IF NOT EXISTS contas (
-			id_empresa     INTEGER,
-			codigo         VARCHAR NOT NULL,
-			descr          VARCHAR NOT NULL,
-			grupo          VARCHAR NOT NULL,
-			consolidado    INTEGER NOT NULL,
-			data_ini_exerc VARCHAR,
-			data_fim_exerc VARCHAR NOT NULL,
-			meses          INTEGER NOT NULL,
-			valor          REAL NOT NULL,
-			escala         INTEGER NOT NULL,
-			moeda          VARCHAR,
-			PRIMARY KEY (id_empresa, codigo, data_ini_exerc, data_fim_exerc)
-		)`,
-		down: `DROP TABLE IF EXISTS contas`,
-	},
-	{
-		nome:   "hashes",
-		versão: _ver_,
-		up: `CREATE TABLE IF NOT EXISTS hashes (
-			id             INTEGER PRIMARY KEY AUTOINCREMENT,
-			hash           VARCHAR NOT NULL,
-			UNIQUE (hash)
-		)`,
-		down: "DROP TABLE IF EXISTS hashes",
-	},
+	return nil
 }
 
-const (
-	_ver_                 = 17
-	sqlCreateTableTabelas = `CREATE TABLE IF NOT EXISTS tabelas (
-		nome   VARCHAR PRIMARY KEY,
-		versao INTEGER NOT NULL
-	)`
-)
-
-func criarTabelas(db *sqlx.DB) (err error) {
-	ins := func(n string, v int) error {
-		query := `INSERT OR REPLACE INTO tabelas (nome, versao) VALUES (?, ?)`
-		_, err := db.Exec(query, n, v)
-		return err
-	}
-
-	ver := func(tabela string) int {
-		var versão int
-		err := db.Get(&versão, `SELECT versao FROM tabelas WHERE nome=?`, tabela)
-		if err != nil {
-			progress.Debug("Erro ao buscar versão da tabela %s: %v", tabela, err)
+// novaSqliteConta converte a conta para o registro da tabela contas.
+func novaSqliteConta(id int, conta dominio.Conta) sqliteConta {
+	boolToInt := func(is bool) int {
+		if is {
+			return 1
 		}
-		return versão
+		return 0
 	}
 
-	_, _ = db.Exec(sqlCreateTableTabelas)
-
-	for _, t := range tabelas {
-		v := ver(t.nome)
-		if v == t.versão {
-			continue
-		}
-		progress.Status(`Apagando tabela "%s", versão %d, e recriando nova versão (v%d)`,
-			t.nome, v, t.versão)
-
-		_, _ = db.Exec(t.down)
-		_, err := db.Exec(t.up)
-		if err != nil {
-			return err
-		}
-		err = ins(t.nome, t.versão)
-		if err != nil {
-			return err
-		}
+	c := sqliteConta{
+		ID:           id,
+		Código:       conta.Código,
+		Descr:        conta.Descr,
+		Grupo:        conta.Grupo,
+		Consolidado:  boolToInt(conta.Consolidado),
+		DataIniExerc: conta.DataIniExerc,
+		DataFimExerc: conta.DataFimExerc,
+		Meses:        conta.Meses,
+		Valor:        conta.Total.Valor,
+		Escala:       conta.Total.Escala,
+		Moeda:        conta.Total.Moeda,
+		DataRefer:    conta.DataRefer,
+		Versão:       conta.Versão,
+		Coluna:       conta.Coluna,
 	}
-
-	return nil
+	if c.DataRefer == "" {
+		c.DataRefer = c.DataFimExerc
+	}
+	return c
 }
@@ -0,0 +1,83 @@
+// SPDX-FileCopyrightText: 2023 Adriano Prado <dev@dude333.com>
+//
+// SPDX-License-Identifier: MIT
+
+package repositorio
+
+import (
+	"context"
+
+	rapina "github.com/dude333/rapinav2"
+)
+
+type sqliteAções struct {
+	CNPJ          string `db:"cnpj"`
+	Data          string `db:"data"`
+	Ordinárias    int64  `db:"ordinarias"`
+	Preferenciais int64  `db:"preferenciais"`
+	TesourariaON  int64  `db:"tesouraria_on"`
+	TesourariaPN  int64  `db:"tesouraria_pn"`
+}
+
+// SalvarAções salva a quantidade de ações numa única transação, substituindo
+// a quantidade já existente da mesma empresa e data.
+func (s *Sqlite) SalvarAções(ctx context.Context, ações []rapina.QuantidadeAções) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.PrepareNamedContext(ctx, `INSERT OR REPLACE INTO acoes
+		(cnpj, data, ordinarias, preferenciais, tesouraria_on, tesouraria_pn)
+		VALUES
+		(:cnpj, :data, :ordinarias, :preferenciais, :tesouraria_on, :tesouraria_pn)`)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	for _, a := range ações {
+		r := sqliteAções{
+			CNPJ:          a.CNPJ,
+			Data:          a.Data.String(),
+			Ordinárias:    a.Ordinárias,
+			Preferenciais: a.Preferenciais,
+			TesourariaON:  a.TesourariaON,
+			TesourariaPN:  a.TesourariaPN,
+		}
+		if _, err := stmt.ExecContext(ctx, r); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Ações retorna a quantidade de ações da empresa em cada data de
+// referência, em ordem crescente de data.
+func (s *Sqlite) Ações(ctx context.Context, cnpj string) ([]rapina.QuantidadeAções, error) {
+	var registros []sqliteAções
+	err := s.db.SelectContext(ctx, &registros, `SELECT * FROM acoes WHERE cnpj=? ORDER BY data`, cnpj)
+	if err != nil {
+		return nil, err
+	}
+
+	ações := make([]rapina.QuantidadeAções, 0, len(registros))
+	for _, r := range registros {
+		d, err := rapina.NovaData(r.Data)
+		if err != nil {
+			return nil, err
+		}
+		ações = append(ações, rapina.QuantidadeAções{
+			CNPJ:          r.CNPJ,
+			Data:          d,
+			Ordinárias:    r.Ordinárias,
+			Preferenciais: r.Preferenciais,
+			TesourariaON:  r.TesourariaON,
+			TesourariaPN:  r.TesourariaPN,
+		})
+	}
+	return ações, nil
+}
@@ -0,0 +1,107 @@
+// SPDX-FileCopyrightText: 2023 Adriano Prado <dev@dude333.com>
+//
+// SPDX-License-Identifier: MIT
+
+package repositorio
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+
+	rapina "github.com/dude333/rapinav2"
+	"github.com/dude333/rapinav2/pkg/contabil/dominio"
+	ext "github.com/dude333/rapinav2/pkg/infra"
+)
+
+func Test_processarComposiçãoCapital(t *testing.T) {
+	caminho := filepath.Join(t.TempDir(), "itr_cia_aberta_composicao_capital_2023.csv")
+	conteúdo := "CNPJ_CIA;DENOM_CIA;DT_REFER;VERSAO;ID_DOC;QT_ACAO_ORDIN_CAP_INTEGR;QT_ACAO_PREF_CAP_INTEGR;QT_ACAO_TOTAL_CAP_INTEGR;QT_ACAO_ORDIN_TESOURO;QT_ACAO_PREF_TESOURO;QT_ACAO_TOTAL_TESOURO\n" +
+		"60.840.055/0001-31;FLEURY S.A.;2023-03-31;1;1;318000000;0;318000000;100;0;100\n" +
+		"60.840.055/0001-31;FLEURY S.A.;2023-03-31;2;2;318500000;0;318500000;200;0;200\n" +
+		"60.840.055/0001-31;FLEURY S.A.;2023-06-30;1;3;318500000;0;318500000;0;0;0\n" +
+		"33.000.167/0001-01;PETROBRAS;2023-03-31;1;4;7442454142;5602042788;13044496930;0;72;72\n"
+	if err := os.WriteFile(caminho, []byte(conteúdo), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if !arquivoDFP(caminho) || !arquivoComposiçãoCapital(caminho) {
+		t.Fatalf("arquivo %s não reconhecido", caminho)
+	}
+
+	results := make(chan dominio.Resultado, 2)
+	c := &CVM{versões: novasVersõesDocumentos()}
+	if err := c.processarComposiçãoCapital(context.Background(), arquivoDisco(caminho), results); err != nil {
+		t.Fatal(err)
+	}
+	close(results)
+
+	var got []rapina.QuantidadeAções
+	var hash string
+	for r := range results {
+		got = append(got, r.Ações...)
+		hash += r.Hash
+	}
+	d := func(s string) rapina.Data {
+		d, _ := rapina.NovaData(s)
+		return d
+	}
+	want := []rapina.QuantidadeAções{
+		{CNPJ: "33.000.167/0001-01", Data: d("2023-03-31"), Ordinárias: 7442454142, Preferenciais: 5602042788, TesourariaPN: 72},
+		{CNPJ: "60.840.055/0001-31", Data: d("2023-03-31"), Ordinárias: 318500000, TesourariaON: 200},
+		{CNPJ: "60.840.055/0001-31", Data: d("2023-06-30"), Ordinárias: 318500000},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("processarComposiçãoCapital() = %+v\nwant %+v", got, want)
+	}
+	if want, _ := ext.FileHash(caminho); hash != want {
+		t.Errorf("hash = %q, want %q", hash, want)
+	}
+
+	t.Run("versão antiga em outro arquivo", func(t *testing.T) {
+		outro := filepath.Join(t.TempDir(), "dfp_cia_aberta_composicao_capital_2023.csv")
+		antigo := "CNPJ_CIA;DENOM_CIA;DT_REFER;VERSAO;ID_DOC;QT_ACAO_ORDIN_CAP_INTEGR;QT_ACAO_PREF_CAP_INTEGR;QT_ACAO_TOTAL_CAP_INTEGR;QT_ACAO_ORDIN_TESOURO;QT_ACAO_PREF_TESOURO;QT_ACAO_TOTAL_TESOURO\n" +
+			"60.840.055/0001-31;FLEURY S.A.;2023-03-31;1;1;318000000;0;318000000;100;0;100\n"
+		if err := os.WriteFile(outro, []byte(antigo), 0644); err != nil {
+			t.Fatal(err)
+		}
+		results := make(chan dominio.Resultado, 2)
+		if err := c.processarComposiçãoCapital(context.Background(), arquivoDisco(outro), results); err != nil {
+			t.Fatal(err)
+		}
+		close(results)
+		for r := range results {
+			if len(r.Ações) > 0 {
+				t.Errorf("versão superada não deveria ser enviada: %+v", r.Ações)
+			}
+		}
+	})
+
+	t.Run("sqlite", func(t *testing.T) {
+		db := sqlx.MustConnect("sqlite3", ":memory:")
+		db.SetMaxOpenConns(1)
+		s, err := NovoSqlite(db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx := context.Background()
+
+		if err := s.SalvarAções(ctx, want); err != nil {
+			t.Fatal(err)
+		}
+		// Reimportação substitui a quantidade da mesma data
+		if err := s.SalvarAções(ctx, want[2:]); err != nil {
+			t.Fatal(err)
+		}
+		ações, err := s.Ações(ctx, "60.840.055/0001-31")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(ações, want[1:]) {
+			t.Errorf("Ações() = %+v\nwant %+v", ações, want[1:])
+		}
+	})
+}
@@ -0,0 +1,110 @@
+// SPDX-FileCopyrightText: 2023 Adriano Prado <dev@dude333.com>
+//
+// SPDX-License-Identifier: MIT
+
+package repositorio
+
+import (
+	"context"
+
+	rapina "github.com/dude333/rapinav2"
+)
+
+type sqliteCompanhia struct {
+	CNPJ             string `db:"cnpj"`
+	Nome             string `db:"nome"`
+	Denominação      string `db:"denominacao"`
+	CódigoCVM        string `db:"codigo_cvm"`
+	NomeComercial    string `db:"nome_comercial"`
+	Setor            string `db:"setor"`
+	Situação         string `db:"situacao"`
+	DataRegistro     string `db:"data_registro"`
+	DataCancelamento string `db:"data_cancelamento"`
+	Auditor          string `db:"auditor"`
+}
+
+func (c sqliteCompanhia) empresa() rapina.Empresa {
+	return rapina.Empresa{
+		CNPJ:             c.CNPJ,
+		Nome:             c.Nome,
+		CódigoCVM:        c.CódigoCVM,
+		NomeComercial:    c.NomeComercial,
+		Setor:            c.Setor,
+		Situação:         c.Situação,
+		DataRegistro:     c.DataRegistro,
+		DataCancelamento: c.DataCancelamento,
+		Auditor:          c.Auditor,
+	}
+}
+
+// SalvarCadastro salva o cadastro das companhias abertas numa única
+// transação, substituindo o cadastro existente de cada CNPJ.
+func (s *Sqlite) SalvarCadastro(ctx context.Context, empresas []rapina.Empresa) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.PrepareNamedContext(ctx, `INSERT OR REPLACE INTO companhias
+		(cnpj, denominacao, codigo_cvm, nome_comercial, setor, situacao, data_registro, data_cancelamento, auditor)
+		VALUES
+		(:cnpj, :denominacao, :codigo_cvm, :nome_comercial, :setor, :situacao, :data_registro, :data_cancelamento, :auditor)`)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	for _, e := range empresas {
+		c := sqliteCompanhia{
+			CNPJ:             e.CNPJ,
+			Denominação:      e.Nome,
+			CódigoCVM:        e.CódigoCVM,
+			NomeComercial:    e.NomeComercial,
+			Setor:            e.Setor,
+			Situação:         e.Situação,
+			DataRegistro:     e.DataRegistro,
+			DataCancelamento: e.DataCancelamento,
+			Auditor:          e.Auditor,
+		}
+		if _, err := stmt.ExecContext(ctx, c); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	s.cacheEmpresas = s.cacheEmpresas[:0]
+	return nil
+}
+
+// listarEmpresas retorna as empresas com demonstrações financeiras, com o
+// nome do ano mais recente e os dados do cadastro da CVM, se houver.
+func (s *Sqlite) listarEmpresas(ctx context.Context) ([]rapina.Empresa, error) {
+	var companhias []sqliteCompanhia
+	err := s.db.SelectContext(ctx, &companhias, `SELECT
+		e.cnpj, e.nome,
+		COALESCE(c.denominacao, '')       AS denominacao,
+		COALESCE(c.codigo_cvm, '')        AS codigo_cvm,
+		COALESCE(c.nome_comercial, '')    AS nome_comercial,
+		COALESCE(c.setor, '')             AS setor,
+		COALESCE(c.situacao, '')          AS situacao,
+		COALESCE(c.data_registro, '')     AS data_registro,
+		COALESCE(c.data_cancelamento, '') AS data_cancelamento,
+		COALESCE(c.auditor, '')           AS auditor
+		FROM empresas e
+		LEFT JOIN companhias c ON c.cnpj = e.cnpj
+		WHERE e.ano = (SELECT MAX(ano) FROM empresas WHERE cnpj = e.cnpj)
+		ORDER BY e.nome`)
+	if err != nil {
+		return nil, err
+	}
+
+	empresas := make([]rapina.Empresa, len(companhias))
+	for i := range companhias {
+		empresas[i] = companhias[i].empresa()
+	}
+	return empresas, s.preencherTickers(ctx, empresas)
+}
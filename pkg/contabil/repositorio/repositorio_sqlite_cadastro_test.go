@@ -0,0 +1,87 @@
+// SPDX-FileCopyrightText: 2023 Adriano Prado <dev@dude333.com>
+//
+// SPDX-License-Identifier: MIT
+
+package repositorio
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+
+	rapina "github.com/dude333/rapinav2"
+	"github.com/dude333/rapinav2/pkg/contabil/dominio"
+)
+
+func Test_cadastro(t *testing.T) {
+	registros := []map[string]string{
+		{"CNPJ_CIA": "33.000.167/0001-01", "DENOM_SOCIAL": "PETROLEO BRASILEIRO S.A. PETROBRAS", "DENOM_COMERC": "PETROBRAS", "CD_CVM": "009512", "SETOR_ATIV": "Petróleo e Gás", "SIT": "ATIVO", "DT_INI_SIT": "1977-07-20", "DT_REG": "1977-07-20", "AUDITOR": "KPMG"},
+		{"CNPJ_CIA": "11.111.111/0001-11", "DENOM_SOCIAL": "NOVA", "CD_CVM": "200", "SIT": "ATIVO", "DT_INI_SIT": "2010-01-01"},
+		{"CNPJ_CIA": "11.111.111/0001-11", "DENOM_SOCIAL": "ANTIGA", "CD_CVM": "100", "SIT": "CANCELADA", "DT_INI_SIT": "2015-01-01", "DT_CANCEL": "2015-01-01"},
+		{"CNPJ_CIA": "22.222.222/0001-22", "DENOM_SOCIAL": "X1", "SIT": "CANCELADA", "DT_INI_SIT": "2001-01-01", "DT_CANCEL": "2001-01-01"},
+		{"CNPJ_CIA": "22.222.222/0001-22", "DENOM_SOCIAL": "X2", "SIT": "CANCELADA", "DT_INI_SIT": "2002-01-01", "DT_CANCEL": "2002-01-01"},
+	}
+	got := cadastro(registros)
+	want := []rapina.Empresa{
+		{CNPJ: "11.111.111/0001-11", Nome: "NOVA", CódigoCVM: "200", Situação: "ATIVO"},
+		{CNPJ: "22.222.222/0001-22", Nome: "X2", Situação: "CANCELADA", DataCancelamento: "2002-01-01"},
+		{CNPJ: "33.000.167/0001-01", Nome: "PETROLEO BRASILEIRO S.A. PETROBRAS", CódigoCVM: "9512", NomeComercial: "PETROBRAS",
+			Setor: "Petróleo e Gás", Situação: "ATIVO", DataRegistro: "1977-07-20", Auditor: "KPMG"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("cadastro() = %+v\nwant %+v", got, want)
+	}
+	if !got[1].Cancelada() || got[0].Cancelada() {
+		t.Errorf("Cancelada() = %v, %v", got[0].Cancelada(), got[1].Cancelada())
+	}
+}
+
+func TestSqlite_SalvarCadastro(t *testing.T) {
+	db := sqlx.MustConnect("sqlite3", ":memory:")
+	db.SetMaxOpenConns(1)
+
+	s, err := NovoSqlite(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	const cnpj = "33.000.167/0001-01"
+	for ano, nome := range map[int]string{2021: "PETROBRAS ANTIGA", 2022: "PETROBRAS"} {
+		err = s.Salvar(ctx, &dominio.DemonstraçãoFinanceira{
+			Empresa: rapina.Empresa{CNPJ: cnpj, Nome: nome},
+			Ano:     ano,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Empresa sem cadastro
+	empresas, err := s.Empresas(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(empresas) != 1 || empresas[0].Nome != "PETROBRAS" || empresas[0].Setor != "" {
+		t.Errorf("Empresas() = %+v", empresas)
+	}
+
+	err = s.SalvarCadastro(ctx, []rapina.Empresa{
+		{CNPJ: cnpj, Nome: "PETROLEO BRASILEIRO S.A. PETROBRAS", Setor: "Petróleo e Gás", Situação: "ATIVO"},
+		{CNPJ: "11.111.111/0001-11", Nome: "SEM DFP"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	empresas, err = s.BuscaEmpresas(ctx, "petro")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []rapina.Empresa{{CNPJ: cnpj, Nome: "PETROBRAS", Setor: "Petróleo e Gás", Situação: "ATIVO"}}
+	if !reflect.DeepEqual(empresas, want) {
+		t.Errorf("BuscaEmpresas() = %+v, want %+v", empresas, want)
+	}
+}
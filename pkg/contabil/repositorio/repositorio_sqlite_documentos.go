@@ -0,0 +1,130 @@
+// SPDX-FileCopyrightText: 2023 Adriano Prado <dev@dude333.com>
+//
+// SPDX-License-Identifier: MIT
+
+package repositorio
+
+import (
+	"context"
+
+	rapina "github.com/dude333/rapinav2"
+	"github.com/dude333/rapinav2/pkg/contabil/dominio"
+)
+
+type sqliteDocumento struct {
+	CNPJ            string `db:"cnpj"`
+	Tipo            string `db:"tipo"`
+	DataRefer       string `db:"data_refer"`
+	Versão          int    `db:"versao"`
+	DataRecebimento string `db:"data_receb"`
+}
+
+type sqliteReapresentação struct {
+	DataRefer       string         `db:"data_refer"`
+	Código          string         `db:"codigo"`
+	Descr           string         `db:"descr"`
+	Coluna          string         `db:"coluna"`
+	Consolidado     int            `db:"consolidado"`
+	DataIniExerc    string         `db:"data_ini_exerc"`
+	DataFimExerc    string         `db:"data_fim_exerc"`
+	VersãoAnterior  int            `db:"versao_anterior"`
+	Versão          int            `db:"versao"`
+	DataRecebimento string         `db:"data_receb"`
+	ValorAnterior   rapina.Decimal `db:"valor_anterior"`
+	Valor           rapina.Decimal `db:"valor"`
+	Escala          int            `db:"escala"`
+}
+
+// SalvarDocumentos salva as versões dos documentos entregues numa única
+// transação, substituindo a data de recebimento já existente.
+func (s *Sqlite) SalvarDocumentos(ctx context.Context, docs []dominio.Documento) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.PrepareNamedContext(ctx, `INSERT OR REPLACE INTO documentos
+		(cnpj, tipo, data_refer, versao, data_receb)
+		VALUES
+		(:cnpj, :tipo, :data_refer, :versao, :data_receb)`)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	for _, d := range docs {
+		r := sqliteDocumento{
+			CNPJ:            d.CNPJ,
+			Tipo:            d.Tipo,
+			DataRefer:       d.DataRefer,
+			Versão:          d.Versão,
+			DataRecebimento: d.DataRecebimento,
+		}
+		if _, err := stmt.ExecContext(ctx, r); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Reapresentações retorna as contas da empresa cujo valor foi alterado em
+// uma nova versão do documento, comparando cada versão com a anterior
+// armazenada. Contas incluídas ou removidas na nova versão não são
+// listadas, nem a versão 0 (dados gravados antes do histórico de versões).
+func (s *Sqlite) Reapresentações(ctx context.Context, cnpj string) ([]dominio.Reapresentação, error) {
+	var registros []sqliteReapresentação
+	err := s.db.SelectContext(ctx, &registros, `
+		SELECT
+			c.data_refer, c.codigo, c.descr, c.coluna, c.consolidado,
+			COALESCE(c.data_ini_exerc, '') AS data_ini_exerc, c.data_fim_exerc,
+			a.versao AS versao_anterior, c.versao,
+			COALESCE((SELECT MAX(d.data_receb) FROM documentos d
+				WHERE d.cnpj = e.cnpj AND d.data_refer = c.data_refer AND d.versao = c.versao), '') AS data_receb,
+			a.valor AS valor_anterior, c.valor, c.escala
+		FROM empresas e
+		JOIN contas c ON c.id_empresa = e.id
+		JOIN contas a ON a.id_empresa = c.id_empresa
+			AND a.data_refer = c.data_refer
+			AND a.codigo = c.codigo
+			AND a.coluna = c.coluna
+			AND a.consolidado = c.consolidado
+			AND COALESCE(a.data_ini_exerc, '') = COALESCE(c.data_ini_exerc, '')
+			AND a.data_fim_exerc = c.data_fim_exerc
+			AND a.versao = (
+				SELECT MAX(v.versao) FROM contas v
+				WHERE v.id_empresa = c.id_empresa AND v.data_refer = c.data_refer
+					AND v.versao < c.versao AND v.versao > 0
+			)
+		WHERE e.cnpj = ? AND a.valor <> c.valor
+		ORDER BY c.data_refer, c.versao, c.consolidado DESC, c.codigo, c.coluna, c.data_fim_exerc`, cnpj)
+	if err != nil {
+		return nil, err
+	}
+
+	reapresentações := make([]dominio.Reapresentação, 0, len(registros))
+	for _, r := range registros {
+		if r.ValorAnterior == r.Valor {
+			// mesmo valor gravado com outro texto (ex.: "100.0" e "100")
+			continue
+		}
+		reapresentações = append(reapresentações, dominio.Reapresentação{
+			DataRefer:       r.DataRefer,
+			Código:          r.Código,
+			Descr:           r.Descr,
+			Coluna:          r.Coluna,
+			Consolidado:     r.Consolidado != 0,
+			DataIniExerc:    r.DataIniExerc,
+			DataFimExerc:    r.DataFimExerc,
+			VersãoAnterior:  r.VersãoAnterior,
+			Versão:          r.Versão,
+			DataRecebimento: r.DataRecebimento,
+			ValorAnterior:   r.ValorAnterior,
+			Valor:           r.Valor,
+			Escala:          r.Escala,
+		})
+	}
+	return reapresentações, nil
+}
@@ -0,0 +1,104 @@
+// SPDX-FileCopyrightText: 2023 Adriano Prado <dev@dude333.com>
+//
+// SPDX-License-Identifier: MIT
+
+package repositorio
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+
+	rapina "github.com/dude333/rapinav2"
+	"github.com/dude333/rapinav2/pkg/contabil/dominio"
+)
+
+func Test_documentos(t *testing.T) {
+	registros := []map[string]string{
+		{"CNPJ_CIA": "60.840.055/0001-31", "DT_REFER": "2022-12-31", "VERSAO": "2", "CATEG_DOC": "DFP", "DT_RECEB": "2023-04-20"},
+		{"CNPJ_CIA": "60.840.055/0001-31", "DT_REFER": "2022-12-31", "VERSAO": "1", "DT_RECEB": "2023-03-10"},
+		{"CNPJ_CIA": "60.840.055/0001-31", "DT_REFER": "2022-12-31", "VERSAO": "x"},
+		{"CNPJ_CIA": "", "DT_REFER": "2022-12-31", "VERSAO": "1"},
+	}
+	want := []dominio.Documento{
+		{CNPJ: "60.840.055/0001-31", Tipo: "ITR", DataRefer: "2022-12-31", Versão: 1, DataRecebimento: "2023-03-10"},
+		{CNPJ: "60.840.055/0001-31", Tipo: "DFP", DataRefer: "2022-12-31", Versão: 2, DataRecebimento: "2023-04-20"},
+	}
+	if got := documentos(registros, "ITR"); !reflect.DeepEqual(got, want) {
+		t.Errorf("documentos() = %+v\nwant %+v", got, want)
+	}
+}
+
+func TestSqlite_Reapresentações(t *testing.T) {
+	db := sqlx.MustConnect("sqlite3", ":memory:")
+	db.SetMaxOpenConns(1)
+
+	s, err := NovoSqlite(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	const cnpj = "60.840.055/0001-31"
+	conta := func(código string, versão int, valor float64) dominio.Conta {
+		return dominio.Conta{
+			Código:       código,
+			Descr:        "D" + código,
+			Grupo:        "DRE",
+			Consolidado:  true,
+			DataIniExerc: "2022-01-01",
+			DataFimExerc: "2022-12-31",
+			Meses:        12,
+			DataRefer:    "2022-12-31",
+			Versão:       versão,
+			Total:        rapina.Dinheiro{Valor: rapina.DecimalDeFloat(valor), Escala: 1000, Moeda: "R$"},
+		}
+	}
+	for _, contas := range [][]dominio.Conta{
+		{conta("3.01", 1, 100), conta("3.11", 1, 10)},
+		{conta("3.01", 3, 100), conta("3.11", 3, 12)},
+	} {
+		err := s.Salvar(ctx, &dominio.DemonstraçãoFinanceira{
+			Empresa: rapina.Empresa{CNPJ: cnpj, Nome: "FLEURY"},
+			Ano:     2022,
+			Contas:  contas,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = s.SalvarDocumentos(ctx, []dominio.Documento{
+		{CNPJ: cnpj, Tipo: "DFP", DataRefer: "2022-12-31", Versão: 3, DataRecebimento: "2023-05-02"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("relatório usa a versão mais recente", func(t *testing.T) {
+		dfp, err := s.Ler(ctx, cnpj, 2022)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(dfp.Contas) != 2 || dfp.Contas[1].Total.Valor != rapina.DecimalDeInt(12) || dfp.Contas[1].Versão != 3 {
+			t.Errorf("Ler() = %+v", dfp.Contas)
+		}
+	})
+
+	t.Run("contas alteradas", func(t *testing.T) {
+		got, err := s.Reapresentações(ctx, cnpj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []dominio.Reapresentação{{
+			DataRefer: "2022-12-31", Código: "3.11", Descr: "D3.11", Consolidado: true,
+			DataIniExerc: "2022-01-01", DataFimExerc: "2022-12-31",
+			VersãoAnterior: 1, Versão: 3, DataRecebimento: "2023-05-02",
+			ValorAnterior: rapina.DecimalDeInt(10), Valor: rapina.DecimalDeInt(12), Escala: 1000,
+		}}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Reapresentações() = %+v\nwant %+v", got, want)
+		}
+	})
+}
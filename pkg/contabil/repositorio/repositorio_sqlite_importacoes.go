@@ -0,0 +1,368 @@
+// SPDX-FileCopyrightText: 2023 Adriano Prado <dev@dude333.com>
+//
+// SPDX-License-Identifier: MIT
+
+package repositorio
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/dude333/rapinav2/pkg/contabil/dominio"
+	"github.com/dude333/rapinav2/pkg/progress"
+)
+
+// Tipos de importação registrados no diário.
+const (
+	ImportaçãoAnos     = "anos"     // parâmetros: anos
+	ImportaçãoArquivos = "arquivos" // parâmetros: caminhos dos arquivos
+)
+
+// Situação das importações registradas no diário.
+const (
+	importaçãoAndamento  = "andamento"
+	importaçãoConcluída  = "concluida"
+	importaçãoAbandonada = "abandonada"
+)
+
+// empresasPorTransação é o número de empresas/ano substituídas em cada
+// transação ao aplicar uma importação.
+const empresasPorTransação = 500
+
+var (
+	ErrNenhumaImportação       = errors.New("nenhuma importação interrompida")
+	ErrImportaçãoNãoEncontrada = errors.New("importação não encontrada")
+)
+
+// RegistroImportação é uma importação registrada no diário.
+type RegistroImportação struct {
+	ID         int64
+	Tipo       string   // ImportaçãoAnos ou ImportaçãoArquivos
+	Parâmetros []string // anos ou caminhos dos arquivos
+}
+
+type sqliteImportação struct {
+	ID         int64  `db:"id"`
+	Tipo       string `db:"tipo"`
+	Parâmetros string `db:"parametros"`
+	Status     string `db:"status"`
+	Início     string `db:"inicio"`
+	Fim        string `db:"fim"`
+}
+
+type sqliteContaImportação struct {
+	sqliteConta
+	IDImportação int64  `db:"id_importacao"`
+	CNPJ         string `db:"cnpj"`
+	Ano          int    `db:"ano"`
+}
+
+// NovaImportação registra o início de uma importação no diário. As
+// importações interrompidas são abandonadas e os seus dados ainda não
+// aplicados, descartados.
+func (s *Sqlite) NovaImportação(ctx context.Context, tipo string, parâmetros []string) (int64, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var abandonadas []int64
+	err = tx.SelectContext(ctx, &abandonadas, `SELECT id FROM importacoes WHERE status = ?`, importaçãoAndamento)
+	if err != nil {
+		return 0, err
+	}
+	for _, id := range abandonadas {
+		progress.Warning("Descartando a importação %d interrompida", id)
+		if _, err := tx.ExecContext(ctx, `DELETE FROM importacao_contas WHERE id_importacao = ?`, id); err != nil {
+			return 0, err
+		}
+		_, err := tx.ExecContext(ctx, `UPDATE importacoes SET status = ?, fim = ? WHERE id = ?`,
+			importaçãoAbandonada, agora(), id)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	res, err := tx.ExecContext(ctx, `INSERT INTO importacoes (tipo, parametros, status, inicio) VALUES (?, ?, ?, ?)`,
+		tipo, strings.Join(parâmetros, "\n"), importaçãoAndamento, agora())
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return id, tx.Commit()
+}
+
+// ImportaçãoInterrompida retorna a última importação não concluída, ou
+// ErrNenhumaImportação.
+func (s *Sqlite) ImportaçãoInterrompida(ctx context.Context) (RegistroImportação, error) {
+	var r sqliteImportação
+	err := s.db.GetContext(ctx, &r, `SELECT id, tipo, parametros FROM importacoes
+		WHERE status = ? ORDER BY id DESC LIMIT 1`, importaçãoAndamento)
+	if errors.Is(err, sql.ErrNoRows) {
+		return RegistroImportação{}, ErrNenhumaImportação
+	}
+	if err != nil {
+		return RegistroImportação{}, err
+	}
+
+	imp := RegistroImportação{ID: r.ID, Tipo: r.Tipo}
+	if r.Parâmetros != "" {
+		imp.Parâmetros = strings.Split(r.Parâmetros, "\n")
+	}
+	return imp, nil
+}
+
+// ArquivosImportados retorna os hashes dos arquivos já gravados na
+// importação.
+func (s *Sqlite) ArquivosImportados(ctx context.Context, id int64) ([]string, error) {
+	var hashes []string
+	err := s.db.SelectContext(ctx, &hashes, `SELECT hash FROM importacao_arquivos WHERE id_importacao = ?`, id)
+	return hashes, err
+}
+
+// PrepararLote grava as demonstrações financeiras no diário da importação,
+// numa única transação, sem alterar os dados usados nos relatórios. Se hash
+// não for vazio, o arquivo correspondente é registrado como gravado na mesma
+// transação. Gravar novamente os mesmos dados (ex.: ao retomar a
+// importação) não gera duplicidade.
+func (s *Sqlite) PrepararLote(ctx context.Context, id int64, dfps []*dominio.DemonstraçãoFinanceira, hash string) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if len(dfps) > 0 {
+		if err := prepararContas(ctx, tx, id, dfps); err != nil {
+			return err
+		}
+	}
+
+	if hash != "" {
+		_, err := tx.ExecContext(ctx, `INSERT OR IGNORE INTO importacao_arquivos (id_importacao, hash) VALUES (?, ?)`, id, hash)
+		if err != nil {
+			return err
+		}
+	}
+
+	progress.Spinner()
+
+	return tx.Commit()
+}
+
+func prepararContas(ctx context.Context, tx *sqlx.Tx, id int64, dfps []*dominio.DemonstraçãoFinanceira) error {
+	empresa, err := tx.PreparexContext(ctx, `INSERT INTO importacao_empresas
+		(id_importacao, cnpj, ano, nome) VALUES (?, ?, ?, ?)
+		ON CONFLICT (id_importacao, cnpj, ano) DO UPDATE SET nome = excluded.nome, aplicada = 0`)
+	if err != nil {
+		return err
+	}
+	defer empresa.Close()
+
+	conta, err := tx.PrepareNamedContext(ctx, `INSERT OR IGNORE INTO importacao_contas
+		(id_importacao, cnpj, ano, codigo, descr, grupo, consolidado, data_ini_exerc, data_fim_exerc, meses, valor, escala, moeda, data_refer, versao, coluna)
+		VALUES
+		(:id_importacao, :cnpj, :ano, :codigo, :descr, :grupo, :consolidado, :data_ini_exerc, :data_fim_exerc, :meses, :valor, :escala, :moeda, :data_refer, :versao, :coluna)`)
+	if err != nil {
+		return err
+	}
+	defer conta.Close()
+
+	for _, dfp := range dfps {
+		if _, err := empresa.ExecContext(ctx, id, dfp.CNPJ, dfp.Ano, dfp.Nome); err != nil {
+			return err
+		}
+		for i := range dfp.Contas {
+			c := sqliteContaImportação{
+				sqliteConta:  novaSqliteConta(0, dfp.Contas[i]),
+				IDImportação: id,
+				CNPJ:         dfp.CNPJ,
+				Ano:          dfp.Ano,
+			}
+			if _, err := conta.ExecContext(ctx, c); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// AplicarImportação substitui os dados de cada empresa/ano gravados no
+// diário da importação, cada uma atomicamente, e marca como processados os
+// arquivos importados. Se for interrompida, as empresas já substituídas não
+// são reaplicadas.
+func (s *Sqlite) AplicarImportação(ctx context.Context, id int64) error {
+	type empresaAno struct {
+		CNPJ string `db:"cnpj"`
+		Ano  int    `db:"ano"`
+		Nome string `db:"nome"`
+	}
+
+	var empresas []empresaAno
+	err := s.db.SelectContext(ctx, &empresas, `SELECT cnpj, ano, nome FROM importacao_empresas
+		WHERE id_importacao = ? AND aplicada = 0 ORDER BY ano, cnpj`, id)
+	if err != nil {
+		return err
+	}
+	if len(empresas) > 0 {
+		progress.Status("Gravando %d empresas/ano", len(empresas))
+	}
+
+	for ini := 0; ini < len(empresas); ini += empresasPorTransação {
+		fim := ini + empresasPorTransação
+		if fim > len(empresas) {
+			fim = len(empresas)
+		}
+
+		tx, err := s.db.BeginTxx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		for _, e := range empresas[ini:fim] {
+			if err := aplicarEmpresa(ctx, tx, id, e.CNPJ, e.Ano, e.Nome); err != nil {
+				_ = tx.Rollback()
+				return err
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		progress.Spinner()
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, query := range []string{
+		`INSERT OR IGNORE INTO hashes (hash) SELECT hash FROM importacao_arquivos WHERE id_importacao = ?`,
+		`DELETE FROM importacao_contas WHERE id_importacao = ?`,
+	} {
+		if _, err := tx.ExecContext(ctx, query, id); err != nil {
+			return err
+		}
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE importacoes SET status = ?, fim = ? WHERE id = ?`,
+		importaçãoConcluída, agora(), id)
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	s.cacheEmpresas = s.cacheEmpresas[:0]
+
+	return nil
+}
+
+// aplicarEmpresa substitui os dados da empresa no ano pelos gravados no
+// diário da importação. Apenas as versões dos documentos importadas
+// (data_refer, versao) são substituídas, junto com a versão 0 do mesmo
+// documento (gravada antes do histórico de versões); as demais versões já
+// gravadas são mantidas (histórico de reapresentações).
+func aplicarEmpresa(ctx context.Context, tx *sqlx.Tx, id int64, cnpj string, ano int, nome string) error {
+	idEmpresa, err := registrarEmpresa(ctx, tx, cnpj, ano, nome)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM contas
+		WHERE id_empresa = ? AND (data_refer, versao) IN (
+			SELECT data_refer, versao FROM importacao_contas
+			WHERE id_importacao = ? AND cnpj = ? AND ano = ?
+			UNION
+			SELECT data_refer, 0 FROM importacao_contas
+			WHERE id_importacao = ? AND cnpj = ? AND ano = ?
+		)`, idEmpresa, id, cnpj, ano, id, cnpj, ano)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT OR IGNORE INTO contas
+		(id_empresa, codigo, descr, grupo, consolidado, data_ini_exerc, data_fim_exerc, meses, valor, escala, moeda, data_refer, versao, coluna)
+		SELECT ?, codigo, descr, grupo, consolidado, data_ini_exerc, data_fim_exerc, meses, valor, escala, moeda, data_refer, versao, coluna
+		FROM importacao_contas
+		WHERE id_importacao = ? AND cnpj = ? AND ano = ?
+		ORDER BY rowid`, idEmpresa, id, cnpj, ano)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE importacao_empresas SET aplicada = 1
+		WHERE id_importacao = ? AND cnpj = ? AND ano = ?`, id, cnpj, ano)
+	return err
+}
+
+// SalvarEstatísticas grava as estatísticas de leitura de um arquivo no
+// relatório da importação, substituindo as do mesmo arquivo, se houver
+// (ex.: arquivo reprocessado ao retomar a importação).
+func (s *Sqlite) SalvarEstatísticas(ctx context.Context, id int64, e *dominio.Estatísticas) error {
+	dados, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, `INSERT OR REPLACE INTO importacao_estatisticas
+		(id_importacao, arquivo, estatisticas) VALUES (?, ?, ?)`, id, e.Arquivo, string(dados))
+	return err
+}
+
+// RelatórioImportação retorna o relatório dos registros descartados na
+// importação informada ou, se id <= 0, na última importação registrada no
+// diário.
+func (s *Sqlite) RelatórioImportação(ctx context.Context, id int64) (*dominio.RelatórioImportação, error) {
+	var imp sqliteImportação
+	var err error
+	if id > 0 {
+		err = s.db.GetContext(ctx, &imp, `SELECT id, tipo, parametros, status, inicio, fim
+			FROM importacoes WHERE id = ?`, id)
+	} else {
+		err = s.db.GetContext(ctx, &imp, `SELECT id, tipo, parametros, status, inicio, fim
+			FROM importacoes ORDER BY id DESC LIMIT 1`)
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrImportaçãoNãoEncontrada
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var linhas []string
+	err = s.db.SelectContext(ctx, &linhas, `SELECT estatisticas FROM importacao_estatisticas
+		WHERE id_importacao = ? ORDER BY arquivo`, imp.ID)
+	if err != nil {
+		return nil, err
+	}
+	arquivos := make([]dominio.Estatísticas, len(linhas))
+	for i, l := range linhas {
+		if err := json.Unmarshal([]byte(l), &arquivos[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	r := dominio.NovoRelatórioImportação(arquivos)
+	r.ID = imp.ID
+	r.Tipo = imp.Tipo
+	r.Situação = imp.Status
+	r.Início = imp.Início
+	r.Fim = imp.Fim
+	return r, nil
+}
+
+func agora() string {
+	return time.Now().Format("2006-01-02 15:04:05")
+}
@@ -0,0 +1,262 @@
+// SPDX-FileCopyrightText: 2023 Adriano Prado <dev@dude333.com>
+//
+// SPDX-License-Identifier: MIT
+
+package repositorio
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+
+	rapina "github.com/dude333/rapinav2"
+	"github.com/dude333/rapinav2/pkg/contabil/dominio"
+)
+
+func TestSqlite_Importação(t *testing.T) {
+	db := sqlx.MustConnect("sqlite3", ":memory:")
+	db.SetMaxOpenConns(1)
+	ctx := context.Background()
+
+	s, err := NovoSqlite(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dfp := func(códigos ...string) *dominio.DemonstraçãoFinanceira {
+		d := &dominio.DemonstraçãoFinanceira{
+			Empresa: rapina.Empresa{CNPJ: "60.840.055/0001-31", Nome: "FLEURY"},
+			Ano:     2022,
+		}
+		for _, código := range códigos {
+			d.Contas = append(d.Contas, dominio.Conta{
+				Código:       código,
+				Descr:        "D" + código,
+				Grupo:        "DRE",
+				DataFimExerc: "2022-12-31",
+				Total:        rapina.Dinheiro{Valor: rapina.DecimalDeInt(1), Escala: 1, Moeda: "R$"},
+			})
+		}
+		return d
+	}
+	contas := func() int {
+		d, err := s.Ler(ctx, "60.840.055/0001-31", 2022)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(d.Contas)
+	}
+
+	// Dados existentes, que devem ser substituídos pela importação
+	if err := s.Salvar(ctx, dfp("1.01", "1.02", "1.03")); err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := s.NovaImportação(ctx, ImportaçãoAnos, []string{"2022", "2021"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.PrepararLote(ctx, id, []*dominio.DemonstraçãoFinanceira{dfp("3.01")}, ""); err != nil {
+		t.Fatal(err)
+	}
+	// Arquivo reprocessado ao retomar a importação
+	for i := 0; i < 2; i++ {
+		err := s.PrepararLote(ctx, id, []*dominio.DemonstraçãoFinanceira{dfp("3.01", "3.11")}, "h1")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("dados não aplicados", func(t *testing.T) {
+		if n := contas(); n != 3 {
+			t.Errorf("contas = %d, want 3", n)
+		}
+		for _, h := range s.Hashes() {
+			if h == "h1" {
+				t.Error("hash não deveria ser salvo antes de aplicar a importação")
+			}
+		}
+	})
+
+	t.Run("importação interrompida", func(t *testing.T) {
+		imp, err := s.ImportaçãoInterrompida(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := RegistroImportação{ID: id, Tipo: ImportaçãoAnos, Parâmetros: []string{"2022", "2021"}}
+		if !reflect.DeepEqual(imp, want) {
+			t.Errorf("ImportaçãoInterrompida() = %+v, want %+v", imp, want)
+		}
+		hashes, err := s.ArquivosImportados(ctx, id)
+		if err != nil || !reflect.DeepEqual(hashes, []string{"h1"}) {
+			t.Errorf("ArquivosImportados() = %v, %v", hashes, err)
+		}
+	})
+
+	t.Run("aplicar", func(t *testing.T) {
+		if err := s.AplicarImportação(ctx, id); err != nil {
+			t.Fatal(err)
+		}
+		if n := contas(); n != 2 {
+			t.Errorf("contas = %d, want 2", n)
+		}
+		if hashes := s.Hashes(); !reflect.DeepEqual(hashes, []string{"h1"}) {
+			t.Errorf("Hashes() = %v", hashes)
+		}
+		if _, err := s.ImportaçãoInterrompida(ctx); !errors.Is(err, ErrNenhumaImportação) {
+			t.Errorf("ImportaçãoInterrompida() error = %v, want %v", err, ErrNenhumaImportação)
+		}
+	})
+
+	t.Run("abandonar importação interrompida", func(t *testing.T) {
+		id, err := s.NovaImportação(ctx, ImportaçãoArquivos, []string{"a.zip"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.PrepararLote(ctx, id, []*dominio.DemonstraçãoFinanceira{dfp("4.01")}, "h2"); err != nil {
+			t.Fatal(err)
+		}
+		novo, err := s.NovaImportação(ctx, ImportaçãoArquivos, []string{"b.zip"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		imp, err := s.ImportaçãoInterrompida(ctx)
+		if err != nil || imp.ID != novo {
+			t.Errorf("ImportaçãoInterrompida() = %+v, %v, want id %d", imp, err, novo)
+		}
+		var n int
+		_ = db.Get(&n, `SELECT COUNT(*) FROM importacao_contas WHERE id_importacao = ?`, id)
+		if n != 0 {
+			t.Errorf("contas da importação abandonada = %d, want 0", n)
+		}
+	})
+}
+
+func TestSqlite_Importação_versões(t *testing.T) {
+	db := sqlx.MustConnect("sqlite3", ":memory:")
+	db.SetMaxOpenConns(1)
+	ctx := context.Background()
+	const cnpj = "60.840.055/0001-31"
+
+	dfp := func(versão int, valor int64) *dominio.DemonstraçãoFinanceira {
+		return &dominio.DemonstraçãoFinanceira{
+			Empresa: rapina.Empresa{CNPJ: cnpj, Nome: "FLEURY"},
+			Ano:     2022,
+			Contas: []dominio.Conta{{
+				Código:       "3.01",
+				Descr:        "Receita",
+				Grupo:        "DRE",
+				DataIniExerc: "2022-01-01",
+				DataFimExerc: "2022-12-31",
+				Meses:        12,
+				Total:        rapina.Dinheiro{Valor: rapina.DecimalDeInt(valor), Escala: 1, Moeda: "R$"},
+				DataRefer:    "2022-12-31",
+				Versão:       versão,
+			}},
+		}
+	}
+	// cada versão é importada por uma nova execução do programa
+	importar := func(d *dominio.DemonstraçãoFinanceira) {
+		s, err := NovoSqlite(db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		id, err := s.NovaImportação(ctx, ImportaçãoAnos, []string{"2022"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.PrepararLote(ctx, id, []*dominio.DemonstraçãoFinanceira{d}, ""); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.AplicarImportação(ctx, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	reapresentações := func(s *Sqlite) []dominio.Reapresentação {
+		r, err := s.Reapresentações(ctx, cnpj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+
+	// versão 0: dados gravados antes do histórico de versões
+	importar(dfp(0, 90))
+	importar(dfp(1, 100))
+	var legados int
+	if err := db.Get(&legados, `SELECT COUNT(*) FROM contas WHERE versao = 0`); err != nil || legados != 0 {
+		t.Fatalf("contas na versão 0 = %d (%v), want 0", legados, err)
+	}
+	importar(dfp(3, 120))
+	importar(dfp(3, 120)) // reimportação da mesma versão
+
+	s, err := NovoSqlite(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := reapresentações(s)
+	if len(r) != 1 || r[0].VersãoAnterior != 1 || r[0].Versão != 3 || r[0].Valor != rapina.DecimalDeInt(120) {
+		t.Fatalf("Reapresentações() = %+v, want versão 1 => 3", r)
+	}
+
+	// a gravação direta também substitui apenas a versão gravada
+	if err := s.Salvar(ctx, dfp(3, 130)); err != nil {
+		t.Fatal(err)
+	}
+	r = reapresentações(s)
+	if len(r) != 1 || r[0].VersãoAnterior != 1 || r[0].Valor != rapina.DecimalDeInt(130) {
+		t.Errorf("Reapresentações() após Salvar() = %+v, want versão 1 => 3 com valor 130", r)
+	}
+}
+
+func TestSqlite_RelatórioImportação(t *testing.T) {
+	db := sqlx.MustConnect("sqlite3", ":memory:")
+	db.SetMaxOpenConns(1)
+	ctx := context.Background()
+
+	s, err := NovoSqlite(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.RelatórioImportação(ctx, 0); !errors.Is(err, ErrImportaçãoNãoEncontrada) {
+		t.Errorf("RelatórioImportação() error = %v, want %v", err, ErrImportaçãoNãoEncontrada)
+	}
+
+	id, err := s.NovaImportação(ctx, ImportaçãoArquivos, []string{"dfp.zip"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	estatísticas := func(lidas int) *dominio.Estatísticas {
+		e := &dominio.Estatísticas{Arquivo: "dfp_cia_aberta_DRE_con_2022.csv", Lidas: lidas}
+		e.Rejeitar(dominio.Descarte{Arquivo: e.Arquivo, Linha: 3, CNPJ: "60.840.055/0001-31", Motivo: "valor inválido"})
+		return e
+	}
+	// Arquivo reprocessado ao retomar a importação
+	for _, lidas := range []int{5, 10} {
+		if err := s.SalvarEstatísticas(ctx, id, estatísticas(lidas)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r, err := s.RelatórioImportação(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.ID != id || r.Tipo != ImportaçãoArquivos || r.Situação != importaçãoAndamento {
+		t.Errorf("RelatórioImportação() = %d %s %s, want %d %s %s", r.ID, r.Tipo, r.Situação, id, ImportaçãoArquivos, importaçãoAndamento)
+	}
+	if r.Lidas != 10 || r.Rejeitadas != 1 || len(r.Arquivos) != 1 {
+		t.Errorf("RelatórioImportação() = %d lidas, %d rejeitadas, %d arquivos, want 10, 1 e 1", r.Lidas, r.Rejeitadas, len(r.Arquivos))
+	}
+	if !reflect.DeepEqual(r.Arquivos[0], *estatísticas(10)) {
+		t.Errorf("Arquivos[0] = %+v, want %+v", r.Arquivos[0], *estatísticas(10))
+	}
+
+	if _, err := s.RelatórioImportação(ctx, id+1); !errors.Is(err, ErrImportaçãoNãoEncontrada) {
+		t.Errorf("RelatórioImportação(%d) error = %v, want %v", id+1, err, ErrImportaçãoNãoEncontrada)
+	}
+}
@@ -0,0 +1,580 @@
+// SPDX-FileCopyrightText: 2023 Adriano Prado <dev@dude333.com>
+//
+// SPDX-License-Identifier: MIT
+
+package repositorio
+
+import (
+	"github.com/jmoiron/sqlx"
+
+	ext "github.com/dude333/rapinav2/pkg/infra"
+	"github.com/dude333/rapinav2/pkg/progress"
+)
+
+// tabelas
+//
+//	+------------+      +------------+
+//	| empresas   |      | contas     |
+//	+------------+      +------------+
+//	| id*        |-----<| id_empresa*|
+//	| cnpj       |      | codigo*    |
+//	| nome       |      | descr      |
+//	| ano        |      | versao*    |
+//	+------------+      | data_refer |
+//	      |             | ...        |
+//	      |             +------------+
+//	      |
+//	      |             +------------+
+//	      |             | tickers    |
+//	      |             +------------+
+//	      +------------<| cnpj       |
+//	      |             | ticker*    |
+//	      |             | ...        |
+//	      |             +------------+
+//	      |
+//	      |             +------------+
+//	      |             | acoes      |
+//	      |             +------------+
+//	      +------------<| cnpj*      |
+//	      |             | data*      |
+//	      |             | ...        |
+//	      |             +------------+
+//	      |
+//	      |             +------------+
+//	      |             | companhias |
+//	      |             +------------+
+//	      +-------------| cnpj*      |
+//	      |             | setor      |
+//	      |             | ...        |
+//	      |             +------------+
+//	      |
+//	      |             +------------+
+//	      |             | documentos |
+//	      |             +------------+
+//	      +------------<| cnpj*      |
+//	                    | tipo*      |
+//	                    | data_refer*|
+//	                    | versao*    |
+//	                    | data_receb |
+//	                    +------------+
+//
+// A tabela contas guarda todas as versões (reapresentações) de cada
+// documento. A visão contas_recentes contém apenas a versão mais recente de
+// cada documento (id_empresa + data_refer) e é a usada nos relatórios.
+//
+// Passos ao inserir os dados de uma empresa no ano:
+//
+//  1. Obter o id da empresa, atualizando o nome ou criando o registro:
+//     a. SELECT id FROM empresas WHERE cnpj = ? AND ano = ?;
+//     b. UPDATE empresas SET nome = ? WHERE id = ?; ou
+//     INSERT INTO empresas (cnpj, nome, ano) VALUES (?,?,?);
+//  2. Remover apenas as versões dos documentos que estão sendo importadas,
+//     e também a versão 0 (registros gravados antes do histórico de versões)
+//     do mesmo documento:
+//     DELETE FROM contas WHERE id_empresa = ? AND data_refer = ? AND versao IN (?, 0);
+//  3. Inserir as contas:
+//     for range contas => INSERT INTO contas (id_empresa, ...) VALUES (?, ...)
+//
+// Na atualização (importação registrada no diário), as contas são gravadas
+// antes nas tabelas importacao_empresas e importacao_contas, e cada arquivo
+// processado em importacao_arquivos. Ao final, cada empresa/ano é substituída
+// pelos passos acima numa única transação, e os hashes dos arquivos são
+// copiados para a tabela hashes. Uma importação interrompida pode assim ser
+// retomada sem reprocessar os arquivos já gravados.
+//
+// Alterações no esquema devem ser feitas *apenas* adicionando uma nova
+// migração ao final da lista, com os comandos para alterar as tabelas e
+// mover os dados (up) e para desfazer a alteração (down). Migrações já
+// publicadas nunca devem ser alteradas.
+var migrações = []ext.Migration{
+	{
+		// Esquema criado pela versão 17 da antiga tabela "tabelas", portanto
+		// bancos de dados existentes já estão nesta versão.
+		Version: 1,
+		Descr:   "criar tabelas empresas, contas e hashes",
+		Up: []string{
+			`CREATE TABLE IF NOT EXISTS empresas (
+				id             INTEGER PRIMARY KEY AUTOINCREMENT,
+				cnpj           VARCHAR NOT NULL,
+				nome           VARCHAR NOT NULL,
+				ano            INT NOT NULL,
+				UNIQUE (cnpj, ano)
+			)`,
+			`CREATE TABLE IF NOT EXISTS contas (
+				id_empresa     INTEGER,
+				codigo         VARCHAR NOT NULL,
+				descr          VARCHAR NOT NULL,
+				grupo          VARCHAR NOT NULL,
+				consolidado    INTEGER NOT NULL,
+				data_ini_exerc VARCHAR,
+				data_fim_exerc VARCHAR NOT NULL,
+				meses          INTEGER NOT NULL,
+				valor          REAL NOT NULL,
+				escala         INTEGER NOT NULL,
+				moeda          VARCHAR,
+				PRIMARY KEY (id_empresa, codigo, data_ini_exerc, data_fim_exerc)
+			)`,
+			`CREATE TABLE IF NOT EXISTS hashes (
+				id             INTEGER PRIMARY KEY AUTOINCREMENT,
+				hash           VARCHAR NOT NULL,
+				UNIQUE (hash)
+			)`,
+		},
+		Down: []string{
+			`DROP TABLE IF EXISTS contas`,
+			`DROP TABLE IF EXISTS empresas`,
+			`DROP TABLE IF EXISTS hashes`,
+		},
+	},
+	{
+		// A tabela "tabelas" era usada para apagar e recriar as tabelas a cada
+		// mudança de versão. No downgrade ela é recriada com a versão 17 para
+		// que binários antigos não apaguem os dados.
+		Version: 2,
+		Descr:   "remover tabela de versões legada",
+		Up: []string{
+			`DROP TABLE IF EXISTS tabelas`,
+		},
+		Down: []string{
+			`CREATE TABLE IF NOT EXISTS tabelas (
+				nome   VARCHAR PRIMARY KEY,
+				versao INTEGER NOT NULL
+			)`,
+			`INSERT OR REPLACE INTO tabelas (nome, versao) VALUES
+				('empresas', 17), ('contas', 17), ('hashes', 17)`,
+		},
+	},
+	{
+		Version: 3,
+		Descr:   "criar tabela tickers",
+		Up: []string{
+			`CREATE TABLE IF NOT EXISTS tickers (
+				ticker     VARCHAR PRIMARY KEY,
+				cnpj       VARCHAR NOT NULL,
+				classe     VARCHAR NOT NULL DEFAULT '',
+				isin       VARCHAR NOT NULL DEFAULT '',
+				codigo_cvm VARCHAR NOT NULL DEFAULT ''
+			)`,
+			`CREATE INDEX IF NOT EXISTS tickers_cnpj ON tickers (cnpj)`,
+		},
+		Down: []string{
+			`DROP TABLE IF EXISTS tickers`,
+		},
+	},
+	{
+		Version: 4,
+		Descr:   "criar tabela acoes",
+		Up: []string{
+			`CREATE TABLE IF NOT EXISTS acoes (
+				cnpj          VARCHAR NOT NULL,
+				data          VARCHAR NOT NULL,
+				ordinarias    INTEGER NOT NULL DEFAULT 0,
+				preferenciais INTEGER NOT NULL DEFAULT 0,
+				tesouraria_on INTEGER NOT NULL DEFAULT 0,
+				tesouraria_pn INTEGER NOT NULL DEFAULT 0,
+				PRIMARY KEY (cnpj, data)
+			)`,
+		},
+		Down: []string{
+			`DROP TABLE IF EXISTS acoes`,
+		},
+	},
+	{
+		Version: 5,
+		Descr:   "criar tabela companhias",
+		Up: []string{
+			`CREATE TABLE IF NOT EXISTS companhias (
+				cnpj              VARCHAR PRIMARY KEY,
+				denominacao       VARCHAR NOT NULL DEFAULT '',
+				codigo_cvm        VARCHAR NOT NULL DEFAULT '',
+				nome_comercial    VARCHAR NOT NULL DEFAULT '',
+				setor             VARCHAR NOT NULL DEFAULT '',
+				situacao          VARCHAR NOT NULL DEFAULT '',
+				data_registro     VARCHAR NOT NULL DEFAULT '',
+				data_cancelamento VARCHAR NOT NULL DEFAULT '',
+				auditor           VARCHAR NOT NULL DEFAULT ''
+			)`,
+		},
+		Down: []string{
+			`DROP TABLE IF EXISTS companhias`,
+		},
+	},
+	{
+		// Os registros existentes são considerados como versão 0, com a data
+		// de referência igual à data final do exercício. No downgrade apenas
+		// a versão mais recente de cada documento é mantida.
+		Version: 6,
+		Descr:   "manter todas as versões dos documentos",
+		Up: []string{
+			`CREATE TABLE contas_v6 (
+				id_empresa     INTEGER,
+				codigo         VARCHAR NOT NULL,
+				descr          VARCHAR NOT NULL,
+				grupo          VARCHAR NOT NULL,
+				consolidado    INTEGER NOT NULL,
+				data_ini_exerc VARCHAR,
+				data_fim_exerc VARCHAR NOT NULL,
+				meses          INTEGER NOT NULL,
+				valor          REAL NOT NULL,
+				escala         INTEGER NOT NULL,
+				moeda          VARCHAR,
+				data_refer     VARCHAR NOT NULL DEFAULT '',
+				versao         INTEGER NOT NULL DEFAULT 0,
+				PRIMARY KEY (id_empresa, codigo, data_ini_exerc, data_fim_exerc, versao)
+			)`,
+			`INSERT INTO contas_v6
+				(id_empresa, codigo, descr, grupo, consolidado, data_ini_exerc, data_fim_exerc,
+				 meses, valor, escala, moeda, data_refer, versao)
+				SELECT id_empresa, codigo, descr, grupo, consolidado, data_ini_exerc, data_fim_exerc,
+				 meses, valor, escala, moeda, data_fim_exerc, 0
+				FROM contas`,
+			`DROP TABLE contas`,
+			`ALTER TABLE contas_v6 RENAME TO contas`,
+			`CREATE INDEX IF NOT EXISTS contas_versoes ON contas (id_empresa, data_refer, versao)`,
+			`CREATE VIEW IF NOT EXISTS contas_recentes AS
+				SELECT c.* FROM contas c
+				WHERE c.versao = (
+					SELECT MAX(v.versao) FROM contas v
+					WHERE v.id_empresa = c.id_empresa AND v.data_refer = c.data_refer
+				)`,
+			`CREATE TABLE IF NOT EXISTS documentos (
+				cnpj       VARCHAR NOT NULL,
+				tipo       VARCHAR NOT NULL,
+				data_refer VARCHAR NOT NULL,
+				versao     INTEGER NOT NULL,
+				data_receb VARCHAR NOT NULL DEFAULT '',
+				PRIMARY KEY (cnpj, tipo, data_refer, versao)
+			)`,
+		},
+		Down: []string{
+			`DROP TABLE IF EXISTS documentos`,
+			`CREATE TABLE contas_v5 (
+				id_empresa     INTEGER,
+				codigo         VARCHAR NOT NULL,
+				descr          VARCHAR NOT NULL,
+				grupo          VARCHAR NOT NULL,
+				consolidado    INTEGER NOT NULL,
+				data_ini_exerc VARCHAR,
+				data_fim_exerc VARCHAR NOT NULL,
+				meses          INTEGER NOT NULL,
+				valor          REAL NOT NULL,
+				escala         INTEGER NOT NULL,
+				moeda          VARCHAR,
+				PRIMARY KEY (id_empresa, codigo, data_ini_exerc, data_fim_exerc)
+			)`,
+			`INSERT OR IGNORE INTO contas_v5
+				SELECT id_empresa, codigo, descr, grupo, consolidado, data_ini_exerc, data_fim_exerc,
+				 meses, valor, escala, moeda
+				FROM contas_recentes`,
+			`DROP VIEW IF EXISTS contas_recentes`,
+			`DROP TABLE contas`,
+			`ALTER TABLE contas_v5 RENAME TO contas`,
+		},
+	},
+	{
+		// A DMPL tem uma linha por conta e coluna (ex.: Capital Social,
+		// Reservas de Lucro...), portanto a coluna passa a fazer parte da
+		// chave. No downgrade as linhas com coluna são descartadas.
+		Version: 7,
+		Descr:   "incluir a coluna da DMPL nas contas",
+		Up: []string{
+			`DROP VIEW IF EXISTS contas_recentes`,
+			`CREATE TABLE contas_v7 (
+				id_empresa     INTEGER,
+				codigo         VARCHAR NOT NULL,
+				descr          VARCHAR NOT NULL,
+				grupo          VARCHAR NOT NULL,
+				consolidado    INTEGER NOT NULL,
+				data_ini_exerc VARCHAR,
+				data_fim_exerc VARCHAR NOT NULL,
+				meses          INTEGER NOT NULL,
+				valor          REAL NOT NULL,
+				escala         INTEGER NOT NULL,
+				moeda          VARCHAR,
+				data_refer     VARCHAR NOT NULL DEFAULT '',
+				versao         INTEGER NOT NULL DEFAULT 0,
+				coluna         VARCHAR NOT NULL DEFAULT '',
+				PRIMARY KEY (id_empresa, codigo, coluna, data_ini_exerc, data_fim_exerc, versao)
+			)`,
+			`INSERT INTO contas_v7
+				(id_empresa, codigo, descr, grupo, consolidado, data_ini_exerc, data_fim_exerc,
+				 meses, valor, escala, moeda, data_refer, versao, coluna)
+				SELECT id_empresa, codigo, descr, grupo, consolidado, data_ini_exerc, data_fim_exerc,
+				 meses, valor, escala, moeda, data_refer, versao, ''
+				FROM contas`,
+			`DROP TABLE contas`,
+			`ALTER TABLE contas_v7 RENAME TO contas`,
+			`CREATE INDEX IF NOT EXISTS contas_versoes ON contas (id_empresa, data_refer, versao)`,
+			`CREATE VIEW IF NOT EXISTS contas_recentes AS
+				SELECT c.* FROM contas c
+				WHERE c.versao = (
+					SELECT MAX(v.versao) FROM contas v
+					WHERE v.id_empresa = c.id_empresa AND v.data_refer = c.data_refer
+				)`,
+		},
+		Down: []string{
+			`DROP VIEW IF EXISTS contas_recentes`,
+			`CREATE TABLE contas_v6 (
+				id_empresa     INTEGER,
+				codigo         VARCHAR NOT NULL,
+				descr          VARCHAR NOT NULL,
+				grupo          VARCHAR NOT NULL,
+				consolidado    INTEGER NOT NULL,
+				data_ini_exerc VARCHAR,
+				data_fim_exerc VARCHAR NOT NULL,
+				meses          INTEGER NOT NULL,
+				valor          REAL NOT NULL,
+				escala         INTEGER NOT NULL,
+				moeda          VARCHAR,
+				data_refer     VARCHAR NOT NULL DEFAULT '',
+				versao         INTEGER NOT NULL DEFAULT 0,
+				PRIMARY KEY (id_empresa, codigo, data_ini_exerc, data_fim_exerc, versao)
+			)`,
+			`INSERT OR IGNORE INTO contas_v6
+				SELECT id_empresa, codigo, descr, grupo, consolidado, data_ini_exerc, data_fim_exerc,
+				 meses, valor, escala, moeda, data_refer, versao
+				FROM contas WHERE coluna = ''`,
+			`DROP TABLE contas`,
+			`ALTER TABLE contas_v6 RENAME TO contas`,
+			`CREATE INDEX IF NOT EXISTS contas_versoes ON contas (id_empresa, data_refer, versao)`,
+			`CREATE VIEW IF NOT EXISTS contas_recentes AS
+				SELECT c.* FROM contas c
+				WHERE c.versao = (
+					SELECT MAX(v.versao) FROM contas v
+					WHERE v.id_empresa = c.id_empresa AND v.data_refer = c.data_refer
+				)`,
+		},
+	},
+	{
+		Version: 8,
+		Descr:   "criar diário de importação",
+		Up: []string{
+			`CREATE TABLE IF NOT EXISTS importacoes (
+				id         INTEGER PRIMARY KEY AUTOINCREMENT,
+				tipo       VARCHAR NOT NULL,
+				parametros VARCHAR NOT NULL,
+				status     VARCHAR NOT NULL,
+				inicio     VARCHAR NOT NULL,
+				fim        VARCHAR NOT NULL DEFAULT ''
+			)`,
+			`CREATE TABLE IF NOT EXISTS importacao_arquivos (
+				id_importacao INTEGER NOT NULL,
+				hash          VARCHAR NOT NULL,
+				PRIMARY KEY (id_importacao, hash)
+			)`,
+			`CREATE TABLE IF NOT EXISTS importacao_empresas (
+				id_importacao INTEGER NOT NULL,
+				cnpj          VARCHAR NOT NULL,
+				ano           INT NOT NULL,
+				nome          VARCHAR NOT NULL,
+				aplicada      INTEGER NOT NULL DEFAULT 0,
+				PRIMARY KEY (id_importacao, cnpj, ano)
+			)`,
+			`CREATE TABLE IF NOT EXISTS importacao_contas (
+				id_importacao  INTEGER NOT NULL,
+				cnpj           VARCHAR NOT NULL,
+				ano            INT NOT NULL,
+				codigo         VARCHAR NOT NULL,
+				descr          VARCHAR NOT NULL,
+				grupo          VARCHAR NOT NULL,
+				consolidado    INTEGER NOT NULL,
+				data_ini_exerc VARCHAR,
+				data_fim_exerc VARCHAR NOT NULL,
+				meses          INTEGER NOT NULL,
+				valor          REAL NOT NULL,
+				escala         INTEGER NOT NULL,
+				moeda          VARCHAR,
+				data_refer     VARCHAR NOT NULL,
+				versao         INTEGER NOT NULL,
+				coluna         VARCHAR NOT NULL,
+				PRIMARY KEY (id_importacao, cnpj, ano, codigo, coluna, data_ini_exerc, data_fim_exerc, versao)
+			)`,
+		},
+		Down: []string{
+			`DROP TABLE IF EXISTS importacao_contas`,
+			`DROP TABLE IF EXISTS importacao_empresas`,
+			`DROP TABLE IF EXISTS importacao_arquivos`,
+			`DROP TABLE IF EXISTS importacoes`,
+		},
+	},
+	{
+		Version: 9,
+		Descr:   "criar relatório de importação",
+		Up: []string{
+			`CREATE TABLE IF NOT EXISTS importacao_estatisticas (
+				id_importacao INTEGER NOT NULL,
+				arquivo       VARCHAR NOT NULL,
+				estatisticas  VARCHAR NOT NULL,
+				PRIMARY KEY (id_importacao, arquivo)
+			)`,
+		},
+		Down: []string{
+			`DROP TABLE IF EXISTS importacao_estatisticas`,
+		},
+	},
+	{
+		// Os valores passam a ser gravados como texto (rapina.Decimal), pois
+		// o REAL não representa exatamente os valores decimais da CVM.
+		Version: 10,
+		Descr:   "gravar valores decimais exatos",
+		Up: []string{
+			`DROP VIEW IF EXISTS contas_recentes`,
+			`CREATE TABLE contas_v10 (
+				id_empresa     INTEGER,
+				codigo         VARCHAR NOT NULL,
+				descr          VARCHAR NOT NULL,
+				grupo          VARCHAR NOT NULL,
+				consolidado    INTEGER NOT NULL,
+				data_ini_exerc VARCHAR,
+				data_fim_exerc VARCHAR NOT NULL,
+				meses          INTEGER NOT NULL,
+				valor          TEXT NOT NULL,
+				escala         INTEGER NOT NULL,
+				moeda          VARCHAR,
+				data_refer     VARCHAR NOT NULL DEFAULT '',
+				versao         INTEGER NOT NULL DEFAULT 0,
+				coluna         VARCHAR NOT NULL DEFAULT '',
+				PRIMARY KEY (id_empresa, codigo, coluna, data_ini_exerc, data_fim_exerc, versao)
+			)`,
+			`INSERT INTO contas_v10
+				(id_empresa, codigo, descr, grupo, consolidado, data_ini_exerc, data_fim_exerc,
+				 meses, valor, escala, moeda, data_refer, versao, coluna)
+				SELECT id_empresa, codigo, descr, grupo, consolidado, data_ini_exerc, data_fim_exerc,
+				 meses, CAST(valor AS TEXT), escala, moeda, data_refer, versao, coluna
+				FROM contas`,
+			`DROP TABLE contas`,
+			`ALTER TABLE contas_v10 RENAME TO contas`,
+			`CREATE INDEX IF NOT EXISTS contas_versoes ON contas (id_empresa, data_refer, versao)`,
+			`CREATE VIEW IF NOT EXISTS contas_recentes AS
+				SELECT c.* FROM contas c
+				WHERE c.versao = (
+					SELECT MAX(v.versao) FROM contas v
+					WHERE v.id_empresa = c.id_empresa AND v.data_refer = c.data_refer
+				)`,
+			`CREATE TABLE importacao_contas_v10 (
+				id_importacao  INTEGER NOT NULL,
+				cnpj           VARCHAR NOT NULL,
+				ano            INT NOT NULL,
+				codigo         VARCHAR NOT NULL,
+				descr          VARCHAR NOT NULL,
+				grupo          VARCHAR NOT NULL,
+				consolidado    INTEGER NOT NULL,
+				data_ini_exerc VARCHAR,
+				data_fim_exerc VARCHAR NOT NULL,
+				meses          INTEGER NOT NULL,
+				valor          TEXT NOT NULL,
+				escala         INTEGER NOT NULL,
+				moeda          VARCHAR,
+				data_refer     VARCHAR NOT NULL,
+				versao         INTEGER NOT NULL,
+				coluna         VARCHAR NOT NULL,
+				PRIMARY KEY (id_importacao, cnpj, ano, codigo, coluna, data_ini_exerc, data_fim_exerc, versao)
+			)`,
+			`INSERT INTO importacao_contas_v10
+				SELECT id_importacao, cnpj, ano, codigo, descr, grupo, consolidado, data_ini_exerc, data_fim_exerc,
+				 meses, CAST(valor AS TEXT), escala, moeda, data_refer, versao, coluna
+				FROM importacao_contas`,
+			`DROP TABLE importacao_contas`,
+			`ALTER TABLE importacao_contas_v10 RENAME TO importacao_contas`,
+		},
+		Down: []string{
+			`DROP VIEW IF EXISTS contas_recentes`,
+			`CREATE TABLE contas_v9 (
+				id_empresa     INTEGER,
+				codigo         VARCHAR NOT NULL,
+				descr          VARCHAR NOT NULL,
+				grupo          VARCHAR NOT NULL,
+				consolidado    INTEGER NOT NULL,
+				data_ini_exerc VARCHAR,
+				data_fim_exerc VARCHAR NOT NULL,
+				meses          INTEGER NOT NULL,
+				valor          REAL NOT NULL,
+				escala         INTEGER NOT NULL,
+				moeda          VARCHAR,
+				data_refer     VARCHAR NOT NULL DEFAULT '',
+				versao         INTEGER NOT NULL DEFAULT 0,
+				coluna         VARCHAR NOT NULL DEFAULT '',
+				PRIMARY KEY (id_empresa, codigo, coluna, data_ini_exerc, data_fim_exerc, versao)
+			)`,
+			`INSERT INTO contas_v9
+				(id_empresa, codigo, descr, grupo, consolidado, data_ini_exerc, data_fim_exerc,
+				 meses, valor, escala, moeda, data_refer, versao, coluna)
+				SELECT id_empresa, codigo, descr, grupo, consolidado, data_ini_exerc, data_fim_exerc,
+				 meses, CAST(valor AS REAL), escala, moeda, data_refer, versao, coluna
+				FROM contas`,
+			`DROP TABLE contas`,
+			`ALTER TABLE contas_v9 RENAME TO contas`,
+			`CREATE INDEX IF NOT EXISTS contas_versoes ON contas (id_empresa, data_refer, versao)`,
+			`CREATE VIEW IF NOT EXISTS contas_recentes AS
+				SELECT c.* FROM contas c
+				WHERE c.versao = (
+					SELECT MAX(v.versao) FROM contas v
+					WHERE v.id_empresa = c.id_empresa AND v.data_refer = c.data_refer
+				)`,
+			`CREATE TABLE importacao_contas_v9 (
+				id_importacao  INTEGER NOT NULL,
+				cnpj           VARCHAR NOT NULL,
+				ano            INT NOT NULL,
+				codigo         VARCHAR NOT NULL,
+				descr          VARCHAR NOT NULL,
+				grupo          VARCHAR NOT NULL,
+				consolidado    INTEGER NOT NULL,
+				data_ini_exerc VARCHAR,
+				data_fim_exerc VARCHAR NOT NULL,
+				meses          INTEGER NOT NULL,
+				valor          REAL NOT NULL,
+				escala         INTEGER NOT NULL,
+				moeda          VARCHAR,
+				data_refer     VARCHAR NOT NULL,
+				versao         INTEGER NOT NULL,
+				coluna         VARCHAR NOT NULL,
+				PRIMARY KEY (id_importacao, cnpj, ano, codigo, coluna, data_ini_exerc, data_fim_exerc, versao)
+			)`,
+			`INSERT INTO importacao_contas_v9
+				SELECT id_importacao, cnpj, ano, codigo, descr, grupo, consolidado, data_ini_exerc, data_fim_exerc,
+				 meses, CAST(valor AS REAL), escala, moeda, data_refer, versao, coluna
+				FROM importacao_contas`,
+			`DROP TABLE importacao_contas`,
+			`ALTER TABLE importacao_contas_v9 RENAME TO importacao_contas`,
+		},
+	},
+	{
+		// Os dados gravados antes da v6 ficaram com a versão 0. Apagando os
+		// hashes, a próxima atualização reimporta os arquivos já processados,
+		// gravando as versões dos documentos (a versão 0 de cada documento é
+		// então substituída). Não há o que desfazer.
+		Version: 11,
+		Descr:   "reimportar os documentos para gravar as versões",
+		Up: []string{
+			`DELETE FROM hashes`,
+		},
+	},
+}
+
+const móduloContabil = "contabil"
+
+// Migrar altera o esquema do banco de dados para a versão informada (ou para
+// a última versão se versão < 0), mantendo os dados existentes. Com
+// simular == true, apenas retorna os passos que seriam executados.
+func Migrar(db *sqlx.DB, versão int, simular bool) ([]ext.MigrationStep, error) {
+	return ext.Migrate(db, móduloContabil, migrações, versão, simular)
+}
+
+// VersãoEsquema retorna a versão atual do esquema do banco de dados.
+func VersãoEsquema(db *sqlx.DB) (int, error) {
+	return ext.SchemaVersion(db, móduloContabil)
+}
+
+// VersãoAplicada retorna a versão atual do esquema sem alterar o banco de
+// dados (0 se nenhuma migração foi aplicada).
+func VersãoAplicada(db *sqlx.DB) (int, error) {
+	return ext.AppliedVersion(db, móduloContabil)
+}
+
+func criarTabelas(db *sqlx.DB) error {
+	passos, err := Migrar(db, -1, false)
+	for _, p := range passos {
+		progress.Status(`Migração "%s" aplicada: v%d - %s`, p.Module, p.Version, p.Descr)
+	}
+	return err
+}
@@ -0,0 +1,129 @@
+// SPDX-FileCopyrightText: 2023 Adriano Prado <dev@dude333.com>
+//
+// SPDX-License-Identifier: MIT
+
+package repositorio
+
+import (
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+
+	rapina "github.com/dude333/rapinav2"
+)
+
+func TestMigrar(t *testing.T) {
+	db := sqlx.MustConnect("sqlite3", ":memory:")
+	db.SetMaxOpenConns(1)
+
+	// Banco de dados criado pela versão antiga (tabela "tabelas" na v17)
+	db.MustExec(migrações[0].Up[0])
+	db.MustExec(`CREATE TABLE tabelas (nome VARCHAR PRIMARY KEY, versao INTEGER NOT NULL)`)
+	db.MustExec(`INSERT INTO tabelas (nome, versao) VALUES ('empresas', 17)`)
+	db.MustExec(`INSERT INTO empresas (cnpj, nome, ano) VALUES ('123', 'N1', 2020)`)
+
+	contarEmpresas := func() int {
+		var n int
+		if err := db.Get(&n, `SELECT COUNT(*) FROM empresas`); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	t.Run("simular não altera o banco", func(t *testing.T) {
+		passos, err := Migrar(db, -1, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(passos) != len(migrações) {
+			t.Errorf("passos = %d, want %d", len(passos), len(migrações))
+		}
+		if v, _ := VersãoEsquema(db); v != 0 {
+			t.Errorf("VersãoEsquema() = %d, want 0", v)
+		}
+	})
+
+	t.Run("migrar para a última versão mantém os dados", func(t *testing.T) {
+		if _, err := Migrar(db, -1, false); err != nil {
+			t.Fatal(err)
+		}
+		if v, _ := VersãoEsquema(db); v != len(migrações) {
+			t.Errorf("VersãoEsquema() = %d, want %d", v, len(migrações))
+		}
+		if n := contarEmpresas(); n != 1 {
+			t.Errorf("empresas = %d, want 1", n)
+		}
+	})
+
+	t.Run("reverter para a versão 1", func(t *testing.T) {
+		passos, err := Migrar(db, 1, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(passos) != len(migrações)-1 || passos[0].Up {
+			t.Errorf("passos = %+v", passos)
+		}
+		if v, _ := VersãoEsquema(db); v != 1 {
+			t.Errorf("VersãoEsquema() = %d, want 1", v)
+		}
+		if n := contarEmpresas(); n != 1 {
+			t.Errorf("empresas = %d, want 1", n)
+		}
+	})
+
+	t.Run("versão inexistente", func(t *testing.T) {
+		if _, err := Migrar(db, len(migrações)+1, false); err == nil {
+			t.Error("Migrar() deveria retornar erro")
+		}
+	})
+}
+
+func TestMigrar_valoresDecimais(t *testing.T) {
+	db := sqlx.MustConnect("sqlite3", ":memory:")
+	db.SetMaxOpenConns(1)
+
+	if _, err := Migrar(db, 9, false); err != nil {
+		t.Fatal(err)
+	}
+	db.MustExec(`INSERT INTO empresas (cnpj, nome, ano) VALUES ('123', 'N1', 2020)`)
+	db.MustExec(`INSERT INTO contas (id_empresa, codigo, descr, grupo, consolidado, data_ini_exerc, data_fim_exerc, meses, valor, escala, moeda)
+		VALUES (1, '3.01', 'Receita', 'DRE', 1, '2020-01-01', '2020-12-31', 12, 1234567.89, 1000, 'R$')`)
+
+	if _, err := Migrar(db, -1, false); err != nil {
+		t.Fatal(err)
+	}
+	var valor rapina.Decimal
+	if err := db.Get(&valor, `SELECT valor FROM contas`); err != nil {
+		t.Fatal(err)
+	}
+	if valor.String() != "1234567.89" {
+		t.Errorf("valor = %s, want 1234567.89", valor)
+	}
+
+	if _, err := Migrar(db, 9, false); err != nil {
+		t.Fatal(err)
+	}
+	var real float64
+	if err := db.Get(&real, `SELECT valor FROM contas`); err != nil || real != 1234567.89 {
+		t.Errorf("valor = %v, %v, want 1234567.89", real, err)
+	}
+}
+
+func TestMigrar_reimportarVersões(t *testing.T) {
+	db := sqlx.MustConnect("sqlite3", ":memory:")
+	db.SetMaxOpenConns(1)
+
+	if _, err := Migrar(db, 10, false); err != nil {
+		t.Fatal(err)
+	}
+	db.MustExec(`INSERT INTO hashes (hash) VALUES ('abc')`)
+
+	if _, err := Migrar(db, 11, false); err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	if err := db.Get(&n, `SELECT COUNT(*) FROM hashes`); err != nil || n != 0 {
+		t.Errorf("hashes = %d (%v), want 0", n, err)
+	}
+}
@@ -2,50 +2,174 @@ package repositorio
 
 import (
 	_ "embed"
-	"encoding/json"
 	"fmt"
 	"strings"
 
 	rapina "github.com/dude333/rapinav2"
 )
 
+// resultadoTrimestral é o valor de uma conta acumulado desde o início do
+// exercício (meses), como retornado por sqlTrimestral.
 type resultadoTrimestral struct {
-	Codigo  string `db:"codigo"`
-	Descr   string `db:"descr"`
-	Valores string `db:"valores"`
+	Ano          int            `db:"ano"`
+	Codigo       string         `db:"codigo"`
+	Descr        string         `db:"descr"`
+	Coluna       string         `db:"coluna"`
+	DataIniExerc string         `db:"data_ini_exerc"`
+	MêsFim       string         `db:"mes_fim"`
+	Meses        int            `db:"meses"`
+	Valor        rapina.Decimal `db:"valor"`
+	Escala       int            `db:"escala"`
+	Moeda        string         `db:"moeda"`
+	DataFimExerc string         `db:"data_fim_exerc"`
+	Saldo        bool           `db:"saldo"`
 }
 
-type jsonTrimestral []struct {
-	Ano   int     `json:"ano"`
-	T1    float64 `json:"t1"`
-	T2    float64 `json:"t2"`
-	T3    float64 `json:"t3"`
-	T4    float64 `json:"t4"`
+func (r resultadoTrimestral) mesmaConta(outro resultadoTrimestral) bool {
+	return r.Codigo == outro.Codigo && r.Descr == outro.Descr && r.Coluna == outro.Coluna
 }
 
-func converterResultadosTrimestrais(resultados []resultadoTrimestral) ([]rapina.InformeTrimestral, error) {
-	itr := make([]rapina.InformeTrimestral, len(resultados))
+// conversão define a escala e a moeda dos valores retornados na leitura.
+type conversão struct {
+	escala int
+	moeda  string
+	câmbio *rapina.TabelaCâmbio
+}
+
+// escalar retorna o valor na escala da conversão, ainda na moeda gravada.
+func (c conversão) escalar(r resultadoTrimestral) rapina.Decimal {
+	d := rapina.Dinheiro{Moeda: r.Moeda, Valor: r.Valor, Escala: r.Escala}
+	return d.NaEscala(c.escala).Valor
+}
 
-	for i, resultado := range resultados {
-		var valoresJSON jsonTrimestral
-		err := json.Unmarshal([]byte(resultado.Valores), &valoresJSON)
+// converter converte os valores trimestrais da moeda informada para a moeda
+// da conversão. Cada trimestre, já sem o valor acumulado dos anteriores, é
+// convertido pela cotação do seu último dia. Os valores sem moeda gravada são
+// considerados na moeda da conversão.
+func (c conversão) converter(v rapina.ValoresTrimestrais, moeda string) (rapina.ValoresTrimestrais, error) {
+	if moeda == "" || moeda == c.moeda {
+		return v, nil
+	}
+	for t, valor := range []*rapina.Decimal{&v.T1, &v.T2, &v.T3, &v.T4} {
+		if valor.IsZero() {
+			continue
+		}
+		fim := rapina.Trimestre{Ano: v.Ano, T: t + 1}.Fim()
+		d, err := c.câmbio.Converter(rapina.Dinheiro{Moeda: moeda, Valor: *valor}, c.moeda, fim)
 		if err != nil {
-			return nil, err
+			return v, err
+		}
+		*valor = d.Valor
+	}
+	return v, nil
+}
+
+// acumulado contém a soma dos valores de uma conta num ano, acumulados até o
+// fim de cada trimestre; nil se não houver valor para o trimestre.
+type acumulado struct {
+	q1, q2, q3, q4 *rapina.Decimal
+	q4Anual        *rapina.Decimal // valor anual (ex.: saldo do balanço em 31/12)
+	fluxo          bool            // valor com data inicial (DRE, DFC...)
+	saldo          bool
+	moeda          string // moeda gravada do último valor
+}
+
+func (a *acumulado) somar(r resultadoTrimestral) {
+	somar := func(q **rapina.Decimal) {
+		if *q == nil {
+			*q = new(rapina.Decimal)
+		}
+		**q = (*q).Add(r.Valor)
+	}
+	if r.Meses == 3 || r.Meses == 12 && r.MêsFim == "03" {
+		somar(&a.q1)
+	}
+	if r.Meses == 6 || r.Meses == 12 && r.MêsFim == "06" {
+		somar(&a.q2)
+	}
+	if r.Meses == 9 || r.Meses == 12 && r.MêsFim == "09" {
+		somar(&a.q3)
+	}
+	if r.DataIniExerc != "" && r.Meses == 12 {
+		somar(&a.q4)
+	}
+	if r.Meses == 12 && r.MêsFim == "12" {
+		somar(&a.q4Anual)
+	}
+	a.fluxo = a.fluxo || r.DataIniExerc != ""
+	a.saldo = a.saldo || r.Saldo
+	if r.Moeda != "" {
+		a.moeda = r.Moeda
+	}
+}
+
+// trimestral retorna o valor de cada trimestre, subtraindo dos valores de
+// fluxo o valor acumulado até o trimestre anterior.
+func (a *acumulado) trimestral(ano int) rapina.ValoresTrimestrais {
+	valor := func(q *rapina.Decimal) rapina.Decimal {
+		if q == nil {
+			return rapina.Decimal{}
+		}
+		return *q
+	}
+	trimestre := func(q, anterior *rapina.Decimal) rapina.Decimal {
+		if a.fluxo && !a.saldo && q != nil && anterior != nil {
+			return q.Sub(*anterior)
+		}
+		return valor(q)
+	}
+
+	v := rapina.ValoresTrimestrais{
+		Ano: ano,
+		T1:  valor(a.q1),
+		T2:  trimestre(a.q2, a.q1),
+		T3:  trimestre(a.q3, a.q2),
+		T4:  valor(a.q4Anual),
+	}
+	if a.fluxo && !a.saldo && a.q4 != nil {
+		v.T4 = a.q4.Sub(valor(a.q3))
+	}
+	return v
+}
+
+// converterResultadosTrimestrais agrupa os resultados, ordenados por conta e
+// ano, em informes trimestrais, com os valores na escala e na moeda da
+// conversão. A conversão da moeda é feita depois do cálculo dos valores
+// trimestrais. Os anos sem valores são descartados.
+func converterResultadosTrimestrais(resultados []resultadoTrimestral, conv conversão) ([]rapina.InformeTrimestral, error) {
+	var itr []rapina.InformeTrimestral
+
+	for i := range resultados {
+		resultados[i].Valor = conv.escalar(resultados[i])
+	}
+
+	for i := 0; i < len(resultados); {
+		conta := resultados[i]
+		informe := rapina.InformeTrimestral{
+			Codigo: conta.Codigo,
+			Descr:  conta.Descr,
+		}
+		if conta.Coluna != "" {
+			informe.Descr += " (" + conta.Coluna + ")"
 		}
 
-		valoresTrimestrais := make([]rapina.ValoresTrimestrais, len(valoresJSON))
-		for j, valorJSON := range valoresJSON {
-			valoresTrimestrais[j].Ano = valorJSON.Ano
-			valoresTrimestrais[j].T1 = valorJSON.T1
-			valoresTrimestrais[j].T2 = valorJSON.T2
-			valoresTrimestrais[j].T3 = valorJSON.T3
-			valoresTrimestrais[j].T4 = valorJSON.T4
+		for i < len(resultados) && resultados[i].mesmaConta(conta) {
+			ano := resultados[i].Ano
+			var a acumulado
+			for ; i < len(resultados) && resultados[i].mesmaConta(conta) && resultados[i].Ano == ano; i++ {
+				a.somar(resultados[i])
+			}
+			v, err := conv.converter(a.trimestral(ano), a.moeda)
+			if err != nil {
+				return nil, fmt.Errorf("%s %d: %w", conta.Codigo, ano, err)
+			}
+			if v != (rapina.ValoresTrimestrais{Ano: ano}) {
+				informe.Valores = append(informe.Valores, v)
+			}
 		}
 
-		itr[i] = rapina.InformeTrimestral{
-			Codigo:  resultado.Codigo,
-			Descr:   resultado.Descr,
-			Valores: valoresTrimestrais,
+		if len(informe.Valores) > 0 {
+			itr = append(itr, informe)
 		}
 	}
 
@@ -55,11 +179,22 @@ func converterResultadosTrimestrais(resultados []resultadoTrimestral) ([]rapina.
 //go:embed repositorio_sqlite_trimestral.sql
 var sqlQueryTrimestral string
 
-func sqlTrimestral(ids []int, consolidado bool) string {
+// GruposSeparados são os grupos de contas (DMPL e DRA) que não fazem parte
+// do relatório trimestral principal e são apresentados separadamente.
+var GruposSeparados = []string{"DMPL", "DRA"}
+
+// sqlTrimestral retorna a consulta do relatório trimestral das contas do
+// grupo informado ou, se grupo == "", das contas de todos os grupos exceto
+// os GruposSeparados.
+func sqlTrimestral(ids []int, consolidado bool, grupo string) string {
 	strIds := strings.Trim(strings.Join(strings.Fields(fmt.Sprint(ids)), ","), "[]")
 	intConsolidado := 0
 	if consolidado {
 		intConsolidado = 1
 	}
-	return fmt.Sprintf(sqlQueryTrimestral, strIds, intConsolidado)
+	filtro := "c.grupo NOT IN ('" + strings.Join(GruposSeparados, "', '") + "')"
+	if grupo != "" {
+		filtro = "c.grupo = '" + strings.ReplaceAll(grupo, "'", "''") + "'"
+	}
+	return fmt.Sprintf(sqlQueryTrimestral, strIds, intConsolidado, filtro)
 }
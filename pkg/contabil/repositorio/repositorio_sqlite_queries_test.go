@@ -1,8 +1,17 @@
 package repositorio
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
 	"strings"
 	"testing"
+
+	"github.com/jmoiron/sqlx"
+
+	rapina "github.com/dude333/rapinav2"
+	"github.com/dude333/rapinav2/pkg/contabil/dominio"
 )
 
 func Test_sqlTrimestral(t *testing.T) {
@@ -31,9 +40,172 @@ func Test_sqlTrimestral(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := sqlTrimestral(tt.args.ids, true); !strings.Contains(got, tt.want) {
+			if got := sqlTrimestral(tt.args.ids, true, ""); !strings.Contains(got, tt.want) {
 				t.Errorf("sqlTrimestral() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
+
+func TestSqlite_TrimestralGrupo(t *testing.T) {
+	db := sqlx.MustConnect("sqlite3", ":memory:")
+	db.SetMaxOpenConns(1)
+
+	s, err := NovoSqlite(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	conta := func(grupo, código, coluna, fim string, meses int, valor float64) dominio.Conta {
+		return dominio.Conta{
+			Código:       código,
+			Descr:        "D" + código,
+			Grupo:        grupo,
+			Coluna:       coluna,
+			Consolidado:  true,
+			DataIniExerc: "2023-01-01",
+			DataFimExerc: fim,
+			DataRefer:    fim,
+			Meses:        meses,
+			Total:        rapina.Dinheiro{Valor: rapina.DecimalDeFloat(valor), Escala: 1000, Moeda: "R$"},
+		}
+	}
+	err = s.Salvar(ctx, &dominio.DemonstraçãoFinanceira{
+		Empresa: rapina.Empresa{CNPJ: "123", Nome: "N1"},
+		Ano:     2023,
+		Contas: []dominio.Conta{
+			conta("DRE", "3.11", "", "2023-03-31", 3, 10),
+			conta("DRA", "4.03", "", "2023-03-31", 3, 11),
+			conta("DMPL", "5.04.06", "Lucros Acumulados", "2023-03-31", 3, -5),
+			conta("DMPL", "5.04.06", "Lucros Acumulados", "2023-06-30", 6, -8),
+			conta("DMPL", "5.04.06", "Patrimônio Líquido", "2023-03-31", 3, -5),
+			conta("DMPL", "5.07", "Patrimônio Líquido", "2023-03-31", 3, 100),
+			conta("DMPL", "5.07", "Patrimônio Líquido", "2023-06-30", 6, 120),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	itr, err := s.Trimestral(ctx, "123", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(itr) != 1 || itr[0].Codigo != "3.11" {
+		t.Errorf("Trimestral() = %+v, want apenas a DRE", itr)
+	}
+
+	dmpl, err := s.TrimestralGrupo(ctx, "123", true, "DMPL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []rapina.InformeTrimestral{
+		{Codigo: "5.04.06", Descr: "D5.04.06 (Lucros Acumulados)", Valores: []rapina.ValoresTrimestrais{{Ano: 2023, T1: rapina.DecimalDeInt(-5), T2: rapina.DecimalDeInt(-3)}}},
+		{Codigo: "5.04.06", Descr: "D5.04.06 (Patrimônio Líquido)", Valores: []rapina.ValoresTrimestrais{{Ano: 2023, T1: rapina.DecimalDeInt(-5)}}},
+		{Codigo: "5.07", Descr: "D5.07 (Patrimônio Líquido)", Valores: []rapina.ValoresTrimestrais{{Ano: 2023, T1: rapina.DecimalDeInt(100), T2: rapina.DecimalDeInt(120)}}},
+	}
+	if !reflect.DeepEqual(dmpl, want) {
+		t.Errorf("TrimestralGrupo(DMPL) = %+v\nwant %+v", dmpl, want)
+	}
+
+	dra, err := s.TrimestralGrupo(ctx, "123", true, "DRA")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dra) != 1 || dra[0].Codigo != "4.03" {
+		t.Errorf("TrimestralGrupo(DRA) = %+v", dra)
+	}
+}
+
+func Test_converterResultadosTrimestrais(t *testing.T) {
+	d := func(s string) rapina.Decimal {
+		v, err := rapina.NovoDecimal(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	fluxo := func(ano, meses int, mês, valor string) resultadoTrimestral {
+		return resultadoTrimestral{Ano: ano, Codigo: "3.01", Descr: "Receita", DataIniExerc: "x", MêsFim: mês, Meses: meses, Valor: d(valor)}
+	}
+	saldo := func(ano int, mês, valor string) resultadoTrimestral {
+		return resultadoTrimestral{Ano: ano, Codigo: "1", Descr: "Ativo", MêsFim: mês, Meses: 12, Valor: d(valor)}
+	}
+	resultados := []resultadoTrimestral{
+		saldo(2022, "03", "0"),
+		saldo(2022, "12", "0"),
+		saldo(2023, "03", "10.1"),
+		saldo(2023, "12", "12.3"),
+		fluxo(2023, 3, "03", "1234567.8"),
+		fluxo(2023, 6, "06", "2469135.69"),
+		fluxo(2023, 9, "09", "3703703.7"),
+		fluxo(2023, 12, "12", "4938271.6"),
+		fluxo(2024, 6, "06", "0.3"), // sem o 1º trimestre
+	}
+	want := []rapina.InformeTrimestral{
+		{Codigo: "1", Descr: "Ativo", Valores: []rapina.ValoresTrimestrais{{Ano: 2023, T1: d("10.1"), T4: d("12.3")}}},
+		{Codigo: "3.01", Descr: "Receita", Valores: []rapina.ValoresTrimestrais{
+			{Ano: 2023, T1: d("1234567.8"), T2: d("1234567.89"), T3: d("1234568.01"), T4: d("1234567.9")},
+			{Ano: 2024, T2: d("0.3")},
+		}},
+	}
+	if got, err := converterResultadosTrimestrais(resultados, conversão{escala: 1}); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("converterResultadosTrimestrais() = %v, %v\nwant %v", got, err, want)
+	}
+}
+
+func Test_converterResultadosTrimestrais_unidade(t *testing.T) {
+	receita := func(ano, escala int, moeda, valor string) resultadoTrimestral {
+		v, err := rapina.NovoDecimal(valor)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resultadoTrimestral{Ano: ano, Codigo: "3.01", Descr: "Receita", DataIniExerc: "x", MêsFim: "03", Meses: 3,
+			Valor: v, Escala: escala, Moeda: moeda, DataFimExerc: fmt.Sprintf("%d-03-31", ano)}
+	}
+	resultados := func() []resultadoTrimestral {
+		return []resultadoTrimestral{
+			receita(2021, 1000, rapina.Real, "1500"), // MIL
+			receita(2022, 1, rapina.Real, "2500000"), // UNIDADE
+			receita(2023, 1000, rapina.Dólar, "100"), // US$ MIL
+			receita(2024, 1000000, "", "3"),          // sem moeda gravada
+		}
+	}
+	data, _ := rapina.NovaData("2023-03-31")
+	câmbio := rapina.NovaTabelaCâmbio([]rapina.Câmbio{{Moeda: rapina.Dólar, Data: data, Taxa: rapina.DecimalDeInt(5)}})
+
+	got, err := converterResultadosTrimestrais(resultados(), conversão{escala: 1000, moeda: rapina.Real, câmbio: câmbio})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []rapina.ValoresTrimestrais{
+		{Ano: 2021, T1: rapina.DecimalDeInt(1500)},
+		{Ano: 2022, T1: rapina.DecimalDeInt(2500)},
+		{Ano: 2023, T1: rapina.DecimalDeInt(500)},
+		{Ano: 2024, T1: rapina.DecimalDeInt(3000)},
+	}
+	if len(got) != 1 || !reflect.DeepEqual(got[0].Valores, want) {
+		t.Errorf("converterResultadosTrimestrais() = %v\nwant %v", got, want)
+	}
+
+	_, err = converterResultadosTrimestrais(resultados(), conversão{escala: 1000, moeda: rapina.Real})
+	if !errors.Is(err, rapina.ErrSemCâmbio) || !strings.Contains(err.Error(), "{moeda: US$, data: 2023-03-31") {
+		t.Errorf("converterResultadosTrimestrais() sem câmbio error = %v, want %v com a cotação a adicionar", err, rapina.ErrSemCâmbio)
+	}
+
+	// cada trimestre é convertido pela sua cotação, depois de descontado o
+	// valor acumulado até o trimestre anterior
+	semestre := receita(2023, 1000, rapina.Dólar, "300")
+	semestre.MêsFim, semestre.Meses, semestre.DataFimExerc = "06", 6, "2023-06-30"
+	junho, _ := rapina.NovaData("2023-06-30")
+	câmbio = rapina.NovaTabelaCâmbio([]rapina.Câmbio{
+		{Moeda: rapina.Dólar, Data: data, Taxa: rapina.DecimalDeInt(5)},
+		{Moeda: rapina.Dólar, Data: junho, Taxa: rapina.DecimalDeInt(6)},
+	})
+	got, err = converterResultadosTrimestrais([]resultadoTrimestral{resultados()[2], semestre}, conversão{escala: 1000, moeda: rapina.Real, câmbio: câmbio})
+	want = []rapina.ValoresTrimestrais{{Ano: 2023, T1: rapina.DecimalDeInt(500), T2: rapina.DecimalDeInt(1200)}}
+	if err != nil || len(got) != 1 || !reflect.DeepEqual(got[0].Valores, want) {
+		t.Errorf("converterResultadosTrimestrais() = %v, %v\nwant %v", got, err, want)
+	}
+}
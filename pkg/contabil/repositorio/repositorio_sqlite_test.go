@@ -42,7 +42,7 @@ func Test_inserirDFP(t *testing.T) {
 				DataFimExerc: fmt.Sprintf("D%03d", n),
 				OrdemExerc:   fmt.Sprintf("O%03d", n),
 				Total: rapina.Dinheiro{
-					Valor:  1234567.89,
+					Valor:  rapina.DecimalDeFloat(1234567.89),
 					Escala: 1000,
 					Moeda:  "R$",
 				},
@@ -64,6 +64,84 @@ func Test_inserirDFP(t *testing.T) {
 			t.Logf("%v", err)
 		}
 	})
+
+	t.Run("escala", func(t *testing.T) {
+		got, err := s.Escala(context.Background(), "123")
+		if err != nil || got != 1000 {
+			t.Errorf("Escala() = %d, %v, want 1000", got, err)
+		}
+		got, err = s.Escala(context.Background(), "inexistente")
+		if err != nil || got != 1 {
+			t.Errorf("Escala() = %d, %v, want 1", got, err)
+		}
+	})
+}
+
+func TestSqlite_SalvarLote(t *testing.T) {
+	db := sqlx.MustConnect("sqlite3", ":memory:")
+	db.SetMaxOpenConns(1)
+	ctx := context.Background()
+
+	dfp := func(cnpj string, códigos ...string) *dominio.DemonstraçãoFinanceira {
+		d := &dominio.DemonstraçãoFinanceira{
+			Empresa: rapina.Empresa{CNPJ: cnpj, Nome: "N" + cnpj},
+			Ano:     2022,
+		}
+		for _, código := range códigos {
+			d.Contas = append(d.Contas, dominio.Conta{
+				Código:       código,
+				Descr:        "D" + código,
+				Grupo:        "DRE",
+				DataFimExerc: "2022-12-31",
+				Total:        rapina.Dinheiro{Valor: rapina.DecimalDeInt(1), Escala: 1, Moeda: "R$"},
+			})
+		}
+		return d
+	}
+	contas := func(s *Sqlite, cnpj string) int {
+		d, err := s.Ler(ctx, cnpj, 2022)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(d.Contas)
+	}
+
+	s, err := NovoSqlite(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SalvarLote(ctx, []*dominio.DemonstraçãoFinanceira{dfp("1", "3.01", "3.11"), dfp("2", "3.01")}); err != nil {
+		t.Fatal(err)
+	}
+	if n1, n2 := contas(s, "1"), contas(s, "2"); n1 != 2 || n2 != 1 {
+		t.Fatalf("contas = %d e %d, want 2 e 1", n1, n2)
+	}
+
+	_, err = db.Exec(`CREATE TRIGGER falha BEFORE INSERT ON empresas WHEN NEW.cnpj = 'erro'
+		BEGIN SELECT RAISE(ABORT, 'falha'); END`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Nova importação: o lote com erro não deve alterar os dados existentes
+	s, err = NovoSqlite(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SalvarLote(ctx, []*dominio.DemonstraçãoFinanceira{dfp("1", "3.01"), dfp("erro", "3.01")}); err == nil {
+		t.Fatal("SalvarLote() deveria retornar erro")
+	}
+	if n := contas(s, "1"); n != 2 {
+		t.Errorf("contas após rollback = %d, want 2", n)
+	}
+
+	// Os dados da empresa devem ser substituídos no próximo lote
+	if err := s.SalvarLote(ctx, []*dominio.DemonstraçãoFinanceira{dfp("1", "3.01")}); err != nil {
+		t.Fatal(err)
+	}
+	if n := contas(s, "1"); n != 1 {
+		t.Errorf("contas = %d, want 1", n)
+	}
 }
 
 func TestSqlite_Empresas(t *testing.T) {
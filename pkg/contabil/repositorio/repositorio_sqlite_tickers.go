@@ -0,0 +1,132 @@
+// SPDX-FileCopyrightText: 2023 Adriano Prado <dev@dude333.com>
+//
+// SPDX-License-Identifier: MIT
+
+package repositorio
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	rapina "github.com/dude333/rapinav2"
+)
+
+type sqliteTicker struct {
+	Ticker    string `db:"ticker"`
+	CNPJ      string `db:"cnpj"`
+	Classe    string `db:"classe"`
+	ISIN      string `db:"isin"`
+	CódigoCVM string `db:"codigo_cvm"`
+}
+
+func (t sqliteTicker) valorMobiliário() rapina.ValorMobiliário {
+	return rapina.ValorMobiliário{
+		Ticker:    t.Ticker,
+		Classe:    t.Classe,
+		ISIN:      t.ISIN,
+		CNPJ:      t.CNPJ,
+		CódigoCVM: t.CódigoCVM,
+	}
+}
+
+// SalvarValoresMobiliários salva os valores mobiliários numa única
+// transação. Os campos vazios (ex.: ISIN, que não consta no FCA) não
+// substituem os valores já existentes do mesmo ticker.
+func (s *Sqlite) SalvarValoresMobiliários(ctx context.Context, vms []rapina.ValorMobiliário) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.PrepareNamedContext(ctx, `INSERT INTO tickers
+		(ticker, cnpj, classe, isin, codigo_cvm)
+		VALUES
+		(:ticker, :cnpj, :classe, :isin, :codigo_cvm)
+		ON CONFLICT (ticker) DO UPDATE SET
+		cnpj       = CASE WHEN excluded.cnpj       = '' THEN cnpj       ELSE excluded.cnpj       END,
+		classe     = CASE WHEN excluded.classe     = '' THEN classe     ELSE excluded.classe     END,
+		isin       = CASE WHEN excluded.isin       = '' THEN isin       ELSE excluded.isin       END,
+		codigo_cvm = CASE WHEN excluded.codigo_cvm = '' THEN codigo_cvm ELSE excluded.codigo_cvm END`)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	for _, vm := range vms {
+		t := sqliteTicker{
+			Ticker:    strings.ToUpper(vm.Ticker),
+			CNPJ:      vm.CNPJ,
+			Classe:    vm.Classe,
+			ISIN:      vm.ISIN,
+			CódigoCVM: vm.CódigoCVM,
+		}
+		if _, err := stmt.ExecContext(ctx, t); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ValorMobiliário retorna os dados do ticker informado (ex.: PETR4).
+func (s *Sqlite) ValorMobiliário(ctx context.Context, ticker string) (rapina.ValorMobiliário, error) {
+	var t sqliteTicker
+	err := s.db.GetContext(ctx, &t, `SELECT * FROM tickers WHERE ticker=?`, strings.ToUpper(ticker))
+	if err == sql.ErrNoRows {
+		return rapina.ValorMobiliário{}, ErrTickerNãoEncontrado
+	}
+	if err != nil {
+		return rapina.ValorMobiliário{}, err
+	}
+	return t.valorMobiliário(), nil
+}
+
+// ValoresMobiliários retorna os valores mobiliários da empresa com o CNPJ
+// informado, ou de todas as empresas se o CNPJ for vazio.
+func (s *Sqlite) ValoresMobiliários(ctx context.Context, cnpj string) ([]rapina.ValorMobiliário, error) {
+	var tickers []sqliteTicker
+	var err error
+	if cnpj == "" {
+		err = s.db.SelectContext(ctx, &tickers, `SELECT * FROM tickers ORDER BY ticker`)
+	} else {
+		err = s.db.SelectContext(ctx, &tickers, `SELECT * FROM tickers WHERE cnpj=? ORDER BY ticker`, cnpj)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	vms := make([]rapina.ValorMobiliário, len(tickers))
+	for i := range tickers {
+		vms[i] = tickers[i].valorMobiliário()
+	}
+	return vms, nil
+}
+
+// preencherTickers preenche os tickers de cada empresa.
+func (s *Sqlite) preencherTickers(ctx context.Context, empresas []rapina.Empresa) error {
+	vms, err := s.ValoresMobiliários(ctx, "")
+	if err != nil {
+		return err
+	}
+	tickers := make(map[string][]string)
+	for _, vm := range vms {
+		tickers[vm.CNPJ] = append(tickers[vm.CNPJ], vm.Ticker)
+	}
+	for i := range empresas {
+		empresas[i].Tickers = tickers[empresas[i].CNPJ]
+	}
+	return nil
+}
+
+// temTicker verifica se a empresa possui o ticker informado (ex.: "petr4").
+func temTicker(empresa rapina.Empresa, ticker string) bool {
+	for _, t := range empresa.Tickers {
+		if strings.EqualFold(t, ticker) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+// SPDX-FileCopyrightText: 2023 Adriano Prado <dev@dude333.com>
+//
+// SPDX-License-Identifier: MIT
+
+package repositorio
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"golang.org/x/text/encoding/charmap"
+
+	rapina "github.com/dude333/rapinav2"
+	"github.com/dude333/rapinav2/pkg/contabil/dominio"
+)
+
+func Test_lerFCA(t *testing.T) {
+	dir := t.TempDir()
+	arquivos := map[string]string{
+		"fca_cia_aberta_valor_mobiliario_2023.csv": "CNPJ_Companhia;Data_Referencia;Versao;ID_Documento;Nome_Empresarial;Valor_Mobiliario;Sigla_Classe_Acao_Preferencial;Classe_Acao_Preferencial;Codigo_Negociacao;Mercado\n" +
+			"33.000.167/0001-01;2023-01-01;1;1;PETRÓLEO BRASILEIRO S.A.;Ações Ordinárias;;;PETR3;Bolsa\n" +
+			"33.000.167/0001-01;2023-01-01;2;2;PETRÓLEO BRASILEIRO S.A.;Ações Ordinárias;;;PETR3;Bolsa\n" +
+			"33.000.167/0001-01;2023-01-01;2;2;PETRÓLEO BRASILEIRO S.A.;Ações Preferenciais;;;PETR4;Bolsa\n" +
+			"33.000.167/0001-01;2023-01-01;1;1;PETRÓLEO BRASILEIRO S.A.;Ações Preferenciais;;;PETR9;Bolsa\n" +
+			"07.859.971/0001-30;2023-01-01;1;3;TAESA;Units;;;TAEE11;Bolsa\n" +
+			"07.859.971/0001-30;2023-01-01;1;3;TAESA;Ações Preferenciais;PNA;Preferencial Classe A;taee5;Bolsa\n" +
+			"07.859.971/0001-30;2023-01-01;1;3;TAESA;Debêntures;;;;Balcão\n",
+		"fca_cia_aberta_geral_2023.csv": "CNPJ_Companhia;Data_Referencia;Versao;ID_Documento;Codigo_CVM\n" +
+			"33.000.167/0001-01;2023-01-01;2;2;009512\n" +
+			"07.859.971/0001-30;2023-01-01;1;3;020257\n",
+		"fca_cia_aberta_auditor_2023.csv": "CNPJ_Companhia;Versao\n",
+	}
+	var csvs []Arquivo
+	for nome, conteúdo := range arquivos {
+		latin1, err := charmap.ISO8859_1.NewEncoder().String(conteúdo)
+		if err != nil {
+			t.Fatal(err)
+		}
+		caminho := filepath.Join(dir, nome)
+		if err := os.WriteFile(caminho, []byte(latin1), 0644); err != nil {
+			t.Fatal(err)
+		}
+		csvs = append(csvs, arquivoDisco(caminho))
+	}
+
+	got, err := lerFCA(csvs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []rapina.ValorMobiliário{
+		{Ticker: "PETR3", Classe: "ON", CNPJ: "33.000.167/0001-01", CódigoCVM: "9512"},
+		{Ticker: "PETR4", Classe: "PN", CNPJ: "33.000.167/0001-01", CódigoCVM: "9512"},
+		{Ticker: "TAEE11", Classe: "UNT", CNPJ: "07.859.971/0001-30", CódigoCVM: "20257"},
+		{Ticker: "TAEE5", Classe: "PNA", CNPJ: "07.859.971/0001-30", CódigoCVM: "20257"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("lerFCA() = %+v\nwant %+v", got, want)
+	}
+
+	if _, err := lerFCA(csvs[:0]); err != ErrSemDados {
+		t.Errorf("lerFCA() error = %v, want %v", err, ErrSemDados)
+	}
+}
+
+func TestSqlite_ValoresMobiliários(t *testing.T) {
+	db := sqlx.MustConnect("sqlite3", ":memory:")
+	db.SetMaxOpenConns(1)
+
+	s, err := NovoSqlite(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	const cnpj = "33.000.167/0001-01"
+	err = s.Salvar(ctx, &dominio.DemonstraçãoFinanceira{
+		Empresa: rapina.Empresa{CNPJ: cnpj, Nome: "PETROBRAS"},
+		Ano:     2022,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.SalvarValoresMobiliários(ctx, []rapina.ValorMobiliário{
+		{Ticker: "PETR3", Classe: "ON", CNPJ: cnpj, ISIN: "BRPETRACNOR9"},
+		{Ticker: "petr4", Classe: "PN", CNPJ: cnpj},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Campos vazios não substituem os já existentes
+	err = s.SalvarValoresMobiliários(ctx, []rapina.ValorMobiliário{
+		{Ticker: "PETR3", CNPJ: cnpj, CódigoCVM: "9512"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vm, err := s.ValorMobiliário(ctx, "PETR3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := rapina.ValorMobiliário{Ticker: "PETR3", Classe: "ON", ISIN: "BRPETRACNOR9", CNPJ: cnpj, CódigoCVM: "9512"}
+	if vm != want {
+		t.Errorf("ValorMobiliário() = %+v, want %+v", vm, want)
+	}
+	if _, err := s.ValorMobiliário(ctx, "XXXX3"); err != ErrTickerNãoEncontrado {
+		t.Errorf("ValorMobiliário() error = %v, want %v", err, ErrTickerNãoEncontrado)
+	}
+
+	empresas, err := s.Empresas(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(empresas) != 1 || !reflect.DeepEqual(empresas[0].Tickers, []string{"PETR3", "PETR4"}) {
+		t.Errorf("Empresas() = %+v", empresas)
+	}
+
+	empresas, err = s.BuscaEmpresas(ctx, "petr4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(empresas) != 1 || empresas[0].CNPJ != cnpj {
+		t.Errorf("BuscaEmpresas(petr4) = %+v", empresas)
+	}
+}
@@ -5,10 +5,14 @@
 package repositorio
 
 import (
-	"log"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
 
-	"github.com/dude333/rapinav2/pkg/progress"
 	"gopkg.in/yaml.v2"
+
+	rapina "github.com/dude333/rapinav2"
 )
 
 // Este arquivo provê dados com base no banco de dados usando as definições do
@@ -16,10 +20,16 @@ import (
 // conta, pois em alguns casos há a variação dessas definições entre empresas
 // e períodos.
 
-// ContaYaml armazena os dados importados do arquivo yaml (deve ser convertido
-// em contabil.ConfigConta).
+// Modelo armazena os dados importados do arquivo yaml.
 // Formato: [["1", "Descrição 1"], ["1.1", "Descrição 1.1"]]
+//
+// O modelo "global" é usado para todas as empresas. Os demais modelos são
+// usados apenas para as empresas cujo CNPJ seja o nome do modelo ou esteja
+// listado em "cnpjs" (ex.: um modelo para um setor), substituindo as contas
+// definidas no modelo global.
 type Modelo struct {
+	CNPJs []string `yaml:"cnpjs"`
+
 	AtivoTotal        [][]string `yaml:"AtivoTotal"`
 	AtivoCirc         [][]string `yaml:"AtivoCirc"`
 	AtivoNCirc        [][]string `yaml:"AtivoNCirc"`
@@ -55,15 +65,229 @@ type Contas struct {
 	Modelos map[string]Modelo
 }
 
-func Unmarshal(data string) {
+const modeloGlobal = "global"
+
+// Unmarshal carrega os modelos de contas do conteúdo do arquivo yaml,
+// retornando erro caso algum modelo contenha chaves desconhecidas ou contas
+// fora do formato ["código", "descrição"].
+func Unmarshal(data string) (*Contas, error) {
+	var brutos struct {
+		Modelos map[string]map[string]interface{}
+	}
+	if err := yaml.Unmarshal([]byte(data), &brutos); err != nil {
+		return nil, err
+	}
+
+	válidas := chavesModelo()
+	var desconhecidas []string
+	for nome, modelo := range brutos.Modelos {
+		for chave := range modelo {
+			if !válidas[chave] {
+				desconhecidas = append(desconhecidas, nome+"."+chave)
+			}
+		}
+	}
+	if len(desconhecidas) > 0 {
+		sort.Strings(desconhecidas)
+		return nil, fmt.Errorf("chaves desconhecidas nos modelos: %s",
+			strings.Join(desconhecidas, ", "))
+	}
+
 	var c Contas
-	err := yaml.Unmarshal([]byte(data), &c)
-	if err != nil {
-		log.Fatalf("error: %v", err)
+	if err := yaml.Unmarshal([]byte(data), &c); err != nil {
+		return nil, err
+	}
+
+	for nome, modelo := range c.Modelos {
+		v := reflect.ValueOf(modelo)
+		for i := 0; i < v.NumField(); i++ {
+			contas, ok := v.Field(i).Interface().([][]string)
+			if !ok {
+				continue
+			}
+			for _, conta := range contas {
+				if len(conta) != 2 {
+					return nil, fmt.Errorf("modelo %s, %s: conta %v deve estar no formato [\"código\", \"descrição\"]",
+						nome, v.Type().Field(i).Tag.Get("yaml"), conta)
+				}
+			}
+		}
+	}
+
+	return &c, nil
+}
+
+// ModeloEmpresa retorna o modelo global combinado com os modelos específicos
+// da empresa com o CNPJ informado. As contas dos modelos específicos
+// substituem as do modelo global.
+func (c *Contas) ModeloEmpresa(cnpj string) Modelo {
+	var m Modelo
+	if c == nil {
+		return m
+	}
+	combinar(&m, c.Modelos[modeloGlobal])
+
+	nomes := make([]string, 0, len(c.Modelos))
+	for nome := range c.Modelos {
+		nomes = append(nomes, nome)
+	}
+	sort.Strings(nomes)
+
+	for _, nome := range nomes {
+		modelo := c.Modelos[nome]
+		if nome == modeloGlobal {
+			continue
+		}
+		if nome == cnpj || contém(modelo.CNPJs, cnpj) {
+			combinar(&m, modelo)
+		}
+	}
+
+	return m
+}
+
+// UnmarshalTickers carrega do conteúdo do arquivo yaml (seção "tickers") os
+// valores mobiliários que complementam ou substituem os importados do FCA:
+//
+//	tickers:
+//	  - {ticker: ABCD3, cnpj: 00.000.000/0001-00, classe: ON}
+func UnmarshalTickers(data string) ([]rapina.ValorMobiliário, error) {
+	var c struct {
+		Tickers []struct {
+			Ticker    string `yaml:"ticker"`
+			CNPJ      string `yaml:"cnpj"`
+			Classe    string `yaml:"classe"`
+			ISIN      string `yaml:"isin"`
+			CódigoCVM string `yaml:"codigo_cvm"`
+		}
 	}
+	if err := yaml.Unmarshal([]byte(data), &c); err != nil {
+		return nil, err
+	}
+
+	vms := make([]rapina.ValorMobiliário, 0, len(c.Tickers))
+	for i, t := range c.Tickers {
+		if t.Ticker == "" || t.CNPJ == "" {
+			return nil, fmt.Errorf("tickers, item %d: ticker e cnpj são obrigatórios", i+1)
+		}
+		vms = append(vms, rapina.ValorMobiliário{
+			Ticker:    strings.ToUpper(t.Ticker),
+			Classe:    t.Classe,
+			ISIN:      t.ISIN,
+			CNPJ:      t.CNPJ,
+			CódigoCVM: t.CódigoCVM,
+		})
+	}
+	return vms, nil
+}
+
+// UnmarshalAções carrega do conteúdo do arquivo yaml (seção "acoes") a
+// quantidade de ações de cada empresa, usada no cálculo do valor de mercado:
+//
+//	acoes:
+//	  - {cnpj: 00.000.000/0001-00, data: 2023-12-31, on: 1000, pn: 2000, tesouraria_pn: 10}
+func UnmarshalAções(data string) ([]rapina.QuantidadeAções, error) {
+	var c struct {
+		Ações []struct {
+			CNPJ         string `yaml:"cnpj"`
+			Data         string `yaml:"data"`
+			ON           int64  `yaml:"on"`
+			PN           int64  `yaml:"pn"`
+			TesourariaON int64  `yaml:"tesouraria_on"`
+			TesourariaPN int64  `yaml:"tesouraria_pn"`
+		} `yaml:"acoes"`
+	}
+	if err := yaml.Unmarshal([]byte(data), &c); err != nil {
+		return nil, err
+	}
+
+	ações := make([]rapina.QuantidadeAções, 0, len(c.Ações))
+	for i, a := range c.Ações {
+		if a.CNPJ == "" {
+			return nil, fmt.Errorf("acoes, item %d: cnpj é obrigatório", i+1)
+		}
+		d, err := rapina.NovaData(a.Data)
+		if err != nil {
+			return nil, fmt.Errorf("acoes, item %d: data inválida: %s", i+1, a.Data)
+		}
+		ações = append(ações, rapina.QuantidadeAções{
+			CNPJ:          a.CNPJ,
+			Data:          d,
+			Ordinárias:    a.ON,
+			Preferenciais: a.PN,
+			TesourariaON:  a.TesourariaON,
+			TesourariaPN:  a.TesourariaPN,
+		})
+	}
+	return ações, nil
+}
+
+// UnmarshalCâmbio carrega do conteúdo do arquivo yaml (seção "cambio") as
+// cotações em reais das moedas usadas nos demonstrativos de algumas
+// empresas, usadas na conversão dos valores para uma mesma moeda:
+//
+//	cambio:
+//	  - {moeda: US$, data: 2023-12-29, taxa: 4.8413}
+func UnmarshalCâmbio(data string) ([]rapina.Câmbio, error) {
+	var c struct {
+		Câmbio []struct {
+			Moeda string `yaml:"moeda"`
+			Data  string `yaml:"data"`
+			Taxa  string `yaml:"taxa"`
+		} `yaml:"cambio"`
+	}
+	if err := yaml.Unmarshal([]byte(data), &c); err != nil {
+		return nil, err
+	}
+
+	cotações := make([]rapina.Câmbio, 0, len(c.Câmbio))
+	for i, cot := range c.Câmbio {
+		if cot.Moeda == "" {
+			return nil, fmt.Errorf("cambio, item %d: moeda é obrigatória", i+1)
+		}
+		d, err := rapina.NovaData(cot.Data)
+		if err != nil {
+			return nil, fmt.Errorf("cambio, item %d: data inválida: %s", i+1, cot.Data)
+		}
+		taxa, err := rapina.NovoDecimal(cot.Taxa)
+		if err != nil || taxa.Sign() <= 0 {
+			return nil, fmt.Errorf("cambio, item %d: taxa inválida: %s", i+1, cot.Taxa)
+		}
+		cotações = append(cotações, rapina.Câmbio{
+			Moeda: rapina.NovaMoeda(cot.Moeda),
+			Data:  d,
+			Taxa:  taxa,
+		})
+	}
+	return cotações, nil
+}
+
+// combinar copia as contas não vazias de origem para destino.
+func combinar(destino *Modelo, origem Modelo) {
+	d := reflect.ValueOf(destino).Elem()
+	o := reflect.ValueOf(origem)
+	for i := 0; i < o.NumField(); i++ {
+		if _, ok := o.Field(i).Interface().([][]string); ok && o.Field(i).Len() > 0 {
+			d.Field(i).Set(o.Field(i))
+		}
+	}
+}
+
+// chavesModelo retorna as chaves aceitas em um modelo (tags yaml de Modelo).
+func chavesModelo() map[string]bool {
+	chaves := make(map[string]bool)
+	t := reflect.TypeOf(Modelo{})
+	for i := 0; i < t.NumField(); i++ {
+		chaves[t.Field(i).Tag.Get("yaml")] = true
+	}
+	return chaves
+}
 
-	for k := range c.Modelos {
-		progress.Status("%s", k)
-		progress.Status("%+v", c.Modelos[k])
+func contém(lista []string, s string) bool {
+	for i := range lista {
+		if lista[i] == s {
+			return true
+		}
 	}
+	return false
 }
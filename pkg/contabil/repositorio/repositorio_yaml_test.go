@@ -4,15 +4,22 @@
 
 package repositorio
 
-import "testing"
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	rapina "github.com/dude333/rapinav2"
+)
 
 func TestUnmarshal(t *testing.T) {
 	type args struct {
 		data string
 	}
 	tests := []struct {
-		name string
-		args args
+		name    string
+		args    args
+		wantErr string
 	}{
 		{
 			name: "should work",
@@ -20,10 +27,65 @@ func TestUnmarshal(t *testing.T) {
 				data: fileContent,
 			},
 		},
+		{
+			name: "should list unknown keys",
+			args: args{
+				data: "modelos:\n  global:\n    LucroLiq: [[\"3.11\", \"\"]]\n  bancos:\n    Receita: [[\"3.01\", \"\"]]\n",
+			},
+			wantErr: "bancos.Receita, global.LucroLiq",
+		},
+		{
+			name: "should reject invalid account",
+			args: args{
+				data: "modelos:\n  global:\n    LucLiq: [[\"3.11\"]]\n",
+			},
+			wantErr: "LucLiq",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			Unmarshal(tt.args.data)
+			_, err := Unmarshal(tt.args.data)
+			if (err != nil) != (tt.wantErr != "") {
+				t.Fatalf("Unmarshal() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Unmarshal() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestContas_ModeloEmpresa(t *testing.T) {
+	c, err := Unmarshal(fileContent)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		cnpj    string
+		estoque [][]string
+	}{
+		{
+			name:    "should use global model",
+			cnpj:    "00.000.000/0001-00",
+			estoque: [][]string{{"1.01.04", "Estoques"}},
+		},
+		{
+			name:    "should override by sector CNPJ",
+			cnpj:    "60.840.055/0001-31",
+			estoque: [][]string{{"1.01.05", "Estoques"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := c.ModeloEmpresa(tt.cnpj)
+			if !reflect.DeepEqual(m.Estoque, tt.estoque) {
+				t.Errorf("Estoque = %v, want %v", m.Estoque, tt.estoque)
+			}
+			if len(m.AtivoTotal) != 1 {
+				t.Errorf("AtivoTotal = %v, want global model", m.AtivoTotal)
+			}
 		})
 	}
 }
@@ -106,8 +168,84 @@ modelos:
     - ["7.*", "Dividendos"]
 
   empresaA:
+    cnpjs: ["60.840.055/0001-31"]
     # BPA
     Estoque: 
     - ["1.01.05", "Estoques"]
 
 `
+
+func TestUnmarshalTickers(t *testing.T) {
+	data := `
+modelos:
+  global:
+    LucLiq:
+    - ["3.11", "Lucro Líquido"]
+tickers:
+  - {ticker: abcd3, cnpj: 00.000.000/0001-00, classe: ON}
+  - {ticker: ABCD11, cnpj: 00.000.000/0001-00, isin: BRABCDCDAM10}
+`
+	got, err := UnmarshalTickers(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []rapina.ValorMobiliário{
+		{Ticker: "ABCD3", Classe: "ON", CNPJ: "00.000.000/0001-00"},
+		{Ticker: "ABCD11", ISIN: "BRABCDCDAM10", CNPJ: "00.000.000/0001-00"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("UnmarshalTickers() = %+v, want %+v", got, want)
+	}
+
+	if _, err := UnmarshalTickers("tickers:\n  - {ticker: ABCD3}\n"); err == nil {
+		t.Error("UnmarshalTickers() sem cnpj não retornou erro")
+	}
+}
+
+func TestUnmarshalAções(t *testing.T) {
+	data := `
+acoes:
+  - {cnpj: 00.000.000/0001-00, data: 2023-12-31, on: 1000, pn: 2000, tesouraria_pn: 10}
+`
+	got, err := UnmarshalAções(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, _ := rapina.NovaData("2023-12-31")
+	want := []rapina.QuantidadeAções{
+		{CNPJ: "00.000.000/0001-00", Data: d, Ordinárias: 1000, Preferenciais: 2000, TesourariaPN: 10},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("UnmarshalAções() = %+v, want %+v", got, want)
+	}
+
+	if _, err := UnmarshalAções("acoes:\n  - {cnpj: 00.000.000/0001-00, data: 31/12/2023}\n"); err == nil {
+		t.Error("UnmarshalAções() com data inválida não retornou erro")
+	}
+}
+
+func TestUnmarshalCâmbio(t *testing.T) {
+	data := `
+cambio:
+  - {moeda: US$, data: 2023-12-29, taxa: 4.8413}
+  - {moeda: EURO, data: 2023-12-29, taxa: "5.3516"}
+`
+	got, err := UnmarshalCâmbio(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, _ := rapina.NovaData("2023-12-29")
+	usd, _ := rapina.NovoDecimal("4.8413")
+	eur, _ := rapina.NovoDecimal("5.3516")
+	want := []rapina.Câmbio{
+		{Moeda: rapina.Dólar, Data: d, Taxa: usd},
+		{Moeda: rapina.Euro, Data: d, Taxa: eur},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("UnmarshalCâmbio() = %+v, want %+v", got, want)
+	}
+
+	if _, err := UnmarshalCâmbio("cambio:\n  - {moeda: US$, data: 2023-12-29, taxa: 0}\n"); err == nil {
+		t.Error("UnmarshalCâmbio() com taxa zero não retornou erro")
+	}
+}
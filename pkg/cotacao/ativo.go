@@ -7,6 +7,8 @@ package cotação
 import rapina "github.com/dude333/rapinav2"
 
 // Ativo --------------------------------------------------
+// Contém os dados de um registro de cotação do arquivo de séries históricas
+// da B3 (COTAHIST). Os preços são cotados por lote de FatorCotação ativos.
 type Ativo struct {
 	Código       string
 	Data         rapina.Data
@@ -15,6 +17,22 @@ type Ativo struct {
 	Mínima       rapina.Dinheiro
 	Encerramento rapina.Dinheiro
 	Volume       float64
+
+	ISIN          string          // Código ISIN do papel
+	NomeResumido  string          // Nome resumido da empresa emissora
+	Especificação string          // ON, PN, UNT, CI (fundos)...
+	BDI           string          // 02 = lote padrão, 12 = FII, 96 = fracionário...
+	Mercado       string          // 010 = vista, 020 = fracionário, 070/080 = opções...
+	Médio         rapina.Dinheiro // Preço médio
+	MelhorCompra  rapina.Dinheiro // Melhor oferta de compra
+	MelhorVenda   rapina.Dinheiro // Melhor oferta de venda
+	Negócios      int             // Número de negócios
+	Quantidade    int64           // Quantidade total de títulos negociados
+	FatorCotação  int             // 1 = preço unitário, 1000 = preço por lote de mil
+
+	// Apenas para opções e termos
+	PreçoExercício rapina.Dinheiro
+	Vencimento     rapina.Data // data zero se não houver vencimento
 }
 
 // Ativos -------------------------------------------------
@@ -0,0 +1,201 @@
+// SPDX-FileCopyrightText: 2023 Adriano Prado <dev@dude333.com>
+//
+// SPDX-License-Identifier: MIT
+
+package cotação
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	rapina "github.com/dude333/rapinav2"
+)
+
+// TipoEvento ---------------------------------------------
+type TipoEvento string
+
+const (
+	Desdobramento TipoEvento = "desdobramento" // Fator = ações novas por ação antiga (ex.: 2)
+	Grupamento    TipoEvento = "grupamento"    // Fator = ações novas por ação antiga (ex.: 0.1)
+	Bonificação   TipoEvento = "bonificacao"   // Fator = 1 + ações bonificadas por ação (ex.: 1.1)
+	Dividendo     TipoEvento = "dividendo"     // Valor = R$ por ação
+	JCP           TipoEvento = "jcp"           // Valor = R$ por ação (juros sobre capital próprio)
+)
+
+// NovoTipoEvento converte o texto (ex.: "Desdobramento", "bonificação") em
+// TipoEvento.
+func NovoTipoEvento(s string) (TipoEvento, error) {
+	t := TipoEvento(rapina.NormalizeString(s))
+	switch t {
+	case Desdobramento, Grupamento, Bonificação, Dividendo, JCP:
+		return t, nil
+	}
+	return "", fmt.Errorf("tipo de evento inválido: %s", s)
+}
+
+// proventos são os eventos que só entram no ajuste por retorno total.
+func (t TipoEvento) provento() bool {
+	return t == Dividendo || t == JCP
+}
+
+// Evento -------------------------------------------------
+// Evento corporativo (desdobramento, grupamento, bonificação ou provento)
+// de um ativo. Os preços anteriores à DataEx são ajustados com base nele.
+type Evento struct {
+	Código string
+	DataEx rapina.Data // primeiro dia de negociação "ex" evento
+	Tipo   TipoEvento
+	Fator  float64 // desdobramento, grupamento e bonificação
+	Valor  float64 // dividendo e JCP, por ação
+}
+
+// Validar verifica se os campos obrigatórios do evento foram preenchidos.
+func (e Evento) Validar() error {
+	switch {
+	case e.Código == "":
+		return fmt.Errorf("evento sem código: %+v", e)
+	case time.Time(e.DataEx).IsZero():
+		return fmt.Errorf("evento de %s sem data ex", e.Código)
+	case e.Tipo.provento() && e.Valor <= 0:
+		return fmt.Errorf("%s de %s em %s sem valor", e.Tipo, e.Código, e.DataEx)
+	case !e.Tipo.provento() && e.Fator <= 0:
+		return fmt.Errorf("%s de %s em %s sem fator", e.Tipo, e.Código, e.DataEx)
+	}
+	return nil
+}
+
+// Ajuste -------------------------------------------------
+type Ajuste int
+
+const (
+	SemAjuste           Ajuste = iota // preços conforme negociados
+	AjusteDesdobramento               // desdobramentos, grupamentos e bonificações
+	AjusteRetornoTotal                // AjusteDesdobramento + dividendos e JCP
+)
+
+// Ajustar retorna as cotações em ordem crescente de data, com os preços
+// anteriores a cada evento ajustados para a base atual (a cotação mais
+// recente não é alterada). Exceto em SemAjuste, os preços são convertidos
+// para preço unitário (preço / FatorCotação).
+//
+// No ajuste por retorno total, o fator de cada provento é calculado sobre o
+// fechamento do último pregão antes da data ex: (fechamento - valor) /
+// fechamento.
+func Ajustar(ativos []Ativo, eventos []Evento, ajuste Ajuste) []Ativo {
+	série := make([]Ativo, len(ativos))
+	copy(série, ativos)
+	sort.SliceStable(série, func(i, j int) bool {
+		return antes(série[i].Data, série[j].Data)
+	})
+	if ajuste == SemAjuste {
+		return série
+	}
+
+	for i := range série {
+		série[i].unitário()
+	}
+
+	evs := make([]Evento, 0, len(eventos))
+	for _, e := range eventos {
+		if e.Tipo.provento() && ajuste != AjusteRetornoTotal {
+			continue
+		}
+		evs = append(evs, e)
+	}
+	sort.SliceStable(evs, func(i, j int) bool {
+		return antes(evs[j].DataEx, evs[i].DataEx)
+	})
+
+	fatorPreço, fatorQtd := 1.0, 1.0
+	j := 0
+	for i := len(série) - 1; i >= 0; i-- {
+		// Eventos com data ex posterior a este pregão
+		for ; j < len(evs) && antes(série[i].Data, evs[j].DataEx); j++ {
+			e := evs[j]
+			switch {
+			case e.Tipo.provento():
+				fechamento := série[i].Encerramento.Valor.Float64()
+				if fechamento > e.Valor {
+					fatorPreço *= (fechamento - e.Valor) / fechamento
+				}
+			case e.Fator > 0:
+				fatorPreço /= e.Fator
+				fatorQtd *= e.Fator
+			}
+		}
+		série[i].multiplicar(fatorPreço, fatorQtd)
+	}
+
+	return série
+}
+
+// DetectarEventos procura desdobramentos e grupamentos na série de cotações
+// de um ativo comparando o fechamento de cada pregão com a abertura do
+// pregão seguinte, em preço unitário (preço / FatorCotação). Saltos
+// próximos de um múltiplo inteiro (2x, 3x, 1/10...) são considerados
+// eventos. Por ser uma heurística, os eventos detectados devem ser revistos.
+func DetectarEventos(ativos []Ativo) []Evento {
+	série := Ajustar(ativos, nil, AjusteDesdobramento)
+
+	const (
+		saltoMínimo = 1.9  // razão mínima entre os preços dos dois pregões
+		tolerância  = 0.05 // distância máxima para o múltiplo inteiro
+	)
+
+	var eventos []Evento
+	for i := 1; i < len(série); i++ {
+		anterior := série[i-1].Encerramento.Valor.Float64()
+		atual := série[i].Abertura.Valor.Float64()
+		if atual <= 0 {
+			atual = série[i].Encerramento.Valor.Float64()
+		}
+		if anterior <= 0 || atual <= 0 {
+			continue
+		}
+
+		e := Evento{Código: série[i].Código, DataEx: série[i].Data}
+		r := anterior / atual
+		if r < 1 {
+			r = atual / anterior
+			e.Tipo = Grupamento
+		} else {
+			e.Tipo = Desdobramento
+		}
+		n := math.Round(r)
+		if r < saltoMínimo || math.Abs(r-n)/n > tolerância {
+			continue
+		}
+		e.Fator = n
+		if e.Tipo == Grupamento {
+			e.Fator = 1 / n
+		}
+		eventos = append(eventos, e)
+	}
+
+	return eventos
+}
+
+func antes(a, b rapina.Data) bool {
+	return time.Time(a).Before(time.Time(b))
+}
+
+// unitário converte os preços por lote em preços por ação (a quantidade já
+// é informada em ações).
+func (a *Ativo) unitário() {
+	if a.FatorCotação > 1 {
+		a.multiplicar(1/float64(a.FatorCotação), 1)
+		a.FatorCotação = 1
+	}
+}
+
+func (a *Ativo) multiplicar(fatorPreço, fatorQtd float64) {
+	for _, p := range []*rapina.Dinheiro{
+		&a.Abertura, &a.Máxima, &a.Mínima, &a.Encerramento,
+		&a.Médio, &a.MelhorCompra, &a.MelhorVenda,
+	} {
+		p.Valor = p.Valor.Mul(rapina.DecimalDeFloat(fatorPreço))
+	}
+	a.Quantidade = int64(math.Round(float64(a.Quantidade) * fatorQtd))
+}
@@ -0,0 +1,113 @@
+// SPDX-FileCopyrightText: 2023 Adriano Prado <dev@dude333.com>
+//
+// SPDX-License-Identifier: MIT
+
+package cotação
+
+import (
+	"math"
+	"testing"
+
+	rapina "github.com/dude333/rapinav2"
+)
+
+func data(s string) rapina.Data {
+	d, _ := rapina.NovaData(s)
+	return d
+}
+
+func ativo(dia string, abertura, encerramento float64, fator int) Ativo {
+	return Ativo{
+		Código:       "TEST3",
+		Data:         data(dia),
+		Abertura:     rapina.Dinheiro{Valor: rapina.DecimalDeFloat(abertura), Moeda: "R$"},
+		Encerramento: rapina.Dinheiro{Valor: rapina.DecimalDeFloat(encerramento), Moeda: "R$"},
+		Quantidade:   100,
+		FatorCotação: fator,
+	}
+}
+
+// Série com desdobramento 1:2 em 05/01 e dividendo de R$ 1 em 07/01.
+var _série = []Ativo{
+	ativo("2021-01-07", 9, 10, 1),
+	ativo("2021-01-04", 20, 20, 1),
+	ativo("2021-01-05", 10, 10, 1),
+	ativo("2021-01-06", 10, 10, 1),
+}
+
+var _eventos = []Evento{
+	{Código: "TEST3", DataEx: data("2021-01-05"), Tipo: Desdobramento, Fator: 2},
+	{Código: "TEST3", DataEx: data("2021-01-07"), Tipo: Dividendo, Valor: 1},
+}
+
+func TestAjustar(t *testing.T) {
+	tests := []struct {
+		name   string
+		ajuste Ajuste
+		want   []float64 // fechamentos
+		qtd    []int64
+	}{
+		{"sem ajuste", SemAjuste, []float64{20, 10, 10, 10}, []int64{100, 100, 100, 100}},
+		{"desdobramento", AjusteDesdobramento, []float64{10, 10, 10, 10}, []int64{200, 100, 100, 100}},
+		{"retorno total", AjusteRetornoTotal, []float64{9, 9, 9, 10}, []int64{200, 100, 100, 100}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Ajustar(_série, _eventos, tt.ajuste)
+			for i := range got {
+				if math.Abs(got[i].Encerramento.Valor.Float64()-tt.want[i]) > 1e-9 || got[i].Quantidade != tt.qtd[i] {
+					t.Errorf("%s: fechamento = %v, quantidade = %d, want %v, %d",
+						got[i].Data, got[i].Encerramento.Valor, got[i].Quantidade, tt.want[i], tt.qtd[i])
+				}
+			}
+		})
+	}
+
+	if _série[0].Encerramento.Valor != rapina.DecimalDeFloat(10) {
+		t.Error("Ajustar() alterou a série original")
+	}
+}
+
+func TestAjustar_FatorCotação(t *testing.T) {
+	série := []Ativo{
+		ativo("2021-01-04", 5000, 5000, 1000),
+		ativo("2021-01-05", 5, 5, 1),
+	}
+	got := Ajustar(série, nil, AjusteDesdobramento)
+	if got[0].Encerramento.Valor != rapina.DecimalDeFloat(5) || got[0].FatorCotação != 1 {
+		t.Errorf("Ajustar() = %+v", got[0])
+	}
+	if len(DetectarEventos(série)) != 0 {
+		t.Error("mudança do fator de cotação detectada como evento")
+	}
+}
+
+func TestDetectarEventos(t *testing.T) {
+	série := append([]Ativo{}, _série...)
+	série = append(série,
+		ativo("2021-01-08", 30, 30, 1), // grupamento 3:1
+		ativo("2021-01-11", 20, 20, 1), // queda de 33%: não é evento
+	)
+
+	got := DetectarEventos(série)
+	if len(got) != 2 {
+		t.Fatalf("DetectarEventos() = %+v, want 2 eventos", got)
+	}
+	if got[0].Tipo != Desdobramento || got[0].Fator != 2 || got[0].DataEx.String() != "2021-01-05" {
+		t.Errorf("DetectarEventos()[0] = %+v", got[0])
+	}
+	if got[1].Tipo != Grupamento || math.Abs(got[1].Fator-1.0/3) > 1e-9 || got[1].DataEx.String() != "2021-01-08" {
+		t.Errorf("DetectarEventos()[1] = %+v", got[1])
+	}
+}
+
+func TestNovoTipoEvento(t *testing.T) {
+	for _, s := range []string{"Desdobramento", "bonificação", " JCP "} {
+		if _, err := NovoTipoEvento(s); err != nil {
+			t.Errorf("NovoTipoEvento(%q) error = %v", s, err)
+		}
+	}
+	if _, err := NovoTipoEvento("cisão"); err == nil {
+		t.Error("NovoTipoEvento(cisão) sem erro")
+	}
+}
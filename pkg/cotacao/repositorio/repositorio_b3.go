@@ -9,9 +9,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	rapina "github.com/dude333/rapinav2"
 	cotação "github.com/dude333/rapinav2/pkg/cotacao"
@@ -24,29 +24,64 @@ import (
 // no site da B3.
 type B3 struct {
 	infra
+	cfg
 }
 
-func NovoB3(dirDados string) *B3 {
-	return &B3{
+// NovoB3 cria o repositório que importa as séries históricas da B3. Por
+// padrão, apenas os registros do lote padrão, de FIIs e do lote fracionário
+// (BDI 02, 12 e 96) dos mercados à vista e fracionário (010 e 020) são
+// importados; os filtros podem ser alterados com CfgBDI e CfgMercados.
+func NovoB3(dirDados string, configs ...ConfigFn) *B3 {
+	b := B3{
 		infra: &localInfra{dirDados: dirDados},
+		cfg: cfg{
+			bdi:      []string{"02", "12", "96"},
+			mercados: []string{"010", "020"},
+			urlBase:  urlB3,
+		},
 	}
+	for _, cfg := range configs {
+		cfg(&b.cfg)
+	}
+	return &b
 }
 
 // Importar baixa o arquivo de cotações de todas as empresas de um determinado
 // dia do site da B3.
 func (b *B3) Importar(ctx context.Context, dia rapina.Data) <-chan cotação.Resultado {
+	url, zip, err := arquivoCotação(b.urlBase, dia)
+	return b.importar(ctx, url, zip, err)
+}
+
+// ImportarAno baixa o arquivo com as cotações de todas as empresas de um
+// determinado ano do site da B3 (COTAHIST_AAAAA.ZIP).
+func (b *B3) ImportarAno(ctx context.Context, ano int) <-chan cotação.Resultado {
+	url, zip, err := arquivoCotaçãoAno(b.urlBase, ano)
+	return b.importar(ctx, url, zip, err)
+}
+
+// ImportarMês baixa o arquivo com as cotações de todas as empresas de um
+// determinado mês do site da B3 (COTAHIST_MMMAAAA.ZIP).
+func (b *B3) ImportarMês(ctx context.Context, ano, mês int) <-chan cotação.Resultado {
+	url, zip, err := arquivoCotaçãoMês(b.urlBase, ano, mês)
+	return b.importar(ctx, url, zip, err)
+}
+
+// importar baixa o arquivo de séries históricas, enviando as cotações para o
+// canal retornado à medida que as linhas são lidas diretamente do zip, sem
+// extrair nem carregar o arquivo na memória.
+func (b *B3) importar(ctx context.Context, url, zip string, err error) <-chan cotação.Resultado {
 	results := make(chan cotação.Resultado)
 
 	go func() {
 		defer close(results)
 
-		url, zip, err := arquivoCotação(dia)
 		if err != nil {
 			results <- cotação.Resultado{Error: err}
 			return
 		}
 
-		arquivos, err := b.infra.DownloadAndUnzip(url, zip, []string{})
+		arquivos, err := b.infra.DownloadZip(ctx, url, zip, []string{})
 		if err != nil {
 			results <- cotação.Resultado{Error: err}
 			return
@@ -56,7 +91,7 @@ func (b *B3) Importar(ctx context.Context, dia rapina.Data) <-chan cotação.Res
 		}()
 
 		for _, arquivo := range arquivos {
-			progress.Running(arquivo)
+			progress.Running(arquivo.nome)
 			b.processarSériesHistóricas(ctx, arquivo, results)
 			progress.RunOK()
 			select {
@@ -71,7 +106,10 @@ func (b *B3) Importar(ctx context.Context, dia rapina.Data) <-chan cotação.Res
 	return results
 }
 
-func arquivoCotação(dia rapina.Data) (url, zip string, err error) {
+// caminhoSériesHistóricas é o caminho dos arquivos no site da B3.
+const caminhoSériesHistóricas = `/InstDados/SerHist/`
+
+func arquivoCotação(urlBase string, dia rapina.Data) (url, zip string, err error) {
 	data := dia.String()
 	if len(data) != len("2021-05-03") {
 		return "", "", ErrDataInválidaFn(data)
@@ -79,15 +117,43 @@ func arquivoCotação(dia rapina.Data) (url, zip string, err error) {
 	conv := data[8:10] + data[5:7] + data[0:4] // DDMMAAAA
 
 	zip = fmt.Sprintf(`COTAHIST_D%s.ZIP`, conv)
-	url = `http://bvmf.bmfbovespa.com.br/InstDados/SerHist/` + zip
+	url = urlBase + caminhoSériesHistóricas + zip
+
+	return url, zip, nil
+}
+
+// Primeiro ano com séries históricas disponíveis no site da B3.
+const primeiroAnoSérie = 1986
+
+func arquivoCotaçãoAno(urlBase string, ano int) (url, zip string, err error) {
+	if ano < primeiroAnoSérie || ano > time.Now().Year() {
+		return "", "", ErrAnoInválidoFn(ano)
+	}
+
+	zip = fmt.Sprintf(`COTAHIST_A%d.ZIP`, ano)
+	url = urlBase + caminhoSériesHistóricas + zip
+
+	return url, zip, nil
+}
+
+func arquivoCotaçãoMês(urlBase string, ano, mês int) (url, zip string, err error) {
+	if ano < primeiroAnoSérie || ano > time.Now().Year() {
+		return "", "", ErrAnoInválidoFn(ano)
+	}
+	if mês < 1 || mês > 12 {
+		return "", "", ErrMêsInválidoFn(mês)
+	}
+
+	zip = fmt.Sprintf(`COTAHIST_M%02d%d.ZIP`, mês, ano) // MMAAAA
+	url = urlBase + caminhoSériesHistóricas + zip
 
 	return url, zip, nil
 }
 
 // processarSériesHistóricas lê o arquivo de séries históricas baixado da B3
 // e envia os valores do ativo para o canal "result".
-func (b *B3) processarSériesHistóricas(ctx context.Context, arquivo string, result chan<- cotação.Resultado) {
-	fh, err := os.Open(arquivo)
+func (b *B3) processarSériesHistóricas(ctx context.Context, arquivo Arquivo, result chan<- cotação.Resultado) {
+	fh, err := arquivo.abrir()
 	if err != nil {
 		result <- cotação.Resultado{Error: err}
 		return
@@ -98,7 +164,7 @@ func (b *B3) processarSériesHistóricas(ctx context.Context, arquivo string, re
 	scanner := bufio.NewScanner(stream)
 	for scanner.Scan() {
 		l := scanner.Text()
-		atv, err := analisarLinha(l)
+		atv, err := analisarLinha(l, b.cfg)
 		if err != nil {
 			continue
 		}
@@ -112,82 +178,137 @@ func (b *B3) processarSériesHistóricas(ctx context.Context, arquivo string, re
 	}
 }
 
-// parseB3Quote parses the line based on this layout:
+// analisarLinha analisa a linha com base no layout abaixo, retornando erro
+// para os registros que não sejam cotações ou que não sejam aceitos pelos
+// filtros de BDI e de tipo de mercado:
 // http://www.b3.com.br/data/files/33/67/B9/50/D84057102C784E47AC094EA8/SeriesHistoricas_Layout.pdf
 //
-//   CAMPO/CONTEÚDO  TIPO E TAMANHO  POS. INIC.	 POS. FINAL
-//   TIPREG “01”     N(02)           01          02
-//   DATA “AAAAMMDD” N(08)           03          10
-//   CODBDI          X(02)           11          12
-//   CODNEG          X(12)           13          24
-//   TPMERC          N(03)           25          27
-//   PREABE          (11)V99         57          69
-//   PREMAX          (11)V99         70          82
-//   PREMIN          (11)V99         83          95
-//   PREULT          (11)V99         109         121
-//   QUATOT          N18             153         170
-//   VOLTOT          (16)V99         171         188
+//	CAMPO/CONTEÚDO  TIPO E TAMANHO  POS. INIC.  POS. FINAL
+//	TIPREG “01”     N(02)           01          02
+//	DATA “AAAAMMDD” N(08)           03          10
+//	CODBDI          X(02)           11          12
+//	CODNEG          X(12)           13          24
+//	TPMERC          N(03)           25          27
+//	NOMRES          X(12)           28          39
+//	ESPECI          X(10)           40          49
+//	PRAZOT          X(03)           50          52
+//	MODREF          X(04)           53          56
+//	PREABE          (11)V99         57          69
+//	PREMAX          (11)V99         70          82
+//	PREMIN          (11)V99         83          95
+//	PREMED          (11)V99         96          108
+//	PREULT          (11)V99         109         121
+//	PREOFC          (11)V99         122         134
+//	PREOFV          (11)V99         135         147
+//	TOTNEG          N(05)           148         152
+//	QUATOT          N(18)           153         170
+//	VOLTOT          (16)V99         171         188
+//	PREEXE          (11)V99         189         201
+//	INDOPC          N(01)           202         202
+//	DATVEN          N(08)           203         210
+//	FATCOT          N(07)           211         217
+//	PTOEXE          (07)V06         218         230
+//	CODISI          X(12)           231         242
+//	DISMES          N(03)           243         245
+//
+// CODBDI (alguns):
 //
-// CODBDI:
-//   02 LOTE PADRÃO
-//   12 FUNDO IMOBILIÁRIO
+//	02 LOTE PADRÃO
+//	12 FUNDO IMOBILIÁRIO
+//	14 CERTIFIC. INVESTIMENTO / DEBÊNTURES / TÍTULOS DIVIDA PÚBLICA
+//	78 OPÇÕES DE COMPRA
+//	82 OPÇÕES DE VENDA
+//	96 MERCADO FRACIONÁRIO
 //
-// TPMERC:
-//   010 VISTA
-//   020 FRACIONÁRIO
-func analisarLinha(linha string) (*cotação.Ativo, error) {
+// TPMERC (alguns):
+//
+//	010 VISTA
+//	020 FRACIONÁRIO
+//	070 OPÇÕES DE COMPRA
+//	080 OPÇÕES DE VENDA
+func analisarLinha(linha string, filtro cfg) (*cotação.Ativo, error) {
 	if len(linha) != 245 {
 		return nil, errors.New("linha deve conter 245 bytes")
 	}
 
-	tipReg := linha[0:2]
-	if tipReg != "01" {
-		return nil, fmt.Errorf("registro %s ignorado", tipReg)
+	// campo retorna o conteúdo entre as posições inicial e final (base 1)
+	campo := func(ini, fim int) string {
+		return linha[ini-1 : fim]
 	}
 
-	codBDI := linha[10:12]
-	if codBDI != "02" && codBDI != "12" {
-		return nil, fmt.Errorf("BDI %s ignorado", codBDI)
+	tipReg := campo(1, 2)
+	if tipReg != "01" {
+		return nil, fmt.Errorf("registro %s ignorado", tipReg)
 	}
 
-	tpMerc := linha[24:27]
-	if tpMerc != "010" && tpMerc != "020" {
-		return nil, fmt.Errorf("tipo de mercado %s ignorado", tpMerc)
+	codBDI := strings.TrimSpace(campo(11, 12))
+	tpMerc := campo(25, 27)
+	if !filtro.aceita(codBDI, tpMerc) {
+		return nil, fmt.Errorf("BDI %s / mercado %s ignorado", codBDI, tpMerc)
 	}
 
-	código := strings.TrimSpace(linha[12:24])
-	data, err := rapina.NovaData(linha[2:6] + "-" + linha[6:8] + "-" + linha[8:10])
+	data, err := converterData(campo(3, 10))
 	if err != nil {
 		return &cotação.Ativo{}, err
 	}
 
-	numRanges := [5]struct {
-		i, f int
-	}{
-		{56, 69},   // PREABE = Abertura
-		{69, 82},   // PREMAX = Màxima
-		{82, 95},   // PREMIN = Mínima
-		{108, 121}, // PREULT = Encerramento
-		{170, 188}, // VOLTOT = Volume
+	var numErr error
+	número := func(ini, fim int) int64 {
+		n, err := strconv.ParseInt(campo(ini, fim), 10, 64)
+		if err != nil && numErr == nil {
+			numErr = err
+		}
+		return n
 	}
-	var vals [5]float64
-	for i, r := range numRanges {
-		num, err := strconv.Atoi(linha[r.i:r.f])
+	const r = "R$"
+	preço := func(ini, fim int) rapina.Dinheiro {
+		return rapina.Dinheiro{Valor: rapina.DecimalDeInt(número(ini, fim)).Div(rapina.DecimalDeInt(100)), Moeda: r}
+	}
+
+	atv := cotação.Ativo{
+		Código:        strings.TrimSpace(campo(13, 24)),
+		Data:          data,
+		Abertura:      preço(57, 69),
+		Máxima:        preço(70, 82),
+		Mínima:        preço(83, 95),
+		Encerramento:  preço(109, 121),
+		Volume:        float64(número(171, 188)) / 100,
+		ISIN:          campo(231, 242),
+		NomeResumido:  strings.TrimSpace(campo(28, 39)),
+		Especificação: strings.Join(strings.Fields(campo(40, 49)), " "),
+		BDI:           codBDI,
+		Mercado:       tpMerc,
+		Médio:         preço(96, 108),
+		MelhorCompra:  preço(122, 134),
+		MelhorVenda:   preço(135, 147),
+		Negócios:      int(número(148, 152)),
+		Quantidade:    número(153, 170),
+		FatorCotação:  int(número(211, 217)),
+	}
+	if numErr != nil {
+		return &cotação.Ativo{}, numErr
+	}
+
+	// Apenas opções e termos têm preço de exercício e vencimento; nos demais
+	// mercados, DATVEN vem preenchido com 99991231.
+	if datVen := campo(203, 210); datVen != "99991231" {
+		atv.PreçoExercício = preço(189, 201)
+		atv.Vencimento, err = converterData(datVen)
 		if err != nil {
 			return &cotação.Ativo{}, err
 		}
-		vals[i] = float64(num) / 100
+		if numErr != nil {
+			return &cotação.Ativo{}, numErr
+		}
 	}
 
-	const r = "R$"
+	return &atv, nil
+}
 
-	return &cotação.Ativo{
-		Código:       código,
-		Data:         data,
-		Abertura:     rapina.Dinheiro{Valor: vals[0], Moeda: r},
-		Máxima:       rapina.Dinheiro{Valor: vals[1], Moeda: r},
-		Mínima:       rapina.Dinheiro{Valor: vals[2], Moeda: r},
-		Encerramento: rapina.Dinheiro{Valor: vals[3], Moeda: r},
-		Volume:       vals[4],
-	}, nil
+// converterData converte AAAAMMDD em rapina.Data.
+func converterData(s string) (rapina.Data, error) {
+	if len(s) != len("AAAAMMDD") {
+		return rapina.Data{}, ErrDataInválidaFn(s)
+	}
+	return rapina.NovaData(s[0:4] + "-" + s[4:6] + "-" + s[6:8])
 }
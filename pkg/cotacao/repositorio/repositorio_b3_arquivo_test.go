@@ -0,0 +1,162 @@
+// SPDX-FileCopyrightText: 2023 Adriano Prado <dev@dude333.com>
+//
+// SPDX-License-Identifier: MIT
+
+package repositorio
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	rapina "github.com/dude333/rapinav2"
+)
+
+func Test_arquivoCotação(t *testing.T) {
+	dia, _ := rapina.NovaData("2021-05-03")
+
+	tests := []struct {
+		name    string
+		fn      func() (string, string, error)
+		want    string
+		wantErr bool
+	}{
+		{"diário", func() (string, string, error) { return arquivoCotação(urlB3, dia) }, "COTAHIST_D03052021.ZIP", false},
+		{"mensal", func() (string, string, error) { return arquivoCotaçãoMês(urlB3, 2021, 5) }, "COTAHIST_M052021.ZIP", false},
+		{"anual", func() (string, string, error) { return arquivoCotaçãoAno(urlB3, 2009) }, "COTAHIST_A2009.ZIP", false},
+		{"mês inválido", func() (string, string, error) { return arquivoCotaçãoMês(urlB3, 2021, 13) }, "", true},
+		{"ano inválido", func() (string, string, error) { return arquivoCotaçãoAno(urlB3, 1900) }, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			url, zip, err := tt.fn()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if zip != tt.want {
+				t.Errorf("zip = %s, want %s", zip, tt.want)
+			}
+			if zip != "" && url != "http://bvmf.bmfbovespa.com.br/InstDados/SerHist/"+zip {
+				t.Errorf("url = %s", url)
+			}
+		})
+	}
+}
+
+// linhaCotahist monta um registro de cotação no layout do COTAHIST.
+func linhaCotahist(bdi, código, mercado, especi string, preço, preçoExe int, vencimento string) string {
+	return fmt.Sprintf("01%s%-2s%-12s%s%-12s%-10s%3s%4s%013d%013d%013d%013d%013d%013d%013d%05d%018d%018d%013d%1d%s%07d%013d%-12s%03d",
+		"20210503", bdi, código, mercado, "TESTE", especi, "", "R$",
+		preço, preço+10, preço-10, preço+1, preço+2, preço+3, preço+4,
+		7, 1500, preço*1500, preçoExe, 0, vencimento, 1, 0, "BRTESTACNOR0", 0)
+}
+
+func Test_analisarLinha(t *testing.T) {
+	padrão := NovoB3("").cfg
+
+	t.Run("vista", func(t *testing.T) {
+		l := linhaCotahist("02", "TEST3", "010", "ON      NM", 2150, 0, "99991231")
+		if len(l) != 245 {
+			t.Fatalf("len = %d", len(l))
+		}
+		atv, err := analisarLinha(l, padrão)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if atv.Código != "TEST3" || atv.Data.String() != "2021-05-03" ||
+			atv.Abertura.Valor != rapina.DecimalDeFloat(21.50) || atv.Máxima.Valor != rapina.DecimalDeFloat(21.60) ||
+			atv.Mínima.Valor != rapina.DecimalDeFloat(21.40) || atv.Médio.Valor != rapina.DecimalDeFloat(21.51) ||
+			atv.Encerramento.Valor != rapina.DecimalDeFloat(21.52) || atv.MelhorCompra.Valor != rapina.DecimalDeFloat(21.53) ||
+			atv.MelhorVenda.Valor != rapina.DecimalDeFloat(21.54) || atv.Volume != 32250 {
+			t.Errorf("preços = %+v", atv)
+		}
+		if atv.ISIN != "BRTESTACNOR0" || atv.NomeResumido != "TESTE" ||
+			atv.Especificação != "ON NM" || atv.BDI != "02" || atv.Mercado != "010" ||
+			atv.Negócios != 7 || atv.Quantidade != 1500 || atv.FatorCotação != 1 {
+			t.Errorf("campos = %+v", atv)
+		}
+		if !time.Time(atv.Vencimento).IsZero() || atv.PreçoExercício.Valor != rapina.DecimalDeFloat(0) {
+			t.Errorf("vencimento = %v, exercício = %v", atv.Vencimento, atv.PreçoExercício)
+		}
+	})
+
+	t.Run("fracionário", func(t *testing.T) {
+		l := linhaCotahist("96", "TEST3F", "020", "ON      NM", 2150, 0, "99991231")
+		atv, err := analisarLinha(l, padrão)
+		if err != nil {
+			t.Fatalf("fracionário deveria passar pelo filtro padrão: %v", err)
+		}
+		if atv.Código != "TEST3F" || atv.BDI != "96" || atv.Mercado != "020" {
+			t.Errorf("campos = %+v", atv)
+		}
+	})
+
+	t.Run("opção", func(t *testing.T) {
+		l := linhaCotahist("78", "TESTE215", "070", "ON", 35, 2150, "20210517")
+		if _, err := analisarLinha(l, padrão); err == nil {
+			t.Error("opção não deveria passar pelo filtro padrão")
+		}
+		atv, err := analisarLinha(l, cfg{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if atv.PreçoExercício.Valor != rapina.DecimalDeFloat(21.50) || atv.Vencimento.String() != "2021-05-17" {
+			t.Errorf("exercício = %v, vencimento = %v", atv.PreçoExercício, atv.Vencimento)
+		}
+	})
+
+	t.Run("inválidas", func(t *testing.T) {
+		for _, l := range []string{
+			"00COTAHIST.2021BOVESPA 20210503",
+			"99" + linhaCotahist("02", "TEST3", "010", "ON", 100, 0, "99991231")[2:],
+			strings.Replace(linhaCotahist("02", "TEST3", "010", "ON", 100, 0, "99991231"), "0000000000100", "00000000001X0", 1),
+		} {
+			if _, err := analisarLinha(l, cfg{}); err == nil {
+				t.Errorf("analisarLinha(%q) sem erro", l[:20])
+			}
+		}
+	})
+}
+
+func TestB3_CfgURLBase(t *testing.T) {
+	var buf bytes.Buffer
+	z := zip.NewWriter(&buf)
+	w, err := z.Create("COTAHIST_D03052021.TXT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Fprintln(w, linhaCotahist("02", "TEST3", "010", "ON      NM", 2150, 0, "99991231"))
+	if err := z.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var caminhos []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		caminhos = append(caminhos, r.URL.Path)
+		_, _ = w.Write(buf.Bytes())
+	}))
+	defer ts.Close()
+
+	b := NovoB3(t.TempDir(), CfgURLBase(ts.URL+"/"))
+	dia, _ := rapina.NovaData("2021-05-03")
+	var códigos []string
+	for r := range b.Importar(context.Background(), dia) {
+		if r.Error != nil {
+			t.Fatal(r.Error)
+		}
+		códigos = append(códigos, r.Ativo.Código)
+	}
+
+	if len(caminhos) != 1 || caminhos[0] != "/InstDados/SerHist/COTAHIST_D03052021.ZIP" {
+		t.Errorf("caminhos = %v", caminhos)
+	}
+	if len(códigos) != 1 || códigos[0] != "TEST3" {
+		t.Errorf("ativos = %v, want [TEST3]", códigos)
+	}
+}
@@ -0,0 +1,66 @@
+// SPDX-FileCopyrightText: 2023 Adriano Prado <dev@dude333.com>
+//
+// SPDX-License-Identifier: MIT
+
+package repositorio
+
+import "strings"
+
+// urlB3 é o endereço do site da B3 com as séries históricas.
+const urlB3 = "http://bvmf.bmfbovespa.com.br"
+
+// cfg contém as configurações usadas nos construtores deste repositório.
+type cfg struct {
+	bdi      []string // Códigos BDI aceitos (vazio = todos)
+	mercados []string // Tipos de mercado aceitos (vazio = todos)
+	urlBase  string   // Site da B3 ou espelho
+}
+
+type ConfigFn func(*cfg)
+
+// CfgBDI define os códigos BDI dos registros que serão importados (ex.: "02"
+// lote padrão, "12" FII, "14" BDR, "78" opções de compra). Sem códigos,
+// todos os registros são importados.
+func CfgBDI(códigos ...string) ConfigFn {
+	return func(c *cfg) {
+		c.bdi = códigos
+	}
+}
+
+// CfgMercados define os tipos de mercado dos registros que serão importados
+// (ex.: "010" vista, "020" fracionário, "070" opções de compra, "080" opções
+// de venda). Sem códigos, todos os registros são importados.
+func CfgMercados(códigos ...string) ConfigFn {
+	return func(c *cfg) {
+		c.mercados = códigos
+	}
+}
+
+// CfgURLBase define o endereço do site da B3 (padrão urlB3), que pode ser
+// substituído por um espelho local ou um servidor de testes. Os arquivos são
+// buscados no mesmo caminho do site da B3 (ex.:
+// <url>/InstDados/SerHist/COTAHIST_A2022.ZIP).
+func CfgURLBase(url string) ConfigFn {
+	return func(c *cfg) {
+		if len(url) > 0 {
+			c.urlBase = strings.TrimSuffix(url, "/")
+		}
+	}
+}
+
+func (c cfg) aceita(bdi, mercado string) bool {
+	return contém(c.bdi, bdi) && contém(c.mercados, mercado)
+}
+
+// contém retorna verdadeiro se s estiver na lista ou se a lista estiver vazia.
+func contém(lista []string, s string) bool {
+	if len(lista) == 0 {
+		return true
+	}
+	for i := range lista {
+		if lista[i] == s {
+			return true
+		}
+	}
+	return false
+}
@@ -14,4 +14,6 @@ var (
 	ErrAtivoNãoEncontrado = errors.New("ativo não encontrado")
 
 	ErrDataInválidaFn = func(dia string) error { return fmt.Errorf("data com formato inválido: %s", dia) }
+	ErrAnoInválidoFn  = func(ano int) error { return fmt.Errorf("ano inválido: %d", ano) }
+	ErrMêsInválidoFn  = func(mês int) error { return fmt.Errorf("mês inválido: %d", mês) }
 )
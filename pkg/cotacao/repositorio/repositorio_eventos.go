@@ -0,0 +1,154 @@
+// SPDX-FileCopyrightText: 2023 Adriano Prado <dev@dude333.com>
+//
+// SPDX-License-Identifier: MIT
+
+package repositorio
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	rapina "github.com/dude333/rapinav2"
+	cotação "github.com/dude333/rapinav2/pkg/cotacao"
+)
+
+// registroEvento contém os campos de um evento corporativo nos arquivos CSV
+// e YAML.
+type registroEvento struct {
+	Código string `yaml:"codigo"`
+	DataEx string `yaml:"data_ex"`
+	Tipo   string `yaml:"tipo"`
+	Fator  string `yaml:"fator"`
+	Valor  string `yaml:"valor"`
+}
+
+// LerEventos lê os eventos corporativos de um arquivo CSV (separado por ";",
+// com o cabeçalho codigo;data_ex;tipo;fator;valor) ou YAML (lista de eventos
+// com as mesmas chaves). As datas podem estar nos formatos AAAA-MM-DD ou
+// DD/MM/AAAA e os números podem usar vírgula como separador decimal.
+func LerEventos(arquivo string) ([]cotação.Evento, error) {
+	fh, err := os.Open(arquivo)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+
+	var registros []registroEvento
+	switch strings.ToLower(filepath.Ext(arquivo)) {
+	case ".csv":
+		registros, err = lerEventosCSV(fh)
+	case ".yaml", ".yml":
+		err = yaml.NewDecoder(fh).Decode(&registros)
+	default:
+		err = fmt.Errorf("formato de arquivo não suportado: %s", arquivo)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	eventos := make([]cotação.Evento, 0, len(registros))
+	for i, r := range registros {
+		e, err := r.evento()
+		if err != nil {
+			return nil, fmt.Errorf("%s, evento %d: %w", arquivo, i+1, err)
+		}
+		eventos = append(eventos, e)
+	}
+	return eventos, nil
+}
+
+func lerEventosCSV(r io.Reader) ([]registroEvento, error) {
+	c := csv.NewReader(r)
+	c.Comma = ';'
+	c.FieldsPerRecord = -1
+	c.TrimLeadingSpace = true
+
+	cabeçalho, err := c.Read()
+	if err != nil {
+		return nil, err
+	}
+	colunas := make(map[string]int)
+	for i, col := range cabeçalho {
+		colunas[strings.ToLower(strings.TrimSpace(col))] = i
+	}
+	for _, col := range []string{"codigo", "data_ex", "tipo"} {
+		if _, ok := colunas[col]; !ok {
+			return nil, fmt.Errorf("coluna %s não encontrada", col)
+		}
+	}
+
+	campo := func(linha []string, col string) string {
+		i, ok := colunas[col]
+		if !ok || i >= len(linha) {
+			return ""
+		}
+		return strings.TrimSpace(linha[i])
+	}
+
+	var registros []registroEvento
+	for {
+		linha, err := c.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		registros = append(registros, registroEvento{
+			Código: campo(linha, "codigo"),
+			DataEx: campo(linha, "data_ex"),
+			Tipo:   campo(linha, "tipo"),
+			Fator:  campo(linha, "fator"),
+			Valor:  campo(linha, "valor"),
+		})
+	}
+	return registros, nil
+}
+
+func (r registroEvento) evento() (cotação.Evento, error) {
+	var e cotação.Evento
+	var err error
+
+	e.Código = strings.ToUpper(strings.TrimSpace(r.Código))
+	if e.DataEx, err = lerData(r.DataEx); err != nil {
+		return e, err
+	}
+	if e.Tipo, err = cotação.NovoTipoEvento(r.Tipo); err != nil {
+		return e, err
+	}
+	if e.Fator, err = lerNúmero(r.Fator); err != nil {
+		return e, err
+	}
+	if e.Valor, err = lerNúmero(r.Valor); err != nil {
+		return e, err
+	}
+	return e, e.Validar()
+}
+
+func lerData(s string) (rapina.Data, error) {
+	s = strings.TrimSpace(s)
+	if t, err := time.Parse("02/01/2006", s); err == nil {
+		return rapina.Data(t), nil
+	}
+	d, err := rapina.NovaData(s)
+	if err != nil {
+		return d, ErrDataInválidaFn(s)
+	}
+	return d, nil
+}
+
+func lerNúmero(s string) (float64, error) {
+	s = strings.ReplaceAll(strings.TrimSpace(s), ",", ".")
+	if s == "" {
+		return 0, nil
+	}
+	return strconv.ParseFloat(s, 64)
+}
@@ -0,0 +1,92 @@
+// SPDX-FileCopyrightText: 2023 Adriano Prado <dev@dude333.com>
+//
+// SPDX-License-Identifier: MIT
+
+package repositorio_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+
+	rapina "github.com/dude333/rapinav2"
+	cotação "github.com/dude333/rapinav2/pkg/cotacao"
+	repositório "github.com/dude333/rapinav2/pkg/cotacao/repositorio"
+)
+
+func TestLerEventos(t *testing.T) {
+	d1, _ := rapina.NovaData("2021-05-03")
+	d2, _ := rapina.NovaData("2021-06-10")
+	want := []cotação.Evento{
+		{Código: "TEST3", DataEx: d1, Tipo: cotação.Desdobramento, Fator: 2},
+		{Código: "TEST3", DataEx: d2, Tipo: cotação.JCP, Valor: 0.35},
+	}
+
+	dir := t.TempDir()
+	tests := []struct {
+		arquivo  string
+		conteúdo string
+		wantErr  bool
+	}{
+		{"eventos.csv", "codigo;data_ex;tipo;fator;valor\ntest3;03/05/2021;Desdobramento;2;\nTEST3;2021-06-10;JCP;;0,35\n", false},
+		{"eventos.yaml", "- {codigo: TEST3, data_ex: 2021-05-03, tipo: desdobramento, fator: 2}\n- {codigo: TEST3, data_ex: 10/06/2021, tipo: jcp, valor: 0.35}\n", false},
+		{"sem_fator.csv", "codigo;data_ex;tipo\nTEST3;2021-05-03;grupamento\n", true},
+		{"tipo.csv", "codigo;data_ex;tipo;fator\nTEST3;2021-05-03;cisão;1\n", true},
+		{"eventos.txt", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.arquivo, func(t *testing.T) {
+			arquivo := filepath.Join(dir, tt.arquivo)
+			if err := os.WriteFile(arquivo, []byte(tt.conteúdo), 0644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := repositório.LerEventos(arquivo)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LerEventos() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, want) {
+				t.Errorf("LerEventos() = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestSqlite_Eventos(t *testing.T) {
+	db := sqlx.MustConnect("sqlite3", ":memory:")
+	db.SetMaxOpenConns(1)
+
+	s, err := repositório.NovoSqlite(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d1, _ := rapina.NovaData("2021-05-03")
+	d2, _ := rapina.NovaData("2021-06-10")
+	eventos := []cotação.Evento{
+		{Código: "TEST3", DataEx: d2, Tipo: cotação.Dividendo, Valor: 1},
+		{Código: "TEST3", DataEx: d1, Tipo: cotação.Desdobramento, Fator: 2},
+		{Código: "TEST4", DataEx: d1, Tipo: cotação.Grupamento, Fator: 0.1},
+	}
+	ctx := context.Background()
+	if err := s.SalvarEventos(ctx, eventos); err != nil {
+		t.Fatal(err)
+	}
+	// Salvar novamente deve substituir o evento existente
+	eventos[0].Valor = 1.5
+	if err := s.SalvarEventos(ctx, eventos[:1]); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := s.Eventos(ctx, "TEST3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []cotação.Evento{eventos[1], eventos[0]}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Eventos() = %+v, want %+v", got, want)
+	}
+}
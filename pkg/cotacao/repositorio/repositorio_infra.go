@@ -5,6 +5,8 @@
 package repositorio
 
 import (
+	"context"
+	"io"
 	"path"
 
 	ext "github.com/dude333/rapinav2/pkg/infra"
@@ -13,19 +15,44 @@ import (
 // infra define uma interface para que este respositório não fique amarrado
 // na implementação de uma única biblioteca externa.
 type infra interface {
-	DownloadAndUnzip(url, zip string, filtros []string) ([]string, error)
-	Cleanup(files []string) []string
+	DownloadZip(ctx context.Context, url, zip string, filtros []string) ([]Arquivo, error)
+	Cleanup(files []Arquivo) []string
+}
+
+// Arquivo é um arquivo lido diretamente do zip, sem ser extraído.
+type Arquivo struct {
+	nome   string
+	abrir  func() (io.ReadCloser, error)
+	fechar func() error // libera o zip
 }
 
 type localInfra struct {
 	dirDados string // diretório de dados
 }
 
-func (l localInfra) DownloadAndUnzip(url, arquivo string, filtros []string) ([]string, error) {
+func (l localInfra) DownloadZip(ctx context.Context, url, arquivo string, filtros []string) ([]Arquivo, error) {
 	zip := path.Join(l.dirDados, arquivo)
-	return ext.DownloadAndUnzip(url, zip, filtros)
+	z, err := ext.DownloadZip(ctx, url, zip, filtros)
+	if err != nil {
+		return []Arquivo{}, err
+	}
+
+	arquivos := make([]Arquivo, len(z.Files))
+	for i, f := range z.Files {
+		arquivos[i] = Arquivo{nome: f.Name, abrir: f.Open, fechar: z.Close}
+	}
+	if len(arquivos) == 0 {
+		_ = z.Close()
+	}
+	return arquivos, nil
 }
 
-func (l localInfra) Cleanup(files []string) []string {
-	return ext.Cleanup(files)
+func (l localInfra) Cleanup(files []Arquivo) []string {
+	var falhas []string
+	for _, f := range files {
+		if err := f.fechar(); err != nil {
+			falhas = append(falhas, f.nome)
+		}
+	}
+	return falhas
 }
@@ -0,0 +1,384 @@
+// SPDX-FileCopyrightText: 2023 Adriano Prado <dev@dude333.com>
+//
+// SPDX-License-Identifier: MIT
+
+package repositorio
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	rapina "github.com/dude333/rapinav2"
+	cotação "github.com/dude333/rapinav2/pkg/cotacao"
+	ext "github.com/dude333/rapinav2/pkg/infra"
+	"github.com/dude333/rapinav2/pkg/progress"
+)
+
+// Sqlite implementa serviço.LeituraEscrita, armazenando as cotações no
+// mesmo banco de dados usado pelo repositório contábil.
+type Sqlite struct {
+	db *sqlx.DB
+}
+
+func NovoSqlite(db *sqlx.DB) (*Sqlite, error) {
+	passos, err := Migrar(db, -1, false)
+	for _, p := range passos {
+		progress.Status(`Migração "%s" aplicada: v%d - %s`, p.Module, p.Version, p.Descr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &Sqlite{db: db}, nil
+}
+
+type sqliteAtivo struct {
+	Código       string         `db:"codigo"`
+	Data         string         `db:"data"`
+	Abertura     rapina.Decimal `db:"abertura"`
+	Máxima       rapina.Decimal `db:"maxima"`
+	Mínima       rapina.Decimal `db:"minima"`
+	Encerramento rapina.Decimal `db:"encerramento"`
+	Volume       float64        `db:"volume"`
+	Moeda        string         `db:"moeda"`
+
+	ISIN           string         `db:"isin"`
+	NomeResumido   string         `db:"nome_resumido"`
+	Especificação  string         `db:"especificacao"`
+	BDI            string         `db:"bdi"`
+	Mercado        string         `db:"mercado"`
+	Médio          rapina.Decimal `db:"medio"`
+	MelhorCompra   rapina.Decimal `db:"melhor_compra"`
+	MelhorVenda    rapina.Decimal `db:"melhor_venda"`
+	Negócios       int            `db:"negocios"`
+	Quantidade     int64          `db:"quantidade"`
+	FatorCotação   int            `db:"fator_cotacao"`
+	PreçoExercício rapina.Decimal `db:"preco_exercicio"`
+	Vencimento     string         `db:"vencimento"`
+}
+
+func (a sqliteAtivo) ativo() (*cotação.Ativo, error) {
+	data, err := rapina.NovaData(a.Data)
+	if err != nil {
+		return nil, err
+	}
+	var vencimento rapina.Data
+	if a.Vencimento != "" {
+		vencimento, err = rapina.NovaData(a.Vencimento)
+		if err != nil {
+			return nil, err
+		}
+	}
+	dinheiro := func(v rapina.Decimal) rapina.Dinheiro {
+		return rapina.Dinheiro{Valor: v, Moeda: a.Moeda}
+	}
+	return &cotação.Ativo{
+		Código:         a.Código,
+		Data:           data,
+		Abertura:       dinheiro(a.Abertura),
+		Máxima:         dinheiro(a.Máxima),
+		Mínima:         dinheiro(a.Mínima),
+		Encerramento:   dinheiro(a.Encerramento),
+		Volume:         a.Volume,
+		ISIN:           a.ISIN,
+		NomeResumido:   a.NomeResumido,
+		Especificação:  a.Especificação,
+		BDI:            a.BDI,
+		Mercado:        a.Mercado,
+		Médio:          dinheiro(a.Médio),
+		MelhorCompra:   dinheiro(a.MelhorCompra),
+		MelhorVenda:    dinheiro(a.MelhorVenda),
+		Negócios:       a.Negócios,
+		Quantidade:     a.Quantidade,
+		FatorCotação:   a.FatorCotação,
+		PreçoExercício: dinheiro(a.PreçoExercício),
+		Vencimento:     vencimento,
+	}, nil
+}
+
+// Cotação retorna a cotação do ativo com o "código" no "dia" informado.
+func (s *Sqlite) Cotação(ctx context.Context, código string, dia rapina.Data) (*cotação.Ativo, error) {
+	var a sqliteAtivo
+	err := s.db.GetContext(ctx, &a,
+		`SELECT * FROM cotacoes WHERE codigo=? AND data=?`, código, dia.String())
+	if err == sql.ErrNoRows {
+		return nil, ErrAtivoNãoEncontrado
+	}
+	if err != nil {
+		return nil, err
+	}
+	return a.ativo()
+}
+
+// Cotações retorna as cotações do ativo com o "código" entre as datas "de" e
+// "até" (inclusive), em ordem crescente de data.
+func (s *Sqlite) Cotações(ctx context.Context, código string, de, até rapina.Data) ([]cotação.Ativo, error) {
+	var registros []sqliteAtivo
+	err := s.db.SelectContext(ctx, &registros,
+		`SELECT * FROM cotacoes WHERE codigo=? AND data BETWEEN ? AND ? ORDER BY data`,
+		código, de.String(), até.String())
+	if err != nil {
+		return nil, err
+	}
+
+	ativos := make([]cotação.Ativo, 0, len(registros))
+	for i := range registros {
+		a, err := registros[i].ativo()
+		if err != nil {
+			return nil, err
+		}
+		ativos = append(ativos, *a)
+	}
+	return ativos, nil
+}
+
+// ISINs retorna o código ISIN mais recente de cada ativo.
+func (s *Sqlite) ISINs(ctx context.Context) (map[string]string, error) {
+	var registros []struct {
+		Código string `db:"codigo"`
+		ISIN   string `db:"isin"`
+	}
+	err := s.db.SelectContext(ctx, &registros,
+		`SELECT codigo, isin FROM cotacoes c
+		WHERE isin <> '' AND data = (SELECT MAX(data) FROM cotacoes WHERE codigo = c.codigo)`)
+	if err != nil {
+		return nil, err
+	}
+
+	isins := make(map[string]string, len(registros))
+	for _, r := range registros {
+		isins[r.Código] = r.ISIN
+	}
+	return isins, nil
+}
+
+func (s *Sqlite) Salvar(ctx context.Context, ativo *cotação.Ativo) error {
+	return s.SalvarLote(ctx, []*cotação.Ativo{ativo})
+}
+
+// SalvarLote salva as cotações numa única transação, substituindo as
+// cotações já existentes do mesmo ativo e data.
+func (s *Sqlite) SalvarLote(ctx context.Context, ativos []*cotação.Ativo) error {
+	if len(ativos) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.PrepareNamedContext(ctx, `INSERT OR REPLACE INTO cotacoes
+		(codigo, data, abertura, maxima, minima, encerramento, volume, moeda,
+		isin, nome_resumido, especificacao, bdi, mercado, medio, melhor_compra,
+		melhor_venda, negocios, quantidade, fator_cotacao, preco_exercicio, vencimento)
+		VALUES
+		(:codigo, :data, :abertura, :maxima, :minima, :encerramento, :volume, :moeda,
+		:isin, :nome_resumido, :especificacao, :bdi, :mercado, :medio, :melhor_compra,
+		:melhor_venda, :negocios, :quantidade, :fator_cotacao, :preco_exercicio, :vencimento)`)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	for _, atv := range ativos {
+		if atv == nil {
+			continue
+		}
+		a := sqliteAtivo{
+			Código:       atv.Código,
+			Data:         atv.Data.String(),
+			Abertura:     atv.Abertura.Valor,
+			Máxima:       atv.Máxima.Valor,
+			Mínima:       atv.Mínima.Valor,
+			Encerramento: atv.Encerramento.Valor,
+			Volume:       atv.Volume,
+			Moeda:        atv.Encerramento.Moeda,
+
+			ISIN:           atv.ISIN,
+			NomeResumido:   atv.NomeResumido,
+			Especificação:  atv.Especificação,
+			BDI:            atv.BDI,
+			Mercado:        atv.Mercado,
+			Médio:          atv.Médio.Valor,
+			MelhorCompra:   atv.MelhorCompra.Valor,
+			MelhorVenda:    atv.MelhorVenda.Valor,
+			Negócios:       atv.Negócios,
+			Quantidade:     atv.Quantidade,
+			FatorCotação:   atv.FatorCotação,
+			PreçoExercício: atv.PreçoExercício.Valor,
+		}
+		if !time.Time(atv.Vencimento).IsZero() {
+			a.Vencimento = atv.Vencimento.String()
+		}
+		if _, err := stmt.ExecContext(ctx, a); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+type sqliteEvento struct {
+	Código string  `db:"codigo"`
+	DataEx string  `db:"data_ex"`
+	Tipo   string  `db:"tipo"`
+	Fator  float64 `db:"fator"`
+	Valor  float64 `db:"valor"`
+}
+
+// Eventos retorna os eventos corporativos do ativo com o "código", em ordem
+// crescente de data ex.
+func (s *Sqlite) Eventos(ctx context.Context, código string) ([]cotação.Evento, error) {
+	var registros []sqliteEvento
+	err := s.db.SelectContext(ctx, &registros,
+		`SELECT * FROM eventos WHERE codigo=? ORDER BY data_ex`, código)
+	if err != nil {
+		return nil, err
+	}
+
+	eventos := make([]cotação.Evento, 0, len(registros))
+	for _, r := range registros {
+		dataEx, err := rapina.NovaData(r.DataEx)
+		if err != nil {
+			return nil, err
+		}
+		eventos = append(eventos, cotação.Evento{
+			Código: r.Código,
+			DataEx: dataEx,
+			Tipo:   cotação.TipoEvento(r.Tipo),
+			Fator:  r.Fator,
+			Valor:  r.Valor,
+		})
+	}
+	return eventos, nil
+}
+
+// SalvarEventos salva os eventos numa única transação, substituindo os
+// eventos já existentes do mesmo ativo, data ex e tipo.
+func (s *Sqlite) SalvarEventos(ctx context.Context, eventos []cotação.Evento) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range eventos {
+		_, err := tx.NamedExecContext(ctx, `INSERT OR REPLACE INTO eventos
+			(codigo, data_ex, tipo, fator, valor)
+			VALUES
+			(:codigo, :data_ex, :tipo, :fator, :valor)`,
+			sqliteEvento{
+				Código: e.Código,
+				DataEx: e.DataEx.String(),
+				Tipo:   string(e.Tipo),
+				Fator:  e.Fator,
+				Valor:  e.Valor,
+			})
+		if err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Alterações no esquema devem ser feitas *apenas* adicionando uma nova
+// migração ao final da lista.
+var migrações = []ext.Migration{
+	{
+		Version: 1,
+		Descr:   "criar tabela cotacoes",
+		Up: []string{
+			`CREATE TABLE IF NOT EXISTS cotacoes (
+				codigo       VARCHAR NOT NULL,
+				data         VARCHAR NOT NULL,
+				abertura     REAL NOT NULL,
+				maxima       REAL NOT NULL,
+				minima       REAL NOT NULL,
+				encerramento REAL NOT NULL,
+				volume       REAL NOT NULL,
+				moeda        VARCHAR,
+				PRIMARY KEY (codigo, data)
+			)`,
+		},
+		Down: []string{
+			`DROP TABLE IF EXISTS cotacoes`,
+		},
+	},
+	{
+		Version: 2,
+		Descr:   "campos completos do COTAHIST",
+		Up: []string{
+			`ALTER TABLE cotacoes ADD COLUMN isin VARCHAR NOT NULL DEFAULT ''`,
+			`ALTER TABLE cotacoes ADD COLUMN nome_resumido VARCHAR NOT NULL DEFAULT ''`,
+			`ALTER TABLE cotacoes ADD COLUMN especificacao VARCHAR NOT NULL DEFAULT ''`,
+			`ALTER TABLE cotacoes ADD COLUMN bdi VARCHAR NOT NULL DEFAULT ''`,
+			`ALTER TABLE cotacoes ADD COLUMN mercado VARCHAR NOT NULL DEFAULT ''`,
+			`ALTER TABLE cotacoes ADD COLUMN medio REAL NOT NULL DEFAULT 0`,
+			`ALTER TABLE cotacoes ADD COLUMN melhor_compra REAL NOT NULL DEFAULT 0`,
+			`ALTER TABLE cotacoes ADD COLUMN melhor_venda REAL NOT NULL DEFAULT 0`,
+			`ALTER TABLE cotacoes ADD COLUMN negocios INTEGER NOT NULL DEFAULT 0`,
+			`ALTER TABLE cotacoes ADD COLUMN quantidade INTEGER NOT NULL DEFAULT 0`,
+			`ALTER TABLE cotacoes ADD COLUMN fator_cotacao INTEGER NOT NULL DEFAULT 1`,
+			`ALTER TABLE cotacoes ADD COLUMN preco_exercicio REAL NOT NULL DEFAULT 0`,
+			`ALTER TABLE cotacoes ADD COLUMN vencimento VARCHAR NOT NULL DEFAULT ''`,
+		},
+		Down: []string{
+			`ALTER TABLE cotacoes DROP COLUMN isin`,
+			`ALTER TABLE cotacoes DROP COLUMN nome_resumido`,
+			`ALTER TABLE cotacoes DROP COLUMN especificacao`,
+			`ALTER TABLE cotacoes DROP COLUMN bdi`,
+			`ALTER TABLE cotacoes DROP COLUMN mercado`,
+			`ALTER TABLE cotacoes DROP COLUMN medio`,
+			`ALTER TABLE cotacoes DROP COLUMN melhor_compra`,
+			`ALTER TABLE cotacoes DROP COLUMN melhor_venda`,
+			`ALTER TABLE cotacoes DROP COLUMN negocios`,
+			`ALTER TABLE cotacoes DROP COLUMN quantidade`,
+			`ALTER TABLE cotacoes DROP COLUMN fator_cotacao`,
+			`ALTER TABLE cotacoes DROP COLUMN preco_exercicio`,
+			`ALTER TABLE cotacoes DROP COLUMN vencimento`,
+		},
+	},
+	{
+		Version: 3,
+		Descr:   "criar tabela eventos",
+		Up: []string{
+			`CREATE TABLE IF NOT EXISTS eventos (
+				codigo  VARCHAR NOT NULL,
+				data_ex VARCHAR NOT NULL,
+				tipo    VARCHAR NOT NULL,
+				fator   REAL NOT NULL DEFAULT 0,
+				valor   REAL NOT NULL DEFAULT 0,
+				PRIMARY KEY (codigo, data_ex, tipo)
+			)`,
+		},
+		Down: []string{
+			`DROP TABLE IF EXISTS eventos`,
+		},
+	},
+}
+
+const móduloCotação = "cotacao"
+
+// Migrar move o esquema das cotações para a "versão" informada (negativo =
+// última versão). Com simular == true apenas retorna os passos.
+func Migrar(db *sqlx.DB, versão int, simular bool) ([]ext.MigrationStep, error) {
+	return ext.Migrate(db, móduloCotação, migrações, versão, simular)
+}
+
+// VersãoEsquema retorna a versão atual do esquema das cotações.
+func VersãoEsquema(db *sqlx.DB) (int, error) {
+	return ext.SchemaVersion(db, móduloCotação)
+}
+
+// VersãoAplicada retorna a versão atual do esquema das cotações sem alterar
+// o banco de dados (0 se nenhuma migração foi aplicada).
+func VersãoAplicada(db *sqlx.DB) (int, error) {
+	return ext.AppliedVersion(db, móduloCotação)
+}
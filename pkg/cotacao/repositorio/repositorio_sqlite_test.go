@@ -0,0 +1,113 @@
+// SPDX-FileCopyrightText: 2023 Adriano Prado <dev@dude333.com>
+//
+// SPDX-License-Identifier: MIT
+
+package repositorio_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+
+	rapina "github.com/dude333/rapinav2"
+	cotação "github.com/dude333/rapinav2/pkg/cotacao"
+	repositório "github.com/dude333/rapinav2/pkg/cotacao/repositorio"
+)
+
+func TestSqlite_Cotações(t *testing.T) {
+	db := sqlx.MustConnect("sqlite3", ":memory:")
+	db.SetMaxOpenConns(1)
+
+	s, err := repositório.NovoSqlite(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const m = "R$"
+	var ativos []*cotação.Ativo
+	for dia := 1; dia <= 10; dia++ {
+		d, _ := rapina.NovaData(fmt.Sprintf("2021-10-%02d", dia))
+		for _, código := range []string{"TEST3", "TEST4"} {
+			ativos = append(ativos, &cotação.Ativo{
+				Código:       código,
+				Data:         d,
+				Abertura:     rapina.Dinheiro{Valor: rapina.DecimalDeFloat(1), Moeda: m},
+				Máxima:       rapina.Dinheiro{Valor: rapina.DecimalDeFloat(2), Moeda: m},
+				Mínima:       rapina.Dinheiro{Valor: rapina.DecimalDeFloat(0.8), Moeda: m},
+				Encerramento: rapina.Dinheiro{Valor: rapina.DecimalDeFloat(float64(dia)), Moeda: m},
+				Volume:       1000000,
+			})
+		}
+	}
+
+	ctx := context.Background()
+	if err := s.SalvarLote(ctx, ativos); err != nil {
+		t.Fatal(err)
+	}
+	// Salvar novamente deve substituir os registros existentes
+	if err := s.Salvar(ctx, ativos[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	d5, _ := rapina.NovaData("2021-10-05")
+	atv, err := s.Cotação(ctx, "TEST4", d5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if atv.Código != "TEST4" || atv.Encerramento.Valor != rapina.DecimalDeFloat(5) || atv.Data.String() != "2021-10-05" {
+		t.Errorf("Cotação() = %v", atv)
+	}
+
+	d1, _ := rapina.NovaData("2021-10-01")
+	if _, err := s.Cotação(ctx, "XXXX3", d1); err != repositório.ErrAtivoNãoEncontrado {
+		t.Errorf("Cotação() error = %v, want %v", err, repositório.ErrAtivoNãoEncontrado)
+	}
+
+	d3, _ := rapina.NovaData("2021-10-03")
+	d7, _ := rapina.NovaData("2021-10-07")
+	cotações, err := s.Cotações(ctx, "TEST3", d3, d7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cotações) != 5 {
+		t.Fatalf("Cotações() = %d registros, want 5", len(cotações))
+	}
+	if cotações[0].Data.String() != "2021-10-03" || cotações[4].Data.String() != "2021-10-07" {
+		t.Errorf("Cotações() = %v ... %v", cotações[0].Data, cotações[4].Data)
+	}
+
+	// Campos adicionais do COTAHIST
+	venc, _ := rapina.NovaData("2021-11-19")
+	opção := &cotação.Ativo{
+		Código:         "TESTK100",
+		Data:           d1,
+		Encerramento:   rapina.Dinheiro{Valor: rapina.DecimalDeFloat(0.5), Moeda: m},
+		ISIN:           "BRTESTACNOR0",
+		Especificação:  "ON",
+		BDI:            "78",
+		Mercado:        "070",
+		Negócios:       12,
+		Quantidade:     3400,
+		FatorCotação:   1,
+		PreçoExercício: rapina.Dinheiro{Valor: rapina.DecimalDeFloat(10.5), Moeda: m},
+		Vencimento:     venc,
+	}
+	if err := s.Salvar(ctx, opção); err != nil {
+		t.Fatal(err)
+	}
+	atv, err = s.Cotação(ctx, "TESTK100", d1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if atv.ISIN != opção.ISIN || atv.Mercado != "070" || atv.Quantidade != 3400 ||
+		atv.PreçoExercício.Valor != rapina.DecimalDeFloat(10.5) || atv.Vencimento.String() != "2021-11-19" {
+		t.Errorf("Cotação() = %+v", atv)
+	}
+	if !time.Time(cotações[0].Vencimento).IsZero() {
+		t.Errorf("Vencimento = %v, want zero", cotações[0].Vencimento)
+	}
+}
@@ -31,6 +31,13 @@ type Importação interface {
 	Importar(ctx context.Context, dia rapina.Data) <-chan domínio.Resultado
 }
 
+// ImportaçãoSérie é implementada pelos repositórios que disponibilizam as
+// séries históricas mensais e anuais.
+type ImportaçãoSérie interface {
+	ImportarAno(ctx context.Context, ano int) <-chan domínio.Resultado
+	ImportarMês(ctx context.Context, ano, mês int) <-chan domínio.Resultado
+}
+
 type Leitura interface {
 	Cotação(ctx context.Context, código string, data rapina.Data) (*domínio.Ativo, error)
 }
@@ -44,6 +51,25 @@ type LeituraEscrita interface {
 	Escrita
 }
 
+// EscritaLote é implementada pelos repositórios que conseguem salvar várias
+// cotações de uma só vez (ex.: numa única transação).
+type EscritaLote interface {
+	SalvarLote(ctx context.Context, ativos []*domínio.Ativo) error
+}
+
+// LeituraSérie é implementada pelos repositórios que retornam a série de
+// cotações de um ativo num período.
+type LeituraSérie interface {
+	Cotações(ctx context.Context, código string, de, até rapina.Data) ([]domínio.Ativo, error)
+}
+
+// LeituraEscritaEventos é implementada pelos repositórios que armazenam os
+// eventos corporativos usados no ajuste das cotações.
+type LeituraEscritaEventos interface {
+	Eventos(ctx context.Context, código string) ([]domínio.Evento, error)
+	SalvarEventos(ctx context.Context, eventos []domínio.Evento) error
+}
+
 // Serviço é um serviço que implementa Importação e busca
 // cotações de um Ativo em vários repositórios (API e BD).
 type Serviço struct {
@@ -97,6 +123,7 @@ func (a *Serviço) cotaçãoAPI(código string, dia rapina.Data) (*domínio.Ativ
 
 	// Tentativa de coletar a cotação usando vários servidores de API
 	for i := range a.api {
+		var ativos []*domínio.Ativo
 		// result retorna o registro após a leitura de cada linha
 		// do arquivo importado
 		for result := range a.api[i].Importar(ctx, dia) {
@@ -106,10 +133,9 @@ func (a *Serviço) cotaçãoAPI(código string, dia rapina.Data) (*domínio.Ativ
 			if result.Ativo.Código == código {
 				atv = result.Ativo
 			}
-			if a.bd != nil {
-				_ = a.bd.Salvar(ctx, result.Ativo)
-			}
+			ativos = append(ativos, result.Ativo)
 		}
+		_ = a.salvar(ctx, ativos)
 		// Finaliza se ativo já tiver sido encontrado
 		if atv != nil {
 			break
@@ -122,3 +148,195 @@ func (a *Serviço) cotaçãoAPI(código string, dia rapina.Data) (*domínio.Ativ
 
 	return atv, nil
 }
+
+// tamanhoLote é o número de cotações salvas por transação na importação das
+// séries históricas, para que os arquivos anuais não sejam carregados
+// inteiros na memória.
+const tamanhoLote = 10000
+
+// ImportarAno importa as cotações de todos os ativos no ano informado e as
+// salva no banco de dados, retornando o número de cotações salvas.
+func (a *Serviço) ImportarAno(ano int) (int, error) {
+	return a.importarSérie(func(ctx context.Context, api ImportaçãoSérie) <-chan domínio.Resultado {
+		return api.ImportarAno(ctx, ano)
+	})
+}
+
+// ImportarMês importa as cotações de todos os ativos no mês informado e as
+// salva no banco de dados, retornando o número de cotações salvas.
+func (a *Serviço) ImportarMês(ano, mês int) (int, error) {
+	return a.importarSérie(func(ctx context.Context, api ImportaçãoSérie) <-chan domínio.Resultado {
+		return api.ImportarMês(ctx, ano, mês)
+	})
+}
+
+// importarSérie tenta importar a série histórica de cada um dos repositórios
+// de API, até que um deles funcione, salvando as cotações em lotes.
+func (a *Serviço) importarSérie(importar func(context.Context, ImportaçãoSérie) <-chan domínio.Resultado) (int, error) {
+	if a.bd == nil {
+		return 0, ErrRepositórioInválido
+	}
+
+	err := ErrRepositórioInválido
+	for i := range a.api {
+		api, ok := a.api[i].(ImportaçãoSérie)
+		if !ok {
+			continue
+		}
+
+		var n int
+		n, err = a.salvarSérie(importar(context.Background(), api))
+		if err == nil {
+			return n, nil
+		}
+	}
+
+	return 0, err
+}
+
+// salvarSérie salva as cotações recebidas em lotes, retornando o número de
+// cotações dos lotes gravados com sucesso.
+func (a *Serviço) salvarSérie(results <-chan domínio.Resultado) (int, error) {
+	ctx := context.Background()
+	n := 0
+	lote := make([]*domínio.Ativo, 0, tamanhoLote)
+
+	var err error
+	for result := range results {
+		if err != nil {
+			continue // esvazia o canal
+		}
+		if result.Error != nil {
+			err = result.Error
+			continue
+		}
+		lote = append(lote, result.Ativo)
+		if len(lote) == tamanhoLote {
+			if err = a.salvar(ctx, lote); err == nil {
+				n += len(lote)
+			}
+			lote = lote[:0]
+		}
+	}
+	if err != nil {
+		return n, err
+	}
+
+	if err = a.salvar(ctx, lote); err != nil {
+		return n, err
+	}
+	return n + len(lote), nil
+}
+
+// salvar armazena as cotações no banco de dados, de uma só vez caso o
+// repositório implemente EscritaLote.
+func (a *Serviço) salvar(ctx context.Context, ativos []*domínio.Ativo) error {
+	if a.bd == nil || len(ativos) == 0 {
+		return nil
+	}
+	if bd, ok := a.bd.(EscritaLote); ok {
+		return bd.SalvarLote(ctx, ativos)
+	}
+	for _, atv := range ativos {
+		if err := a.bd.Salvar(ctx, atv); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Cotações retorna as cotações do ativo com o "código" entre as datas "de" e
+// "até", em ordem crescente de data, com os preços ajustados pelos eventos
+// corporativos armazenados no banco de dados conforme o "ajuste".
+func (a *Serviço) Cotações(código string, de, até rapina.Data, ajuste domínio.Ajuste) ([]domínio.Ativo, error) {
+	bd, ok := a.bd.(LeituraSérie)
+	if !ok {
+		return nil, ErrRepositórioInválido
+	}
+	ctx := context.Background()
+
+	if ajuste == domínio.SemAjuste {
+		return bd.Cotações(ctx, código, de, até)
+	}
+
+	eventos, err := a.eventos(ctx, código)
+	if err != nil {
+		return nil, err
+	}
+
+	// O ajuste é feito a partir da cotação mais recente, e os proventos
+	// dependem do fechamento anterior à data ex, por isso a série é lida
+	// até o fim.
+	ativos, err := bd.Cotações(ctx, código, de, _últimaData)
+	if err != nil {
+		return nil, err
+	}
+	ativos = domínio.Ajustar(ativos, eventos, ajuste)
+
+	for i := range ativos {
+		if time.Time(ativos[i].Data).After(time.Time(até)) {
+			return ativos[:i], nil
+		}
+	}
+	return ativos, nil
+}
+
+var _últimaData = rapina.Data(time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC))
+
+func (a *Serviço) eventos(ctx context.Context, código string) ([]domínio.Evento, error) {
+	bd, ok := a.bd.(LeituraEscritaEventos)
+	if !ok {
+		return nil, ErrRepositórioInválido
+	}
+	return bd.Eventos(ctx, código)
+}
+
+// SalvarEventos valida e armazena os eventos corporativos.
+func (a *Serviço) SalvarEventos(eventos []domínio.Evento) error {
+	bd, ok := a.bd.(LeituraEscritaEventos)
+	if !ok {
+		return ErrRepositórioInválido
+	}
+	for _, e := range eventos {
+		if err := e.Validar(); err != nil {
+			return err
+		}
+	}
+	return bd.SalvarEventos(context.Background(), eventos)
+}
+
+// DetectarEventos procura desdobramentos e grupamentos na série completa de
+// cotações do ativo, retornando os que ainda não estiverem cadastrados (mesmo
+// tipo e data ex). Os eventos detectados são apenas candidatos, estimados
+// pelos saltos de preço, e não são salvos: após revisados, podem ser salvos
+// com SalvarEventos.
+func (a *Serviço) DetectarEventos(código string) ([]domínio.Evento, error) {
+	bd, ok := a.bd.(LeituraSérie)
+	if !ok {
+		return nil, ErrRepositórioInválido
+	}
+	ctx := context.Background()
+
+	existentes, err := a.eventos(ctx, código)
+	if err != nil {
+		return nil, err
+	}
+	cadastrado := make(map[string]bool, len(existentes))
+	for _, e := range existentes {
+		cadastrado[e.DataEx.String()+string(e.Tipo)] = true
+	}
+
+	ativos, err := bd.Cotações(ctx, código, rapina.Data{}, _últimaData)
+	if err != nil {
+		return nil, err
+	}
+
+	var novos []domínio.Evento
+	for _, e := range domínio.DetectarEventos(ativos) {
+		if !cadastrado[e.DataEx.String()+string(e.Tipo)] {
+			novos = append(novos, e)
+		}
+	}
+
+	return novos, nil
+}
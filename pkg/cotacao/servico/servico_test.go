@@ -6,6 +6,7 @@ package serviço
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"reflect"
 	"testing"
@@ -30,10 +31,10 @@ func init() {
 	_ativo1 = cotação.Ativo{
 		Código:       "TEST3",
 		Data:         d,
-		Abertura:     rapina.Dinheiro{Valor: 1, Moeda: m},
-		Máxima:       rapina.Dinheiro{Valor: 2, Moeda: m},
-		Mínima:       rapina.Dinheiro{Valor: 0.8, Moeda: m},
-		Encerramento: rapina.Dinheiro{Valor: 1.6, Moeda: m},
+		Abertura:     rapina.Dinheiro{Valor: rapina.DecimalDeFloat(1), Moeda: m},
+		Máxima:       rapina.Dinheiro{Valor: rapina.DecimalDeFloat(2), Moeda: m},
+		Mínima:       rapina.Dinheiro{Valor: rapina.DecimalDeFloat(0.8), Moeda: m},
+		Encerramento: rapina.Dinheiro{Valor: rapina.DecimalDeFloat(1.6), Moeda: m},
 		Volume:       1000000,
 	}
 
@@ -41,10 +42,10 @@ func init() {
 		r := cotação.Ativo{
 			Código:       fmt.Sprintf("TEST%d", i),
 			Data:         d,
-			Abertura:     rapina.Dinheiro{Valor: 1, Moeda: m},
-			Máxima:       rapina.Dinheiro{Valor: 2, Moeda: m},
-			Mínima:       rapina.Dinheiro{Valor: 0.8, Moeda: m},
-			Encerramento: rapina.Dinheiro{Valor: 1.6, Moeda: m},
+			Abertura:     rapina.Dinheiro{Valor: rapina.DecimalDeFloat(1), Moeda: m},
+			Máxima:       rapina.Dinheiro{Valor: rapina.DecimalDeFloat(2), Moeda: m},
+			Mínima:       rapina.Dinheiro{Valor: rapina.DecimalDeFloat(0.8), Moeda: m},
+			Encerramento: rapina.Dinheiro{Valor: rapina.DecimalDeFloat(1.6), Moeda: m},
 			Volume:       1000000,
 		}
 		_exemplos = append(_exemplos, &r)
@@ -224,3 +225,164 @@ func TestServiçoAtivo_Cotação(t *testing.T) {
 		})
 	}
 }
+
+// bdMockLote implementa LeituraEscrita e EscritaLote
+type bdMockLote struct {
+	bdMock
+	lotes int
+	erro  error // erro retornado por SalvarLote
+}
+
+func (r *bdMockLote) SalvarLote(ctx context.Context, ativos []*cotação.Ativo) error {
+	r.lotes++
+	if r.erro != nil {
+		return r.erro
+	}
+	for _, ativo := range ativos {
+		_ = r.Salvar(ctx, ativo)
+	}
+	return nil
+}
+
+func TestServiçoAtivo_CotaçãoSalvarLote(t *testing.T) {
+	d1, _ := rapina.NovaData("2021-10-09")
+	bd := &bdMockLote{}
+	s := NovoServiço([]Importação{&apiMockOk{}}, bd)
+
+	if _, err := s.Cotação("TEST5", d1); err != nil {
+		t.Fatal(err)
+	}
+	if bd.lotes != 1 {
+		t.Errorf("SalvarLote() chamado %d vezes, want 1", bd.lotes)
+	}
+}
+
+// apiMockSérie implementa Importação e ImportaçãoSérie
+type apiMockSérie struct {
+	apiMockOk
+}
+
+func (r *apiMockSérie) ImportarAno(ctx context.Context, ano int) <-chan cotação.Resultado {
+	return r.Importar(ctx, rapina.Data{})
+}
+
+func (r *apiMockSérie) ImportarMês(ctx context.Context, ano, mês int) <-chan cotação.Resultado {
+	return r.Importar(ctx, rapina.Data{})
+}
+
+func TestServiço_ImportarAno(t *testing.T) {
+	bd := &bdMockLote{}
+	s := NovoServiço([]Importação{&apiMockOk{}, &apiMockSérie{}}, bd)
+
+	n, err := s.ImportarAno(2021)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(_exemplos) || bd.lotes != 1 {
+		t.Errorf("ImportarAno() = %d cotações em %d lotes, want %d em 1", n, bd.lotes, len(_exemplos))
+	}
+
+	s = NovoServiço([]Importação{&apiMockOk{}}, bd)
+	if _, err := s.ImportarMês(2021, 1); err != ErrRepositórioInválido {
+		t.Errorf("ImportarMês() error = %v, want %v", err, ErrRepositórioInválido)
+	}
+}
+
+func TestServiço_salvarSérie_falha(t *testing.T) {
+	errGravação := errors.New("falha na gravação")
+	bd := &bdMockLote{erro: errGravação}
+	s := NovoServiço(nil, bd)
+
+	n, err := s.salvarSérie((&apiMockOk{}).Importar(context.Background(), rapina.Data{}))
+	if n != 0 || !errors.Is(err, errGravação) {
+		t.Errorf("salvarSérie() = %d, %v, want 0, %v", n, err, errGravação)
+	}
+}
+
+// bdMockEventos implementa LeituraEscrita, LeituraSérie e
+// LeituraEscritaEventos
+type bdMockEventos struct {
+	bdMock
+	série   []cotação.Ativo
+	eventos []cotação.Evento
+}
+
+func (r *bdMockEventos) Cotações(ctx context.Context, código string, de, até rapina.Data) ([]cotação.Ativo, error) {
+	var ativos []cotação.Ativo
+	for _, a := range r.série {
+		if a.Código == código && !time.Time(a.Data).Before(time.Time(de)) && !time.Time(a.Data).After(time.Time(até)) {
+			ativos = append(ativos, a)
+		}
+	}
+	return ativos, nil
+}
+
+func (r *bdMockEventos) Eventos(ctx context.Context, código string) ([]cotação.Evento, error) {
+	return r.eventos, nil
+}
+
+func (r *bdMockEventos) SalvarEventos(ctx context.Context, eventos []cotação.Evento) error {
+	r.eventos = append(r.eventos, eventos...)
+	return nil
+}
+
+func TestServiço_CotaçõesAjustadas(t *testing.T) {
+	bd := &bdMockEventos{}
+	for i, fechamento := range []float64{40, 42, 21, 22} {
+		d, _ := rapina.NovaData(fmt.Sprintf("2021-10-%02d", i+11))
+		bd.série = append(bd.série, cotação.Ativo{
+			Código:       "TEST3",
+			Data:         d,
+			Abertura:     rapina.Dinheiro{Valor: rapina.DecimalDeFloat(fechamento), Moeda: "R$"},
+			Encerramento: rapina.Dinheiro{Valor: rapina.DecimalDeFloat(fechamento), Moeda: "R$"},
+		})
+	}
+	s := NovoServiço(nil, bd)
+
+	eventos, err := s.DetectarEventos("TEST3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(eventos) != 1 || eventos[0].Fator != 2 {
+		t.Fatalf("DetectarEventos() = %+v", eventos)
+	}
+	// Os candidatos só são gravados após revisados
+	if len(bd.eventos) != 0 {
+		t.Fatalf("DetectarEventos() salvou %+v", bd.eventos)
+	}
+	if err := s.SalvarEventos(eventos); err != nil {
+		t.Fatal(err)
+	}
+	// Eventos já cadastrados não são detectados de novo
+	if eventos, _ = s.DetectarEventos("TEST3"); len(eventos) != 0 {
+		t.Errorf("DetectarEventos() = %+v, want nenhum", eventos)
+	}
+
+	de, _ := rapina.NovaData("2021-10-11")
+	até, _ := rapina.NovaData("2021-10-13")
+	tests := []struct {
+		ajuste cotação.Ajuste
+		want   []float64
+	}{
+		{cotação.SemAjuste, []float64{40, 42, 21}},
+		{cotação.AjusteDesdobramento, []float64{20, 21, 21}},
+	}
+	for _, tt := range tests {
+		ativos, err := s.Cotações("TEST3", de, até, tt.ajuste)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []float64
+		for _, a := range ativos {
+			got = append(got, a.Encerramento.Valor.Float64())
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Cotações(%d) = %v, want %v", tt.ajuste, got, tt.want)
+		}
+	}
+
+	s = NovoServiço(nil, &bdMock{})
+	if _, err := s.Cotações("TEST3", de, até, cotação.SemAjuste); err != ErrRepositórioInválido {
+		t.Errorf("Cotações() error = %v, want %v", err, ErrRepositórioInválido)
+	}
+}
@@ -91,7 +91,13 @@ func (x *Excel) SetNumber(size float64, bold bool, format string) (int, error) {
 	})
 }
 
+// PrintCell writes the value and sets the cell style. Exact decimal values
+// (e.g. rapina.Decimal) are converted to float64, the number type used by
+// Excel, only here.
 func (x *Excel) PrintCell(row, col, style int, value interface{}) {
+	if v, ok := value.(interface{ Float64() float64 }); ok {
+		value = v.Float64()
+	}
 	_ = x.file.SetCellValue(x.sheetName, cell(row, col), value)
 	_ = x.file.SetCellStyle(x.sheetName, cell(row, col), cell(row, col), style)
 }
@@ -0,0 +1,353 @@
+// SPDX-FileCopyrightText: 2023 Adriano Prado <dev@dude333.com>
+//
+// SPDX-License-Identifier: MIT
+
+package infra
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dude333/rapinav2/pkg/progress"
+)
+
+// Cache is a persistent cache of downloaded files (e.g. the CVM and B3 zip
+// files), so they are not downloaded again when re-importing the data.
+//
+// The files are stored by content (objects/<sha256>) and indexed by URL
+// (entries/<sha256 of the URL>.json), together with the validators
+// (ETag/Last-Modified) used to check with a conditional GET if the cached
+// file is up to date. When the cache exceeds its maximum size, the least
+// recently used files are removed, except the ones returned by Fetch since
+// the cache was opened, which may still be in use (the cache may then stay
+// above its maximum size until it is opened again).
+type Cache struct {
+	dir     string
+	maxSize int64 // bytes, 0 = unlimited
+
+	mu     sync.Mutex      // protects the index (entries and objects)
+	pinned map[string]bool // hashes of the files returned by Fetch
+}
+
+// CacheEntry describes a cached file.
+type CacheEntry struct {
+	URL          string    `json:"url"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	Hash         string    `json:"sha256"`
+	Size         int64     `json:"size"`
+	Downloaded   time.Time `json:"downloaded"`
+	Used         time.Time `json:"used"`
+}
+
+// NewCache opens (or creates) the cache in dir. maxSize is the maximum size
+// of the cached files in bytes (0 = unlimited).
+func NewCache(dir string, maxSize int64) (*Cache, error) {
+	c := &Cache{dir: dir, maxSize: maxSize, pinned: make(map[string]bool)}
+	for _, d := range []string{c.objectsDir(), c.entriesDir(), c.tmpDir()} {
+		if err := os.MkdirAll(d, os.ModePerm); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// Dir returns the cache directory.
+func (c *Cache) Dir() string { return c.dir }
+
+func (c *Cache) objectsDir() string { return filepath.Join(c.dir, "objects") }
+func (c *Cache) entriesDir() string { return filepath.Join(c.dir, "entries") }
+func (c *Cache) tmpDir() string     { return filepath.Join(c.dir, "tmp") }
+
+// Path returns the path of the cached file.
+func (c *Cache) Path(e CacheEntry) string {
+	return filepath.Join(c.objectsDir(), e.Hash)
+}
+
+func urlKey(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return hex.EncodeToString(sum[:])
+}
+
+func (c *Cache) entryPath(url string) string {
+	return filepath.Join(c.entriesDir(), urlKey(url)+".json")
+}
+
+// Fetch returns the path of the cached file downloaded from url, checking
+// first with the server if it changed. If the server cannot be reached, the
+// cached file (if any) is used, unless ctx was canceled.
+func (c *Cache) Fetch(ctx context.Context, d *Downloader, url string) (string, error) {
+	c.mu.Lock()
+	e, cached := c.entry(url)
+	if cached {
+		if _, err := os.Stat(c.Path(e)); err != nil {
+			cached = false
+		}
+	}
+	if cached {
+		// keeps the cached file while checking with the server, as it may
+		// be returned below
+		c.pinned[e.Hash] = true
+	}
+	c.mu.Unlock()
+
+	var cond fileMeta
+	if cached {
+		cond = fileMeta{URL: url, ETag: e.ETag, LastModified: e.LastModified}
+	}
+
+	tmp := filepath.Join(c.tmpDir(), urlKey(url))
+	m, modified, err := d.fetch(ctx, url, tmp, cond)
+	if err != nil {
+		if cached && ctx.Err() == nil {
+			if d.verbose {
+				progress.Warning("%v; usando a cópia de %s", err, e.Downloaded.Format("2006-01-02"))
+			}
+			return c.Path(e), c.touch(url)
+		}
+		return "", err
+	}
+	if !modified {
+		return c.Path(e), c.touch(url)
+	}
+
+	hash, size, err := sha256File(tmp)
+	if err != nil {
+		return "", err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if cached && e.Hash != hash {
+		delete(c.pinned, e.Hash)
+	}
+	c.pinned[hash] = true
+
+	now := time.Now()
+	novo := CacheEntry{
+		URL:          url,
+		ETag:         m.ETag,
+		LastModified: m.LastModified,
+		Hash:         hash,
+		Size:         size,
+		Downloaded:   now,
+		Used:         now,
+	}
+	if err := os.Rename(tmp, c.Path(novo)); err != nil {
+		return "", err
+	}
+	if err := c.writeEntry(novo); err != nil {
+		return "", err
+	}
+	if err := c.evict(url); err != nil {
+		return "", err
+	}
+
+	return c.Path(novo), nil
+}
+
+// Entries returns the cached files, sorted by URL.
+func (c *Cache) Entries() ([]CacheEntry, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.entries()
+}
+
+// Clear removes all cached files, returning the number of files and bytes
+// removed.
+func (c *Cache) Clear() (int, int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entries, err := c.entries()
+	if err != nil {
+		return 0, 0, err
+	}
+	var size int64
+	for _, e := range entries {
+		size += e.Size
+	}
+	for _, d := range []string{c.objectsDir(), c.entriesDir(), c.tmpDir()} {
+		if err := os.RemoveAll(d); err != nil {
+			return 0, 0, err
+		}
+		if err := os.MkdirAll(d, os.ModePerm); err != nil {
+			return 0, 0, err
+		}
+	}
+	return len(entries), size, nil
+}
+
+// Verify checks the hash of the cached files, removing the missing or
+// corrupted ones (returned), and removes the files not indexed.
+func (c *Cache) Verify() ([]CacheEntry, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entries, err := c.entries()
+	if err != nil {
+		return nil, err
+	}
+
+	var bad []CacheEntry
+	for _, e := range entries {
+		hash, size, err := sha256File(c.Path(e))
+		if err == nil && hash == e.Hash && size == e.Size {
+			continue
+		}
+		bad = append(bad, e)
+		if err := os.Remove(c.entryPath(e.URL)); err != nil {
+			return bad, err
+		}
+		if err := os.Remove(c.Path(e)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return bad, err
+		}
+		delete(c.pinned, e.Hash)
+	}
+
+	return bad, c.removeOrphans()
+}
+
+// entry returns the cache entry of url.
+func (c *Cache) entry(url string) (CacheEntry, bool) {
+	var e CacheEntry
+	b, err := os.ReadFile(c.entryPath(url))
+	if err != nil || json.Unmarshal(b, &e) != nil || e.URL != url {
+		return CacheEntry{}, false
+	}
+	return e, true
+}
+
+func (c *Cache) writeEntry(e CacheEntry) error {
+	b, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := c.entryPath(e.URL) + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.entryPath(e.URL))
+}
+
+// touch updates the last time the cached file of url was used.
+func (c *Cache) touch(url string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entry(url)
+	if !ok {
+		return nil
+	}
+	e.Used = time.Now()
+	return c.writeEntry(e)
+}
+
+func (c *Cache) entries() ([]CacheEntry, error) {
+	files, err := filepath.Glob(filepath.Join(c.entriesDir(), "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]CacheEntry, 0, len(files))
+	for _, f := range files {
+		var e CacheEntry
+		b, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(b, &e); err != nil {
+			return nil, fmt.Errorf("%s: %w", f, err)
+		}
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].URL < entries[j].URL })
+	return entries, nil
+}
+
+// evict removes the least recently used files (except the one from url and
+// the ones returned by Fetch) until the cache fits in maxSize, and the files
+// no longer indexed.
+func (c *Cache) evict(url string) error {
+	entries, err := c.entries()
+	if err != nil {
+		return err
+	}
+
+	if c.maxSize > 0 {
+		sort.Slice(entries, func(i, j int) bool { return entries[i].Used.Before(entries[j].Used) })
+		var total int64
+		for _, e := range entries {
+			total += e.Size
+		}
+		for _, e := range entries {
+			if total <= c.maxSize {
+				break
+			}
+			if e.URL == url || c.pinned[e.Hash] {
+				continue
+			}
+			if err := os.Remove(c.entryPath(e.URL)); err != nil {
+				return err
+			}
+			total -= e.Size
+		}
+	}
+
+	return c.removeOrphans()
+}
+
+// removeOrphans removes the files that are not indexed by any entry nor
+// returned by Fetch.
+func (c *Cache) removeOrphans() error {
+	entries, err := c.entries()
+	if err != nil {
+		return err
+	}
+	used := make(map[string]bool, len(entries)+len(c.pinned))
+	for hash := range c.pinned {
+		used[hash] = true
+	}
+	for _, e := range entries {
+		used[e.Hash] = true
+	}
+
+	objects, err := os.ReadDir(c.objectsDir())
+	if err != nil {
+		return err
+	}
+	for _, o := range objects {
+		if used[o.Name()] {
+			continue
+		}
+		err := os.Remove(filepath.Join(c.objectsDir(), o.Name()))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+func sha256File(name string) (string, int64, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), size, nil
+}
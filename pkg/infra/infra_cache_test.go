@@ -0,0 +1,174 @@
+// SPDX-FileCopyrightText: 2023 Adriano Prado <dev@dude333.com>
+//
+// SPDX-License-Identifier: MIT
+
+package infra
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCache_Fetch(t *testing.T) {
+	conteúdo := []byte(strings.Repeat("0123456789", 10))
+	novaCache := func(t *testing.T, maxSize int64) (*Cache, *Downloader) {
+		c, err := NewCache(t.TempDir(), maxSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		d := NewDownloader(WithRetries(0), WithVerbose(false), WithCache(c))
+		return c, d
+	}
+	ctx := context.Background()
+
+	t.Run("arquivo não alterado", func(t *testing.T) {
+		c, d := novaCache(t, 0)
+		s := &servidorTeste{conteúdo: conteúdo}
+		ts := httptest.NewServer(s)
+		defer ts.Close()
+
+		p1, err := c.Fetch(ctx, d, ts.URL+"/a.zip")
+		if err != nil {
+			t.Fatal(err)
+		}
+		p2, err := c.Fetch(ctx, d, ts.URL+"/a.zip")
+		if err != nil || p1 != p2 {
+			t.Fatalf("Fetch() = %s, %v, want %s", p2, err, p1)
+		}
+		if h := s.cabeçalho(1, "If-None-Match"); h != `"v1"` {
+			t.Errorf("If-None-Match = %q", h)
+		}
+		if b, _ := os.ReadFile(p2); !bytes.Equal(b, conteúdo) {
+			t.Errorf("conteúdo = %q", b)
+		}
+	})
+
+	t.Run("arquivo alterado", func(t *testing.T) {
+		c, d := novaCache(t, 0)
+		s := &servidorTeste{conteúdo: conteúdo}
+		ts := httptest.NewServer(s)
+		defer ts.Close()
+
+		p1, err := c.Fetch(ctx, d, ts.URL+"/a.zip")
+		if err != nil {
+			t.Fatal(err)
+		}
+		novo := []byte(strings.Repeat("x", 100))
+		s.alterar(novo, `"v2"`)
+		p2, err := c.Fetch(ctx, d, ts.URL+"/a.zip")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b, _ := os.ReadFile(p2); !bytes.Equal(b, novo) {
+			t.Errorf("conteúdo = %q", b)
+		}
+		if _, err := os.Stat(p1); p1 == p2 || err == nil {
+			t.Errorf("a versão anterior (%s) deveria ser removida", p1)
+		}
+	})
+
+	t.Run("servidor fora do ar", func(t *testing.T) {
+		c, d := novaCache(t, 0)
+		ts := httptest.NewServer(&servidorTeste{conteúdo: conteúdo})
+		url := ts.URL + "/a.zip"
+		p1, err := c.Fetch(ctx, d, url)
+		ts.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		p2, err := c.Fetch(ctx, d, url)
+		if err != nil || p2 != p1 {
+			t.Errorf("Fetch() = %s, %v, want cópia em cache %s", p2, err, p1)
+		}
+	})
+
+	t.Run("tamanho máximo", func(t *testing.T) {
+		c, d := novaCache(t, 150)
+		s := &servidorTeste{conteúdo: conteúdo}
+		ts := httptest.NewServer(s)
+		defer ts.Close()
+
+		if _, err := c.Fetch(ctx, d, ts.URL+"/a.zip"); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(10 * time.Millisecond)
+
+		// nova execução: a.zip não está mais em uso
+		c, err := NewCache(c.Dir(), 150)
+		if err != nil {
+			t.Fatal(err)
+		}
+		d = NewDownloader(WithRetries(0), WithVerbose(false), WithCache(c))
+		s.alterar([]byte(strings.Repeat("y", 100)), `"v2"`)
+		if _, err := c.Fetch(ctx, d, ts.URL+"/b.zip"); err != nil {
+			t.Fatal(err)
+		}
+		entries, err := c.Entries()
+		if err != nil || len(entries) != 1 || entries[0].URL != ts.URL+"/b.zip" {
+			t.Errorf("Entries() = %+v, %v, want apenas b.zip", entries, err)
+		}
+	})
+
+	t.Run("arquivos em uso não são removidos", func(t *testing.T) {
+		c, d := novaCache(t, 150)
+		s := &servidorTeste{conteúdo: conteúdo}
+		ts := httptest.NewServer(s)
+		defer ts.Close()
+
+		p, err := c.Fetch(ctx, d, ts.URL+"/a.zip")
+		if err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(10 * time.Millisecond)
+		s.alterar([]byte(strings.Repeat("y", 100)), `"v2"`)
+		if _, err := c.Fetch(ctx, d, ts.URL+"/b.zip"); err != nil {
+			t.Fatal(err)
+		}
+		if b, err := os.ReadFile(p); err != nil || !bytes.Equal(b, conteúdo) {
+			t.Errorf("arquivo em uso removido: %v", err)
+		}
+		if entries, _ := c.Entries(); len(entries) != 2 {
+			t.Errorf("Entries() = %+v, want a.zip e b.zip", entries)
+		}
+	})
+
+	t.Run("verificar e limpar", func(t *testing.T) {
+		c, d := novaCache(t, 0)
+		s := &servidorTeste{conteúdo: conteúdo}
+		ts := httptest.NewServer(s)
+		defer ts.Close()
+
+		p, err := c.Fetch(ctx, d, ts.URL+"/a.zip")
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.alterar([]byte(strings.Repeat("y", 100)), `"v2"`)
+		if _, err := c.Fetch(ctx, d, ts.URL+"/b.zip"); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte("corrompido"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		ruins, err := c.Verify()
+		if err != nil || len(ruins) != 1 || ruins[0].URL != ts.URL+"/a.zip" {
+			t.Errorf("Verify() = %+v, %v, want a.zip", ruins, err)
+		}
+		if _, err := os.Stat(p); err == nil {
+			t.Error("arquivo corrompido deveria ser removido")
+		}
+
+		n, tamanho, err := c.Clear()
+		if err != nil || n != 1 || tamanho != 100 {
+			t.Errorf("Clear() = %d, %d, %v, want 1, 100", n, tamanho, err)
+		}
+		if entries, _ := c.Entries(); len(entries) != 0 {
+			t.Errorf("Entries() = %+v, want vazio", entries)
+		}
+	})
+}
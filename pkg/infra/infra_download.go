@@ -5,56 +5,267 @@
 package infra
 
 import (
+	"context"
 	"crypto/tls"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"path"
-	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/dustin/go-humanize"
+
+	"github.com/dude333/rapinav2/pkg/progress"
 )
 
-func DownloadAndUnzip(url, zip string, filters []string) ([]string, error) {
-	verbose := true
+// Downloader downloads files over HTTP, retrying with exponential backoff,
+// resuming partial downloads (HTTP Range) and skipping files that did not
+// change since the last download (ETag/Last-Modified).
+//
+// The validators of each downloaded file are stored in <file>.meta and the
+// partial downloads in <file>.part, both next to the file.
+type Downloader struct {
+	client   *http.Client
+	retries  int           // attempts after the first one
+	backoff  time.Duration // wait before the first retry, doubled on each retry
+	insecure bool
+	proxy    *url.URL
+	timeout  time.Duration // timeout of each attempt
+	verbose  bool
+	cache    *Cache
+}
 
-	err := downloadFile(url, zip, verbose)
-	if err != nil {
-		return []string{}, err
+// DownloaderOption configures a Downloader.
+type DownloaderOption func(*Downloader)
+
+// WithRetries sets the number of retries after a failed attempt (default 4).
+func WithRetries(n int) DownloaderOption {
+	return func(d *Downloader) {
+		if n >= 0 {
+			d.retries = n
+		}
 	}
+}
 
-	fmt.Println()
+// WithBackoff sets the wait before the first retry, doubled on each retry
+// (default 2s).
+func WithBackoff(wait time.Duration) DownloaderOption {
+	return func(d *Downloader) {
+		if wait > 0 {
+			d.backoff = wait
+		}
+	}
+}
+
+// WithInsecureSkipVerify disables the verification of the server
+// certificates. Use only if the system certificates are not available.
+func WithInsecureSkipVerify(insecure bool) DownloaderOption {
+	return func(d *Downloader) {
+		d.insecure = insecure
+	}
+}
 
-	dataDir := filepath.Dir(zip)
+// WithProxy sets the proxy URL. By default the proxy is read from the
+// HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables.
+func WithProxy(proxy *url.URL) DownloaderOption {
+	return func(d *Downloader) {
+		d.proxy = proxy
+	}
+}
 
-	// Unzip and list files
-	files, err := Unzip(zip, dataDir, filters, verbose)
-	os.Remove(zip)
+// WithTimeout sets the timeout of each attempt, including the transfer of
+// the body (default 15min). Interrupted transfers are resumed on the next
+// attempt.
+func WithTimeout(timeout time.Duration) DownloaderOption {
+	return func(d *Downloader) {
+		if timeout > 0 {
+			d.timeout = timeout
+		}
+	}
+}
+
+// WithVerbose shows the download progress on stderr (default true).
+func WithVerbose(verbose bool) DownloaderOption {
+	return func(d *Downloader) {
+		d.verbose = verbose
+	}
+}
+
+// WithCache keeps the downloaded files in the cache, so they are downloaded
+// again only if they change.
+func WithCache(cache *Cache) DownloaderOption {
+	return func(d *Downloader) {
+		d.cache = cache
+	}
+}
+
+// NewDownloader creates a Downloader.
+func NewDownloader(options ...DownloaderOption) *Downloader {
+	d := &Downloader{
+		retries: 4,
+		backoff: 2 * time.Second,
+		timeout: 15 * time.Minute,
+		verbose: true,
+	}
+	for _, opt := range options {
+		opt(d)
+	}
+
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	tr.IdleConnTimeout = _http_timeout
+	tr.ResponseHeaderTimeout = _http_timeout
+	tr.TLSClientConfig = &tls.Config{InsecureSkipVerify: d.insecure}
+	if d.proxy != nil {
+		tr.Proxy = http.ProxyURL(d.proxy)
+	}
+	d.client = &http.Client{Transport: tr, Timeout: d.timeout}
+
+	return d
+}
+
+var (
+	defaultDownloader   = NewDownloader()
+	defaultDownloaderMu sync.RWMutex
+)
+
+// SetDefaultDownloader sets the Downloader used by Download and DownloadZip.
+func SetDefaultDownloader(d *Downloader) {
+	defaultDownloaderMu.Lock()
+	defer defaultDownloaderMu.Unlock()
+	defaultDownloader = d
+}
+
+func getDefaultDownloader() *Downloader {
+	defaultDownloaderMu.RLock()
+	defer defaultDownloaderMu.RUnlock()
+	return defaultDownloader
+}
+
+// DownloadZip downloads the zip file from url (see Downloader.Download) and
+// opens it, listing the files matching filters.
+func DownloadZip(ctx context.Context, url, zip string, filters []string) (*ZipArchive, error) {
+	return getDefaultDownloader().DownloadZip(ctx, url, zip, filters)
+}
+
+// Download downloads the file from url, without extracting it.
+func Download(ctx context.Context, url, filepath string) error {
+	_, err := getDefaultDownloader().Download(ctx, url, filepath)
+	return err
+}
+
+// DownloadZip downloads the zip file from url and opens it, listing the
+// files matching filters, which are read directly from the zip. With a cache,
+// the cached zip is opened. Without a cache, the zip file is kept if the
+// server sent validators (ETag/Last-Modified), so it is not downloaded again
+// while it does not change; otherwise it is removed when the archive is
+// closed. The download is aborted when ctx is canceled.
+func (d *Downloader) DownloadZip(ctx context.Context, url, zip string, filters []string) (*ZipArchive, error) {
+	if d.cache != nil {
+		cached, err := d.cache.Fetch(ctx, d, url)
+		if err != nil {
+			return nil, err
+		}
+		return OpenZip(cached, filters)
+	}
+
+	if _, err := d.Download(ctx, url, zip); err != nil {
+		return nil, err
+	}
+
+	a, err := OpenZip(zip, filters)
+	_, metaErr := os.Stat(metaPath(zip))
 	if err != nil {
-		return []string{}, err
+		if metaErr != nil {
+			os.Remove(zip)
+		}
+		return nil, err
+	}
+	if metaErr != nil {
+		a.remove = zip
 	}
 
-	return files, nil
+	return a, nil
 }
 
-//
-// downloadFile source: https://stackoverflow.com/a/33853856/276311
-//
-func downloadFile(url, filepath string, verbose bool) error {
+// ErrDownload is returned when the server answers with an unexpected status.
+var ErrDownload = errors.New("falha no download")
+
+// Download downloads the file from url to filepath, returning false if the
+// local file did not change since the last download (HTTP 304). With a
+// cache, the cached file is copied to filepath. The download is aborted when
+// ctx is canceled.
+func (d *Downloader) Download(ctx context.Context, url, filepath string) (bool, error) {
+	if d.cache != nil {
+		cached, err := d.cache.Fetch(ctx, d, url)
+		if err != nil {
+			return false, err
+		}
+		return true, copyFile(cached, filepath)
+	}
+
+	var cond fileMeta
+	if _, err := os.Stat(filepath); err == nil {
+		cond, _ = readMeta(filepath, url)
+	}
+
+	m, modified, err := d.fetch(ctx, url, filepath, cond)
+	if err != nil || !modified {
+		return modified, err
+	}
+	return true, writeMeta(filepath, m)
+}
+
+// fetch downloads the file from url to filepath, retrying on failures. If
+// cond has validators, the file is not downloaded if it did not change (HTTP
+// 304). Returns the validators sent by the server. Retries stop as soon as
+// ctx is canceled.
+func (d *Downloader) fetch(ctx context.Context, url, filepath string, cond fileMeta) (fileMeta, bool, error) {
 	// Create dir if necessary
-	basepath := path.Dir(filepath)
-	if err := os.MkdirAll(basepath, os.ModePerm); err != nil {
-		return err
+	if err := os.MkdirAll(path.Dir(filepath), os.ModePerm); err != nil {
+		return fileMeta{}, false, err
 	}
 
-	// Create the file
-	out, err := os.Create(filepath)
+	wait := d.backoff
+	for attempt := 0; ; attempt++ {
+		m, modified, err := d.downloadFile(ctx, url, filepath, cond)
+		if err == nil || !retryable(err) || attempt >= d.retries || ctx.Err() != nil {
+			return m, modified, err
+		}
+		if d.verbose {
+			progress.Warning("%v; nova tentativa em %v", err, wait)
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return fileMeta{}, false, ctx.Err()
+		}
+		wait *= 2
+	}
+}
+
+func copyFile(src, dst string) (err error) {
+	in, err := os.Open(src)
 	if err != nil {
 		return err
 	}
+	defer in.Close()
 
-	// https://www.joeshaw.org/dont-defer-close-on-writable-files/
+	if err := os.MkdirAll(path.Dir(dst), os.ModePerm); err != nil {
+		return err
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
 	defer func() {
 		cerr := out.Close()
 		if err == nil {
@@ -62,39 +273,194 @@ func downloadFile(url, filepath string, verbose bool) error {
 		}
 	}()
 
-	// Custom Client
-	tr := &http.Transport{
-		IdleConnTimeout: _http_timeout,
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	_, err = io.Copy(out, in)
+	return err
+}
+
+// fileMeta contains the validators of a downloaded file.
+type fileMeta struct {
+	URL          string `json:"url"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+func (m fileMeta) empty() bool {
+	return m.ETag == "" && m.LastModified == ""
+}
+
+func metaPath(filepath string) string { return filepath + ".meta" }
+func partPath(filepath string) string { return filepath + ".part" }
+
+func readMeta(filepath, url string) (fileMeta, bool) {
+	var m fileMeta
+	b, err := os.ReadFile(metaPath(filepath))
+	if err != nil || json.Unmarshal(b, &m) != nil || m.URL != url || m.empty() {
+		return fileMeta{}, false
 	}
-	client := &http.Client{Transport: tr}
+	return m, true
+}
 
-	// Get the data
-	resp, err := client.Get(url)
+func writeMeta(filepath string, m fileMeta) error {
+	if m.empty() {
+		err := os.Remove(metaPath(filepath))
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	b, err := json.Marshal(m)
 	if err != nil {
 		return err
 	}
+	return os.WriteFile(metaPath(filepath), b, 0o644)
+}
+
+// retryableError is a failure that may succeed on a new attempt (network
+// errors, 5xx and 429).
+type retryableError struct{ err error }
+
+func (e retryableError) Error() string { return e.err.Error() }
+func (e retryableError) Unwrap() error { return e.err }
+
+func retryable(err error) bool {
+	var r retryableError
+	return errors.As(err, &r)
+}
+
+// downloadFile makes one attempt to download the file, resuming the partial
+// download if there is one.
+// Source: https://stackoverflow.com/a/33853856/276311
+func (d *Downloader) downloadFile(ctx context.Context, url, filepath string, cond fileMeta) (fileMeta, bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fileMeta{}, false, err
+	}
+
+	// Conditional GET, if the file was already downloaded
+	if cond.ETag != "" {
+		req.Header.Set("If-None-Match", cond.ETag)
+	}
+	if cond.LastModified != "" {
+		req.Header.Set("If-Modified-Since", cond.LastModified)
+	}
+
+	// Resume the partial download, if the remote file did not change
+	part := partPath(filepath)
+	var offset int64
+	if m, ok := readMeta(part, url); ok {
+		if fi, err := os.Stat(part); err == nil && fi.Size() > 0 {
+			offset = fi.Size()
+			req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+			if m.ETag != "" {
+				req.Header.Set("If-Range", m.ETag)
+			} else {
+				req.Header.Set("If-Range", m.LastModified)
+			}
+		}
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return fileMeta{}, false, retryableError{err}
+	}
 	defer resp.Body.Close()
 
-	// Check server response
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("bad status: %s", resp.Status)
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	switch {
+	case resp.StatusCode == http.StatusNotModified:
+		if d.verbose {
+			progress.Status("%s não foi alterado", filepath)
+		}
+		return cond, false, nil
+	case resp.StatusCode == http.StatusPartialContent && offset > 0:
+		if contentRangeStart(resp) != offset {
+			// The server sent another range; start over without Range
+			resp.Body.Close()
+			_ = os.Remove(part)
+			_ = os.Remove(metaPath(part))
+			return d.downloadFile(ctx, url, filepath, cond)
+		}
+		flags = os.O_WRONLY | os.O_APPEND
+	case resp.StatusCode == http.StatusOK:
+		offset = 0
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable:
+		// The partial file is invalid; start over on the next attempt
+		_ = os.Remove(part)
+		_ = os.Remove(metaPath(part))
+		return fileMeta{}, false, retryableError{fmt.Errorf("%w: %s: %s", ErrDownload, url, resp.Status)}
+	case resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests:
+		return fileMeta{}, false, retryableError{fmt.Errorf("%w: %s: %s", ErrDownload, url, resp.Status)}
+	default:
+		return fileMeta{}, false, fmt.Errorf("%w: %s: %s", ErrDownload, url, resp.Status)
 	}
 
-	// Write the body to file
-	counter := io.Discard
-	if verbose {
-		fmt.Printf("[          ] Baixando %s", filepath)
-		counter = &WriteCounter{}
+	m := fileMeta{
+		URL:          url,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
 	}
-	_, err = io.Copy(out, io.TeeReader(resp.Body, counter))
+	if err := writeMeta(part, m); err != nil {
+		return fileMeta{}, false, err
+	}
+
+	if err := d.writeBody(part, flags, offset, resp.Body); err != nil {
+		return fileMeta{}, false, retryableError{err}
+	}
+
+	if err := os.Rename(part, filepath); err != nil {
+		return fileMeta{}, false, err
+	}
+	_ = os.Remove(metaPath(part))
+
+	return m, true, nil
+}
+
+// writeBody writes the response body to file, starting at offset.
+// https://www.joeshaw.org/dont-defer-close-on-writable-files/
+func (d *Downloader) writeBody(file string, flags int, offset int64, body io.Reader) (err error) {
+	out, err := os.OpenFile(file, flags, 0o644)
 	if err != nil {
 		return err
 	}
+	defer func() {
+		cerr := out.Close()
+		if err == nil {
+			err = cerr
+		}
+	}()
+
+	counter := io.Discard
+	if d.verbose {
+		progress.Download("Baixando " + file)
+		counter = &WriteCounter{Total: uint64(offset)}
+	}
+	_, err = io.Copy(out, io.TeeReader(body, counter))
+	if d.verbose {
+		if err != nil {
+			progress.RunFail()
+		} else {
+			progress.RunOK()
+		}
+	}
 
 	return err
 }
 
+// contentRangeStart returns the first byte position of the Content-Range
+// header ("bytes 100-199/200"), or -1.
+func contentRangeStart(resp *http.Response) int64 {
+	cr := strings.TrimPrefix(resp.Header.Get("Content-Range"), "bytes ")
+	i := strings.IndexByte(cr, '-')
+	if i < 0 {
+		return -1
+	}
+	start, err := strconv.ParseInt(cr[:i], 10, 64)
+	if err != nil {
+		return -1
+	}
+	return start
+}
+
 // WriteCounter counts the number of bytes written the io.Writer.
 // source: https://golangcode.com/download-a-file-with-progress/
 type WriteCounter struct {
@@ -110,7 +476,7 @@ func (wc *WriteCounter) Write(p []byte) (int, error) {
 }
 
 func (wc WriteCounter) printProgress() {
-	fmt.Printf("\r[  %7s", humanize.Bytes(wc.Total))
+	progress.DownloadProgress(humanize.Bytes(wc.Total))
 }
 
 // Cleanup remove files and return a list of files NOT removed.
@@ -0,0 +1,220 @@
+// SPDX-FileCopyrightText: 2023 Adriano Prado <dev@dude333.com>
+//
+// SPDX-License-Identifier: MIT
+
+package infra
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// servidorTeste serve conteúdo com ETag, suportando Range e GET condicional
+// (http.ServeContent). falhas define as respostas das primeiras requisições:
+// um status HTTP ou 0 para interromper a conexão no meio do arquivo.
+type servidorTeste struct {
+	conteúdo []byte
+	etag     string // padrão "v1"
+	falhas   []int
+
+	mu          sync.Mutex
+	requisições []*http.Request
+}
+
+func (s *servidorTeste) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	n := len(s.requisições)
+	s.requisições = append(s.requisições, r)
+	conteúdo, etag := s.conteúdo, s.etag
+	s.mu.Unlock()
+
+	if etag == "" {
+		etag = `"v1"`
+	}
+	w.Header().Set("ETag", etag)
+	if n < len(s.falhas) {
+		if s.falhas[n] != 0 {
+			w.WriteHeader(s.falhas[n])
+			return
+		}
+		w.Header().Set("Content-Length", "100")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(conteúdo[:len(conteúdo)/2])
+		w.(http.Flusher).Flush()
+		panic(http.ErrAbortHandler)
+	}
+	http.ServeContent(w, r, "arquivo.zip", time.Time{}, bytes.NewReader(conteúdo))
+}
+
+// alterar altera o conteúdo servido.
+func (s *servidorTeste) alterar(conteúdo []byte, etag string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.conteúdo, s.etag = conteúdo, etag
+}
+
+func (s *servidorTeste) cabeçalho(i int, nome string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i >= len(s.requisições) {
+		return ""
+	}
+	return s.requisições[i].Header.Get(nome)
+}
+
+func TestDownloader_Download(t *testing.T) {
+	conteúdo := []byte(strings.Repeat("0123456789", 10))
+	d := NewDownloader(WithBackoff(time.Millisecond), WithVerbose(false))
+	ctx := context.Background()
+
+	baixar := func(t *testing.T, d *Downloader, url string) (string, bool, error) {
+		arquivo := filepath.Join(t.TempDir(), "arquivo.zip")
+		alterado, err := d.Download(ctx, url, arquivo)
+		return arquivo, alterado, err
+	}
+
+	t.Run("GET condicional", func(t *testing.T) {
+		s := &servidorTeste{conteúdo: conteúdo}
+		ts := httptest.NewServer(s)
+		defer ts.Close()
+
+		arquivo, alterado, err := baixar(t, d, ts.URL)
+		if err != nil || !alterado {
+			t.Fatalf("Download() = %v, %v", alterado, err)
+		}
+		alterado, err = d.Download(ctx, ts.URL, arquivo)
+		if err != nil || alterado {
+			t.Errorf("Download() = %v, %v, want false (não alterado)", alterado, err)
+		}
+		if h := s.cabeçalho(1, "If-None-Match"); h != `"v1"` {
+			t.Errorf("If-None-Match = %q", h)
+		}
+		if b, _ := os.ReadFile(arquivo); !bytes.Equal(b, conteúdo) {
+			t.Errorf("conteúdo = %q", b)
+		}
+	})
+
+	t.Run("retomar download interrompido", func(t *testing.T) {
+		s := &servidorTeste{conteúdo: conteúdo, falhas: []int{0}}
+		ts := httptest.NewServer(s)
+		defer ts.Close()
+
+		arquivo, _, err := baixar(t, d, ts.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if h := s.cabeçalho(1, "Range"); h != "bytes=50-" {
+			t.Errorf("Range = %q, want bytes=50-", h)
+		}
+		if b, _ := os.ReadFile(arquivo); !bytes.Equal(b, conteúdo) {
+			t.Errorf("conteúdo = %q", b)
+		}
+		if _, err := os.Stat(partPath(arquivo)); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("arquivo parcial não foi removido: %v", err)
+		}
+	})
+
+	t.Run("intervalo diferente do pedido", func(t *testing.T) {
+		var requisições []*http.Request
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requisições = append(requisições, r)
+			w.Header().Set("ETag", `"v1"`)
+			if r.Header.Get("Range") != "" {
+				w.Header().Set("Content-Range", "bytes 0-99/100")
+				w.WriteHeader(http.StatusPartialContent)
+			}
+			_, _ = w.Write(conteúdo)
+		}))
+		defer ts.Close()
+
+		arquivo := filepath.Join(t.TempDir(), "arquivo.zip")
+		if err := os.WriteFile(partPath(arquivo), []byte("parcial"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := writeMeta(partPath(arquivo), fileMeta{URL: ts.URL, ETag: `"v1"`}); err != nil {
+			t.Fatal(err)
+		}
+
+		d := NewDownloader(WithRetries(0), WithVerbose(false))
+		if _, err := d.Download(ctx, ts.URL, arquivo); err != nil {
+			t.Fatal(err)
+		}
+		if len(requisições) != 2 || requisições[1].Header.Get("Range") != "" {
+			t.Errorf("requisições = %d, want 2, a última sem Range", len(requisições))
+		}
+		if b, _ := os.ReadFile(arquivo); !bytes.Equal(b, conteúdo) {
+			t.Errorf("conteúdo = %q", b)
+		}
+	})
+
+	t.Run("novas tentativas", func(t *testing.T) {
+		s := &servidorTeste{conteúdo: conteúdo, falhas: []int{http.StatusServiceUnavailable, http.StatusTooManyRequests}}
+		ts := httptest.NewServer(s)
+		defer ts.Close()
+
+		if _, _, err := baixar(t, d, ts.URL); err != nil {
+			t.Fatal(err)
+		}
+
+		d := NewDownloader(WithRetries(1), WithBackoff(time.Millisecond), WithVerbose(false))
+		s = &servidorTeste{conteúdo: conteúdo, falhas: []int{500, 500}}
+		ts2 := httptest.NewServer(s)
+		defer ts2.Close()
+		if _, _, err := baixar(t, d, ts2.URL); !errors.Is(err, ErrDownload) {
+			t.Errorf("Download() error = %v, want %v", err, ErrDownload)
+		}
+	})
+
+	t.Run("erro permanente", func(t *testing.T) {
+		s := &servidorTeste{conteúdo: conteúdo, falhas: []int{http.StatusNotFound}}
+		ts := httptest.NewServer(s)
+		defer ts.Close()
+
+		if _, _, err := baixar(t, d, ts.URL); !errors.Is(err, ErrDownload) {
+			t.Errorf("Download() error = %v, want %v", err, ErrDownload)
+		}
+		if len(s.requisições) != 1 {
+			t.Errorf("requisições = %d, want 1", len(s.requisições))
+		}
+	})
+
+	t.Run("cancelamento durante a espera", func(t *testing.T) {
+		s := &servidorTeste{conteúdo: conteúdo, falhas: []int{http.StatusServiceUnavailable}}
+		ts := httptest.NewServer(s)
+		defer ts.Close()
+
+		d := NewDownloader(WithRetries(1), WithBackoff(time.Hour), WithVerbose(false))
+		ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		arquivo := filepath.Join(t.TempDir(), "arquivo.zip")
+		if _, err := d.Download(ctx, ts.URL, arquivo); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Download() error = %v, want %v", err, context.DeadlineExceeded)
+		}
+		if len(s.requisições) != 1 {
+			t.Errorf("requisições = %d, want 1", len(s.requisições))
+		}
+	})
+
+	t.Run("verificação TLS", func(t *testing.T) {
+		ts := httptest.NewTLSServer(&servidorTeste{conteúdo: conteúdo})
+		defer ts.Close()
+
+		d := NewDownloader(WithRetries(0), WithVerbose(false))
+		if _, _, err := baixar(t, d, ts.URL); err == nil {
+			t.Error("Download() deveria falhar com certificado não confiável")
+		}
+		d = NewDownloader(WithRetries(0), WithVerbose(false), WithInsecureSkipVerify(true))
+		if _, _, err := baixar(t, d, ts.URL); err != nil {
+			t.Errorf("Download() error = %v", err)
+		}
+	})
+}
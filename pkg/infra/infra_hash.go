@@ -6,14 +6,14 @@ package infra
 
 import (
 	"fmt"
+	"hash"
 	"hash/fnv"
 	"io"
 	"os"
 )
 
-// fnvHash is a global var set to speed up Hash
-var fnvHash = fnv.New64a()
-
+// FileHash returns the FNV-1a hash of the file contents. It is safe for
+// concurrent use.
 func FileHash(filename string) (string, error) {
 	f, err := os.Open(filename)
 	if err != nil {
@@ -21,9 +21,34 @@ func FileHash(filename string) (string, error) {
 	}
 	defer f.Close()
 
-	fnvHash.Reset()
-	if _, err = io.Copy(fnvHash, f); err != nil {
+	return NewHashReader(f).Sum()
+}
+
+// HashReader computes the FNV-1a hash of the data read from r, so a stream
+// can be hashed while it is processed. The hash is the same returned by
+// FileHash for a file with the same contents.
+type HashReader struct {
+	r io.Reader
+	h hash.Hash64
+}
+
+// NewHashReader returns a HashReader that reads from r.
+func NewHashReader(r io.Reader) *HashReader {
+	return &HashReader{r: r, h: fnv.New64a()}
+}
+
+// Read implements the io.Reader interface.
+func (hr *HashReader) Read(p []byte) (int, error) {
+	n, err := hr.r.Read(p)
+	_, _ = hr.h.Write(p[:n])
+	return n, err
+}
+
+// Sum reads the data not yet read from r and returns the hash of the whole
+// stream.
+func (hr *HashReader) Sum() (string, error) {
+	if _, err := io.Copy(io.Discard, hr); err != nil {
 		return "", err
 	}
-	return fmt.Sprintf("%x", fnvHash.Sum64()), nil
+	return fmt.Sprintf("%x", hr.h.Sum64()), nil
 }
@@ -0,0 +1,157 @@
+// SPDX-FileCopyrightText: 2023 Adriano Prado <dev@dude333.com>
+//
+// SPDX-License-Identifier: MIT
+
+package infra
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Migration is one versioned step of a database schema. Up moves the schema
+// from Version-1 to Version and Down reverts it, both preserving the data.
+type Migration struct {
+	Version int
+	Descr   string
+	Up      []string
+	Down    []string
+}
+
+// MigrationStep describes a migration that was (or would be, on dry runs)
+// applied to the database.
+type MigrationStep struct {
+	Module  string
+	Version int
+	Descr   string
+	Up      bool // true = upgrade, false = downgrade
+	SQL     []string
+}
+
+const sqlCreateTableMigrations = `CREATE TABLE IF NOT EXISTS migracoes (
+	modulo      VARCHAR NOT NULL,
+	versao      INTEGER NOT NULL,
+	descr       VARCHAR NOT NULL,
+	aplicada_em VARCHAR NOT NULL,
+	PRIMARY KEY (modulo, versao)
+)`
+
+// SchemaVersion returns the last migration version applied to module, or 0
+// when no migration was applied yet.
+func SchemaVersion(db *sqlx.DB, module string) (int, error) {
+	if _, err := db.Exec(sqlCreateTableMigrations); err != nil {
+		return 0, err
+	}
+	var version int
+	err := db.Get(&version,
+		`SELECT COALESCE(MAX(versao), 0) FROM migracoes WHERE modulo=?`, module)
+	return version, err
+}
+
+// AppliedVersion returns the last migration version applied to module
+// without writing to the database: 0 if the migrations table does not exist.
+func AppliedVersion(db *sqlx.DB, module string) (int, error) {
+	var exists int
+	err := db.Get(&exists, `SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name='migracoes'`)
+	if err != nil || exists == 0 {
+		return 0, err
+	}
+	var version int
+	err = db.Get(&version,
+		`SELECT COALESCE(MAX(versao), 0) FROM migracoes WHERE modulo=?`, module)
+	return version, err
+}
+
+// Migrate moves the schema of module to version "to" (a negative value means
+// the latest version available in migrations), running each step inside its
+// own transaction. With dryRun == true the steps are only returned, nothing
+// is changed in the database.
+func Migrate(db *sqlx.DB, module string, migrations []Migration, to int, dryRun bool) ([]MigrationStep, error) {
+	sorted := make([]Migration, len(migrations))
+	copy(sorted, migrations)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+
+	latest := 0
+	if len(sorted) > 0 {
+		latest = sorted[len(sorted)-1].Version
+	}
+	if to < 0 {
+		to = latest
+	}
+	if to > latest {
+		return nil, fmt.Errorf("versão %d inexistente (última versão: %d)", to, latest)
+	}
+
+	var current int
+	var err error
+	if dryRun {
+		current, err = AppliedVersion(db, module)
+	} else {
+		current, err = SchemaVersion(db, module)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var steps []MigrationStep
+	if to >= current {
+		for _, m := range sorted {
+			if m.Version > current && m.Version <= to {
+				steps = append(steps, MigrationStep{
+					Module: module, Version: m.Version, Descr: m.Descr, Up: true, SQL: m.Up,
+				})
+			}
+		}
+	} else {
+		for i := len(sorted) - 1; i >= 0; i-- {
+			m := sorted[i]
+			if m.Version <= current && m.Version > to {
+				steps = append(steps, MigrationStep{
+					Module: module, Version: m.Version, Descr: m.Descr, Up: false, SQL: m.Down,
+				})
+			}
+		}
+	}
+
+	if dryRun {
+		return steps, nil
+	}
+
+	for i := range steps {
+		if err := applyStep(db, steps[i]); err != nil {
+			return steps[:i], err
+		}
+	}
+
+	return steps, nil
+}
+
+func applyStep(db *sqlx.DB, step MigrationStep) error {
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+
+	for _, query := range step.SQL {
+		if _, err := tx.Exec(query); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("migração %s v%d: %w", step.Module, step.Version, err)
+		}
+	}
+
+	if step.Up {
+		_, err = tx.Exec(`INSERT INTO migracoes (modulo, versao, descr, aplicada_em) VALUES (?, ?, ?, ?)`,
+			step.Module, step.Version, step.Descr, time.Now().Format(time.RFC3339))
+	} else {
+		_, err = tx.Exec(`DELETE FROM migracoes WHERE modulo=? AND versao=?`, step.Module, step.Version)
+	}
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,42 @@
+// SPDX-FileCopyrightText: 2023 Adriano Prado <dev@dude333.com>
+//
+// SPDX-License-Identifier: MIT
+
+package infra
+
+import (
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestMigrate_dryRun(t *testing.T) {
+	db := sqlx.MustConnect("sqlite3", ":memory:")
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+
+	migrations := []Migration{
+		{Version: 1, Descr: "criar t", Up: []string{`CREATE TABLE t (a INTEGER)`}, Down: []string{`DROP TABLE t`}},
+	}
+	steps, err := Migrate(db, "teste", migrations, -1, true)
+	if err != nil || len(steps) != 1 || !steps[0].Up {
+		t.Fatalf("Migrate(dryRun) = %+v, %v", steps, err)
+	}
+
+	var tables int
+	if err := db.Get(&tables, `SELECT COUNT(*) FROM sqlite_master`); err != nil || tables != 0 {
+		t.Errorf("dry run gravou no banco de dados: %d tabelas, %v", tables, err)
+	}
+
+	if _, err := Migrate(db, "teste", migrations, -1, false); err != nil {
+		t.Fatal(err)
+	}
+	steps, err = Migrate(db, "teste", migrations, 0, true)
+	if err != nil || len(steps) != 1 || steps[0].Up {
+		t.Errorf("Migrate(dryRun, 0) = %+v, %v", steps, err)
+	}
+	if v, err := SchemaVersion(db, "teste"); err != nil || v != 1 {
+		t.Errorf("SchemaVersion() = %d, %v, want 1", v, err)
+	}
+}
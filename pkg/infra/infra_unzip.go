@@ -6,101 +6,56 @@ package infra
 
 import (
 	"archive/zip"
-	"fmt"
-	"io"
 	"os"
-	"path/filepath"
 	"strings"
+	"sync"
 )
 
-//
-// UnzipVerbosity will decompress a zip archive, moving all files and folders
-// within the zip file (parameter 1) to an output directory (parameter 2).
-// Source: https://golangcode.com/unzip-files-in-go/
-//
-func Unzip(src, dest string, filters []string, verbose bool) ([]string, error) {
+// ZipArchive is an open zip file whose files are read as streams, without
+// extracting them to disk. The files can be read concurrently.
+type ZipArchive struct {
+	Files []*zip.File // files matching the filters, without directories
 
-	var filenames []string
+	r      *zip.ReadCloser
+	remove string // file removed on Close
+	once   sync.Once
+	err    error
+}
 
+// OpenZip opens the zip file src without extracting it. Files lists the
+// files whose names contain one of the filters (case insensitive; all files
+// if filters is empty), skipping directories, and each one is read with
+// Open directly from the zip. The archive must be closed after the files are
+// read.
+func OpenZip(src string, filters []string) (*ZipArchive, error) {
 	r, err := zip.OpenReader(src)
 	if err != nil {
-		return filenames, err
+		return nil, err
 	}
-	defer r.Close()
 
+	a := &ZipArchive{r: r}
 	for _, f := range r.File {
+		if f.FileInfo().IsDir() || !matchFilter(f.Name, filters) {
+			continue
+		}
+		a.Files = append(a.Files, f)
+	}
 
-		// deferInLoop: stackoverflow.com/a/45617791/276311
-		ff, err := func() ([]string, error) {
-			if !matchFilter(f.Name, filters) {
-				return filenames, nil // continue
-			}
-
-			rc, err := f.Open()
-			if err != nil {
-				return filenames, err
-			}
-			defer rc.Close()
-
-			// Store filename/path for returning and using later on
-			fpath, err := sanitizeArchivePath(dest, f.Name)
-			if err != nil {
-				return filenames, err
-			}
-
-			// Check for ZipSlip. More Info: http://bit.ly/2MsjAWE
-			if !strings.HasPrefix(fpath, filepath.Clean(dest)+string(os.PathSeparator)) {
-				return filenames, fmt.Errorf("%s: illegal file path", fpath)
-			}
-
-			filenames = append(filenames, fpath)
-
-			if f.FileInfo().IsDir() {
-
-				// Make Folder
-				if err := os.MkdirAll(fpath, os.ModePerm); err != nil {
-					return nil, err
-				}
-
-			} else {
-
-				// Make File
-				if err := os.MkdirAll(filepath.Dir(fpath), os.ModePerm); err != nil {
-					return filenames, err
-				}
-
-				outFile, err := os.OpenFile(fpath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, f.Mode())
-				if err != nil {
-					return filenames, err
-				}
-
-				counter := io.Discard
-				if verbose {
-					fmt.Printf("[          ] Unziping %s", fpath)
-					counter = &WriteCounter{}
-				}
-				_, err = io.Copy(outFile, io.TeeReader(rc, counter))
-				if verbose {
-					fmt.Println()
-				}
-
-				// Close the file without defer to close before next iteration of loop
-				outFile.Close()
-
-				if err != nil {
-					return filenames, err
-				}
+	return a, nil
+}
 
+// Close closes the zip file, removing it if it was downloaded only to be
+// read (see Downloader.DownloadZip). It may be called more than once.
+func (a *ZipArchive) Close() error {
+	a.once.Do(func() {
+		a.err = a.r.Close()
+		if a.remove != "" {
+			if err := os.Remove(a.remove); err != nil && a.err == nil {
+				a.err = err
 			}
-			return filenames, err
-		}()
-
-		if err != nil {
-			return ff, err
 		}
-	}
-
-	return filenames, nil
+	})
+	return a.err
 }
 
 func matchFilter(s string, f []string) bool {
@@ -114,13 +69,3 @@ func matchFilter(s string, f []string) bool {
 	}
 	return false
 }
-
-// Sanitize archive file pathing from "G305: Zip Slip vulnerability"
-func sanitizeArchivePath(d, t string) (v string, err error) {
-	v = filepath.Join(d, t)
-	if strings.HasPrefix(v, filepath.Clean(d)) {
-		return v, nil
-	}
-
-	return "", fmt.Errorf("%s: %s", "content filepath is tainted", t)
-}
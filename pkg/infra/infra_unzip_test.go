@@ -0,0 +1,104 @@
+// SPDX-FileCopyrightText: 2023 Adriano Prado <dev@dude333.com>
+//
+// SPDX-License-Identifier: MIT
+
+package infra
+
+import (
+	"archive/zip"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func criarZip(t *testing.T, caminho string, arquivos map[string]string) {
+	t.Helper()
+	fh, err := os.Create(caminho)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fh.Close()
+	zw := zip.NewWriter(fh)
+	for nome, conteúdo := range arquivos {
+		w, err := zw.Create(nome)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(conteúdo)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOpenZip(t *testing.T) {
+	dir := t.TempDir()
+	caminho := filepath.Join(dir, "dfp_cia_aberta_2022.zip")
+	criarZip(t, caminho, map[string]string{
+		"dados/":                          "",
+		"dfp_cia_aberta_DRE_con_2022.csv": "DRE",
+		"dfp_cia_aberta_BPA_con_2022.csv": "BPA",
+		"dfp_cia_aberta_DVA_ind_2022.csv": "DVA",
+	})
+
+	a, err := OpenZip(caminho, []string{"dre_con", "BPA_con", "dados"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]string{}
+	for _, f := range a.Files {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got[f.Name] = string(b)
+	}
+	want := map[string]string{
+		"dfp_cia_aberta_DRE_con_2022.csv": "DRE",
+		"dfp_cia_aberta_BPA_con_2022.csv": "BPA",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("OpenZip() = %v, want %v", got, want)
+	}
+
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+	if entradas, _ := os.ReadDir(dir); len(entradas) != 1 {
+		t.Errorf("arquivos no diretório = %v, want apenas o zip", entradas)
+	}
+}
+
+func TestHashReader(t *testing.T) {
+	conteúdo := strings.Repeat("CNPJ_CIA;DT_REFER;VERSAO\n", 1000)
+	caminho := filepath.Join(t.TempDir(), "arquivo.csv")
+	if err := os.WriteFile(caminho, []byte(conteúdo), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	want, err := FileHash(caminho)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Leitura parcial: Sum lê o restante do conteúdo
+	hr := NewHashReader(strings.NewReader(conteúdo))
+	if _, err := io.ReadFull(hr, make([]byte, 100)); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := hr.Sum(); err != nil || got != want {
+		t.Errorf("Sum() = %q, %v, want %q", got, err, want)
+	}
+}
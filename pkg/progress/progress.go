@@ -11,6 +11,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"sync"
 
 	"github.com/pkg/errors"
 )
@@ -38,7 +39,8 @@ const (
 )
 
 type Progress struct {
-	out     io.Writer // destination for output, usually os.Stderr
+	mu      sync.Mutex // allows calls from multiple goroutines
+	out     io.Writer  // destination for output, usually os.Stderr
 	running []byte
 	seq     int // sequence of spinners
 	debug   bool
@@ -52,14 +54,23 @@ func init() {
 }
 
 func SetDebug(on bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
 	p.debug = on
 }
 
 func SetTrace(on bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
 	p.trace = on
 }
 
 func Cursor(show bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
 	if p.out != os.Stdout && p.out != os.Stderr {
 		return
 	}
@@ -71,6 +82,9 @@ func Cursor(show bool) {
 }
 
 func Status(format string, a ...interface{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
 	if len(p.running) > 0 {
 		clearLine()
 		output([]byte(colorCyan))
@@ -95,6 +109,9 @@ func FatalMsg(format string, a ...interface{}) {
 }
 
 func Error(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
 	if len(p.running) > 0 {
 		clearLine()
 	}
@@ -113,6 +130,9 @@ type stackTracer interface {
 }
 
 func ErrorStack(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
 	if len(p.running) > 0 {
 		clearLine()
 	}
@@ -134,6 +154,9 @@ func ErrorStack(err error) {
 }
 
 func ErrorMsg(format string, a ...interface{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
 	if len(p.running) > 0 {
 		clearLine()
 	}
@@ -148,6 +171,9 @@ func ErrorMsg(format string, a ...interface{}) {
 }
 
 func Warning(format string, a ...interface{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
 	if len(p.running) > 0 {
 		clearLine()
 	}
@@ -162,6 +188,9 @@ func Warning(format string, a ...interface{}) {
 }
 
 func Debug(format string, a ...interface{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
 	if !p.debug {
 		return
 	}
@@ -179,6 +208,9 @@ func Debug(format string, a ...interface{}) {
 }
 
 func Trace(format string, a ...interface{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
 	if !p.trace {
 		return
 	}
@@ -196,21 +228,33 @@ func Trace(format string, a ...interface{}) {
 }
 
 func Running(msg string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
 	p.running = []byte(fmt.Sprintf(evRunning, msg))
 	output(p.running)
 }
 
 func Spinner() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
 	output([]byte{'\r', '[', spinners[p.seq], ']'})
 	p.seq = (p.seq + 1) % len(spinners)
 }
 
 func RunOK() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
 	outputln(evRunOk)
 	p.running = p.running[:0]
 }
 
 func RunFail() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
 	output([]byte(colorRed))
 
 	if len(p.running) > 0 {
@@ -225,10 +269,16 @@ func RunFail() {
 }
 
 func RunWarningMsg(msg string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
 	runEndMsg(colorYellow, msg)
 }
 
 func RunFailMsg(msg string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
 	runEndMsg(colorRed, msg)
 }
 
@@ -248,10 +298,22 @@ func runEndMsg(color, msg string) {
 }
 
 func Download(a string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
 	p.running = []byte(fmt.Sprintf("[          ] %s", a))
 	output(p.running)
 }
 
+// DownloadProgress updates the counter (e.g. bytes received) of the line
+// started by Download.
+func DownloadProgress(count string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	output([]byte(fmt.Sprintf("\r[  %7s", count)))
+}
+
 /* ------- output ------- */
 
 func clearLine() {
@@ -7,6 +7,7 @@ package progress_test
 import (
 	"fmt"
 	"strconv"
+	"sync"
 	"testing"
 	"time"
 
@@ -52,6 +53,23 @@ func TestProgress(t *testing.T) {
 	progress.Status("end.")
 }
 
+func TestProgressConcorrente(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				progress.Running(fmt.Sprintf("goroutine %d, %d", i, j))
+				progress.Spinner()
+				progress.Debug("debug %d", j)
+				progress.RunOK()
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
 func f1() {
 	progress.Running("Running *f1*")
 	time.Sleep(time.Second)
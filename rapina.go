@@ -16,12 +16,13 @@ type InformeTrimestral struct {
 	Valores []ValoresTrimestrais
 }
 
+// ValoresTrimestrais contém os valores exatos de cada trimestre de um ano.
 type ValoresTrimestrais struct {
 	Ano int
-	T1  float64
-	T2  float64
-	T3  float64
-	T4  float64
+	T1  Decimal
+	T2  Decimal
+	T3  Decimal
+	T4  Decimal
 }
 
 func (v ValoresTrimestrais) Add(other ValoresTrimestrais) ValoresTrimestrais {
@@ -30,10 +31,10 @@ func (v ValoresTrimestrais) Add(other ValoresTrimestrais) ValoresTrimestrais {
 	}
 	return ValoresTrimestrais{
 		Ano: v.Ano,
-		T1:  v.T1 + other.T1,
-		T2:  v.T2 + other.T2,
-		T3:  v.T3 + other.T3,
-		T4:  v.T4 + other.T4,
+		T1:  v.T1.Add(other.T1),
+		T2:  v.T2.Add(other.T2),
+		T3:  v.T3.Add(other.T3),
+		T4:  v.T4.Add(other.T4),
 	}
 }
 
@@ -43,10 +44,10 @@ func (v ValoresTrimestrais) Sub(other ValoresTrimestrais) ValoresTrimestrais {
 	}
 	return ValoresTrimestrais{
 		Ano: v.Ano,
-		T1:  v.T1 - other.T1,
-		T2:  v.T2 - other.T2,
-		T3:  v.T3 - other.T3,
-		T4:  v.T4 - other.T4,
+		T1:  v.T1.Sub(other.T1),
+		T2:  v.T2.Sub(other.T2),
+		T3:  v.T3.Sub(other.T3),
+		T4:  v.T4.Sub(other.T4),
 	}
 }
 
@@ -56,49 +57,42 @@ func (v ValoresTrimestrais) Mult(other ValoresTrimestrais) ValoresTrimestrais {
 	}
 	return ValoresTrimestrais{
 		Ano: v.Ano,
-		T1:  v.T1 * other.T1,
-		T2:  v.T2 * other.T2,
-		T3:  v.T3 * other.T3,
-		T4:  v.T4 * other.T4,
+		T1:  v.T1.Mul(other.T1),
+		T2:  v.T2.Mul(other.T2),
+		T3:  v.T3.Mul(other.T3),
+		T4:  v.T4.Mul(other.T4),
 	}
 }
 
+// Div divide os valores de cada trimestre; a divisão por zero resulta em
+// zero.
 func (v ValoresTrimestrais) Div(other ValoresTrimestrais) ValoresTrimestrais {
-	safeDiv := func(num, divisor float64) float64 {
-		if divisor == 0 {
-			return 0.0
-		}
-		return num / divisor
-	}
 	return ValoresTrimestrais{
 		Ano: v.Ano,
-		T1:  safeDiv(v.T1, other.T1),
-		T2:  safeDiv(v.T2, other.T2),
-		T3:  safeDiv(v.T3, other.T3),
-		T4:  safeDiv(v.T4, other.T4),
+		T1:  v.T1.Div(other.T1),
+		T2:  v.T2.Div(other.T2),
+		T3:  v.T3.Div(other.T3),
+		T4:  v.T4.Div(other.T4),
 	}
 }
 
-func (v ValoresTrimestrais) MultNum(factor float64) ValoresTrimestrais {
+func (v ValoresTrimestrais) MultNum(factor Decimal) ValoresTrimestrais {
 	return ValoresTrimestrais{
 		Ano: v.Ano,
-		T1:  v.T1 * factor,
-		T2:  v.T2 * factor,
-		T3:  v.T3 * factor,
-		T4:  v.T4 * factor,
+		T1:  v.T1.Mul(factor),
+		T2:  v.T2.Mul(factor),
+		T3:  v.T3.Mul(factor),
+		T4:  v.T4.Mul(factor),
 	}
 }
 
-func (v ValoresTrimestrais) DivNum(divisor float64) ValoresTrimestrais {
-	if divisor == 0 {
-		return ValoresTrimestrais{v.Ano, 0.0, 0.0, 0.0, 0.0}
-	}
+func (v ValoresTrimestrais) DivNum(divisor Decimal) ValoresTrimestrais {
 	return ValoresTrimestrais{
 		Ano: v.Ano,
-		T1:  v.T1 / divisor,
-		T2:  v.T2 / divisor,
-		T3:  v.T3 / divisor,
-		T4:  v.T4 / divisor,
+		T1:  v.T1.Div(divisor),
+		T2:  v.T2.Div(divisor),
+		T3:  v.T3.Div(divisor),
+		T4:  v.T4.Div(divisor),
 	}
 }
 
@@ -130,9 +124,9 @@ func OpVTs(op rune, v1, v2 []ValoresTrimestrais) []ValoresTrimestrais {
 			p2Ptr = v2[j]
 		} else if pares[k].p1 && !pares[k].p2 {
 			p1Ptr = v1[i]
-			p2Ptr = ValoresTrimestrais{v1[i].Ano, 0.0, 0.0, 0.0, 0.0}
+			p2Ptr = ValoresTrimestrais{Ano: v1[i].Ano}
 		} else if !pares[k].p1 && pares[k].p2 {
-			p1Ptr = ValoresTrimestrais{v2[j].Ano, 0.0, 0.0, 0.0, 0.0}
+			p1Ptr = ValoresTrimestrais{Ano: v2[j].Ano}
 			p2Ptr = v2[j]
 		} else {
 			continue
@@ -250,11 +244,11 @@ func equalizarValores(ano int, v1, v2 ValoresTrimestrais) (ValoresTrimestrais, b
 	v.Ano = ano
 	ok := true
 
-	check := func(v1Tn, v2Tn float64) (float64, bool) {
-		if !ok || (v1Tn != 0.0 && v2Tn != 0.0) {
-			return 0.0, false
+	check := func(v1Tn, v2Tn Decimal) (Decimal, bool) {
+		if !ok || (!v1Tn.IsZero() && !v2Tn.IsZero()) {
+			return Decimal{}, false
 		}
-		if v1Tn != 0.0 && v2Tn == 0.0 {
+		if !v1Tn.IsZero() && v2Tn.IsZero() {
 			return v1Tn, true
 		}
 		return v2Tn, true
@@ -279,7 +273,7 @@ func valorAno(ano int, valores []ValoresTrimestrais) (ValoresTrimestrais, bool)
 
 func Zerado(valores []ValoresTrimestrais) bool {
 	for _, v := range valores {
-		if v.T1 != 0 || v.T2 != 0 || v.T3 != 0 || v.T4 != 0 {
+		if !v.T1.IsZero() || !v.T2.IsZero() || !v.T3.IsZero() || !v.T4.IsZero() {
 			return false
 		}
 	}
@@ -297,16 +291,16 @@ func TrimestresComDados(itr []InformeTrimestral) []bool {
 					continue
 				}
 				i := (v.Ano - minAno) * 4
-				if !colunas[i+0] && v.T1 != 0.0 {
+				if !colunas[i+0] && !v.T1.IsZero() {
 					colunas[i+0] = true
 				}
-				if v.T2 != 0.0 {
+				if !v.T2.IsZero() {
 					colunas[i+1] = true
 				}
-				if v.T3 != 0.0 {
+				if !v.T3.IsZero() {
 					colunas[i+2] = true
 				}
-				if v.T4 != 0.0 {
+				if !v.T4.IsZero() {
 					colunas[i+3] = true
 				}
 			}
@@ -22,9 +22,9 @@ func Test_Zerado(t *testing.T) {
 			name: "testar zerado",
 			args: args{
 				valores: []ValoresTrimestrais{
-					{Ano: 2020, T1: 0, T2: 0, T3: 0, T4: 0},
-					{Ano: 2021, T1: 0, T2: 0, T3: 0, T4: 0},
-					{Ano: 2022, T1: 0, T2: 0, T3: 0, T4: 0},
+					vt(2020, 0, 0, 0, 0),
+					vt(2021, 0, 0, 0, 0),
+					vt(2022, 0, 0, 0, 0),
 				},
 			},
 			want: true,
@@ -33,9 +33,9 @@ func Test_Zerado(t *testing.T) {
 			name: "testar não zerado 1",
 			args: args{
 				valores: []ValoresTrimestrais{
-					{Ano: 2020, T1: 0, T2: 0, T3: 0, T4: 0},
-					{Ano: 2021, T1: 0, T2: 0, T3: 0, T4: 0},
-					{Ano: 2022, T1: 0, T2: 1, T3: 0, T4: 0},
+					vt(2020, 0, 0, 0, 0),
+					vt(2021, 0, 0, 0, 0),
+					vt(2022, 0, 1, 0, 0),
 				},
 			},
 			want: false,
@@ -44,10 +44,10 @@ func Test_Zerado(t *testing.T) {
 			name: "testar não zerado 2",
 			args: args{
 				valores: []ValoresTrimestrais{
-					{Ano: 2020, T1: 0, T2: 0, T3: 0, T4: 0},
-					{Ano: 2021, T1: 0, T2: 0, T3: 0, T4: 0},
-					{Ano: 2022, T1: 0, T2: 0, T3: 0, T4: 0},
-					{Ano: 2023, T1: 10.1, T2: 0, T3: 0, T4: 0},
+					vt(2020, 0, 0, 0, 0),
+					vt(2021, 0, 0, 0, 0),
+					vt(2022, 0, 0, 0, 0),
+					vt(2023, 10.1, 0, 0, 0),
 				},
 			},
 			want: false,
@@ -87,45 +87,45 @@ func TestAddVTs(t *testing.T) {
 			name: "deveria somar anos balanceados",
 			args: args{
 				v1: []ValoresTrimestrais{
-					{Ano: 2023, T1: 10.0, T2: 20.0, T3: 30.0, T4: 40.0},
-					{Ano: 2024, T1: 15.0, T2: 25.0, T3: 35.0, T4: 45.0},
+					vt(2023, 10.0, 20.0, 30.0, 40.0),
+					vt(2024, 15.0, 25.0, 35.0, 45.0),
 				},
 				v2: []ValoresTrimestrais{
-					{Ano: 2023, T1: 5.0, T2: 10.0, T3: 15.0, T4: 20.0},
-					{Ano: 2024, T1: 7.0, T2: 12.0, T3: 17.0, T4: 22.0},
+					vt(2023, 5.0, 10.0, 15.0, 20.0),
+					vt(2024, 7.0, 12.0, 17.0, 22.0),
 				},
 			},
-			want: []ValoresTrimestrais{{2023, 15, 30, 45, 60}, {2024, 22, 37, 52, 67}},
+			want: []ValoresTrimestrais{vt(2023, 15, 30, 45, 60), vt(2024, 22, 37, 52, 67)},
 		},
 		{
 			name: "deveria somar anos desbalanceados (final)",
 			args: args{
 				v1: []ValoresTrimestrais{
-					{Ano: 2022, T1: 10.0, T2: 20.0, T3: 30.0, T4: 40.0},
-					{Ano: 2024, T1: 15.0, T2: 25.0, T3: 35.0, T4: 45.0},
+					vt(2022, 10.0, 20.0, 30.0, 40.0),
+					vt(2024, 15.0, 25.0, 35.0, 45.0),
 				},
 				v2: []ValoresTrimestrais{
-					{Ano: 2023, T1: 5.0, T2: 10.0, T3: 15.0, T4: 20.0},
-					{Ano: 2024, T1: 7.0, T2: 12.0, T3: 17.0, T4: 22.0},
+					vt(2023, 5.0, 10.0, 15.0, 20.0),
+					vt(2024, 7.0, 12.0, 17.0, 22.0),
 				},
 			},
-			want: []ValoresTrimestrais{{2022, 10, 20, 30, 40}, {2023, 5, 10, 15, 20}, {2024, 22, 37, 52, 67}},
+			want: []ValoresTrimestrais{vt(2022, 10, 20, 30, 40), vt(2023, 5, 10, 15, 20), vt(2024, 22, 37, 52, 67)},
 		},
 		{
 			name: "deveria somar anos desbalanceados (início')",
 			args: args{
-				v1: []ValoresTrimestrais{{2010, 1, 1, 1, 1}, {2012, 10, 10, 10, 10}},
-				v2: []ValoresTrimestrais{{2011, 2, 2, 2, 2}, {2012, 5, 5, 5, 5}, {2023, 100, 100, 100, 100}},
+				v1: []ValoresTrimestrais{vt(2010, 1, 1, 1, 1), vt(2012, 10, 10, 10, 10)},
+				v2: []ValoresTrimestrais{vt(2011, 2, 2, 2, 2), vt(2012, 5, 5, 5, 5), vt(2023, 100, 100, 100, 100)},
 			},
-			want: []ValoresTrimestrais{{2010, 1, 1, 1, 1}, {2011, 2, 2, 2, 2}, {2012, 15, 15, 15, 15}, {2023, 100, 100, 100, 100}},
+			want: []ValoresTrimestrais{vt(2010, 1, 1, 1, 1), vt(2011, 2, 2, 2, 2), vt(2012, 15, 15, 15, 15), vt(2023, 100, 100, 100, 100)},
 		},
 		{
 			name: "deveria somar anos desbalanceados (meio)",
 			args: args{
-				v1: []ValoresTrimestrais{{2011, 2, 2, 2, 2}, {2012, 5, 5, 5, 5}, {2023, 100, 100, 100, 100}},
-				v2: []ValoresTrimestrais{{2011, 1, 1, 1, 1}, {2023, 10, 10, 10, 10}},
+				v1: []ValoresTrimestrais{vt(2011, 2, 2, 2, 2), vt(2012, 5, 5, 5, 5), vt(2023, 100, 100, 100, 100)},
+				v2: []ValoresTrimestrais{vt(2011, 1, 1, 1, 1), vt(2023, 10, 10, 10, 10)},
 			},
-			want: []ValoresTrimestrais{{2011, 3, 3, 3, 3}, {2012, 5, 5, 5, 5}, {2023, 110, 110, 110, 110}},
+			want: []ValoresTrimestrais{vt(2011, 3, 3, 3, 3), vt(2012, 5, 5, 5, 5), vt(2023, 110, 110, 110, 110)},
 		},
 	}
 	for _, tt := range tests {
@@ -150,18 +150,18 @@ func TestSubVTs(t *testing.T) {
 		{
 			name: "deveria subtrair anos balanceados",
 			args: args{
-				v1: []ValoresTrimestrais{{2010, 10, 10, 10, 10}, {2011, 20, 20, 20, 20}, {2020, 30, 30, 30, 3}},
-				v2: []ValoresTrimestrais{{2010, 2, 2, 2, 2}, {2011, 10, 2, 2, 2}, {2020, 2, 2, 2, 2}},
+				v1: []ValoresTrimestrais{vt(2010, 10, 10, 10, 10), vt(2011, 20, 20, 20, 20), vt(2020, 30, 30, 30, 3)},
+				v2: []ValoresTrimestrais{vt(2010, 2, 2, 2, 2), vt(2011, 10, 2, 2, 2), vt(2020, 2, 2, 2, 2)},
 			},
-			want: []ValoresTrimestrais{{2010, 8, 8, 8, 8}, {2011, 10, 18, 18, 18}, {2020, 28, 28, 28, 1}},
+			want: []ValoresTrimestrais{vt(2010, 8, 8, 8, 8), vt(2011, 10, 18, 18, 18), vt(2020, 28, 28, 28, 1)},
 		},
 		{
 			name: "deveria subtrair anos desbalanceados",
 			args: args{
-				v1: []ValoresTrimestrais{{2011, 2, 2, 2, 2}, {2012, 5, 5, 5, 5}, {2023, 100, 100, 100, 100}},
-				v2: []ValoresTrimestrais{{2011, 1, 1, 1, 1}, {2023, 110, 110, 110, 110}},
+				v1: []ValoresTrimestrais{vt(2011, 2, 2, 2, 2), vt(2012, 5, 5, 5, 5), vt(2023, 100, 100, 100, 100)},
+				v2: []ValoresTrimestrais{vt(2011, 1, 1, 1, 1), vt(2023, 110, 110, 110, 110)},
 			},
-			want: []ValoresTrimestrais{{2011, 1, 1, 1, 1}, {2012, 5, 5, 5, 5}, {2023, -10, -10, -10, -10}},
+			want: []ValoresTrimestrais{vt(2011, 1, 1, 1, 1), vt(2012, 5, 5, 5, 5), vt(2023, -10, -10, -10, -10)},
 		},
 	}
 	for _, tt := range tests {
@@ -186,18 +186,18 @@ func TestDivVTs(t *testing.T) {
 		{
 			name: "deveria dividir anos balanceados",
 			args: args{
-				v1: []ValoresTrimestrais{{2010, 10, 10, 10, 10}, {2011, 20, 20, 20, 20}, {2020, 30, 30, 30, 3}},
-				v2: []ValoresTrimestrais{{2010, 2, 2, 2, 2}, {2011, 10, 2, 2, 2}, {2020, 2, 2, 2, 2}},
+				v1: []ValoresTrimestrais{vt(2010, 10, 10, 10, 10), vt(2011, 20, 20, 20, 20), vt(2020, 30, 30, 30, 3)},
+				v2: []ValoresTrimestrais{vt(2010, 2, 2, 2, 2), vt(2011, 10, 2, 2, 2), vt(2020, 2, 2, 2, 2)},
 			},
-			want: []ValoresTrimestrais{{2010, 5, 5, 5, 5}, {2011, 2, 10, 10, 10}, {2020, 15, 15, 15, 1.5}},
+			want: []ValoresTrimestrais{vt(2010, 5, 5, 5, 5), vt(2011, 2, 10, 10, 10), vt(2020, 15, 15, 15, 1.5)},
 		},
 		{
 			name: "deveria dividir anos desbalanceados",
 			args: args{
-				v1: []ValoresTrimestrais{{2011, 2, 2, 2, 2}, {2012, 5, 5, 5, 5}, {2023, 100, 100, 100, 100}},
-				v2: []ValoresTrimestrais{{2011, 1, 1, 1, 1}, {2023, 10, 10, 10, 10}},
+				v1: []ValoresTrimestrais{vt(2011, 2, 2, 2, 2), vt(2012, 5, 5, 5, 5), vt(2023, 100, 100, 100, 100)},
+				v2: []ValoresTrimestrais{vt(2011, 1, 1, 1, 1), vt(2023, 10, 10, 10, 10)},
 			},
-			want: []ValoresTrimestrais{{2011, 2, 2, 2, 2}, {2012, 0, 0, 0, 0}, {2023, 10, 10, 10, 10}},
+			want: []ValoresTrimestrais{vt(2011, 2, 2, 2, 2), vt(2012, 0, 0, 0, 0), vt(2023, 10, 10, 10, 10)},
 		},
 	}
 	for _, tt := range tests {
@@ -208,3 +208,14 @@ func TestDivVTs(t *testing.T) {
 		})
 	}
 }
+
+// vt cria os ValoresTrimestrais de um ano a partir de valores float64.
+func vt(ano int, t1, t2, t3, t4 float64) ValoresTrimestrais {
+	return ValoresTrimestrais{
+		Ano: ano,
+		T1:  DecimalDeFloat(t1),
+		T2:  DecimalDeFloat(t2),
+		T3:  DecimalDeFloat(t3),
+		T4:  DecimalDeFloat(t4),
+	}
+}
@@ -5,6 +5,9 @@
 package rapina
 
 import (
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"golang.org/x/text/language"
@@ -13,24 +16,152 @@ import (
 
 // Empresa ------------------------------------------------
 type Empresa struct {
-	CNPJ string
-	Nome string
+	CNPJ    string
+	Nome    string
+	Tickers []string `db:"-"` // Códigos de negociação (ex.: PETR3, PETR4)
+
+	// Dados do cadastro de companhias abertas da CVM
+	CódigoCVM        string `db:"-"` // 9512
+	NomeComercial    string `db:"-"` // PETROBRAS
+	Setor            string `db:"-"` // Petróleo e Gás
+	Situação         string `db:"-"` // ATIVO, CANCELADA, SUSPENSO(A) - DECISÃO ADM...
+	DataRegistro     string `db:"-"` // AAAA-MM-DD
+	DataCancelamento string `db:"-"` // AAAA-MM-DD
+	Auditor          string `db:"-"`
 }
 
 func (e Empresa) String() string {
 	return e.CNPJ + " - " + e.Nome
 }
 
+// Cancelada retorna verdadeiro se o registro de companhia aberta da empresa
+// foi cancelado na CVM.
+func (e Empresa) Cancelada() bool {
+	return e.DataCancelamento != "" || strings.HasPrefix(NormalizeString(e.Situação), "cancelad")
+}
+
+// ValorMobiliário ----------------------------------------
+// Liga o código de negociação na B3 (ticker) à empresa emissora.
+type ValorMobiliário struct {
+	Ticker    string // PETR4
+	Classe    string // ON, PN, PNA, UNT...
+	ISIN      string // BRPETRACNPR6
+	CNPJ      string // 33.000.167/0001-01
+	CódigoCVM string // 9512
+}
+
+// QuantidadeAções ----------------------------------------
+// Composição do capital social de uma empresa numa data.
+type QuantidadeAções struct {
+	CNPJ          string
+	Data          Data
+	Ordinárias    int64
+	Preferenciais int64
+	TesourariaON  int64
+	TesourariaPN  int64
+}
+
+// EmCirculação retorna a quantidade de ações ordinárias e preferenciais,
+// excluídas as ações em tesouraria.
+func (q QuantidadeAções) EmCirculação() (on, pn int64) {
+	return q.Ordinárias - q.TesourariaON, q.Preferenciais - q.TesourariaPN
+}
+
 // Dinheiro -----------------------------------------------
 type Dinheiro struct {
 	Moeda  string
-	Valor  float64
-	Escala int
+	Valor  Decimal
+	Escala int // 1, 1000 (MIL) ou 1000000 (MILHÃO); 0 equivale a 1
+}
+
+// Moedas dos valores monetários, como gravadas no banco de dados.
+const (
+	Real  = "R$"
+	Dólar = "US$"
+	Euro  = "€"
+)
+
+var (
+	ErrEscalaInválida   = errors.New("escala inválida")
+	ErrMoedasDiferentes = errors.New("moedas diferentes")
+)
+
+// NovaEscala converte o nome da escala (UNIDADE, MIL ou MILHÃO, como em
+// ESCALA_MOEDA nos arquivos da CVM) no seu valor (1, 1000 ou 1000000).
+func NovaEscala(s string) (int, error) {
+	switch NormalizeString(strings.TrimSpace(s)) {
+	case "unidade", "1":
+		return 1, nil
+	case "mil", "1000":
+		return 1000, nil
+	case "milhao", "1000000":
+		return 1e6, nil
+	}
+	return 0, fmt.Errorf("%w: %q", ErrEscalaInválida, s)
+}
+
+// NovaMoeda converte o nome da moeda (REAL, DÓLAR ou EURO, como em MOEDA nos
+// arquivos da CVM) no seu símbolo. Símbolos e moedas desconhecidas são
+// retornados sem alteração.
+func NovaMoeda(s string) string {
+	switch NormalizeString(strings.TrimSpace(s)) {
+	case "real", "reais", "brl":
+		return Real
+	case "dolar", "dolares", "dolaramericano", "usd":
+		return Dólar
+	case "euro", "euros", "eur":
+		return Euro
+	}
+	return s
+}
+
+// NaEscala retorna o mesmo valor expresso em outra escala (ex.: R$ 1.500
+// mil => R$ 1,5 milhão).
+func (d Dinheiro) NaEscala(escala int) Dinheiro {
+	escala = max(escala, 1)
+	if escala != d.escala() {
+		d.Valor = d.Valor.Mul(DecimalDeInt(int64(d.escala()))).Div(DecimalDeInt(int64(escala)))
+	}
+	d.Escala = escala
+	return d
+}
+
+// Somar retorna a soma dos valores na escala de d, ou ErrMoedasDiferentes se
+// as moedas não forem iguais.
+func (d Dinheiro) Somar(outro Dinheiro) (Dinheiro, error) {
+	if d.Moeda != outro.Moeda {
+		return d, fmt.Errorf("%w: %s e %s", ErrMoedasDiferentes, d.Moeda, outro.Moeda)
+	}
+	d.Valor = d.Valor.Add(outro.NaEscala(d.escala()).Valor)
+	return d, nil
+}
+
+// Converter retorna o valor convertido para a moeda informada, sendo taxa o
+// valor de uma unidade da moeda de d na nova moeda.
+func (d Dinheiro) Converter(moeda string, taxa Decimal) Dinheiro {
+	return Dinheiro{Moeda: moeda, Valor: d.Valor.Mul(taxa), Escala: d.Escala}
+}
+
+// Unidade retorna a moeda e a escala dos valores (ex.: "R$ mil").
+func (d Dinheiro) Unidade() string {
+	switch d.escala() {
+	case 1000:
+		return d.Moeda + " mil"
+	case 1e6:
+		return d.Moeda + " milhões"
+	case 1:
+		return d.Moeda
+	}
+	return fmt.Sprintf("%s × %d", d.Moeda, d.escala())
 }
 
 func (d Dinheiro) String() string {
 	p := message.NewPrinter(language.BrazilianPortuguese)
-	return p.Sprintf(`%s %.2f`, d.Moeda, d.Valor*float64(d.Escala))
+	return p.Sprintf(`%s %.2f`, d.Moeda, d.Valor.Mul(DecimalDeInt(int64(d.escala()))).Float64())
+}
+
+func (d Dinheiro) escala() int {
+	return max(d.Escala, 1)
 }
 
 // Data ---------------------------------------------------
@@ -0,0 +1,84 @@
+// SPDX-FileCopyrightText: 2023 Adriano Prado <dev@dude333.com>
+//
+// SPDX-License-Identifier: MIT
+
+package rapina
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNovaEscala(t *testing.T) {
+	tests := []struct {
+		s    string
+		want int
+		err  error
+	}{
+		{"UNIDADE", 1, nil},
+		{"mil", 1000, nil},
+		{"MILHÃO", 1e6, nil},
+		{"milhao", 1e6, nil},
+		{"bilhão", 0, ErrEscalaInválida},
+		{"", 0, ErrEscalaInválida},
+	}
+	for _, tt := range tests {
+		got, err := NovaEscala(tt.s)
+		if got != tt.want || !errors.Is(err, tt.err) {
+			t.Errorf("NovaEscala(%q) = %d, %v, want %d, %v", tt.s, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestNovaMoeda(t *testing.T) {
+	for s, want := range map[string]string{
+		"REAL":  Real,
+		"Dólar": Dólar,
+		"DOLAR": Dólar,
+		"EURO":  Euro,
+		"US$":   Dólar,
+		"IENE":  "IENE",
+	} {
+		if got := NovaMoeda(s); got != want {
+			t.Errorf("NovaMoeda(%q) = %q, want %q", s, got, want)
+		}
+	}
+}
+
+func TestDinheiro(t *testing.T) {
+	mil := Dinheiro{Moeda: Real, Valor: dec("1500"), Escala: 1000}
+
+	if got := mil.NaEscala(1e6); got != (Dinheiro{Moeda: Real, Valor: dec("1.5"), Escala: 1e6}) {
+		t.Errorf("NaEscala(1e6) = %+v", got)
+	}
+	if got := mil.NaEscala(0); got != (Dinheiro{Moeda: Real, Valor: dec("1500000"), Escala: 1}) {
+		t.Errorf("NaEscala(0) = %+v", got)
+	}
+
+	got, err := mil.Somar(Dinheiro{Moeda: Real, Valor: dec("250"), Escala: 1})
+	if err != nil || got.Valor != dec("1500.25") || got.Escala != 1000 {
+		t.Errorf("Somar() = %+v, %v", got, err)
+	}
+	if _, err := mil.Somar(Dinheiro{Moeda: Dólar, Valor: dec("1")}); !errors.Is(err, ErrMoedasDiferentes) {
+		t.Errorf("Somar() error = %v, want %v", err, ErrMoedasDiferentes)
+	}
+
+	usd := Dinheiro{Moeda: Dólar, Valor: dec("10"), Escala: 1000}
+	if got := usd.Converter(Real, dec("4.8413")); got != (Dinheiro{Moeda: Real, Valor: dec("48.413"), Escala: 1000}) {
+		t.Errorf("Converter() = %+v", got)
+	}
+
+	for d, want := range map[Dinheiro]string{
+		mil:                         "R$ mil",
+		{Moeda: Dólar, Escala: 1e6}: "US$ milhões",
+		{Moeda: Real}:               "R$",
+		{Moeda: Real, Escala: 1}:    "R$",
+	} {
+		if got := d.Unidade(); got != want {
+			t.Errorf("Unidade() = %q, want %q", got, want)
+		}
+	}
+	if got := (Dinheiro{Moeda: Real, Valor: dec("1.5")}).String(); got != "R$ 1,50" {
+		t.Errorf("String() = %q", got)
+	}
+}